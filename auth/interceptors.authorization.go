@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"context"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/storage"
+	"slices"
+
+	charm "github.com/charmbracelet/log"
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// scope is what a request is about, the caller roles are resolved against it
+type scope int
+
+const (
+	scopeNone scope = iota
+	// scopeGame requests carry a game_uid
+	scopeGame
+	// scopeMap requests carry the uid of a map which belongs to a game
+	scopeMap
+	// scopeUser requests are about a single user
+	scopeUser
+)
+
+type policy struct {
+	allow []Role
+	scope scope
+}
+
+var gameMembers = []Role{RoleGameOwner, RoleParticipant, RoleSpectator, RoleDungeonMaster}
+
+// policies lists who may call every rpc, the system is always allowed and rpcs without a policy are denied
+var policies = map[string]policy{
+	// the event server checks the caller is a member of the game the event belongs to
+	v1.Events_GetEvent_FullMethodName:   {allow: []Role{RoleAuthenticated}},
+	v1.Events_ListEvents_FullMethodName: {allow: gameMembers, scope: scopeGame},
+	// the event bus checks every submitted event is for the caller's own actor and that the actor takes part in the game
+	v1.Events_Submit_FullMethodName:    {allow: []Role{RoleAuthenticated}},
+	v1.Events_Subscribe_FullMethodName: {allow: []Role{RoleAuthenticated}},
+	v1.Events_WatchGame_FullMethodName: {allow: gameMembers, scope: scopeGame},
+
+	v1.Games_CreateGame_FullMethodName: {allow: []Role{RoleAuthenticated}},
+	v1.Games_GetGame_FullMethodName:    {allow: gameMembers, scope: scopeGame},
+	v1.Games_JoinGame_FullMethodName:   {allow: []Role{RoleAuthenticated}},
+	v1.Games_LockGame_FullMethodName:   {allow: []Role{RoleGameOwner, RoleParticipant, RoleDungeonMaster}, scope: scopeGame},
+	v1.Games_UnlockGame_FullMethodName: {allow: []Role{RoleGameOwner, RoleParticipant, RoleDungeonMaster}, scope: scopeGame},
+	v1.Games_RenewLock_FullMethodName:  {allow: []Role{RoleGameOwner, RoleParticipant, RoleDungeonMaster}, scope: scopeGame},
+	v1.Games_ListLocks_FullMethodName:  {allow: []Role{RoleSystem}},
+	v1.Games_BreakLock_FullMethodName:  {allow: []Role{RoleSystem}},
+	v1.Games_EndGame_FullMethodName:    {allow: []Role{RoleGameOwner, RoleDungeonMaster}, scope: scopeGame},
+
+	// the whole map is only for those running the game, players see what they discovered through GetKnownMap
+	// and the map server limits their peeks to the same
+	v1.Maps_CreateMap_FullMethodName:              {allow: []Role{RoleGameOwner, RoleDungeonMaster}, scope: scopeGame},
+	v1.Maps_GetMap_FullMethodName:                 {allow: gameMembers, scope: scopeMap},
+	v1.Maps_GetMapDetail_FullMethodName:           {allow: []Role{RoleGameOwner, RoleDungeonMaster}, scope: scopeMap},
+	v1.Maps_GetMapGenerationStatus_FullMethodName: {allow: gameMembers, scope: scopeMap},
+	v1.Maps_GetPosition_FullMethodName:            {allow: []Role{RoleAuthenticated}},
+	v1.Maps_PeekCoordinate_FullMethodName:         {allow: gameMembers, scope: scopeGame},
+	v1.Maps_PlayerMovement_FullMethodName:         {allow: []Role{RoleParticipant, RoleDungeonMaster}, scope: scopeGame},
+	v1.Maps_GetKnownMap_FullMethodName:            {allow: gameMembers, scope: scopeGame},
+
+	v1.Users_RegisterUser_FullMethodName: {allow: []Role{RoleSelf}, scope: scopeUser},
+	// the user server leaves actors of discord, slack and telnet to the front ends, players could otherwise claim someone else's identity
+	v1.Users_RegisterActor_FullMethodName: {allow: []Role{RoleSelf}, scope: scopeUser},
+	v1.Users_GetUser_FullMethodName:       {allow: []Role{RoleAuthenticated}},
+	v1.Users_GetActor_FullMethodName:      {allow: []Role{RoleAuthenticated}},
+	v1.Users_GetActors_FullMethodName:     {allow: []Role{RoleSelf}, scope: scopeUser},
+	// front ends look up who is talking to them before they can act on their behalf
+	v1.Users_GetActorBySource_FullMethodName: {allow: []Role{RoleSystem}},
+	v1.Users_IssueApiKey_FullMethodName:      {allow: []Role{RoleSelf}, scope: scopeUser},
+	// the user server checks the key belongs to the caller
+	v1.Users_RevokeApiKey_FullMethodName: {allow: []Role{RoleAuthenticated}},
+	v1.Users_IssueToken_FullMethodName:   {allow: []Role{RoleAuthenticated}},
+	// linking always acts on the caller's own user, the user server checks the actors involved
+	v1.Users_CreateLinkCode_FullMethodName:   {allow: []Role{RoleAuthenticated}},
+	v1.Users_RedeemLinkCode_FullMethodName:   {allow: []Role{RoleAuthenticated}},
+	v1.Users_UnlinkActor_FullMethodName:      {allow: []Role{RoleAuthenticated}},
+	v1.Users_ListLinkedActors_FullMethodName: {allow: []Role{RoleAuthenticated}},
+}
+
+// Authorizer checks every rpc against its policy and redacts what the caller may not see from the response
+// it must run after the Authenticator
+type Authorizer struct {
+	games storage.GameStore
+	maps  storage.MapStore
+	log   *charm.Logger
+}
+
+func NewAuthorizer(games storage.GameStore, maps storage.MapStore) *Authorizer {
+	return &Authorizer{
+		games: games,
+		maps:  maps,
+		log:   common.GetLogger("server.authorization"),
+	}
+}
+
+func (a *Authorizer) UnaryServerAuthorizeFunc(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if slices.Contains(reflectionMethods, info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	roles, err := a.authorize(ctx, info.FullMethod, req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := handler(WithRoles(ctx, roles), req)
+	if err != nil {
+		return nil, err
+	}
+	return Redact(res, roles), nil
+}
+
+func (a *Authorizer) StreamServerAuthorizeFunc(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if slices.Contains(reflectionMethods, info.FullMethod) {
+		return handler(srv, stream)
+	}
+
+	wrapped := &authorizedStream{
+		WrappedServerStream: middleware.WrapServerStream(stream),
+		authorizer:          a,
+		method:              info.FullMethod,
+	}
+	return handler(srv, wrapped)
+}
+
+// authorize resolves the roles of the caller and checks them against the policy of the method
+func (a *Authorizer) authorize(ctx context.Context, method string, req any) ([]Role, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p, ok := policies[method]
+	if !ok {
+		a.log.Error("no policy for method", info.LoggingContext("method", method)...)
+		return nil, status.Error(codes.PermissionDenied, "method is not available")
+	}
+
+	roles := CallerRoles(info)
+	if slices.Contains(roles, RoleSystem) {
+		return roles, nil
+	}
+
+	scoped, err := a.scopedRoles(ctx, info, p.scope, req)
+	if err != nil {
+		a.log.Warn("failed to resolve roles", info.LoggingContext("method", method, "error", err)...)
+		return nil, err
+	}
+	roles = append(roles, scoped...)
+
+	for _, role := range p.allow {
+		if slices.Contains(roles, role) {
+			return roles, nil
+		}
+	}
+
+	a.log.Warn("permission denied", info.LoggingContext("method", method, "roles", roles)...)
+	return nil, status.Error(codes.PermissionDenied, "permission denied")
+}
+
+func (a *Authorizer) scopedRoles(ctx context.Context, info *common.OverseerContextInformation, s scope, req any) ([]Role, error) {
+	switch s {
+	case scopeUser:
+		if userScoped(req) == info.User.GetUid() {
+			return []Role{RoleSelf}, nil
+		}
+		return nil, nil
+	case scopeGame:
+		scoped, ok := req.(interface{ GetGameUid() string })
+		if !ok {
+			return nil, status.Error(codes.Internal, "request has no game")
+		}
+		return a.gameRoles(ctx, info, scoped.GetGameUid())
+	case scopeMap:
+		scoped, ok := req.(*v1.GetMapRequest)
+		if !ok {
+			return nil, status.Error(codes.Internal, "request has no map")
+		}
+		gameMap, err := a.maps.GetMap(ctx, scoped.GetUid())
+		if err != nil {
+			return nil, err
+		}
+		return a.gameRoles(ctx, info, gameMap.GetGameUid())
+	default:
+		return nil, nil
+	}
+}
+
+func (a *Authorizer) gameRoles(ctx context.Context, info *common.OverseerContextInformation, gameUid string) ([]Role, error) {
+	game, err := a.games.GetGame(ctx, gameUid)
+	if err != nil {
+		return nil, err
+	}
+	return GameRoles(info, game), nil
+}
+
+// userScoped returns the user a user scoped request is about
+func userScoped(req any) string {
+	switch r := req.(type) {
+	case *v1.User:
+		return r.GetUid()
+	case *v1.RegisterActorRequest:
+		return r.GetUserId()
+	case *v1.IssueApiKeyRequest:
+		return r.GetUserId()
+	default:
+		return ""
+	}
+}
+
+// authorizedStream authorizes the first message received on the stream, for server streams that is the request
+type authorizedStream struct {
+	*middleware.WrappedServerStream
+	authorizer *Authorizer
+	method     string
+	roles      []Role
+	authorized bool
+}
+
+func (s *authorizedStream) RecvMsg(m any) error {
+	if err := s.WrappedServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.authorized {
+		return nil
+	}
+
+	roles, err := s.authorizer.authorize(s.Context(), s.method, m)
+	if err != nil {
+		return err
+	}
+	s.roles = roles
+	s.authorized = true
+	s.WrappedContext = WithRoles(s.WrappedServerStream.Context(), roles)
+	return nil
+}
+
+func (s *authorizedStream) SendMsg(m any) error {
+	return s.WrappedServerStream.SendMsg(Redact(m, s.roles))
+}
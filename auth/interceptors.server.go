@@ -2,8 +2,11 @@ package auth
 
 import (
 	"context"
+	v1 "overseer/build/go"
 	"overseer/common"
+	"overseer/storage"
 	"slices"
+	"strings"
 
 	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/metadata"
@@ -13,7 +16,22 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-func UnaryServerAuthFunc(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+// Authenticator resolves the caller of every request from the system token, an api key or a signed bearer token
+type Authenticator struct {
+	keys   storage.ApiKeyStore
+	users  storage.UserStore
+	tokens *TokenSigner
+}
+
+func NewAuthenticator(keys storage.ApiKeyStore, users storage.UserStore, tokens *TokenSigner) *Authenticator {
+	return &Authenticator{
+		keys:   keys,
+		users:  users,
+		tokens: tokens,
+	}
+}
+
+func (a *Authenticator) UnaryServerAuthFunc(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 	if slices.Contains(reflectionMethods, info.FullMethod) {
 		common.GetLogger("server.auth.urnary").Info("auth bypassed for reflection method", "method", info.FullMethod)
 		return handler(ctx, req)
@@ -30,7 +48,7 @@ func UnaryServerAuthFunc(ctx context.Context, req any, info *grpc.UnaryServerInf
 		return nil, status.Error(codes.Unauthenticated, "peer was nil")
 	}
 
-	userInfo, err := authenticateFromMetadata(md)
+	userInfo, err := a.authenticateFromMetadata(ctx, md)
 	if err != nil {
 		common.GetLogger("server.auth.urnary").Error("failed to authenticate", "method", info.FullMethod, "error", err)
 		return nil, status.Error(codes.Unauthenticated, "failed to authenticate")
@@ -46,7 +64,7 @@ func UnaryServerAuthFunc(ctx context.Context, req any, info *grpc.UnaryServerInf
 	return handler(authCtx, req)
 }
 
-func StreamServerAuthFunc(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+func (a *Authenticator) StreamServerAuthFunc(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 	if slices.Contains(reflectionMethods, info.FullMethod) {
 		common.GetLogger("server.auth.stream").Info("auth bypassed for reflection method", "method", info.FullMethod)
 		return handler(srv, stream)
@@ -63,7 +81,7 @@ func StreamServerAuthFunc(srv any, stream grpc.ServerStream, info *grpc.StreamSe
 		return status.Error(codes.Unauthenticated, "peer was nil")
 	}
 
-	userInfo, err := authenticateFromMetadata(md)
+	userInfo, err := a.authenticateFromMetadata(stream.Context(), md)
 	if err != nil {
 		common.GetLogger("server.auth.stream").Error("failed to authenticate", "method", info.FullMethod, "error", err)
 		return status.Error(codes.Unauthenticated, "failed to authenticate")
@@ -82,14 +100,102 @@ func StreamServerAuthFunc(srv any, stream grpc.ServerStream, info *grpc.StreamSe
 	return handler(srv, wrapped)
 }
 
-func authenticateFromMetadata(md metadata.MD) (*common.OverseerContextInformation, error) {
+func (a *Authenticator) authenticateFromMetadata(ctx context.Context, md metadata.MD) (*common.OverseerContextInformation, error) {
 	if common.GetConfiguration().Server.EnableSystemToken {
 		if systemToken, ok := md[systemTokenKey]; ok {
 			if systemToken[0] == common.GetConfiguration().Server.SystemToken {
+				// front ends hold the system token and name the actor they are calling for
+				if actorId := md.Get(actorKey); actorId != "" {
+					info, err := a.onBehalfOf(ctx, actorId)
+					if err != nil {
+						return nil, err
+					}
+					info.Credential = common.CredentialSystemToken
+					return info, nil
+				}
 				return systemContextInformation, nil
 			}
 		}
 	}
-	// todo: implement authentication
-	return nil, status.Error(codes.Unauthenticated, "authentication not implemented")
+
+	if authorization := md.Get(authorizationKey); authorization != "" {
+		token, ok := strings.CutPrefix(authorization, bearerPrefix)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "unsupported authorization scheme")
+		}
+		claims, err := a.tokens.Verify(strings.TrimSpace(token))
+		if err != nil {
+			return nil, err
+		}
+		info, err := a.resolve(ctx, claims.Subject, claims.Actor)
+		if err != nil {
+			return nil, err
+		}
+		info.Credential = common.CredentialBearerToken
+		return info, nil
+	}
+
+	if secret := md.Get(apiKeyKey); secret != "" {
+		key, err := a.keys.GetApiKeyBySecretHash(ctx, HashApiKeySecret(secret))
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "unknown api key")
+		}
+		if key.RevokedAt != nil {
+			return nil, status.Error(codes.Unauthenticated, "api key revoked")
+		}
+		// keys bound to an actor always act as it, otherwise the caller picks one of the user's actors
+		actorId := key.GetActorId()
+		if actorId == "" {
+			actorId = md.Get(actorKey)
+		}
+		info, err := a.resolve(ctx, key.GetUserId(), actorId)
+		if err != nil {
+			return nil, err
+		}
+		info.Credential = common.CredentialApiKey
+		return info, nil
+	}
+
+	return nil, status.Error(codes.Unauthenticated, "no credentials provided")
+}
+
+// onBehalfOf builds the context information for an actor whose user is looked up as the system
+func (a *Authenticator) onBehalfOf(ctx context.Context, actorId string) (*common.OverseerContextInformation, error) {
+	lookupCtx, err := common.SetContextInformation(ctx, systemContextInformation)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := a.users.GetUserForActor(lookupCtx, actorId)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "unknown actor")
+	}
+	return a.resolve(ctx, owner.GetUid(), actorId)
+}
+
+// resolve builds the context information for a user, the actor must belong to the user
+func (a *Authenticator) resolve(ctx context.Context, userId string, actorId string) (*common.OverseerContextInformation, error) {
+	info := &common.OverseerContextInformation{User: &v1.User{Uid: userId}}
+	// the stores expect to know who is asking so authentication runs as the claimed user
+	lookupCtx, err := common.SetContextInformation(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = a.users.GetUser(lookupCtx, userId); err != nil {
+		return nil, status.Error(codes.Unauthenticated, "unknown user")
+	}
+	if actorId == "" {
+		return info, nil
+	}
+
+	owner, err := a.users.GetUserForActor(lookupCtx, actorId)
+	if err != nil || owner.GetUid() != userId {
+		return nil, status.Error(codes.Unauthenticated, "actor does not belong to user")
+	}
+	info.Actor, err = a.users.GetActor(lookupCtx, actorId)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "unknown actor")
+	}
+	return info, nil
 }
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"slices"
+)
+
+// Role is what a caller is allowed to do, game roles only hold for the game the request is about
+type Role string
+
+const (
+	// RoleSystem is the server itself and the operators holding the system token
+	RoleSystem Role = "system"
+	// RoleAuthenticated is held by every caller that got past authentication
+	RoleAuthenticated Role = "authenticated"
+	// RoleSelf is held when the request is about the calling user
+	RoleSelf Role = "self"
+	// RoleGameOwner is the actor that created the game
+	RoleGameOwner Role = "game_owner"
+	// RoleParticipant is a player in the game
+	RoleParticipant Role = "participant"
+	// RoleSpectator may follow the game but not act in it
+	RoleSpectator Role = "spectator"
+	// RoleDungeonMaster runs the game and may see everything on the map
+	RoleDungeonMaster Role = "dungeon_master"
+)
+
+// GameRoles returns the roles the caller holds in the game
+func GameRoles(info *common.OverseerContextInformation, game *v1.Game) []Role {
+	roles := make([]Role, 0)
+	if info == nil || game == nil || info.Actor == nil {
+		return roles
+	}
+
+	actorId := info.Actor.GetUid()
+	if game.GetOwnerUid() != "" && game.GetOwnerUid() == actorId {
+		roles = append(roles, RoleGameOwner)
+	}
+	if containsActor(game.GetParticipants(), actorId) {
+		roles = append(roles, RoleParticipant)
+	}
+	if containsActor(game.GetSpectators(), actorId) {
+		roles = append(roles, RoleSpectator)
+	}
+	if actorId == DungeonMasterActorId || (game.DungeonMasterUid != nil && game.GetDungeonMasterUid() == actorId) {
+		roles = append(roles, RoleDungeonMaster)
+	}
+	return roles
+}
+
+// IsGameMember reports whether the caller owns, plays, watches or runs the game
+func IsGameMember(info *common.OverseerContextInformation, game *v1.Game) bool {
+	for _, role := range GameRoles(info, game) {
+		if slices.Contains(gameMembers, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// CallerRoles returns the roles the caller holds regardless of any game
+func CallerRoles(info *common.OverseerContextInformation) []Role {
+	roles := []Role{RoleAuthenticated}
+	if info != nil && info.User.GetUid() == SystemUserId {
+		roles = append(roles, RoleSystem)
+	}
+	return roles
+}
+
+type rolesKey struct{}
+
+// WithRoles records the roles the caller was authorized with
+func WithRoles(ctx context.Context, roles []Role) context.Context {
+	return context.WithValue(ctx, rolesKey{}, roles)
+}
+
+// HasRole reports whether the caller was authorized with any of the roles
+func HasRole(ctx context.Context, roles ...Role) bool {
+	held, ok := ctx.Value(rolesKey{}).([]Role)
+	if !ok {
+		return false
+	}
+	for _, role := range roles {
+		if slices.Contains(held, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanSeeHiddenLore reports whether the roles include one allowed to read internal lore
+func CanSeeHiddenLore(roles []Role) bool {
+	return slices.Contains(roles, RoleSystem) || slices.Contains(roles, RoleDungeonMaster)
+}
+
+func containsActor(actors []*v1.Actor, actorId string) bool {
+	return slices.ContainsFunc(actors, func(a *v1.Actor) bool {
+		return a.GetUid() == actorId
+	})
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	v1 "overseer/build/go"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Redact strips what the roles may not see from a response, anything that is redacted is cloned first so stored messages are never touched
+func Redact(res any, roles []Role) any {
+	if CanSeeHiddenLore(roles) {
+		return res
+	}
+
+	switch r := res.(type) {
+	case *v1.MapDetail:
+		redacted := proto.Clone(r).(*v1.MapDetail)
+		for _, coordinate := range redacted.GetCoordinates() {
+			redactCoordinate(coordinate)
+		}
+		return redacted
+	case *v1.KnownMap:
+		redacted := proto.Clone(r).(*v1.KnownMap)
+		for _, known := range redacted.GetCoordinates() {
+			redactCoordinate(known.GetCoordinate())
+		}
+		return redacted
+	case *v1.MapCoordinateDetail:
+		redacted := proto.Clone(r).(*v1.MapCoordinateDetail)
+		redactCoordinate(redacted)
+		return redacted
+	case *v1.Sprite:
+		redacted := proto.Clone(r).(*v1.Sprite)
+		redacted.LoreInternal = ""
+		return redacted
+	default:
+		return res
+	}
+}
+
+func redactCoordinate(coordinate *v1.MapCoordinateDetail) {
+	for _, sprite := range coordinate.GetSprites() {
+		sprite.LoreInternal = ""
+	}
+}
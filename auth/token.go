@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"overseer/common"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	tokenIssuer = "overseer"
+	// apiKeyPrefix makes leaked keys easy to recognise when scanning logs and repositories
+	apiKeyPrefix = "ovs_"
+)
+
+// jwtHeader is fixed, only HS256 tokens are ever issued or accepted
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// TokenClaims are the claims carried by bearer tokens
+type TokenClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Actor     string `json:"act,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// TokenSigner issues and verifies HMAC signed bearer tokens, a nil signer has bearer tokens disabled
+type TokenSigner struct {
+	secret []byte
+	ttl    time.Duration
+	// maxTtl caps the lifetime of every token, the default ttl included
+	maxTtl time.Duration
+}
+
+func NewTokenSigner(secret string, ttl time.Duration, maxTtl time.Duration) *TokenSigner {
+	return &TokenSigner{
+		secret: []byte(secret),
+		ttl:    min(ttl, maxTtl),
+		maxTtl: maxTtl,
+	}
+}
+
+// NewTokenSignerFromConfiguration returns nil when no server.tokenSecret is configured
+func NewTokenSignerFromConfiguration() *TokenSigner {
+	config := common.GetConfiguration().Server
+	if config.TokenSecret == "" {
+		return nil
+	}
+	return NewTokenSigner(config.TokenSecret, time.Duration(config.TokenTtlSeconds)*time.Second, time.Duration(config.TokenMaxTtlSeconds)*time.Second)
+}
+
+// Issue signs a bearer token for the user acting as the actor, a ttl of zero uses the default of the signer
+// and a ttl beyond the maximum of the signer is refused
+func (t *TokenSigner) Issue(userId string, actorId string, ttl time.Duration) (string, *TokenClaims, error) {
+	if t == nil {
+		return "", nil, status.Error(codes.FailedPrecondition, "bearer tokens are not enabled")
+	}
+	if ttl > t.maxTtl {
+		return "", nil, status.Error(codes.InvalidArgument, fmt.Sprintf("tokens may live for at most %s", t.maxTtl))
+	}
+	if ttl <= 0 {
+		ttl = t.ttl
+	}
+
+	now := time.Now()
+	claims := &TokenClaims{
+		Issuer:    tokenIssuer,
+		Subject:   userId,
+		Actor:     actorId,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", nil, status.Error(codes.Internal, fmt.Sprintf("failed to encode claims: %s", err))
+	}
+
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + t.sign(unsigned), claims, nil
+}
+
+// Verify checks the signature, issuer and expiry of a bearer token and returns its claims
+func (t *TokenSigner) Verify(token string) (*TokenClaims, error) {
+	if t == nil {
+		return nil, status.Error(codes.Unauthenticated, "bearer tokens are not enabled")
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, status.Error(codes.Unauthenticated, "malformed token")
+	}
+	if parts[0] != jwtHeader {
+		return nil, status.Error(codes.Unauthenticated, "unsupported token header")
+	}
+	if !hmac.Equal([]byte(t.sign(parts[0]+"."+parts[1])), []byte(parts[2])) {
+		return nil, status.Error(codes.Unauthenticated, "invalid token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "malformed token payload")
+	}
+	claims := &TokenClaims{}
+	if err = json.Unmarshal(payload, claims); err != nil {
+		return nil, status.Error(codes.Unauthenticated, "malformed token claims")
+	}
+	if claims.Issuer != tokenIssuer {
+		return nil, status.Error(codes.Unauthenticated, "unknown token issuer")
+	}
+	if claims.Subject == "" {
+		return nil, status.Error(codes.Unauthenticated, "token has no subject")
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, status.Error(codes.Unauthenticated, "token expired")
+	}
+	return claims, nil
+}
+
+func (t *TokenSigner) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateApiKeySecret returns a new random api key secret
+func GenerateApiKeySecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", status.Error(codes.Internal, fmt.Sprintf("failed to generate api key: %s", err))
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// linkCodeAlphabet leaves out characters that are easily mistaken for each other when a code is typed in by hand
+const linkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+const linkCodeLength = 8
+
+// GenerateLinkCode returns a new random code short enough to type on another front end
+func GenerateLinkCode() (string, error) {
+	raw := make([]byte, linkCodeLength)
+	if _, err := rand.Read(raw); err != nil {
+		return "", status.Error(codes.Internal, fmt.Sprintf("failed to generate link code: %s", err))
+	}
+	code := make([]byte, linkCodeLength)
+	for i, b := range raw {
+		code[i] = linkCodeAlphabet[int(b)%len(linkCodeAlphabet)]
+	}
+	return string(code), nil
+}
+
+// HashApiKeySecret is what gets stored in place of the secret, keys are random enough that a plain digest is sufficient
+func HashApiKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"os"
 	v1 "overseer/build/go"
 	"overseer/common"
@@ -13,6 +14,13 @@ var reflectionMethods = []string{
 var systemHostname string
 
 const systemTokenKey = "x-auth-system"
+const authorizationKey = "authorization"
+const bearerPrefix = "Bearer "
+const apiKeyKey = "x-api-key"
+
+// actorKey selects which of the user's actors a call made with an unbound api key acts as,
+// with the system token it names the actor a front end is calling for
+const actorKey = "x-actor"
 
 func init() {
 	hname, err := os.Hostname()
@@ -26,7 +34,11 @@ func init() {
 const SystemUserId = "system"
 const SystemActorId = "system"
 
+// DungeonMasterActorId is the speaker recorded on utterances voiced by the dungeon master
+const DungeonMasterActorId = "dungeon-master"
+
 var systemContextInformation = &common.OverseerContextInformation{
+	Credential: common.CredentialSystemToken,
 	User: &v1.User{
 		Uid: "system",
 	},
@@ -43,3 +55,8 @@ var systemContextInformation = &common.OverseerContextInformation{
 		},
 	},
 }
+
+// SystemContext acts as the server itself, for work no caller is waiting on such as resuming jobs after a restart
+func SystemContext(ctx context.Context) (context.Context, error) {
+	return common.SetContextInformation(ctx, systemContextInformation)
+}
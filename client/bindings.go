@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"sort"
+	"sync"
+
+	charm "github.com/charmbracelet/log"
+)
+
+// Binding is a game played in a channel of a front end, the thread is set where the game is played in one
+type Binding struct {
+	ChannelId string `json:"channel"`
+	GameUid   string `json:"game"`
+	ThreadId  string `json:"thread,omitempty"`
+}
+
+// DeliverFunc hands a receipt of a game to the front end along with every channel the game is bound to,
+// it is called once per receipt so whispers are only sent to their targets once
+type DeliverFunc func(ctx context.Context, bindings []Binding, receipt *v1.EventReceipt)
+
+// Bindings ties games to the channels of a front end such as discord or slack, a channel is only ever bound to one game.
+// Each game is watched once however many channels it is bound to, and the bindings are kept in a file so they
+// survive the front end restarting
+type Bindings struct {
+	overseer *OverseerClient
+	file     string
+	deliver  DeliverFunc
+	mu       sync.Mutex
+	bindings map[string]Binding
+	// watches maps a game to the cancel of its subscription
+	watches map[string]context.CancelFunc
+	log     *charm.Logger
+}
+
+// NewBindings keeps the bindings in the file, an empty file name keeps them in memory only
+func NewBindings(overseer *OverseerClient, file string, deliver DeliverFunc) *Bindings {
+	return &Bindings{
+		overseer: overseer,
+		file:     file,
+		deliver:  deliver,
+		bindings: make(map[string]Binding),
+		watches:  make(map[string]context.CancelFunc),
+		log:      common.GetLogger("client.bindings"),
+	}
+}
+
+// Restore binds the channels saved by a previous run again, channels whose game can no longer be watched are dropped
+func (b *Bindings) Restore() error {
+	if b.file == "" {
+		return nil
+	}
+	contents, err := os.ReadFile(b.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		b.log.Error("failed to read bindings", "error", err, "file", b.file)
+		return err
+	}
+	saved := make([]Binding, 0)
+	if err = json.Unmarshal(contents, &saved); err != nil {
+		b.log.Error("failed to parse bindings", "error", err, "file", b.file)
+		return err
+	}
+
+	for _, binding := range saved {
+		if err = b.Bind(binding); err != nil {
+			b.log.Warn("failed to restore binding", "error", err, "game", binding.GameUid, "channel", binding.ChannelId)
+		}
+	}
+	b.log.Info("bindings restored", "channels", len(saved))
+	return nil
+}
+
+// Bind delivers the receipts of the game to the channel from now on, binding a channel again replaces its game
+func (b *Bindings) Bind(binding Binding) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	previous, bound := b.bindings[binding.ChannelId]
+	if bound && previous == binding {
+		return nil
+	}
+
+	if _, watched := b.watches[binding.GameUid]; !watched {
+		gameUid := binding.GameUid
+		ctx, cancel := context.WithCancel(AsSystem(context.Background()))
+		err := b.overseer.Watch(ctx, gameUid, func(receipt *v1.EventReceipt) {
+			b.deliver(ctx, b.Channels(gameUid), receipt)
+		})
+		if err != nil {
+			cancel()
+			b.log.Error("failed to watch game", "error", err, "game", gameUid, "channel", binding.ChannelId)
+			return err
+		}
+		b.watches[gameUid] = cancel
+	}
+
+	b.bindings[binding.ChannelId] = binding
+	if bound {
+		b.stopWatching(previous.GameUid)
+	}
+	b.save()
+
+	b.log.Info("channel bound to game", "game", binding.GameUid, "channel", binding.ChannelId, "thread", binding.ThreadId)
+	return nil
+}
+
+// Get returns the binding of the channel
+func (b *Bindings) Get(channelId string) (Binding, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	binding, ok := b.bindings[channelId]
+	return binding, ok
+}
+
+// Channels returns every binding of the game ordered by channel
+func (b *Bindings) Channels(gameUid string) []Binding {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bindings := make([]Binding, 0)
+	for _, binding := range b.bindings {
+		if binding.GameUid == gameUid {
+			bindings = append(bindings, binding)
+		}
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		return bindings[i].ChannelId < bindings[j].ChannelId
+	})
+	return bindings
+}
+
+// UnbindGame stops delivering the receipts of the game to every channel it is bound to
+func (b *Bindings) UnbindGame(gameUid string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for channelId, binding := range b.bindings {
+		if binding.GameUid == gameUid {
+			delete(b.bindings, channelId)
+			b.log.Info("channel unbound from game", "game", gameUid, "channel", channelId)
+		}
+	}
+	b.stopWatching(gameUid)
+	b.save()
+}
+
+// Close stops watching every game, the bindings stay saved for the next run
+func (b *Bindings) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for gameUid, cancel := range b.watches {
+		cancel()
+		delete(b.watches, gameUid)
+	}
+}
+
+// stopWatching cancels the subscription of a game no channel is bound to anymore, must be called with the mutex held
+func (b *Bindings) stopWatching(gameUid string) {
+	for _, binding := range b.bindings {
+		if binding.GameUid == gameUid {
+			return
+		}
+	}
+	if cancel, ok := b.watches[gameUid]; ok {
+		cancel()
+		delete(b.watches, gameUid)
+	}
+}
+
+// save writes the bindings to the file, a failure is only logged since the bindings still work until the front end stops.
+// must be called with the mutex held
+func (b *Bindings) save() {
+	if b.file == "" {
+		return
+	}
+	saved := make([]Binding, 0, len(b.bindings))
+	for _, binding := range b.bindings {
+		saved = append(saved, binding)
+	}
+	contents, err := json.Marshal(saved)
+	if err != nil {
+		b.log.Error("failed to encode bindings", "error", err)
+		return
+	}
+	if err = os.WriteFile(b.file, contents, 0o600); err != nil {
+		b.log.Error("failed to save bindings", "error", err, "file", b.file)
+	}
+}
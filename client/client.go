@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+const systemTokenKey = "x-auth-system"
+const apiKeyKey = "x-api-key"
+const actorKey = "x-actor"
+
+// OverseerClient is how front ends such as the discord bot talk to the overseer server,
+// calls are made with the system token, or an api key, on behalf of the actor found in the context information
+type OverseerClient struct {
+	Users  v1.UsersClient
+	Games  v1.GamesClient
+	Events v1.EventsClient
+	Maps   v1.MapsClient
+	conn   *grpc.ClientConn
+}
+
+func NewOverseerClient(address string, systemToken string) (*OverseerClient, error) {
+	return dial(address, systemTokenKey, systemToken)
+}
+
+// NewOverseerClientWithApiKey calls as the owner of the api key rather than with the system token,
+// front ends whose players log in themselves such as the telnet server use it
+func NewOverseerClientWithApiKey(address string, apiKey string) (*OverseerClient, error) {
+	return dial(address, apiKeyKey, apiKey)
+}
+
+func dial(address string, credentialKey string, credential string) (*OverseerClient, error) {
+	conn, err := grpc.NewClient(address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(outgoingContext(ctx, credentialKey, credential), method, req, reply, cc, opts...)
+		}),
+		grpc.WithStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(outgoingContext(ctx, credentialKey, credential), desc, cc, method, opts...)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OverseerClient{
+		Users:  v1.NewUsersClient(conn),
+		Games:  v1.NewGamesClient(conn),
+		Events: v1.NewEventsClient(conn),
+		Maps:   v1.NewMapsClient(conn),
+		conn:   conn,
+	}, nil
+}
+
+func (c *OverseerClient) Close() error {
+	return c.conn.Close()
+}
+
+// outgoingContext adds the credential and, once the caller is known, the actor being called for
+func outgoingContext(ctx context.Context, credentialKey string, credential string) context.Context {
+	ctx = metadata.AppendToOutgoingContext(ctx, credentialKey, credential)
+	if _, ok := ctx.Value(systemKey{}).(bool); ok {
+		return ctx
+	}
+	if info, err := common.GetContextInformation(ctx); err == nil && info.Actor != nil {
+		ctx = metadata.AppendToOutgoingContext(ctx, actorKey, info.Actor.GetUid())
+	}
+	return ctx
+}
+
+type systemKey struct{}
+
+// AsSystem makes calls as the holder of the credential itself rather than on behalf of the actor in the context information,
+// front ends use it for lookups such as resolving who they are talking to
+func AsSystem(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemKey{}, true)
+}
+
+type clientKey struct{}
+
+// WithClient makes the client available to handlers further down
+func WithClient(ctx context.Context, client *OverseerClient) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// FromContext returns the client added with WithClient, nil if there is none
+func FromContext(ctx context.Context) *OverseerClient {
+	client, _ := ctx.Value(clientKey{}).(*OverseerClient)
+	return client
+}
+
+// reconnectDelay is how long a watch waits before subscribing again after the stream broke
+const reconnectDelay = 2 * time.Second
+
+// Watch subscribes to the receipts of a game and hands every receipt to deliver until the context is done,
+// it returns once the subscription is live and resumes from the last receipt whenever the stream breaks
+func (c *OverseerClient) Watch(ctx context.Context, gameUid string, deliver func(receipt *v1.EventReceipt)) error {
+	stream, err := c.subscribe(ctx, gameUid, nil)
+	if err != nil {
+		return err
+	}
+
+	log := common.GetLogger("client.watch")
+	go func() {
+		var last *string
+		for {
+			receipt, err := stream.Recv()
+			if err == nil {
+				last = &receipt.Uid
+				deliver(receipt)
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+
+			log.Warn("game watch interrupted", "error", err, "game", gameUid)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(reconnectDelay):
+				}
+				stream, err = c.subscribe(ctx, gameUid, last)
+				if err == nil {
+					break
+				}
+				log.Warn("failed to watch game again", "error", err, "game", gameUid)
+			}
+		}
+	}()
+	return nil
+}
+
+// subscribe opens a watch on the game and waits until the server has subscribed it
+func (c *OverseerClient) subscribe(ctx context.Context, gameUid string, after *string) (v1.Events_WatchGameClient, error) {
+	stream, err := c.Events.WatchGame(ctx, &v1.WatchGameRequest{GameUid: gameUid, AfterReceiptUid: after})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = stream.Header(); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	v1 "overseer/build/go"
+	"overseer/common"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ResolveIdentity adds the overseer user and actor registered for the identity on the source to the context,
+// the context is returned unchanged for people who have not registered yet
+func (c *OverseerClient) ResolveIdentity(ctx context.Context, source v1.Actor_Source, identity string) (context.Context, error) {
+	actor, err := c.Users.GetActorBySource(AsSystem(ctx), &v1.GetActorBySourceRequest{
+		Source:         source,
+		SourceIdentity: identity,
+	})
+	if status.Code(err) == codes.NotFound {
+		return ctx, nil
+	}
+	if err != nil {
+		return ctx, err
+	}
+
+	user, err := c.Users.GetUser(AsSystem(ctx), actor)
+	if err != nil {
+		return ctx, err
+	}
+
+	return common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actor})
+}
+
+// DescribeError is how front ends tell a player a call failed, only the message of the status is shown
+func DescribeError(err error) string {
+	return fmt.Sprintf("Something went wrong: %s", status.Convert(err).Message())
+}
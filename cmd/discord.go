@@ -2,11 +2,13 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
+	"overseer/client"
 	"overseer/common"
 	"overseer/discord"
 )
 
 var botToken string
+var serverAddress string
 
 var discordCmd = &cobra.Command{
 	Use:   "discord",
@@ -17,13 +19,23 @@ This allows for the provisioning of a discord bot to service requests from Disco
 		log := common.GetLogger("cli.discord")
 		log.Debug("starting discord command")
 
+		if serverAddress == "" {
+			serverAddress = common.GetConfiguration().Discord.ServerAddress
+		}
+		overseer, err := client.NewOverseerClient(serverAddress, common.GetConfiguration().Server.SystemToken)
+		if err != nil {
+			log.Fatal("failed to create overseer client", "error", err, "address", serverAddress)
+			return
+		}
+		defer overseer.Close()
+
 		var server discord.DiscordServer
 		if botToken != "" {
 			log.Info("configuring discord from command line")
-			server = discord.NewDiscordServer(botToken)
+			server = discord.NewDiscordServer(botToken, overseer)
 		} else if common.GetConfiguration().Discord.BotToken != "" {
 			log.Info("configuring discord from configuration")
-			server = discord.NewDiscordServer(common.GetConfiguration().Discord.BotToken)
+			server = discord.NewDiscordServer(common.GetConfiguration().Discord.BotToken, overseer)
 		} else {
 			log.Fatal("no bot token provided")
 			return
@@ -41,4 +53,5 @@ func init() {
 	rootCmd.AddCommand(discordCmd)
 
 	discordCmd.Flags().StringVarP(&botToken, "bot-token", "t", "", "the bot token to use for the discord bot")
+	discordCmd.Flags().StringVar(&serverAddress, "server-address", "", "the address of the overseer server, defaults to discord.serverAddress")
 }
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"overseer/client"
+	"overseer/common"
+	"overseer/slack"
+)
+
+var slackAppToken string
+var slackBotToken string
+var slackServerAddress string
+
+var slackCmd = &cobra.Command{
+	Use:   "slack",
+	Short: "start up a slack bot",
+	Long: `This is the primary entrypoint for the slack bot.
+This allows for the provisioning of a slack bot over socket mode to service requests from Slack`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log := common.GetLogger("cli.slack")
+		log.Debug("starting slack command")
+
+		configuration := common.GetConfiguration().Slack
+		if slackServerAddress == "" {
+			slackServerAddress = configuration.ServerAddress
+		}
+		if slackAppToken == "" {
+			slackAppToken = configuration.AppToken
+		}
+		if slackBotToken == "" {
+			slackBotToken = configuration.BotToken
+		}
+		if slackAppToken == "" || slackBotToken == "" {
+			log.Fatal("both an app token and a bot token are required")
+			return
+		}
+
+		overseer, err := client.NewOverseerClient(slackServerAddress, common.GetConfiguration().Server.SystemToken)
+		if err != nil {
+			log.Fatal("failed to create overseer client", "error", err, "address", slackServerAddress)
+			return
+		}
+		defer overseer.Close()
+
+		server := slack.NewSlackServer(slackAppToken, slackBotToken, overseer)
+		if err := server.Connect(); err != nil {
+			log.Fatal("failed to start slack server", "error", err)
+		} else {
+			log.Info("slack server shutdown")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(slackCmd)
+
+	slackCmd.Flags().StringVar(&slackAppToken, "app-token", "", "the app level token used to open the socket mode connection, defaults to slack.appToken")
+	slackCmd.Flags().StringVarP(&slackBotToken, "bot-token", "t", "", "the bot token used to call the slack api, defaults to slack.botToken")
+	slackCmd.Flags().StringVar(&slackServerAddress, "server-address", "", "the address of the overseer server, defaults to slack.serverAddress")
+}
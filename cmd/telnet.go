@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"overseer/client"
+	"overseer/common"
+	"overseer/telnet"
+)
+
+var telnetListenAddress string
+var telnetServerAddress string
+
+var telnetCmd = &cobra.Command{
+	Use:   "telnet",
+	Short: "start up a telnet server",
+	Long: `This is the primary entrypoint for the text front end.
+This allows players to log in with any telnet client and play alongside discord and slack players`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log := common.GetLogger("cli.telnet")
+		log.Debug("starting telnet command")
+
+		configuration := common.GetConfiguration().Telnet
+		if telnetListenAddress == "" {
+			telnetListenAddress = configuration.ListenAddress
+		}
+		if telnetServerAddress == "" {
+			telnetServerAddress = configuration.ServerAddress
+		}
+
+		overseer, err := client.NewOverseerClient(telnetServerAddress, common.GetConfiguration().Server.SystemToken)
+		if err != nil {
+			log.Fatal("failed to create overseer client", "error", err, "address", telnetServerAddress)
+			return
+		}
+		defer overseer.Close()
+
+		server := telnet.NewTelnetServer(telnetListenAddress, overseer, func(apiKey string) (*client.OverseerClient, error) {
+			return client.NewOverseerClientWithApiKey(telnetServerAddress, apiKey)
+		})
+		if err := server.Connect(); err != nil {
+			log.Fatal("failed to start telnet server", "error", err)
+		} else {
+			log.Info("telnet server shutdown")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(telnetCmd)
+
+	telnetCmd.Flags().StringVarP(&telnetListenAddress, "listen-address", "l", "", "where players connect, defaults to telnet.listenAddress")
+	telnetCmd.Flags().StringVar(&telnetServerAddress, "server-address", "", "the address of the overseer server, defaults to telnet.serverAddress")
+}
@@ -19,12 +19,24 @@ type Configuration struct {
 	MapGeneration              MapGenerationConfiguration `yaml:"mapGeneration" mapstructure:"mapGeneration" json:"mapGeneration"`
 	GenerativeFeaturesProvider GenerativeFeatureProvider  `yaml:"generativeFeaturesProvider" mapstructure:"generativeFeaturesProvider" json:"generativeFeaturesProvider"`
 	Ollama                     OllamaConfiguration        `yaml:"ollama" mapstructure:"ollama" json:"ollama"`
+	OpenAI                     OpenAIConfiguration        `yaml:"openai" mapstructure:"openai" json:"openai"`
 	Discord                    DiscordConfiguration       `yaml:"discord" mapstructure:"discord" json:"discord"`
+	Slack                      SlackConfiguration         `yaml:"slack" mapstructure:"slack" json:"slack"`
+	Telnet                     TelnetConfiguration        `yaml:"telnet" mapstructure:"telnet" json:"telnet"`
+	DungeonMaster              DungeonMasterConfiguration `yaml:"dungeonMaster" mapstructure:"dungeonMaster" json:"dungeonMaster"`
+	Locks                      LockConfiguration          `yaml:"locks" mapstructure:"locks" json:"locks"`
 }
 
 type ServerConfiguration struct {
 	EnableSystemToken bool   `yaml:"enableSystemToken" mapstructure:"enableSystemToken" json:"enableSystemToken"`
 	SystemToken       string `yaml:"systemToken" mapstructure:"systemToken" json:"systemToken"`
+	// TokenSecret signs bearer tokens, bearer tokens are rejected while it is empty
+	TokenSecret     string `yaml:"tokenSecret" mapstructure:"tokenSecret" json:"tokenSecret"`
+	TokenTtlSeconds int64  `yaml:"tokenTtlSeconds" mapstructure:"tokenTtlSeconds" json:"tokenTtlSeconds"`
+	// TokenMaxTtlSeconds caps how long any bearer token may live, longer requests are refused
+	TokenMaxTtlSeconds int64 `yaml:"tokenMaxTtlSeconds" mapstructure:"tokenMaxTtlSeconds" json:"tokenMaxTtlSeconds"`
+	// LinkCodeTtlSeconds is how long a code for linking actors across front ends can be redeemed
+	LinkCodeTtlSeconds int64 `yaml:"linkCodeTtlSeconds" mapstructure:"linkCodeTtlSeconds" json:"linkCodeTtlSeconds"`
 }
 
 type TemplatingConfiguration struct {
@@ -32,17 +44,40 @@ type TemplatingConfiguration struct {
 }
 
 type MapGenerationConfiguration struct {
-	ChanceOfRandomTheme         float64 `yaml:"chanceOfRandomTheme" mapstructure:"chanceOfRandomTheme" json:"chanceOfRandomTheme"`
 	MaximumTerrainDifficulty    float32 `yaml:"maximumTerrainDifficulty" mapstructure:"maximumTerrainDifficulty" json:"maximumTerrainDifficulty"`
 	MinimumTerrainDifficulty    float32 `yaml:"minimumTerrainDifficulty" mapstructure:"minimumTerrainDifficulty" json:"minimumTerrainDifficulty"`
 	MaximumSpriteDensity        float32 `yaml:"maximumSpriteDensity" mapstructure:"maximumSpriteDensity" json:"maximumSpriteDensity"`
 	MinimumSpriteDensity        float32 `yaml:"minimumSpriteDensity" mapstructure:"minimumSpriteDensity" json:"minimumSpriteDensity"`
 	MaximumSpritesPerCoordinate int     `yaml:"maximumSpritesPerCoordinate" mapstructure:"maximumSpritesPerCoordinate" json:"maximumSpritesPerCoordinate"`
+	// Concurrency is how many coordinates of a map are generated at the same time
+	Concurrency int `yaml:"concurrency" mapstructure:"concurrency" json:"concurrency"`
+	// SpriteGenerationAttempts is how often the model is asked again for a sprite it answered with malformed JSON
+	SpriteGenerationAttempts int `yaml:"spriteGenerationAttempts" mapstructure:"spriteGenerationAttempts" json:"spriteGenerationAttempts"`
+	// CharacteristicRanges clamps generated sprite stats, keyed by the lower case characteristic type
+	CharacteristicRanges map[string]CharacteristicRange `yaml:"characteristicRanges" mapstructure:"characteristicRanges" json:"characteristicRanges"`
+	// the defaults below are used for the map created when a new game is started
+	DefaultMaxX                   int64   `yaml:"defaultMaxX" mapstructure:"defaultMaxX" json:"defaultMaxX"`
+	DefaultMaxY                   int64   `yaml:"defaultMaxY" mapstructure:"defaultMaxY" json:"defaultMaxY"`
+	DefaultDifficultTerrainChance float32 `yaml:"defaultDifficultTerrainChance" mapstructure:"defaultDifficultTerrainChance" json:"defaultDifficultTerrainChance"`
+	DefaultSpriteDensity          float32 `yaml:"defaultSpriteDensity" mapstructure:"defaultSpriteDensity" json:"defaultSpriteDensity"`
+}
+
+type CharacteristicRange struct {
+	Minimum float32 `yaml:"minimum" mapstructure:"minimum" json:"minimum"`
+	Maximum float32 `yaml:"maximum" mapstructure:"maximum" json:"maximum"`
+}
+
+type LockConfiguration struct {
+	// LeaseSeconds is how long a lock is held without being renewed
+	LeaseSeconds int64 `yaml:"leaseSeconds" mapstructure:"leaseSeconds" json:"leaseSeconds"`
+}
+
+type DungeonMasterConfiguration struct {
+	HistoryLength int `yaml:"historyLength" mapstructure:"historyLength" json:"historyLength"`
 }
 
 type GenerativeFeatureProvider string
 
-// todo: should we offer OpenAI?
 const (
 	OpenAIProvider GenerativeFeatureProvider = "openai"
 	OllamaProvider GenerativeFeatureProvider = "ollama"
@@ -92,10 +127,44 @@ type OllamaConfiguration struct {
 	BaseUrl  string      `yaml:"baseUrl" mapstructure:"baseUrl" json:"baseUrl"`
 	Model    OllamaModel `yaml:"model" mapstructure:"model" json:"model"`
 	Insecure bool        `yaml:"insecure" mapstructure:"insecure" json:"insecure"`
+	// EmbeddingModel answers embedding requests, unlike Model it is not pulled on start up
+	EmbeddingModel string `yaml:"embeddingModel" mapstructure:"embeddingModel" json:"embeddingModel"`
+}
+
+// OpenAIConfiguration points at any OpenAI compatible chat completions api, llama.cpp server, vLLM and LM Studio included
+type OpenAIConfiguration struct {
+	// BaseUrl is the server without the /v1 suffix, requests go to {baseUrl}/v1/chat/completions
+	BaseUrl string `yaml:"baseUrl" mapstructure:"baseUrl" json:"baseUrl"`
+	Model   string `yaml:"model" mapstructure:"model" json:"model"`
+	// ApiKey is sent as a bearer token, local servers usually do not need one
+	ApiKey         string `yaml:"apiKey" mapstructure:"apiKey" json:"apiKey"`
+	EmbeddingModel string `yaml:"embeddingModel" mapstructure:"embeddingModel" json:"embeddingModel"`
 }
 
 type DiscordConfiguration struct {
 	BotToken string `yaml:"botToken" mapstructure:"botToken" json:"botToken"`
+	// ServerAddress is the overseer server the bot calls with the system token
+	ServerAddress string `yaml:"serverAddress" mapstructure:"serverAddress" json:"serverAddress"`
+	// BindingsFile is where the channels bound to games are kept between runs
+	BindingsFile string `yaml:"bindingsFile" mapstructure:"bindingsFile" json:"bindingsFile"`
+}
+
+type SlackConfiguration struct {
+	// AppToken (xapp-) opens the socket mode connection, BotToken (xoxb-) calls the web api
+	AppToken string `yaml:"appToken" mapstructure:"appToken" json:"appToken"`
+	BotToken string `yaml:"botToken" mapstructure:"botToken" json:"botToken"`
+	// ServerAddress is the overseer server the bot calls with the system token
+	ServerAddress string `yaml:"serverAddress" mapstructure:"serverAddress" json:"serverAddress"`
+	// BindingsFile is where the threads bound to games are kept between runs
+	BindingsFile string `yaml:"bindingsFile" mapstructure:"bindingsFile" json:"bindingsFile"`
+}
+
+type TelnetConfiguration struct {
+	// ListenAddress is where players connect with a telnet client
+	ListenAddress string `yaml:"listenAddress" mapstructure:"listenAddress" json:"listenAddress"`
+	// ServerAddress is the overseer server, new characters are registered with the system token
+	// and players call it with their own api key once logged in
+	ServerAddress string `yaml:"serverAddress" mapstructure:"serverAddress" json:"serverAddress"`
 }
 
 func init() {
@@ -108,18 +177,48 @@ func init() {
 	viper.SetDefault("channelBuffer", 10000)
 	viper.SetDefault("server.enableSystemToken", false)
 	viper.SetDefault("server.systemToken", "")
+	viper.SetDefault("server.tokenSecret", "")
+	viper.SetDefault("server.tokenTtlSeconds", 3600)
+	viper.SetDefault("server.tokenMaxTtlSeconds", 86400)
+	viper.SetDefault("server.linkCodeTtlSeconds", 600)
 	viper.SetDefault("templating.templateBasePath", "./templates")
-	viper.SetDefault("mapGeneration.chanceOfRandomTheme", 0.1)
 	viper.SetDefault("mapGeneration.maximumTerrainDifficulty", 2.0)
 	viper.SetDefault("mapGeneration.minimumTerrainDifficulty", 0.01)
 	viper.SetDefault("mapGeneration.maximumSpriteDensity", 2.0)
 	viper.SetDefault("mapGeneration.minimumSpriteDensity", 0.01)
 	viper.SetDefault("mapGeneration.maximumSpritesPerCoordinate", 12)
+	viper.SetDefault("mapGeneration.spriteGenerationAttempts", 3)
+	viper.SetDefault("mapGeneration.concurrency", 4)
+	viper.SetDefault("mapGeneration.characteristicRanges", map[string]any{
+		"health":  map[string]any{"minimum": 1, "maximum": 100},
+		"attack":  map[string]any{"minimum": 0, "maximum": 100},
+		"defense": map[string]any{"minimum": 0, "maximum": 100},
+		"speed":   map[string]any{"minimum": 0, "maximum": 100},
+	})
+	viper.SetDefault("mapGeneration.defaultMaxX", 5)
+	viper.SetDefault("mapGeneration.defaultMaxY", 5)
+	viper.SetDefault("mapGeneration.defaultDifficultTerrainChance", 0.2)
+	viper.SetDefault("mapGeneration.defaultSpriteDensity", 0.5)
 	viper.SetDefault("generativeFeaturesProvider", OllamaProvider.String())
 	viper.SetDefault("ollama.baseUrl", "http://localhost:11434")
 	viper.SetDefault("ollama.model", Llama3.String())
 	viper.SetDefault("ollama.insecure", false)
+	viper.SetDefault("ollama.embeddingModel", "nomic-embed-text")
+	viper.SetDefault("openai.baseUrl", "https://api.openai.com")
+	viper.SetDefault("openai.model", "gpt-4o-mini")
+	viper.SetDefault("openai.apiKey", "")
+	viper.SetDefault("openai.embeddingModel", "text-embedding-3-small")
 	viper.SetDefault("discord.botToken", "")
+	viper.SetDefault("discord.serverAddress", "localhost:4242")
+	viper.SetDefault("discord.bindingsFile", "discord.bindings.json")
+	viper.SetDefault("slack.appToken", "")
+	viper.SetDefault("slack.botToken", "")
+	viper.SetDefault("slack.serverAddress", "localhost:4242")
+	viper.SetDefault("slack.bindingsFile", "slack.bindings.json")
+	viper.SetDefault("telnet.listenAddress", "localhost:4000")
+	viper.SetDefault("telnet.serverAddress", "localhost:4242")
+	viper.SetDefault("dungeonMaster.historyLength", 25)
+	viper.SetDefault("locks.leaseSeconds", 30)
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
@@ -13,8 +13,20 @@ type overseerContextKey string
 type OverseerContextInformation struct {
 	User  *v1.User
 	Actor *v1.Actor
+	// Credential is how the caller authenticated, work started inside the server carries none
+	Credential Credential
 }
 
+// Credential is the kind of secret a caller presented
+type Credential int
+
+const (
+	CredentialNone Credential = iota
+	CredentialSystemToken
+	CredentialApiKey
+	CredentialBearerToken
+)
+
 func (c *OverseerContextInformation) LoggingContext(additionalKV ...interface{}) []interface{} {
 	userContext := make([]interface{}, 0)
 	if c.User != nil {
@@ -52,3 +64,22 @@ func SetContextInformation(ctx context.Context, info *OverseerContextInformation
 	}
 	return context.WithValue(ctx, actiorInformationKey, info), nil
 }
+
+// GameLease is the lease a handler holds on a game, storage refuses writes made under a lease that has been superseded
+type GameLease struct {
+	GameId       string
+	FencingToken int64
+}
+
+const (
+	gameLeaseKey overseerContextKey = "game:lease"
+)
+
+func WithGameLease(ctx context.Context, gameId string, fencingToken int64) context.Context {
+	return context.WithValue(ctx, gameLeaseKey, &GameLease{GameId: gameId, FencingToken: fencingToken})
+}
+
+func GetGameLease(ctx context.Context) (*GameLease, bool) {
+	lease, ok := ctx.Value(gameLeaseKey).(*GameLease)
+	return lease, ok && lease != nil
+}
@@ -0,0 +1,98 @@
+package common
+
+import (
+	"math/rand"
+	v1 "overseer/build/go"
+)
+
+// RollDice rolls count dice with the given number of sides and totals them with the modifier
+func RollDice(count int64, sides int64, modifier int64) *v1.DiceRoll {
+	roll := &v1.DiceRoll{
+		Count:    count,
+		Sides:    sides,
+		Rolls:    make([]int64, 0, count),
+		Modifier: modifier,
+		Total:    modifier,
+	}
+	for i := int64(0); i < count; i++ {
+		value := rand.Int63n(sides) + 1
+		roll.Rolls = append(roll.Rolls, value)
+		roll.Total += value
+	}
+	return roll
+}
+
+// DefaultCharacteristicValue is used for sprites missing a characteristic (such as freshly created players) so they are treated as average
+const DefaultCharacteristicValue float32 = 50
+
+// CharacteristicValue returns the value of the characteristic on the sprite
+func CharacteristicValue(sprite *v1.Sprite, characteristic v1.Characteristic_Type) float32 {
+	for _, c := range sprite.GetCharacteristics() {
+		if c.GetType() == characteristic {
+			return c.GetValue()
+		}
+	}
+	return DefaultCharacteristicValue
+}
+
+// SetCharacteristic replaces the value of the characteristic on the sprite adding it when missing
+func SetCharacteristic(sprite *v1.Sprite, characteristic v1.Characteristic_Type, value float32) {
+	for _, c := range sprite.GetCharacteristics() {
+		if c.GetType() == characteristic {
+			c.Value = value
+			return
+		}
+	}
+	sprite.Characteristics = append(sprite.Characteristics, &v1.Characteristic{Type: characteristic, Value: value})
+}
+
+// AbilityCharacteristic maps an ability check onto the sprite characteristic that backs it
+// abilities without a backing characteristic always have a modifier of zero
+func AbilityCharacteristic(ability v1.ActionEffect_Ability) (v1.Characteristic_Type, bool) {
+	switch ability {
+	case v1.ActionEffect_STRENGTH:
+		return v1.Characteristic_ATTACK, true
+	case v1.ActionEffect_DEXTERITY:
+		return v1.Characteristic_SPEED, true
+	case v1.ActionEffect_CONSTITUTION:
+		return v1.Characteristic_HEALTH, true
+	default:
+		return v1.Characteristic_TYPE_UNSPECIFIED, false
+	}
+}
+
+// CharacteristicModifier converts a characteristic on the 0-100 scale into a modifier between -5 and +5
+func CharacteristicModifier(sprite *v1.Sprite, characteristic v1.Characteristic_Type) int64 {
+	modifier := int64(CharacteristicValue(sprite, characteristic)/10) - 5
+	return min(max(modifier, -5), 5)
+}
+
+// AbilityModifier is the modifier applied to a check of the ability by the sprite
+func AbilityModifier(sprite *v1.Sprite, ability v1.ActionEffect_Ability) int64 {
+	characteristic, ok := AbilityCharacteristic(ability)
+	if !ok {
+		return 0
+	}
+	return CharacteristicModifier(sprite, characteristic)
+}
+
+// ResolveCheck decides the outcome of a d20 check against the difficulty, a natural 20 or 1 is always critical
+func ResolveCheck(check *v1.DiceRoll, difficulty int64) v1.ActionEffect_Outcome {
+	if len(check.GetRolls()) == 1 && check.GetSides() == 20 {
+		switch check.GetRolls()[0] {
+		case 20:
+			return v1.ActionEffect_CRITICAL_SUCCESS
+		case 1:
+			return v1.ActionEffect_CRITICAL_FAILURE
+		}
+	}
+	if check.GetTotal() >= difficulty {
+		return v1.ActionEffect_SUCCESS
+	}
+	return v1.ActionEffect_FAILURE
+}
+
+// IsSuccessful reports whether the outcome of a check succeeded
+func IsSuccessful(outcome v1.ActionEffect_Outcome) bool {
+	return outcome == v1.ActionEffect_SUCCESS || outcome == v1.ActionEffect_CRITICAL_SUCCESS
+}
@@ -0,0 +1,79 @@
+package common
+
+import (
+	v1 "overseer/build/go"
+	"testing"
+)
+
+func TestRollDice(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		roll := RollDice(3, 6, 2)
+		if len(roll.Rolls) != 3 {
+			t.Fatalf("expected 3 rolls got %d", len(roll.Rolls))
+		}
+		total := roll.Modifier
+		for _, value := range roll.Rolls {
+			if value < 1 || value > 6 {
+				t.Fatalf("rolled %d on a d6", value)
+			}
+			total += value
+		}
+		if total != roll.Total {
+			t.Fatalf("expected total %d got %d", total, roll.Total)
+		}
+	}
+}
+
+func TestResolveCheck(t *testing.T) {
+	d20 := func(value int64, modifier int64) *v1.DiceRoll {
+		return &v1.DiceRoll{Count: 1, Sides: 20, Rolls: []int64{value}, Modifier: modifier, Total: value + modifier}
+	}
+
+	tests := []struct {
+		name       string
+		check      *v1.DiceRoll
+		difficulty int64
+		expected   v1.ActionEffect_Outcome
+	}{
+		{"natural 20 always succeeds", d20(20, -5), 30, v1.ActionEffect_CRITICAL_SUCCESS},
+		{"natural 1 always fails", d20(1, 5), 2, v1.ActionEffect_CRITICAL_FAILURE},
+		{"meeting the difficulty succeeds", d20(8, 2), 10, v1.ActionEffect_SUCCESS},
+		{"missing the difficulty fails", d20(8, 1), 10, v1.ActionEffect_FAILURE},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if outcome := ResolveCheck(test.check, test.difficulty); outcome != test.expected {
+				t.Errorf("expected %s got %s", test.expected, outcome)
+			}
+		})
+	}
+}
+
+func TestAbilityModifier(t *testing.T) {
+	strong := &v1.Sprite{Characteristics: []*v1.Characteristic{
+		{Type: v1.Characteristic_ATTACK, Value: 100},
+		{Type: v1.Characteristic_SPEED, Value: 0},
+	}}
+
+	tests := []struct {
+		name     string
+		sprite   *v1.Sprite
+		ability  v1.ActionEffect_Ability
+		expected int64
+	}{
+		{"strength is backed by attack", strong, v1.ActionEffect_STRENGTH, 5},
+		{"dexterity is backed by speed", strong, v1.ActionEffect_DEXTERITY, -5},
+		{"missing characteristics are average", strong, v1.ActionEffect_CONSTITUTION, 0},
+		{"abilities without a characteristic have no modifier", strong, v1.ActionEffect_CHARISMA, 0},
+		{"sprites without characteristics are average", nil, v1.ActionEffect_STRENGTH, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if modifier := AbilityModifier(test.sprite, test.ability); modifier != test.expected {
+				t.Errorf("expected %d got %d", test.expected, modifier)
+			}
+		})
+	}
+}
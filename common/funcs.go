@@ -2,7 +2,6 @@ package common
 
 import (
 	"math/rand"
-	"time"
 )
 
 func Reduce[T any](s []T, fn func(T) bool) []T {
@@ -32,7 +31,8 @@ func FindFirst[T any](s []T, fn func(T) bool) *T {
 	return nil
 }
 
-func RandomizedProgressiveValue(minimum float32, selection float32, maximum float32, maxInt int) int {
+// RandomizedProgressiveValue draws a value between 1 and maxInt from r, larger selections allow larger values
+func RandomizedProgressiveValue(r *rand.Rand, minimum float32, selection float32, maximum float32, maxInt int) int {
 	// Ensure selection is within the range
 	if selection < minimum {
 		selection = minimum
@@ -44,7 +44,6 @@ func RandomizedProgressiveValue(minimum float32, selection float32, maximum floa
 	normalized := (selection - minimum) / (maximum - minimum)
 
 	// Generate a random value in the range [0, 1) and multiply by normalized position
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	randomFactor := r.Float32() * normalized
 
 	// Scale the random factor to the maxInt range and ensure the result is nonzero
@@ -2,6 +2,7 @@ package common
 
 import (
 	"fmt"
+	"math/rand"
 	"testing"
 )
 
@@ -21,8 +22,9 @@ func TestRangedInt(t *testing.T) {
 		{0.01, 2.0, 2.0, 256},
 	}
 
+	r := rand.New(rand.NewSource(1))
 	for _, tt := range tests {
-		result := RandomizedProgressiveValue(tt.minimum, tt.selection, tt.maximum, tt.maxInt)
+		result := RandomizedProgressiveValue(r, tt.minimum, tt.selection, tt.maximum, tt.maxInt)
 		fmt.Printf("inputs: min=%f selection=%f max=%f maxInt=%d -- result=%d\n", tt.minimum, tt.selection, tt.maximum, tt.maxInt, result)
 		if result <= 0 || result > tt.maxInt {
 			t.Errorf("rangedInt(%v, %v, %v, %v) = %v; want a value between 1 and %v", tt.minimum, tt.selection, tt.maximum, tt.maxInt, result, tt.maxInt)
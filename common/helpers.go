@@ -3,6 +3,7 @@ package common
 import (
 	"crypto/sha512"
 	"encoding/hex"
+	v1 "overseer/build/go"
 	"strings"
 )
 
@@ -15,3 +16,8 @@ func GenerateRandomStringFromSeed(seeds ...string) string {
 	sum := sha512.Sum512_256([]byte(builder.String()))
 	return hex.EncodeToString(sum[:])[:10]
 }
+
+// SourceUserId is the user a front end registers for whoever it knows by the identity, the same identity always has the same user
+func SourceUserId(source v1.Actor_Source, identity ...string) string {
+	return GenerateRandomStringFromSeed(append([]string{"user", source.String()}, identity...)...)
+}
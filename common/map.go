@@ -1,6 +1,12 @@
 package common
 
-import v1 "overseer/build/go"
+import (
+	v1 "overseer/build/go"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
 
 type MapTranslation struct {
 	X         int64
@@ -28,6 +34,64 @@ func (t MapTranslation) IsCoordinate(origin *v1.MapPosition, target *v1.MapPosit
 	return x == target.X && y == target.Y
 }
 
+// Apply returns the position reached by moving from origin along the translation
+func (t MapTranslation) Apply(origin *v1.MapPosition) *v1.MapPosition {
+	x := origin.X
+	y := origin.Y
+
+	if t.XPositive {
+		x += t.X
+	} else {
+		x -= t.X
+	}
+
+	if t.YPositive {
+		y += t.Y
+	} else {
+		y -= t.Y
+	}
+
+	return &v1.MapPosition{X: x, Y: y}
+}
+
+// IsDiagonal reports whether the translation moves along both axes at once
+func (t MapTranslation) IsDiagonal() bool {
+	return t.X != 0 && t.Y != 0
+}
+
+// ParseDirection resolves a player supplied direction (e.g. "north", "ne", "south west") into a translation
+// the direction names mirror the values produced by GetDirection
+func ParseDirection(direction string) (MapTranslation, error) {
+	normalized := strings.ToLower(strings.TrimSpace(direction))
+	normalized = strings.NewReplacer(" ", "-", "_", "-").Replace(normalized)
+
+	switch normalized {
+	case "north", "n", "up":
+		return NorthernNeighborTranslation, nil
+	case "south", "s", "down":
+		return SouthernNeighborTranslation, nil
+	case "east", "e":
+		return EasternNeighborTranslation, nil
+	case "west", "w":
+		return WesternNeighborTranslation, nil
+	case "north-east", "northeast", "ne":
+		return NorthEasternNeighborTranslation, nil
+	case "north-west", "northwest", "nw":
+		return NorthWesternNeighborTranslation, nil
+	case "south-east", "southeast", "se":
+		return SouthEasternNeighborTranslation, nil
+	case "south-west", "southwest", "sw":
+		return SouthWesternNeighborTranslation, nil
+	default:
+		return MapTranslation{}, status.Error(codes.InvalidArgument, "unknown direction: "+direction)
+	}
+}
+
+// IsWithinBounds reports whether the position is on a map spanning -maxX..maxX and -maxY..maxY
+func IsWithinBounds(position *v1.MapPosition, maxX int64, maxY int64) bool {
+	return position.X >= -maxX && position.X <= maxX && position.Y >= -maxY && position.Y <= maxY
+}
+
 func GetDirection(origin *v1.MapPosition, target *v1.MapPosition) string {
 	if origin.X == target.X && origin.Y == target.Y {
 		return "none"
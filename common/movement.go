@@ -0,0 +1,99 @@
+package common
+
+import (
+	v1 "overseer/build/go"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	MovementCostNormal    int64 = 1
+	MovementCostDifficult int64 = 2
+)
+
+// MovementCost is what entering the coordinate costs, difficult terrain costs double
+func MovementCost(destination *v1.MapCoordinateDetail) int64 {
+	if destination.GetDifficultTerrain() {
+		return MovementCostDifficult
+	}
+	return MovementCostNormal
+}
+
+// IsAdjacent reports whether the positions are a single step apart, diagonals included
+func IsAdjacent(origin *v1.MapPosition, target *v1.MapPosition) bool {
+	dx, dy := target.GetX()-origin.GetX(), target.GetY()-origin.GetY()
+	return (dx != 0 || dy != 0) && dx >= -1 && dx <= 1 && dy >= -1 && dy <= 1
+}
+
+// CheckMovement applies the movement rules to an actor stepping from origin to destination,
+// a broken rule is a FailedPrecondition whose message can be shown to the player
+func CheckMovement(origin *v1.MapCoordinateDetail, destination *v1.MapCoordinateDetail, actorId string) error {
+	if !IsAdjacent(origin.GetPosition(), destination.GetPosition()) {
+		return status.Error(codes.FailedPrecondition, "you can only travel to a neighboring location")
+	}
+	if sprite := ActorSprite(origin, actorId); sprite != nil && !sprite.GetIsMoveable() {
+		return status.Error(codes.FailedPrecondition, "you cannot move")
+	}
+	if BlockingSprite(destination) != nil {
+		return status.Error(codes.FailedPrecondition, "the way is blocked")
+	}
+	return nil
+}
+
+// BlockingSprite returns the first sprite that cannot be passed through or pushed aside
+func BlockingSprite(coordinate *v1.MapCoordinateDetail) *v1.Sprite {
+	for _, sprite := range coordinate.GetSprites() {
+		if sprite.GetIsObstacle() && !sprite.GetIsMoveable() {
+			return sprite
+		}
+	}
+	return nil
+}
+
+// ActorSprite returns the sprite of the actor on the coordinate, nil when they have none
+func ActorSprite(coordinate *v1.MapCoordinateDetail, actorId string) *v1.Sprite {
+	for _, sprite := range coordinate.GetSprites() {
+		if sprite.GetActor() != nil && sprite.GetActor().GetUid() == actorId {
+			return sprite
+		}
+	}
+	return nil
+}
+
+// TransferActor takes the actor and their sprite off of the origin and puts them on the destination,
+// an actor without a sprite is given one
+func TransferActor(origin *v1.MapCoordinateDetail, destination *v1.MapCoordinateDetail, actorId string) {
+	var actor *v1.Actor
+	origin.Actors = Reduce(origin.GetActors(), func(a *v1.Actor) bool {
+		if a.GetUid() == actorId {
+			actor = a
+			return false
+		}
+		return true
+	})
+
+	var sprite *v1.Sprite
+	origin.Sprites = Reduce(origin.GetSprites(), func(s *v1.Sprite) bool {
+		if s.GetActor() != nil && s.GetActor().GetUid() == actorId {
+			sprite = s
+			return false
+		}
+		return true
+	})
+
+	if actor == nil {
+		actor = &v1.Actor{Uid: actorId}
+	}
+	if sprite == nil {
+		sprite = &v1.Sprite{
+			Uid:             GenerateUniqueId(),
+			Actor:           actor,
+			Characteristics: make([]*v1.Characteristic, 0),
+			IsObstacle:      true,
+			IsMoveable:      true,
+		}
+	}
+	destination.Actors = append(destination.Actors, actor)
+	destination.Sprites = append(destination.Sprites, sprite)
+}
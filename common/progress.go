@@ -0,0 +1,22 @@
+package common
+
+import "context"
+
+// ProgressReporter is told how much of a long running operation has completed
+type ProgressReporter func(completed int64, total int64)
+
+const (
+	progressReporterKey overseerContextKey = "progress:reporter"
+)
+
+// WithProgressReporter attaches a reporter to the context so slow operations can report how far along they are
+func WithProgressReporter(ctx context.Context, reporter ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressReporterKey, reporter)
+}
+
+// ReportProgress tells the reporter on the context about progress, it does nothing when no one is listening
+func ReportProgress(ctx context.Context, completed int64, total int64) {
+	if reporter, ok := ctx.Value(progressReporterKey).(ProgressReporter); ok && reporter != nil {
+		reporter(completed, total)
+	}
+}
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+const (
+	randomKey overseerContextKey = "random"
+)
+
+// NewSeed picks a seed for a world that wasn't given one
+func NewSeed() int64 {
+	return rand.New(rand.NewSource(time.Now().UnixNano())).Int63()
+}
+
+// SeededRandom derives a generator from a seed and salts such as a coordinate's position,
+// each part of a world draws from its own generator so the draws don't depend on the order the parts are generated in
+func SeededRandom(seed int64, salts ...int64) *rand.Rand {
+	mixed := uint64(seed)
+	for _, salt := range salts {
+		mixed = splitMix(mixed ^ uint64(salt))
+	}
+	return rand.New(rand.NewSource(int64(splitMix(mixed))))
+}
+
+// splitMix scrambles a value so neighboring salts give unrelated seeds
+func splitMix(value uint64) uint64 {
+	value += 0x9e3779b97f4a7c15
+	value = (value ^ (value >> 30)) * 0xbf58476d1ce4e5b9
+	value = (value ^ (value >> 27)) * 0x94d049bb133111eb
+	return value ^ (value >> 31)
+}
+
+// WithRandom attaches the generator random choices made under the context draw from, it is not safe for concurrent use
+func WithRandom(ctx context.Context, r *rand.Rand) context.Context {
+	return context.WithValue(ctx, randomKey, r)
+}
+
+// Random is the generator attached to the context, a time seeded one when none is attached
+func Random(ctx context.Context) *rand.Rand {
+	if r, ok := ctx.Value(randomKey).(*rand.Rand); ok && r != nil {
+		return r
+	}
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
@@ -0,0 +1,16 @@
+package common
+
+import "testing"
+
+func TestSeededRandom(t *testing.T) {
+	first := SeededRandom(42, 1, 2).Int63()
+	if again := SeededRandom(42, 1, 2).Int63(); again != first {
+		t.Errorf("SeededRandom(42, 1, 2) drew %d then %d; want the same draw", first, again)
+	}
+	if swapped := SeededRandom(42, 2, 1).Int63(); swapped == first {
+		t.Errorf("SeededRandom(42, 2, 1) drew %d like SeededRandom(42, 1, 2); want the salts to matter", swapped)
+	}
+	if other := SeededRandom(43, 1, 2).Int63(); other == first {
+		t.Errorf("SeededRandom(43, 1, 2) drew %d like SeededRandom(42, 1, 2); want the seed to matter", other)
+	}
+}
@@ -0,0 +1,21 @@
+package common
+
+import (
+	v1 "overseer/build/go"
+	"slices"
+)
+
+// IsReceiptVisibleTo reports whether a front end should show the receipt to the actor
+// whispered utterances are only visible to the speaker and the actors they were addressed to, everything else is public to the table
+func IsReceiptVisibleTo(receipt *v1.EventReceipt, actorUid string) bool {
+	utterance := receipt.GetUtterance()
+	if utterance == nil || !utterance.GetWhisper() {
+		return true
+	}
+
+	if utterance.GetActor() == actorUid {
+		return true
+	}
+
+	return slices.Contains(utterance.GetRecipients(), actorUid)
+}
@@ -0,0 +1,40 @@
+package common
+
+import (
+	v1 "overseer/build/go"
+	"testing"
+)
+
+func TestIsReceiptVisibleTo(t *testing.T) {
+	whisper := &v1.EventReceipt{Effect: &v1.EventReceipt_Utterance{Utterance: &v1.UtteranceEffect{
+		Actor:      "speaker",
+		Whisper:    true,
+		Recipients: []string{"target"},
+		Audience:   v1.UtteranceEffect_PLAYER,
+	}}}
+	spoken := &v1.EventReceipt{Effect: &v1.EventReceipt_Utterance{Utterance: &v1.UtteranceEffect{
+		Actor:      "speaker",
+		Recipients: []string{"target"},
+		Audience:   v1.UtteranceEffect_PLAYER,
+	}}}
+	ack := &v1.EventReceipt{Effect: &v1.EventReceipt_Ack{Ack: &v1.Acknowledgement{}}}
+
+	tests := []struct {
+		name     string
+		receipt  *v1.EventReceipt
+		actor    string
+		expected bool
+	}{
+		{"speaker sees their whisper", whisper, "speaker", true},
+		{"target sees the whisper", whisper, "target", true},
+		{"bystander does not see the whisper", whisper, "bystander", false},
+		{"bystander overhears spoken utterances", spoken, "bystander", true},
+		{"non utterances are public", ack, "bystander", true},
+	}
+
+	for _, tt := range tests {
+		if result := IsReceiptVisibleTo(tt.receipt, tt.actor); result != tt.expected {
+			t.Errorf("%s: IsReceiptVisibleTo(%v) = %v; want %v", tt.name, tt.actor, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package common
+
+import (
+	v1 "overseer/build/go"
+)
+
+const (
+	SightRadiusNormal int64 = 2
+	// SightRadiusOpen is how far an actor sees across an open field
+	SightRadiusOpen int64 = 3
+	// SightRadiusObstructed is how far an actor sees from inside a forest or on a mountain
+	SightRadiusObstructed int64 = 1
+	// SightRadiusMaximum is the furthest anyone sees, the window of coordinates to load when working out what is visible
+	SightRadiusMaximum = SightRadiusOpen
+)
+
+// SightRadius is how far an actor standing on the coordinate can see
+func SightRadius(coordinate *v1.MapCoordinateDetail) int64 {
+	switch coordinate.GetType() {
+	case v1.MapCoordinateDetail_OPEN_FIELD:
+		return SightRadiusOpen
+	case v1.MapCoordinateDetail_FOREST, v1.MapCoordinateDetail_MOUNTAIN:
+		return SightRadiusObstructed
+	default:
+		return SightRadiusNormal
+	}
+}
+
+// BlocksSight reports whether nothing behind the coordinate can be seen, the coordinate itself still can
+func BlocksSight(coordinate *v1.MapCoordinateDetail) bool {
+	return coordinate.GetType() == v1.MapCoordinateDetail_FOREST || coordinate.GetType() == v1.MapCoordinateDetail_MOUNTAIN
+}
+
+// VisibleFrom picks the coordinates of the window an actor standing on origin can see, those within their sight radius
+// with no forest or mountain in between
+func VisibleFrom(origin *v1.MapCoordinateDetail, window []*v1.MapCoordinateDetail) []*v1.MapCoordinateDetail {
+	radius := SightRadius(origin)
+	byPosition := make(map[[2]int64]*v1.MapCoordinateDetail, len(window))
+	for _, coordinate := range window {
+		byPosition[[2]int64{coordinate.GetPosition().GetX(), coordinate.GetPosition().GetY()}] = coordinate
+	}
+
+	visible := make([]*v1.MapCoordinateDetail, 0)
+	for _, coordinate := range window {
+		if distance(origin.GetPosition(), coordinate.GetPosition()) > radius {
+			continue
+		}
+		blocked := false
+		for _, between := range lineBetween(origin.GetPosition(), coordinate.GetPosition()) {
+			if BlocksSight(byPosition[between]) {
+				blocked = true
+				break
+			}
+		}
+		if !blocked {
+			visible = append(visible, coordinate)
+		}
+	}
+	return visible
+}
+
+// distance counts the steps between the positions, a diagonal step is a single step
+func distance(a *v1.MapPosition, b *v1.MapPosition) int64 {
+	return max(abs(a.GetX()-b.GetX()), abs(a.GetY()-b.GetY()))
+}
+
+func abs(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+// lineBetween is the positions a line of sight from a to b passes through, without a and b themselves
+func lineBetween(a *v1.MapPosition, b *v1.MapPosition) [][2]int64 {
+	line := make([][2]int64, 0)
+	x, y := a.GetX(), a.GetY()
+	if x == b.GetX() && y == b.GetY() {
+		return line
+	}
+	dx, dy := abs(b.GetX()-x), -abs(b.GetY()-y)
+	sx, sy := int64(1), int64(1)
+	if b.GetX() < x {
+		sx = -1
+	}
+	if b.GetY() < y {
+		sy = -1
+	}
+
+	// bresenham's line walks one position at a time trading the error along each axis
+	err := dx + dy
+	for {
+		doubled := 2 * err
+		if doubled >= dy {
+			err += dy
+			x += sx
+		}
+		if doubled <= dx {
+			err += dx
+			y += sy
+		}
+		if x == b.GetX() && y == b.GetY() {
+			return line
+		}
+		line = append(line, [2]int64{x, y})
+	}
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	v1 "overseer/build/go"
+	"testing"
+)
+
+// field is a square of open field around the origin with the given types placed over it
+func field(radius int64, placed map[[2]int64]v1.MapCoordinateDetail_CoordinateType) []*v1.MapCoordinateDetail {
+	window := make([]*v1.MapCoordinateDetail, 0)
+	for x := -radius; x <= radius; x++ {
+		for y := -radius; y <= radius; y++ {
+			coordinateType, ok := placed[[2]int64{x, y}]
+			if !ok {
+				coordinateType = v1.MapCoordinateDetail_OPEN_FIELD
+			}
+			window = append(window, &v1.MapCoordinateDetail{Position: &v1.MapPosition{X: x, Y: y}, Type: coordinateType})
+		}
+	}
+	return window
+}
+
+func visibleSet(origin *v1.MapCoordinateDetail, window []*v1.MapCoordinateDetail) map[[2]int64]bool {
+	visible := make(map[[2]int64]bool)
+	for _, coordinate := range VisibleFrom(origin, window) {
+		visible[[2]int64{coordinate.Position.X, coordinate.Position.Y}] = true
+	}
+	return visible
+}
+
+func TestVisibleFrom(t *testing.T) {
+	window := field(4, map[[2]int64]v1.MapCoordinateDetail_CoordinateType{
+		{0, 1}: v1.MapCoordinateDetail_FOREST,
+	})
+	origin := window[4*9+4]
+
+	visible := visibleSet(origin, window)
+	if len(visible) == 0 || !visible[[2]int64{3, 3}] {
+		t.Errorf("VisibleFrom(open field) did not see (3, 3); want the open field sight radius of %d", SightRadiusOpen)
+	}
+	if visible[[2]int64{4, 0}] {
+		t.Errorf("VisibleFrom(open field) saw (4, 0); want nothing beyond a radius of %d", SightRadiusOpen)
+	}
+	if !visible[[2]int64{0, 1}] {
+		t.Errorf("VisibleFrom(open field) did not see the forest at (0, 1); want the forest itself to be seen")
+	}
+	if visible[[2]int64{0, 2}] || visible[[2]int64{0, 3}] {
+		t.Errorf("VisibleFrom(open field) saw behind the forest at (0, 1); want the forest to block the view")
+	}
+
+	forest := &v1.MapCoordinateDetail{Position: &v1.MapPosition{X: 0, Y: 0}, Type: v1.MapCoordinateDetail_FOREST}
+	visible = visibleSet(forest, field(4, nil))
+	if len(visible) != 9 {
+		t.Errorf("VisibleFrom(forest) saw %d coordinates; want the 9 within a radius of %d", len(visible), SightRadiusObstructed)
+	}
+}
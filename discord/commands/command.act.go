@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"context"
+	v1 "overseer/build/go"
+	"overseer/common"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var actCommandLog = common.GetLogger("discord.commands.act")
+var actCommand = &discordgo.ApplicationCommand{
+	Name:        "act",
+	Description: "attempt an action, the dungeon master decides what it takes",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        "action",
+			Description: "what your character does",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Required:    true,
+		},
+		gameOption,
+	},
+}
+
+func actCommandFunc(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate) {
+	actCommandLog.Info("act command executed", "user", InteractionUser(event).ID, "guild", event.GuildID)
+
+	if err := deferInteraction(ctx, session, event, false); err != nil {
+		return
+	}
+	submitInteraction(ctx, session, event, &v1.InteractionEvent{
+		Interaction: &v1.InteractionEvent_Action{
+			Action: &v1.ActionInteraction{
+				Action: optionMap(event.ApplicationCommandData().Options)["action"].StringValue(),
+			},
+		},
+	})
+}
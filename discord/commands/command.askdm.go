@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"context"
+	v1 "overseer/build/go"
+	"overseer/common"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var askDmCommandLog = common.GetLogger("discord.commands.askdm")
+var askDmCommand = &discordgo.ApplicationCommand{
+	Name:        "ask-dm",
+	Description: "ask the dungeon master a question",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        "question",
+			Description: "what to ask",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Required:    true,
+		},
+		{
+			Name:        "private",
+			Description: "only you will see the question and the answer",
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+		},
+		gameOption,
+	},
+}
+
+func askDmCommandFunc(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate) {
+	options := optionMap(event.ApplicationCommandData().Options)
+	private := false
+	if option, ok := options["private"]; ok {
+		private = option.BoolValue()
+	}
+	askDmCommandLog.Info("ask-dm command executed", "user", InteractionUser(event).ID, "guild", event.GuildID, "private", private)
+
+	if err := deferInteraction(ctx, session, event, private); err != nil {
+		return
+	}
+	submitInteraction(ctx, session, event, &v1.InteractionEvent{
+		Interaction: &v1.InteractionEvent_Utterance{
+			Utterance: &v1.UtteranceInteraction{
+				Content: options["question"].StringValue(),
+				Utterance: &v1.UtteranceInteraction_DungeonMaster{
+					DungeonMaster: &v1.DungeonMasterUtterance{Whisper: private},
+				},
+			},
+		},
+	})
+}
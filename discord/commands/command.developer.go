@@ -22,7 +22,7 @@ var devCommand = &discordgo.ApplicationCommand{
 
 func devCommandFunc(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate) {
 	options := event.ApplicationCommandData().Options
-	devCommandLog.Info("dev command executed", "user", event.User.Username, "guild", event.GuildID, "options", options)
+	devCommandLog.Info("dev command executed", "user", InteractionUser(event).Username, "guild", event.GuildID, "options", options)
 
 	switch options[0].Name {
 	case "commands":
@@ -41,7 +41,7 @@ func devCommandFunc(ctx context.Context, session *discordgo.Session, event *disc
 }
 
 func devResetCommands(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate) {
-	devCommandLog.Info("resetting commands", "user", event.User.Username, "guild", event.GuildID)
+	devCommandLog.Info("resetting commands", "user", InteractionUser(event).Username, "guild", event.GuildID)
 	err := session.InteractionRespond(event.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
@@ -0,0 +1,229 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	v1 "overseer/build/go"
+	"overseer/client"
+	"overseer/common"
+	"overseer/discord/sessions"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var gameCommandLog = common.GetLogger("discord.commands.game")
+var gameCommand = &discordgo.ApplicationCommand{
+	Name:        "game",
+	Description: "start, join and end games",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        "new",
+			Description: "start a new game in this channel",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "name",
+					Description: "the name of the game",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+				{
+					Name:        "seed",
+					Description: "the seed of the world, the same seed makes the same world",
+					Type:        discordgo.ApplicationCommandOptionInteger,
+				},
+			},
+		},
+		{
+			Name:        "join",
+			Description: "join a game",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				gameOption,
+				{
+					Name:        "spectate",
+					Description: "follow the game without playing",
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+				},
+			},
+		},
+		{
+			Name:        "status",
+			Description: "show the state of a game",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Options:     []*discordgo.ApplicationCommandOption{gameOption},
+		},
+		{
+			Name:        "end",
+			Description: "end a game",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Options:     []*discordgo.ApplicationCommandOption{gameOption},
+		},
+	},
+}
+
+func gameCommandFunc(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate) {
+	subcommand := event.ApplicationCommandData().Options[0]
+	gameCommandLog.Info("game command executed", "user", InteractionUser(event).ID, "guild", event.GuildID, "subcommand", subcommand.Name)
+
+	if err := deferResponse(session, event, false); err != nil {
+		return
+	}
+	info, ok := requireActor(ctx, session, event)
+	if !ok {
+		return
+	}
+	options := optionMap(subcommand.Options)
+
+	switch subcommand.Name {
+	case "new":
+		gameNew(ctx, session, event, info, options)
+	case "join":
+		gameJoin(ctx, session, event, info, options)
+	case "status":
+		gameStatus(ctx, session, event, info, options)
+	case "end":
+		gameEnd(ctx, session, event, info, options)
+	default:
+		gameCommandLog.Error("unknown subcommand", "subcommand", subcommand.Name)
+		editResponse(session, event, "Unknown subcommand")
+	}
+}
+
+func gameNew(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate, info *common.OverseerContextInformation, options map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	overseer := client.FromContext(ctx)
+	name := options["name"].StringValue()
+	var seed *int64
+	if option, ok := options["seed"]; ok {
+		value := option.IntValue()
+		seed = &value
+	}
+
+	game, err := overseer.Games.CreateGame(ctx, &v1.CreateGameRequest{
+		Name:         name,
+		Theme:        v1.GameTheme_DEFAULT,
+		Participants: []*v1.Actor{info.Actor},
+		Seed:         seed,
+	})
+	if err != nil {
+		gameCommandLog.Error("failed to create game", info.LoggingContext("error", err)...)
+		editResponse(session, event, client.DescribeError(err))
+		return
+	}
+
+	// the game is played in a thread of its own, where threads are not available the channel is used
+	channelId := event.ChannelID
+	if thread, err := session.ThreadStart(event.ChannelID, game.GetName(), discordgo.ChannelTypeGuildPublicThread, 24*60); err == nil {
+		channelId = thread.ID
+	} else {
+		gameCommandLog.Warn("failed to start thread, binding the channel instead", info.LoggingContext("error", err, "channel", event.ChannelID)...)
+	}
+	if err = sessions.FromContext(ctx).Bind(channelId, game.GetUid()); err != nil {
+		gameCommandLog.Error("failed to bind game", info.LoggingContext("error", err, "game", game.GetUid())...)
+		editResponse(session, event, client.DescribeError(err))
+		return
+	}
+	editResponse(session, event, fmt.Sprintf("**%s** (`%s`) is being created in <#%s>", game.GetName(), game.GetUid(), channelId))
+
+	// the world is generated while the new game event is handled, the receipts are posted to the bound channel
+	_, err = overseer.Events.Submit(ctx, &v1.Event{
+		GameUid: game.GetUid(),
+		Actor:   info.Actor,
+		Origin:  eventOrigin(event),
+		Payload: &v1.Event_NewGame{
+			NewGame: &v1.NewGameEvent{
+				Theme:        game.GetTheme(),
+				Name:         game.GetName(),
+				Participants: game.GetParticipants(),
+			},
+		},
+	})
+	if err != nil {
+		gameCommandLog.Error("failed to start game", info.LoggingContext("error", err, "game", game.GetUid())...)
+		editResponse(session, event, client.DescribeError(err))
+		return
+	}
+
+	gameCommandLog.Info("game started", info.LoggingContext("game", game.GetUid(), "channel", channelId)...)
+	editResponse(session, event, fmt.Sprintf("**%s** (`%s`) has begun in <#%s>", game.GetName(), game.GetUid(), channelId))
+}
+
+func gameJoin(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate, info *common.OverseerContextInformation, options map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	gameUid, ok := resolveGame(ctx, session, event, options)
+	if !ok {
+		return
+	}
+	spectate := false
+	if option, ok := options["spectate"]; ok {
+		spectate = option.BoolValue()
+	}
+
+	game, err := client.FromContext(ctx).Games.JoinGame(ctx, &v1.JoinGameRequest{GameUid: gameUid, Spectator: spectate})
+	if err != nil {
+		gameCommandLog.Error("failed to join game", info.LoggingContext("error", err, "game", gameUid)...)
+		editResponse(session, event, client.DescribeError(err))
+		return
+	}
+	if err = sessions.FromContext(ctx).Bind(event.ChannelID, game.GetUid()); err != nil {
+		gameCommandLog.Error("failed to bind game", info.LoggingContext("error", err, "game", game.GetUid())...)
+		editResponse(session, event, client.DescribeError(err))
+		return
+	}
+
+	if spectate {
+		editResponse(session, event, fmt.Sprintf("<@%s> is watching **%s**", InteractionUser(event).ID, game.GetName()))
+		return
+	}
+	editResponse(session, event, fmt.Sprintf("<@%s> joined **%s**", InteractionUser(event).ID, game.GetName()))
+}
+
+func gameStatus(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate, info *common.OverseerContextInformation, options map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	gameUid, ok := resolveGame(ctx, session, event, options)
+	if !ok {
+		return
+	}
+
+	game, err := client.FromContext(ctx).Games.GetGame(ctx, &v1.GetGameRequest{GameUid: gameUid})
+	if err != nil {
+		gameCommandLog.Error("failed to get game", info.LoggingContext("error", err, "game", gameUid)...)
+		editResponse(session, event, client.DescribeError(err))
+		return
+	}
+
+	state := "being created"
+	if game.GetCompleted() {
+		state = "over"
+	} else if game.GetInitialized() {
+		state = "in progress"
+	}
+	participants := make([]string, 0, len(game.GetParticipants()))
+	for _, participant := range game.GetParticipants() {
+		participants = append(participants, sessions.FromContext(ctx).Name(ctx, participant.GetUid()))
+	}
+
+	editResponse(session, event, fmt.Sprintf("**%s** (`%s`) is %s\nPlayers: %s\nSpectators: %d",
+		game.GetName(),
+		game.GetUid(),
+		state,
+		strings.Join(participants, ", "),
+		len(game.GetSpectators()),
+	))
+}
+
+func gameEnd(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate, info *common.OverseerContextInformation, options map[string]*discordgo.ApplicationCommandInteractionDataOption) {
+	gameUid, ok := resolveGame(ctx, session, event, options)
+	if !ok {
+		return
+	}
+
+	if _, err := client.FromContext(ctx).Games.EndGame(ctx, &v1.EndGameRequest{GameUid: gameUid}); err != nil {
+		gameCommandLog.Error("failed to end game", info.LoggingContext("error", err, "game", gameUid)...)
+		editResponse(session, event, client.DescribeError(err))
+		return
+	}
+	sessions.FromContext(ctx).UnbindGame(gameUid)
+
+	gameCommandLog.Info("game ended", info.LoggingContext("game", gameUid)...)
+	editResponse(session, event, "The game has ended")
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	v1 "overseer/build/go"
+	"overseer/client"
+	"overseer/common"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var linkCommandLog = common.GetLogger("discord.commands.link")
+var linkCommand = &discordgo.ApplicationCommand{
+	Name:        "link",
+	Description: "link this discord account with your accounts elsewhere",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        "create",
+			Description: "create a code to redeem from slack or telnet",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+		},
+		{
+			Name:        "redeem",
+			Description: "redeem a code created elsewhere",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "code",
+					Description: "the code you were given",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "list",
+			Description: "list the accounts linked to this one",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+		},
+		{
+			Name:        "unlink",
+			Description: "unlink this discord account from your other accounts",
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+		},
+	},
+}
+
+func linkCommandFunc(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate) {
+	subcommand := event.ApplicationCommandData().Options[0]
+	linkCommandLog.Info("link command executed", "user", InteractionUser(event).ID, "guild", event.GuildID, "subcommand", subcommand.Name)
+
+	// codes are as good as a password for a few minutes so everything here stays private
+	if err := deferResponse(session, event, true); err != nil {
+		return
+	}
+	info, ok := requireActor(ctx, session, event)
+	if !ok {
+		return
+	}
+	users := client.FromContext(ctx).Users
+
+	switch subcommand.Name {
+	case "create":
+		code, err := users.CreateLinkCode(ctx, &v1.CreateLinkCodeRequest{})
+		if err != nil {
+			linkCommandLog.Error("failed to create link code", info.LoggingContext("error", err)...)
+			editResponse(session, event, client.DescribeError(err))
+			return
+		}
+		editResponse(session, event, fmt.Sprintf("Redeem `%s` from your other account before <t:%d:t>", code.GetCode(), code.GetExpiresAt()))
+	case "redeem":
+		_, err := users.RedeemLinkCode(ctx, &v1.RedeemLinkCodeRequest{Code: optionMap(subcommand.Options)["code"].StringValue()})
+		if err != nil {
+			linkCommandLog.Warn("failed to redeem link code", info.LoggingContext("error", err)...)
+			editResponse(session, event, client.DescribeError(err))
+			return
+		}
+		editResponse(session, event, "Your accounts are linked")
+	case "list":
+		actors, err := users.ListLinkedActors(ctx, &v1.ListLinkedActorsRequest{})
+		if err != nil {
+			linkCommandLog.Error("failed to list linked actors", info.LoggingContext("error", err)...)
+			editResponse(session, event, client.DescribeError(err))
+			return
+		}
+		lines := make([]string, 0, len(actors.GetActors()))
+		for _, actor := range actors.GetActors() {
+			lines = append(lines, "- "+describeLinkedActor(actor))
+		}
+		editResponse(session, event, fmt.Sprintf("Linked accounts:\n%s", strings.Join(lines, "\n")))
+	case "unlink":
+		if _, err := users.UnlinkActor(ctx, &v1.UnlinkActorRequest{}); err != nil {
+			linkCommandLog.Warn("failed to unlink actor", info.LoggingContext("error", err)...)
+			editResponse(session, event, client.DescribeError(err))
+			return
+		}
+		editResponse(session, event, "This discord account is no longer linked to your other accounts")
+	default:
+		linkCommandLog.Error("unknown subcommand", "subcommand", subcommand.Name)
+		editResponse(session, event, "Unknown subcommand")
+	}
+}
+
+func describeLinkedActor(actor *v1.Actor) string {
+	if actor.GetSource() == v1.Actor_APP_DISCORD {
+		return fmt.Sprintf("discord <@%s>", actor.GetSourceIdentity())
+	}
+	return fmt.Sprintf("%s %s", strings.ToLower(strings.TrimPrefix(actor.GetSource().String(), "APP_")), actor.GetSourceIdentity())
+}
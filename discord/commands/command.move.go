@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"context"
+	v1 "overseer/build/go"
+	"overseer/common"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var moveCommandLog = common.GetLogger("discord.commands.move")
+var moveCommand = &discordgo.ApplicationCommand{
+	Name:        "move",
+	Description: "move your character",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        "direction",
+			Description: "the direction to move in",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Required:    true,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "north", Value: "north"},
+				{Name: "north east", Value: "north-east"},
+				{Name: "east", Value: "east"},
+				{Name: "south east", Value: "south-east"},
+				{Name: "south", Value: "south"},
+				{Name: "south west", Value: "south-west"},
+				{Name: "west", Value: "west"},
+				{Name: "north west", Value: "north-west"},
+			},
+		},
+		gameOption,
+	},
+}
+
+func moveCommandFunc(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate) {
+	direction := optionMap(event.ApplicationCommandData().Options)["direction"].StringValue()
+	moveCommandLog.Info("move command executed", "user", InteractionUser(event).ID, "guild", event.GuildID, "direction", direction)
+
+	if err := deferInteraction(ctx, session, event, false); err != nil {
+		return
+	}
+	submitInteraction(ctx, session, event, &v1.InteractionEvent{
+		Interaction: &v1.InteractionEvent_Movement{
+			Movement: &v1.MovementInteraction{Direction: direction},
+		},
+	})
+}
@@ -2,9 +2,14 @@ package commands
 
 import (
 	"context"
+	"fmt"
+	v1 "overseer/build/go"
+	"overseer/client"
 	"overseer/common"
 
 	"github.com/bwmarrin/discordgo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var registerCommandLog = common.GetLogger("discord.commands.register")
@@ -14,13 +19,65 @@ var registerCommand = &discordgo.ApplicationCommand{
 }
 
 func registerCommandFunc(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate) {
-	err := session.InteractionRespond(event.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: "Registering user",
-		},
+	discordUser := InteractionUser(event)
+
+	if info, err := common.GetContextInformation(ctx); err == nil {
+		registerCommandLog.Info("user already registered", info.LoggingContext("guild", event.GuildID)...)
+		respondEphemeral(session, event, fmt.Sprintf("You are already registered as %s", info.Actor.GetUid()))
+		return
+	}
+
+	overseer := client.FromContext(ctx)
+	user, err := overseer.Users.RegisterUser(ctx, &v1.User{
+		Uid: common.SourceUserId(v1.Actor_APP_DISCORD, discordUser.ID),
 	})
 	if err != nil {
-		registerCommandLog.Error("failed to respond to register command", "error", err)
+		registerCommandLog.Error("failed to register user", "error", err, "user", discordUser.ID, "guild", event.GuildID)
+		respondEphemeral(session, event, "Registration failed, please try again later")
+		return
+	}
+
+	actor, err := registeredActor(ctx, discordUser)
+	if err != nil {
+		registerCommandLog.Error("failed to look up actor", "error", err, "user", discordUser.ID, "guild", event.GuildID)
+		respondEphemeral(session, event, "Registration failed, please try again later")
+		return
+	}
+	if actor == nil {
+		actor, err = overseer.Users.RegisterActor(ctx, &v1.RegisterActorRequest{
+			UserId:         user.GetUid(),
+			SourceIdentity: discordUser.ID,
+			Source:         v1.Actor_APP_DISCORD,
+			Metadata: &v1.ActorMetadata{
+				Value: &v1.ActorMetadata_Discord{
+					Discord: &v1.ActorSourceDiscord{
+						Guild:   event.GuildID,
+						Channel: event.ChannelID,
+					},
+				},
+			},
+		})
+	}
+	if err != nil {
+		registerCommandLog.Error("failed to register actor", "error", err, "user", discordUser.ID, "guild", event.GuildID)
+		respondEphemeral(session, event, "Registration failed, please try again later")
+		return
+	}
+
+	registerCommandLog.Info("registered user", "user", user.GetUid(), "actor", actor.GetUid(), "guild", event.GuildID)
+	respondEphemeral(session, event, fmt.Sprintf("Welcome %s, you are registered as %s", discordUser.Username, actor.GetUid()))
+}
+
+// registeredActor returns the actor an earlier attempt created for the discord user, nil when there is none.
+// registering the user is an upsert so running register again after a failure picks up where it left off
+// rather than leaving the player with a second actor
+func registeredActor(ctx context.Context, discordUser *discordgo.User) (*v1.Actor, error) {
+	actor, err := client.FromContext(ctx).Users.GetActorBySource(ctx, &v1.GetActorBySourceRequest{
+		Source:         v1.Actor_APP_DISCORD,
+		SourceIdentity: discordUser.ID,
+	})
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
 	}
+	return actor, err
 }
@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"context"
+	v1 "overseer/build/go"
+	"overseer/common"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var sayCommandLog = common.GetLogger("discord.commands.say")
+var sayCommand = &discordgo.ApplicationCommand{
+	Name:        "say",
+	Description: "say something to the table",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        "message",
+			Description: "what to say",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Required:    true,
+		},
+		gameOption,
+	},
+}
+
+func sayCommandFunc(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate) {
+	sayCommandLog.Info("say command executed", "user", InteractionUser(event).ID, "guild", event.GuildID)
+
+	if err := deferInteraction(ctx, session, event, false); err != nil {
+		return
+	}
+	submitInteraction(ctx, session, event, &v1.InteractionEvent{
+		Interaction: &v1.InteractionEvent_Utterance{
+			Utterance: &v1.UtteranceInteraction{
+				Content:   optionMap(event.ApplicationCommandData().Options)["message"].StringValue(),
+				Utterance: &v1.UtteranceInteraction_Table{Table: &v1.TableUtterance{}},
+			},
+		},
+	})
+}
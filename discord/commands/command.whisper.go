@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"context"
+	v1 "overseer/build/go"
+	"overseer/client"
+	"overseer/common"
+
+	"github.com/bwmarrin/discordgo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var whisperCommandLog = common.GetLogger("discord.commands.whisper")
+var whisperCommand = &discordgo.ApplicationCommand{
+	Name:        "whisper",
+	Description: "whisper to another player",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Name:        "player",
+			Description: "who to whisper to",
+			Type:        discordgo.ApplicationCommandOptionUser,
+			Required:    true,
+		},
+		{
+			Name:        "message",
+			Description: "what to whisper",
+			Type:        discordgo.ApplicationCommandOptionString,
+			Required:    true,
+		},
+		gameOption,
+	},
+}
+
+func whisperCommandFunc(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate) {
+	options := optionMap(event.ApplicationCommandData().Options)
+	target := options["player"].UserValue(nil)
+	whisperCommandLog.Info("whisper command executed", "user", InteractionUser(event).ID, "guild", event.GuildID, "target", target.ID)
+
+	// only the speaker sees the reply, the target is told separately
+	if err := deferInteraction(ctx, session, event, true); err != nil {
+		return
+	}
+
+	actor, err := client.FromContext(ctx).Users.GetActorBySource(client.AsSystem(ctx), &v1.GetActorBySourceRequest{
+		Source:         v1.Actor_APP_DISCORD,
+		SourceIdentity: target.ID,
+	})
+	if status.Code(err) == codes.NotFound {
+		editResponse(session, event, "That player has not registered")
+		return
+	}
+	if err != nil {
+		whisperCommandLog.Error("failed to resolve whisper target", "error", err, "target", target.ID)
+		editResponse(session, event, client.DescribeError(err))
+		return
+	}
+
+	submitInteraction(ctx, session, event, &v1.InteractionEvent{
+		Interaction: &v1.InteractionEvent_Utterance{
+			Utterance: &v1.UtteranceInteraction{
+				Content: options["message"].StringValue(),
+				Utterance: &v1.UtteranceInteraction_Player{
+					Player: &v1.PlayerUtterance{Target: actor, Whisper: true},
+				},
+			},
+		},
+	})
+}
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"context"
+	"overseer/client"
 	"overseer/common"
 
 	"github.com/bwmarrin/discordgo"
@@ -24,6 +25,34 @@ func init() {
 			Command: registerCommand,
 			Handler: registerCommandFunc,
 		},
+		gameCommand.Name: {
+			Command: gameCommand,
+			Handler: gameCommandFunc,
+		},
+		moveCommand.Name: {
+			Command: moveCommand,
+			Handler: moveCommandFunc,
+		},
+		sayCommand.Name: {
+			Command: sayCommand,
+			Handler: sayCommandFunc,
+		},
+		whisperCommand.Name: {
+			Command: whisperCommand,
+			Handler: whisperCommandFunc,
+		},
+		askDmCommand.Name: {
+			Command: askDmCommand,
+			Handler: askDmCommandFunc,
+		},
+		actCommand.Name: {
+			Command: actCommand,
+			Handler: actCommandFunc,
+		},
+		linkCommand.Name: {
+			Command: linkCommand,
+			Handler: linkCommandFunc,
+		},
 	}
 }
 
@@ -64,3 +93,22 @@ func DeregisterCommands(session *discordgo.Session, guildId string) error {
 
 	return nil
 }
+
+// respondEphemeral replies to the interaction with a message only the caller can see
+func respondEphemeral(session *discordgo.Session, event *discordgo.InteractionCreate, content string) {
+	err := session.InteractionRespond(event.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		commandsLogger.Error("failed to respond to interaction", "error", err, "guild", event.GuildID)
+	}
+}
+
+// RespondError replies to an interaction that could not be handled with why, only the caller can see it
+func RespondError(session *discordgo.Session, event *discordgo.InteractionCreate, err error) {
+	respondEphemeral(session, event, client.DescribeError(err))
+}
@@ -0,0 +1,11 @@
+package commands
+
+import "github.com/bwmarrin/discordgo"
+
+// InteractionUser returns who triggered the interaction, guild interactions only carry the member
+func InteractionUser(event *discordgo.InteractionCreate) *discordgo.User {
+	if event.Member != nil && event.Member.User != nil {
+		return event.Member.User
+	}
+	return event.User
+}
@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"context"
+	v1 "overseer/build/go"
+	"overseer/client"
+	"overseer/common"
+	"overseer/discord/sessions"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var interactionLog = common.GetLogger("discord.commands.interaction")
+
+// deferResponse acknowledges the interaction straight away, discord only waits three seconds for a response
+// and map generation or the dungeon master take a lot longer than that
+func deferResponse(session *discordgo.Session, event *discordgo.InteractionCreate, ephemeral bool) error {
+	data := &discordgo.InteractionResponseData{}
+	if ephemeral {
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
+	err := session.InteractionRespond(event.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: data,
+	})
+	if err != nil {
+		interactionLog.Error("failed to defer response", "error", err, "guild", event.GuildID)
+	}
+	return err
+}
+
+// deferInteraction defers the response to an interaction with the game, in a channel bound to the game
+// the receipts are posted to the channel anyway so only the caller sees the response
+func deferInteraction(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate, ephemeral bool) error {
+	return deferResponse(session, event, ephemeral || isBound(ctx, event))
+}
+
+// isBound reports whether the receipts of the game the interaction is about are posted to its channel
+func isBound(ctx context.Context, event *discordgo.InteractionCreate) bool {
+	bound, ok := sessions.FromContext(ctx).Game(event.ChannelID)
+	if !ok {
+		return false
+	}
+	option, ok := optionMap(event.ApplicationCommandData().Options)["game"]
+	return !ok || option.StringValue() == "" || option.StringValue() == bound
+}
+
+// editResponse replaces the deferred response with the final content
+func editResponse(session *discordgo.Session, event *discordgo.InteractionCreate, content string) {
+	editResponseWithEmbeds(session, event, content, nil)
+}
+
+func editResponseWithEmbeds(session *discordgo.Session, event *discordgo.InteractionCreate, content string, embeds []*discordgo.MessageEmbed) {
+	edit := &discordgo.WebhookEdit{Content: &content}
+	if len(embeds) > 0 {
+		edit.Embeds = &embeds
+	}
+	if _, err := session.InteractionResponseEdit(event.Interaction, edit); err != nil {
+		interactionLog.Error("failed to edit response", "error", err, "guild", event.GuildID)
+	}
+}
+
+// requireActor returns who is calling, unregistered discord users are told to register first
+func requireActor(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate) (*common.OverseerContextInformation, bool) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil || info.Actor == nil {
+		editResponse(session, event, "You need to `/register` before you can play")
+		return nil, false
+	}
+	return info, true
+}
+
+// resolveGame returns the game named in the options or the game bound to the channel
+func resolveGame(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate, options map[string]*discordgo.ApplicationCommandInteractionDataOption) (string, bool) {
+	if option, ok := options["game"]; ok && option.StringValue() != "" {
+		return option.StringValue(), true
+	}
+	if gameUid, ok := sessions.FromContext(ctx).Game(event.ChannelID); ok {
+		return gameUid, true
+	}
+	editResponse(session, event, "There is no game in this channel, start one with `/game new` or pass the game")
+	return "", false
+}
+
+func optionMap(options []*discordgo.ApplicationCommandInteractionDataOption) map[string]*discordgo.ApplicationCommandInteractionDataOption {
+	mapped := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(options))
+	for _, option := range options {
+		mapped[option.Name] = option
+	}
+	return mapped
+}
+
+// gameOption lets any game command name the game rather than rely on the channel
+var gameOption = &discordgo.ApplicationCommandOption{
+	Name:        "game",
+	Description: "the game, defaults to the game in this channel",
+	Type:        discordgo.ApplicationCommandOptionString,
+}
+
+// eventOrigin records where in discord an event came from
+func eventOrigin(event *discordgo.InteractionCreate) *v1.Event_Discord {
+	return &v1.Event_Discord{
+		Discord: &v1.EventOriginDiscord{
+			Guild:   event.GuildID,
+			Channel: event.ChannelID,
+		},
+	}
+}
+
+// submitInteraction submits an interaction by the caller to the game and replies with the receipts
+func submitInteraction(ctx context.Context, session *discordgo.Session, event *discordgo.InteractionCreate, interaction *v1.InteractionEvent) {
+	info, ok := requireActor(ctx, session, event)
+	if !ok {
+		return
+	}
+	gameUid, ok := resolveGame(ctx, session, event, optionMap(event.ApplicationCommandData().Options))
+	if !ok {
+		return
+	}
+
+	receipts, err := client.FromContext(ctx).Events.Submit(ctx, &v1.Event{
+		GameUid: gameUid,
+		Actor:   info.Actor,
+		Origin:  eventOrigin(event),
+		Payload: &v1.Event_Interaction{Interaction: interaction},
+	})
+	if err != nil {
+		interactionLog.Error("failed to submit interaction", info.LoggingContext("error", err, "game", gameUid)...)
+		editResponse(session, event, client.DescribeError(err))
+		return
+	}
+
+	if isBound(ctx, event) {
+		editResponse(session, event, "Sent")
+		return
+	}
+	editResponseWithEmbeds(session, event, "", sessions.FromContext(ctx).Embeds(ctx, visibleReceipts(info, receipts.GetReceipts())))
+}
+
+// visibleReceipts leaves out whispers meant for someone else, the caller can always see their own
+func visibleReceipts(info *common.OverseerContextInformation, receipts []*v1.EventReceipt) []*v1.EventReceipt {
+	visible := make([]*v1.EventReceipt, 0, len(receipts))
+	for _, receipt := range receipts {
+		if common.IsReceiptVisibleTo(receipt, info.Actor.GetUid()) {
+			visible = append(visible, receipt)
+		}
+	}
+	return visible
+}
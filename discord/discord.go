@@ -1,12 +1,14 @@
 package discord
 
 import (
+	"overseer/client"
 	"overseer/common"
 )
 
-func NewDiscordServer(botToken string) DiscordServer {
+func NewDiscordServer(botToken string, overseer *client.OverseerClient) DiscordServer {
 	return &defaultDiscordServer{
 		botToken: botToken,
+		overseer: overseer,
 		log:      common.GetLogger("discord.server"),
 	}
 }
@@ -3,7 +3,10 @@ package discord
 import (
 	"os"
 	"os/signal"
+	"overseer/client"
+	"overseer/common"
 	"overseer/discord/handlers"
+	"overseer/discord/sessions"
 	"syscall"
 
 	"github.com/bwmarrin/discordgo"
@@ -12,6 +15,7 @@ import (
 
 type defaultDiscordServer struct {
 	botToken string
+	overseer *client.OverseerClient
 	log      *charm.Logger
 }
 
@@ -24,18 +28,26 @@ func (d *defaultDiscordServer) Connect() error {
 
 	session.AddHandler(handlers.Ready)
 	session.AddHandler(handlers.GuildCreate)
-	session.AddHandler(handlers.MessageCreate)
-	session.AddHandler(handlers.InteractionCreate)
+	manager := sessions.NewManager(d.overseer, session, common.GetConfiguration().Discord.BindingsFile)
+	defer manager.Close()
+	session.AddHandler(handlers.MessageCreate(d.overseer, manager))
+	session.AddHandler(handlers.InteractionCreate(d.overseer, manager))
 
-	session.Identify.Intents = discordgo.IntentsGuilds
-	session.Identify.Intents = discordgo.IntentsGuildMessages
-	session.Identify.Intents = discordgo.IntentsGuildMessageReactions
+	// message content is needed to turn messages in bound channels into utterances
+	session.Identify.Intents = discordgo.IntentsGuilds |
+		discordgo.IntentsGuildMessages |
+		discordgo.IntentsGuildMessageReactions |
+		discordgo.IntentsMessageContent
 
 	err = session.Open()
 	if err != nil {
 		d.log.Error("failed to open connection to discord", "error", err)
 		return err
 	}
+	// games bound before a restart keep being posted to their channels
+	if err = manager.Restore(); err != nil {
+		d.log.Error("failed to restore bindings", "error", err)
+	}
 
 	d.log.Info("server waiting for events")
 	sigs := make(chan os.Signal, 1)
@@ -2,22 +2,47 @@ package handlers
 
 import (
 	"context"
+	v1 "overseer/build/go"
+	"overseer/client"
 	"overseer/common"
 	"overseer/discord/commands"
+	"overseer/discord/sessions"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 var interactionCreateLogger = common.GetLogger("discord.handlers.interaction")
 
-func InteractionCreate(session *discordgo.Session, event *discordgo.InteractionCreate) {
-	interactionCreateLogger.Debug("interaction received", "type", event.Type, "command", event.ApplicationCommandData().Name, "user", event.User.Username, "guild", event.GuildID)
-	if handler, ok := commands.Commands[event.ApplicationCommandData().Name]; ok {
-		interactionCreateLogger.Debug("handler found for command", "command", event.ApplicationCommandData().Name, "user", event.User.Username, "guild", event.GuildID)
-		// todo: enrich context with dependencies
-		handler.Handler(context.TODO(), session, event)
-		interactionCreateLogger.Debug("handler executed", "command", event.ApplicationCommandData().Name, "user", event.User.Username, "guild", event.GuildID)
-	} else {
-		interactionCreateLogger.Error("no handler found for command", "command", event.ApplicationCommandData().Name)
+// InteractionCreate dispatches slash commands, the context handed to commands carries the overseer client
+// the channel bindings and the user and actor registered for whoever triggered the interaction
+func InteractionCreate(overseer *client.OverseerClient, manager *sessions.Manager) func(session *discordgo.Session, event *discordgo.InteractionCreate) {
+	return func(session *discordgo.Session, event *discordgo.InteractionCreate) {
+		if event.Type != discordgo.InteractionApplicationCommand {
+			return
+		}
+
+		name := event.ApplicationCommandData().Name
+		discordUser := commands.InteractionUser(event)
+		interactionCreateLogger.Debug("interaction received", "type", event.Type, "command", name, "user", discordUser.Username, "guild", event.GuildID)
+
+		handler, ok := commands.Commands[name]
+		if !ok {
+			interactionCreateLogger.Error("no handler found for command", "command", name)
+			return
+		}
+
+		ctx, cancel := context.WithCancel(sessions.WithManager(client.WithClient(context.Background(), overseer), manager))
+		defer cancel()
+		ctx, err := overseer.ResolveIdentity(ctx, v1.Actor_APP_DISCORD, discordUser.ID)
+		if err != nil {
+			// carrying on would treat a registered player as a stranger, register would even give them a second actor
+			interactionCreateLogger.Error("failed to resolve identity", "error", err, "command", name, "user", discordUser.ID, "guild", event.GuildID)
+			commands.RespondError(session, event, err)
+			return
+		}
+
+		interactionCreateLogger.Debug("handler found for command", "command", name, "user", discordUser.Username, "guild", event.GuildID)
+		handler.Handler(ctx, session, event)
+		interactionCreateLogger.Debug("handler executed", "command", name, "user", discordUser.Username, "guild", event.GuildID)
 	}
 }
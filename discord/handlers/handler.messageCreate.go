@@ -1,20 +1,77 @@
 package handlers
 
 import (
+	"context"
+	v1 "overseer/build/go"
+	"overseer/client"
 	"overseer/common"
+	"overseer/discord/sessions"
+	"strings"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 var messageCreateLog = common.GetLogger("discord.handlers.messageCreate")
 
-func MessageCreate(session *discordgo.Session, message *discordgo.MessageCreate) {
-	if message.Author.ID == session.State.User.ID {
-		return
-	}
+// MessageCreate turns plain messages in a channel bound to a game into utterances to the table
+func MessageCreate(overseer *client.OverseerClient, manager *sessions.Manager) func(session *discordgo.Session, message *discordgo.MessageCreate) {
+	return func(session *discordgo.Session, message *discordgo.MessageCreate) {
+		if message.Author == nil || message.Author.ID == session.State.User.ID || message.Author.Bot {
+			return
+		}
+
+		if message.Content == "ping" {
+			messageCreateLog.Info("ping command received", "author", message.Author.Username)
+			session.ChannelMessageSend(message.ChannelID, "Pong!")
+			return
+		}
+
+		gameUid, ok := manager.Game(message.ChannelID)
+		if !ok || strings.TrimSpace(message.Content) == "" {
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctx, err := overseer.ResolveIdentity(ctx, v1.Actor_APP_DISCORD, message.Author.ID)
+		if err != nil {
+			messageCreateLog.Error("failed to resolve identity", "error", err, "user", message.Author.ID, "channel", message.ChannelID)
+			return
+		}
+		info, err := common.GetContextInformation(ctx)
+		if err != nil {
+			if _, err = session.ChannelMessageSendReply(message.ChannelID, "You need to `/register` before you can play", message.Reference()); err != nil {
+				messageCreateLog.Error("failed to reply to unregistered user", "error", err, "channel", message.ChannelID)
+			}
+			return
+		}
 
-	if message.Content == "ping" {
-		messageCreateLog.Info("ping command received", "author", message.Author.Username)
-		session.ChannelMessageSend(message.ChannelID, "Pong!")
+		// the utterance is posted back to the channel with the rest of the receipts
+		_, err = overseer.Events.Submit(ctx, &v1.Event{
+			GameUid: gameUid,
+			Actor:   info.Actor,
+			Origin: &v1.Event_Discord{
+				Discord: &v1.EventOriginDiscord{
+					Guild:   message.GuildID,
+					Channel: message.ChannelID,
+				},
+			},
+			Payload: &v1.Event_Interaction{
+				Interaction: &v1.InteractionEvent{
+					Interaction: &v1.InteractionEvent_Utterance{
+						Utterance: &v1.UtteranceInteraction{
+							Content:   message.Content,
+							Utterance: &v1.UtteranceInteraction_Table{Table: &v1.TableUtterance{}},
+						},
+					},
+				},
+			},
+		})
+		if err != nil {
+			messageCreateLog.Error("failed to submit utterance", info.LoggingContext("error", err, "game", gameUid)...)
+			if err = session.MessageReactionAdd(message.ChannelID, message.ID, "⚠️"); err != nil {
+				messageCreateLog.Error("failed to react to message", "error", err, "channel", message.ChannelID)
+			}
+		}
 	}
 }
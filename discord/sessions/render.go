@@ -0,0 +1,119 @@
+package sessions
+
+import (
+	"context"
+	"fmt"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	colorNarration = 0x95a5a6
+	colorError     = 0xe74c3c
+	colorUtterance = 0x3498db
+	colorWhisper   = 0x9b59b6
+	colorMovement  = 0xf1c40f
+	colorSuccess   = 0x2ecc71
+	colorFailure   = 0xe67e22
+)
+
+// Embeds renders the receipts for discord, receipts with nothing to show are left out
+func (m *Manager) Embeds(ctx context.Context, receipts []*v1.EventReceipt) []*discordgo.MessageEmbed {
+	embeds := make([]*discordgo.MessageEmbed, 0, len(receipts))
+	for _, receipt := range receipts {
+		if embed := m.Embed(ctx, receipt); embed != nil {
+			embeds = append(embeds, embed)
+		}
+	}
+	return embeds
+}
+
+// Embed renders a receipt for discord, nil when there is nothing to show
+func (m *Manager) Embed(ctx context.Context, receipt *v1.EventReceipt) *discordgo.MessageEmbed {
+	switch effect := receipt.GetEffect().(type) {
+	case *v1.EventReceipt_Ack:
+		if effect.Ack.GetMessage() == "" {
+			return nil
+		}
+		return &discordgo.MessageEmbed{Description: effect.Ack.GetMessage(), Color: colorNarration}
+	case *v1.EventReceipt_Error:
+		return &discordgo.MessageEmbed{Title: "Something went wrong", Description: effect.Error.GetMessage(), Color: colorError}
+	case *v1.EventReceipt_Utterance:
+		utterance := effect.Utterance
+		embed := &discordgo.MessageEmbed{
+			Description: fmt.Sprintf("%s: %s", m.Name(ctx, utterance.GetActor()), utterance.GetContent()),
+			Color:       colorUtterance,
+		}
+		if utterance.GetWhisper() {
+			embed.Title = "Whisper"
+			embed.Color = colorWhisper
+		}
+		return embed
+	case *v1.EventReceipt_GameState:
+		if movement := effect.GameState.GetMovement(); movement != nil {
+			embed := &discordgo.MessageEmbed{
+				Description: fmt.Sprintf("%s moves %s to (%d, %d)",
+					m.Name(ctx, movement.GetActor().GetUid()),
+					movement.GetDirection(),
+					movement.GetDestination().GetX(),
+					movement.GetDestination().GetY(),
+				),
+				Color: colorMovement,
+			}
+			if movement.GetDifficultTerrain() {
+				embed.Footer = &discordgo.MessageEmbedFooter{Text: "difficult terrain"}
+			}
+			return embed
+		}
+		if action := effect.GameState.GetAction(); action != nil {
+			embed := &discordgo.MessageEmbed{
+				Title:       action.GetAction(),
+				Description: fmt.Sprintf("%s rolled %d against %d", m.Name(ctx, action.GetActor().GetUid()), action.GetCheck().GetTotal(), action.GetDifficulty()),
+				Color:       colorFailure,
+				Fields: []*discordgo.MessageEmbedField{
+					{Name: "Outcome", Value: strings.ToLower(strings.ReplaceAll(action.GetOutcome().String(), "_", " ")), Inline: true},
+				},
+			}
+			if common.IsSuccessful(action.GetOutcome()) {
+				embed.Color = colorSuccess
+			}
+			if action.Damage != nil {
+				embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Damage", Value: fmt.Sprintf("%d", action.GetDamage().GetTotal()), Inline: true})
+			}
+			return embed
+		}
+	}
+	return nil
+}
+
+// Name is how an actor is shown, discord actors are mentioned
+func (m *Manager) Name(ctx context.Context, actorUid string) string {
+	if actorUid == auth.DungeonMasterActorId {
+		return "**Dungeon Master**"
+	}
+	actor, err := m.actor(ctx, actorUid)
+	if err != nil {
+		return fmt.Sprintf("**%s**", actorUid)
+	}
+	if actor.GetSource() == v1.Actor_APP_DISCORD {
+		return fmt.Sprintf("<@%s>", actor.GetSourceIdentity())
+	}
+	return fmt.Sprintf("**%s**", actor.GetSourceIdentity())
+}
+
+// actor looks up an actor as the system, actors never change so they are cached
+func (m *Manager) actor(ctx context.Context, actorUid string) (*v1.Actor, error) {
+	if cached, ok := m.actors.Load(actorUid); ok {
+		return cached.(*v1.Actor), nil
+	}
+	actor, err := m.overseer.Users.GetActor(ctx, &v1.GetActorRequest{ActorId: actorUid})
+	if err != nil {
+		return nil, err
+	}
+	m.actors.Store(actorUid, actor)
+	return actor, nil
+}
@@ -0,0 +1,110 @@
+package sessions
+
+import (
+	"context"
+	v1 "overseer/build/go"
+	"overseer/client"
+	"overseer/common"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	charm "github.com/charmbracelet/log"
+)
+
+// Manager binds games to discord channels or threads, every bound channel receives the receipts of its game
+type Manager struct {
+	overseer *client.OverseerClient
+	discord  *discordgo.Session
+	bindings *client.Bindings
+	actors   sync.Map
+	log      *charm.Logger
+}
+
+// NewManager keeps the bindings in the file so they survive the bot restarting
+func NewManager(overseer *client.OverseerClient, discord *discordgo.Session, file string) *Manager {
+	m := &Manager{
+		overseer: overseer,
+		discord:  discord,
+		log:      common.GetLogger("discord.sessions"),
+	}
+	m.bindings = client.NewBindings(overseer, file, m.deliver)
+	return m
+}
+
+// Restore binds the channels saved by a previous run again
+func (m *Manager) Restore() error {
+	return m.bindings.Restore()
+}
+
+// Bind posts the receipts of the game to the channel from now on, a channel is only ever bound to one game
+func (m *Manager) Bind(channelId string, gameUid string) error {
+	return m.bindings.Bind(client.Binding{ChannelId: channelId, GameUid: gameUid})
+}
+
+// Game returns the game bound to the channel
+func (m *Manager) Game(channelId string) (string, bool) {
+	binding, ok := m.bindings.Get(channelId)
+	return binding.GameUid, ok
+}
+
+// UnbindGame stops posting the receipts of the game to every channel it is bound to
+func (m *Manager) UnbindGame(gameUid string) {
+	m.bindings.UnbindGame(gameUid)
+}
+
+// Close stops watching every game, the bindings stay saved for the next run
+func (m *Manager) Close() {
+	m.bindings.Close()
+}
+
+// deliver posts the receipt to every channel bound to the game, whispers are sent once as direct messages to their targets instead
+func (m *Manager) deliver(ctx context.Context, bindings []client.Binding, receipt *v1.EventReceipt) {
+	embed := m.Embed(ctx, receipt)
+	if embed == nil {
+		return
+	}
+
+	utterance := receipt.GetUtterance()
+	if utterance == nil || !utterance.GetWhisper() {
+		for _, binding := range bindings {
+			if _, err := m.discord.ChannelMessageSendEmbed(binding.ChannelId, embed); err != nil {
+				m.log.Error("failed to post receipt", "error", err, "receipt", receipt.GetUid(), "channel", binding.ChannelId)
+			}
+		}
+		return
+	}
+
+	for _, recipient := range utterance.GetRecipients() {
+		actor, err := m.actor(ctx, recipient)
+		if err != nil {
+			m.log.Error("failed to get whisper target", "error", err, "receipt", receipt.GetUid(), "target", recipient)
+			continue
+		}
+		// targets on other front ends receive the whisper there
+		if actor.GetSource() != v1.Actor_APP_DISCORD {
+			continue
+		}
+
+		dm, err := m.discord.UserChannelCreate(actor.GetSourceIdentity())
+		if err != nil {
+			m.log.Error("failed to open direct message", "error", err, "target", recipient)
+			continue
+		}
+		if _, err = m.discord.ChannelMessageSendEmbed(dm.ID, embed); err != nil {
+			m.log.Error("failed to deliver whisper", "error", err, "receipt", receipt.GetUid(), "target", recipient)
+		}
+	}
+}
+
+type managerKey struct{}
+
+// WithManager makes the manager available to commands
+func WithManager(ctx context.Context, manager *Manager) context.Context {
+	return context.WithValue(ctx, managerKey{}, manager)
+}
+
+// FromContext returns the manager added with WithManager, nil if there is none
+func FromContext(ctx context.Context) *Manager {
+	manager, _ := ctx.Value(managerKey{}).(*Manager)
+	return manager
+}
@@ -0,0 +1,83 @@
+package engine
+
+import (
+	v1 "overseer/build/go"
+	"overseer/common"
+	"sync"
+
+	charm "github.com/charmbracelet/log"
+)
+
+// ReceiptBroker fans receipts out to everyone watching a game, it is in-process so watchers only see receipts produced by this node
+// services that record receipts outside of the bus share the bus broker so their receipts reach watchers too
+type ReceiptBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*receiptSubscription]struct{}
+	log         *charm.Logger
+}
+
+type receiptSubscription struct {
+	gameUid  string
+	receipts chan *v1.EventReceipt
+}
+
+func NewReceiptBroker() *ReceiptBroker {
+	return &ReceiptBroker{
+		subscribers: make(map[string]map[*receiptSubscription]struct{}),
+		log:         common.GetLogger("engine.broker"),
+	}
+}
+
+func (b *ReceiptBroker) subscribe(gameUid string) *receiptSubscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &receiptSubscription{
+		gameUid:  gameUid,
+		receipts: make(chan *v1.EventReceipt, common.GetConfiguration().ChannelBuffer),
+	}
+	if _, ok := b.subscribers[gameUid]; !ok {
+		b.subscribers[gameUid] = make(map[*receiptSubscription]struct{})
+	}
+	b.subscribers[gameUid][sub] = struct{}{}
+	b.log.Debug("watcher subscribed", "game_id", gameUid, "watchers", len(b.subscribers[gameUid]))
+	return sub
+}
+
+// unsubscribe removes the subscription closing its channel, it is safe to call more than once
+func (b *ReceiptBroker) unsubscribe(sub *receiptSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+func (b *ReceiptBroker) remove(sub *receiptSubscription) {
+	subs, ok := b.subscribers[sub.gameUid]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	close(sub.receipts)
+	if len(subs) == 0 {
+		delete(b.subscribers, sub.gameUid)
+	}
+	b.log.Debug("watcher unsubscribed", "game_id", sub.gameUid)
+}
+
+// Publish never blocks the bus, a watcher that cannot keep up is dropped and is expected to resume from its last receipt
+func (b *ReceiptBroker) Publish(receipt *v1.EventReceipt) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers[receipt.GetGameUid()] {
+		select {
+		case sub.receipts <- receipt:
+		default:
+			b.log.Warn("watcher fell behind and was dropped", "game_id", receipt.GetGameUid(), "receipt_id", receipt.GetUid())
+			b.remove(sub)
+		}
+	}
+}
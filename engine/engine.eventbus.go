@@ -3,6 +3,7 @@ package engine
 import (
 	"context"
 	"fmt"
+	"overseer/auth"
 	v1 "overseer/build/go"
 	"overseer/common"
 	"overseer/storage"
@@ -15,26 +16,32 @@ import (
 )
 
 type defaultEventBus struct {
-	handlers map[EventHandler]EventPredicate
+	// handlers are kept in pipeline order, see orderHandlers
+	handlers   []EventHandler
+	predicates map[EventHandler]EventPredicate
 	// TODO make this a games client instead of server to avoid loopback dependence
 	games v1.GamesServer
 	// TODO make this a games client instead of server to avoid loopback dependence
 	users  v1.UsersServer
 	events storage.EventStore
+	broker *ReceiptBroker
 	log    *charm.Logger
 }
 
-func NewEventBus(handlers []EventHandler, games v1.GamesServer, user v1.UsersServer, events storage.EventStore) EventBus {
-	hMap := make(map[EventHandler]EventPredicate)
-	for _, h := range handlers {
-		hMap[h] = h.Predicate()
+func NewEventBus(handlers []EventHandler, games v1.GamesServer, user v1.UsersServer, events storage.EventStore, broker *ReceiptBroker) EventBus {
+	ordered := orderHandlers(handlers)
+	predicates := make(map[EventHandler]EventPredicate)
+	for _, h := range ordered {
+		predicates[h] = h.Predicate()
 	}
 	return &defaultEventBus{
-		handlers: hMap,
-		games:    games,
-		users:    user,
-		events:   events,
-		log:      common.GetLogger("engine.eventbus"),
+		handlers:   ordered,
+		predicates: predicates,
+		games:      games,
+		users:      user,
+		events:     events,
+		broker:     broker,
+		log:        common.GetLogger("engine.eventbus"),
 	}
 }
 
@@ -105,8 +112,37 @@ func (b *defaultEventBus) validateActors(ctx context.Context, current *v1.Actor,
 	return nil
 }
 
+// validateSubmitter makes sure players only act as their own actor, the system may submit events for anyone
+func (b *defaultEventBus) validateSubmitter(ctx context.Context, event *v1.Event) error {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return err
+	}
+	if event.GetActor() == nil {
+		b.log.Error("event has no actor", info.LoggingContext("game_id", event.GetGameUid())...)
+		return status.Error(codes.InvalidArgument, "event has no actor")
+	}
+	if info.User.GetUid() == auth.SystemUserId {
+		return nil
+	}
+	if info.Actor == nil || info.Actor.GetUid() != event.GetActor().GetUid() {
+		b.log.Error("event submitted for another actor",
+			info.LoggingContext(
+				"game_id", event.GetGameUid(),
+				"event_actor", event.GetActor().GetUid(),
+			)...,
+		)
+		return status.Error(codes.PermissionDenied, "events can only be submitted for your own actor")
+	}
+	return nil
+}
+
 func (b *defaultEventBus) Submit(ctx context.Context, event *v1.Event) (<-chan *v1.EventReceipt, error) {
 	results := make(chan *v1.EventReceipt, common.GetConfiguration().ChannelBuffer)
+	if err := b.validateSubmitter(ctx, event); err != nil {
+		close(results)
+		return results, err
+	}
 	exists, err := b.gameExists(ctx, event.GameUid)
 	if err != nil {
 		b.log.Error("failed to check if game exists",
@@ -170,7 +206,19 @@ func (b *defaultEventBus) Submit(ctx context.Context, event *v1.Event) (<-chan *
 
 func (b *defaultEventBus) executeSubmission(ctx context.Context, event *v1.EventRecord, results chan<- *v1.EventReceipt) {
 	info, _ := common.GetContextInformation(ctx)
-	for handler, predicate := range b.handlers {
+	ctx, state := withPipeline(ctx)
+	for _, handler := range b.handlers {
+		if state.isStopped() {
+			b.log.Debug("propagation stopped",
+				info.LoggingContext(
+					"game_id", event.GameUid,
+					"event_id", event.Uid,
+					"skipped", handler.Name(),
+				)...,
+			)
+			break
+		}
+		predicate := b.predicates[handler]
 		if eval, err := predicate(ctx, event); err != nil {
 			b.log.Error("failed to evaluate predicate",
 				"error", err,
@@ -190,6 +238,7 @@ func (b *defaultEventBus) executeSubmission(ctx context.Context, event *v1.Event
 				claimId := common.GenerateRandomStringFromSeed(
 					"eventbus",
 					event.GameUid,
+					event.Uid,
 					handler.Name(),
 					fmt.Sprintf("%d", time.Now().UTC().Unix()),
 				)
@@ -218,6 +267,7 @@ func (b *defaultEventBus) executeSubmission(ctx context.Context, event *v1.Event
 						Uid: common.GenerateRandomStringFromSeed(
 							"eventbus",
 							event.GameUid,
+							event.Uid,
 							handler.Name(),
 							"error",
 							fmt.Sprintf("%d", time.Now().UTC().Unix()),
@@ -256,6 +306,7 @@ func (b *defaultEventBus) executeSubmission(ctx context.Context, event *v1.Event
 						Uid: common.GenerateRandomStringFromSeed(
 							"eventbus",
 							event.GameUid,
+							event.Uid,
 							handler.Name(),
 							"error",
 							fmt.Sprintf("%d", time.Now().UTC().Unix()),
@@ -283,14 +334,23 @@ func (b *defaultEventBus) executeSubmission(ctx context.Context, event *v1.Event
 					return
 				}
 
+				stage, priority := stageOf(handler)
 				b.log.Debug("handling event",
 					info.LoggingContext(
 						"game_id", event.GameUid,
 						"handler", handler.Name(),
+						"stage", stage.String(),
+						"priority", priority,
 						"event_id", event.Uid,
 					)...,
 				)
-				receipt, err := handler.Handle(ctx, event)
+				// the handler works under the lease, storage refuses its writes once the game has moved on and
+				// losing the lease cancels the handler rather than letting it keep writing
+				handlerCtx, cancelHandler := context.WithCancelCause(
+					common.WithGameLease(ctx, event.GameUid, lock.GetLock().GetFencingToken()),
+				)
+				stopHeartbeat := b.heartbeat(ctx, lock.GetLock(), cancelHandler)
+				receipt, err := handler.Handle(handlerCtx, event)
 				if err != nil {
 					b.log.Error("failed to handle event",
 						"error", err,
@@ -299,6 +359,7 @@ func (b *defaultEventBus) executeSubmission(ctx context.Context, event *v1.Event
 						Uid: common.GenerateRandomStringFromSeed(
 							"eventbus",
 							event.GameUid,
+							event.Uid,
 							handler.Name(),
 							"error",
 							fmt.Sprintf("%d", time.Now().UTC().Unix()),
@@ -322,6 +383,23 @@ func (b *defaultEventBus) executeSubmission(ctx context.Context, event *v1.Event
 							)...,
 						)
 					}
+					stopHeartbeat()
+					cancelHandler(nil)
+					// release the lease so the next handler is not left waiting for it to expire
+					if _, err := b.games.UnlockGame(ctx, &v1.UnlockGameRequest{
+						GameUid:      event.GameUid,
+						ClaimUid:     claimId,
+						FencingToken: lock.GetLock().GetFencingToken(),
+					}); err != nil {
+						b.log.Error("failed to unlock game after handler failed",
+							info.LoggingContext(
+								"error", err,
+								"game_id", event.GameUid,
+								"handler", handler.Name(),
+								"event_id", event.Uid,
+							)...,
+						)
+					}
 					continue
 				}
 
@@ -334,12 +412,17 @@ func (b *defaultEventBus) executeSubmission(ctx context.Context, event *v1.Event
 							"receipt_id", r.Uid,
 						)...,
 					)
+					state.record(r)
 					results <- r
+					b.broker.Publish(r)
 				}
+				stopHeartbeat()
+				cancelHandler(nil)
 
 				unlock, err := b.games.UnlockGame(ctx, &v1.UnlockGameRequest{
-					GameUid:  event.GameUid,
-					ClaimUid: claimId,
+					GameUid:      event.GameUid,
+					ClaimUid:     claimId,
+					FencingToken: lock.GetLock().GetFencingToken(),
 				})
 				if err != nil {
 					b.log.Error("failed to unlock game",
@@ -354,6 +437,7 @@ func (b *defaultEventBus) executeSubmission(ctx context.Context, event *v1.Event
 						Uid: common.GenerateRandomStringFromSeed(
 							"eventbus",
 							event.GameUid,
+							event.Uid,
 							handler.Name(),
 							"error",
 							fmt.Sprintf("%d", time.Now().UTC().Unix()),
@@ -392,6 +476,7 @@ func (b *defaultEventBus) executeSubmission(ctx context.Context, event *v1.Event
 						Uid: common.GenerateRandomStringFromSeed(
 							"eventbus",
 							event.GameUid,
+							event.Uid,
 							handler.Name(),
 							"error",
 							fmt.Sprintf("%d", time.Now().UTC().Unix()),
@@ -424,6 +509,50 @@ func (b *defaultEventBus) executeSubmission(ctx context.Context, event *v1.Event
 	close(results)
 }
 
+// heartbeat keeps renewing the lease while a handler runs so long running handlers do not lose the game
+// the returned function stops the renewals and must be called before the lease is released,
+// a renewal that fails cancels the handler since it can no longer be sure it holds the game
+func (b *defaultEventBus) heartbeat(ctx context.Context, lock *v1.GameLock, cancel context.CancelCauseFunc) func() {
+	info, _ := common.GetContextInformation(ctx)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	lease := time.Duration(common.GetConfiguration().Locks.LeaseSeconds) * time.Second
+	ticker := time.NewTicker(max(lease/3, time.Second))
+
+	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				_, err := b.games.RenewLock(ctx, &v1.RenewLockRequest{
+					GameUid:      lock.GetGameUid(),
+					ClaimUid:     lock.GetClaimUid(),
+					FencingToken: lock.GetFencingToken(),
+				})
+				if err != nil {
+					b.log.Error("failed to renew lease",
+						info.LoggingContext(
+							"error", err,
+							"game_id", lock.GetGameUid(),
+							"claim_id", lock.GetClaimUid(),
+						)...,
+					)
+					cancel(status.Error(codes.Aborted, fmt.Sprintf("lost the game lock: %s", err)))
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
 func (b *defaultEventBus) sendErrorReceipt(ctx context.Context, receipt *v1.EventReceipt, results chan<- *v1.EventReceipt) error {
 	info, err := common.GetContextInformation(ctx)
 	if err != nil {
@@ -446,5 +575,30 @@ func (b *defaultEventBus) sendErrorReceipt(ctx context.Context, receipt *v1.Even
 	}
 
 	results <- receipt
+	b.broker.Publish(receipt)
 	return nil
 }
+
+func (b *defaultEventBus) Watch(ctx context.Context, gameUid string) (<-chan *v1.EventReceipt, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	game, err := b.games.GetGame(ctx, &v1.GetGameRequest{GameUid: gameUid})
+	if err != nil {
+		b.log.Error("failed to get game", info.LoggingContext("error", err, "game_id", gameUid)...)
+		return nil, err
+	}
+	if game == nil {
+		return nil, status.Error(codes.NotFound, "game not found")
+	}
+
+	sub := b.broker.subscribe(gameUid)
+	go func() {
+		<-ctx.Done()
+		b.broker.unsubscribe(sub)
+	}()
+
+	return sub.receipts, nil
+}
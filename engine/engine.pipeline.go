@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"context"
+	v1 "overseer/build/go"
+	"slices"
+	"sync"
+)
+
+// HandlerStage orders handlers within the pipeline, every handler in a stage runs before any handler in a later stage
+type HandlerStage int
+
+const (
+	// StageValidate handlers check the event makes sense before anything changes
+	StageValidate HandlerStage = iota
+	// StageMutate handlers change the state of the game
+	StageMutate
+	// StageNarrate handlers describe what happened
+	StageNarrate
+	// StageNotify handlers tell the players and the outside world
+	StageNotify
+)
+
+func (s HandlerStage) String() string {
+	switch s {
+	case StageValidate:
+		return "validate"
+	case StageMutate:
+		return "mutate"
+	case StageNarrate:
+		return "narrate"
+	case StageNotify:
+		return "notify"
+	default:
+		return "unknown"
+	}
+}
+
+// StagedEventHandler is a handler that knows where it belongs in the pipeline
+// handlers that do not implement it run in the mutate stage with priority 0
+type StagedEventHandler interface {
+	EventHandler
+	Stage() HandlerStage
+	// Priority orders handlers within a stage, higher priorities run first
+	Priority() int
+}
+
+type stagedHandler struct {
+	EventHandler
+	stage    HandlerStage
+	priority int
+}
+
+func (h stagedHandler) Stage() HandlerStage {
+	return h.stage
+}
+
+func (h stagedHandler) Priority() int {
+	return h.priority
+}
+
+// Register places the handler in a stage of the pipeline with the given priority
+func Register(handler EventHandler, stage HandlerStage, priority int) StagedEventHandler {
+	return stagedHandler{
+		EventHandler: handler,
+		stage:        stage,
+		priority:     priority,
+	}
+}
+
+func stageOf(handler EventHandler) (HandlerStage, int) {
+	if staged, ok := handler.(StagedEventHandler); ok {
+		return staged.Stage(), staged.Priority()
+	}
+	return StageMutate, 0
+}
+
+// orderHandlers sorts the handlers by stage then priority, handlers that tie keep the order they were registered in
+func orderHandlers(handlers []EventHandler) []EventHandler {
+	ordered := slices.Clone(handlers)
+	slices.SortStableFunc(ordered, func(a, b EventHandler) int {
+		aStage, aPriority := stageOf(a)
+		bStage, bPriority := stageOf(b)
+		if aStage != bStage {
+			return int(aStage) - int(bStage)
+		}
+		return bPriority - aPriority
+	})
+	return ordered
+}
+
+type pipelineKey struct{}
+
+// pipeline is the state shared by every handler dispatched for a single event
+type pipeline struct {
+	mu       sync.Mutex
+	receipts []*v1.EventReceipt
+	stopped  bool
+}
+
+func withPipeline(ctx context.Context) (context.Context, *pipeline) {
+	p := &pipeline{receipts: make([]*v1.EventReceipt, 0)}
+	return context.WithValue(ctx, pipelineKey{}, p), p
+}
+
+func (p *pipeline) record(receipt *v1.EventReceipt) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.receipts = append(p.receipts, receipt)
+}
+
+func (p *pipeline) isStopped() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopped
+}
+
+// PriorReceipts returns the receipts emitted by the handlers that already ran for the event being handled
+func PriorReceipts(ctx context.Context) []*v1.EventReceipt {
+	p, ok := ctx.Value(pipelineKey{}).(*pipeline)
+	if !ok {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.receipts)
+}
+
+// StopPropagation prevents any handler after the current one from seeing the event
+// receipts the current handler is still streaming are delivered as normal
+func StopPropagation(ctx context.Context) {
+	p, ok := ctx.Value(pipelineKey{}).(*pipeline)
+	if !ok {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/generative"
+	"overseer/storage"
+
+	charm "github.com/charmbracelet/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type dungeonMasterHandler struct {
+	dm     generative.DungeonMasterService
+	events storage.EventStore
+	games  storage.GameStore
+	maps   storage.MapStore
+	log    *charm.Logger
+}
+
+func NewDungeonMasterHandler(dm generative.DungeonMasterService, games storage.GameStore, maps storage.MapStore, events storage.EventStore) engine.EventHandler {
+	return dungeonMasterHandler{
+		dm:     dm,
+		games:  games,
+		maps:   maps,
+		events: events,
+		log:    common.GetLogger("engine.handler.dm"),
+	}
+}
+
+func (h dungeonMasterHandler) Name() string {
+	return "game.dm.utterance"
+}
+
+func (h dungeonMasterHandler) Predicate() engine.EventPredicate {
+	return func(ctx context.Context, event *v1.EventRecord) (bool, error) {
+		if event == nil {
+			return false, status.Error(codes.InvalidArgument, "event is nil")
+		}
+		if event.GetPayload().GetInteraction().GetUtterance().GetDungeonMaster() == nil {
+			return false, nil
+		}
+		return true, nil
+	}
+}
+
+func (h dungeonMasterHandler) Handle(ctx context.Context, payload *v1.EventRecord) (<-chan *v1.EventReceipt, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	utterance := payload.GetPayload().GetInteraction().GetUtterance()
+	speaker := payload.GetPayload().GetActor()
+	h.log.Info("dungeon master addressed", info.LoggingContext("speaker", speaker.GetUid(), "whisper", utterance.GetDungeonMaster().GetWhisper())...)
+
+	results := make(chan *v1.EventReceipt, common.GetConfiguration().ChannelBuffer)
+	if speaker == nil || rejected(engine.PriorReceipts(ctx)) {
+		// the dungeon master only answers what the table heard, whoever turned the utterance away told the player why
+		close(results)
+		return results, nil
+	}
+
+	game, err := h.games.GetGame(ctx, payload.GetGameUid())
+	if err != nil {
+		h.log.Error("failed to get game", info.LoggingContext("error", err)...)
+		close(results)
+		return nil, err
+	}
+
+	location, err := locateActor(ctx, h.maps, payload.GetGameUid(), speaker.GetUid())
+	if err != nil {
+		h.log.Error("failed to locate speaker", info.LoggingContext("error", err)...)
+		close(results)
+		return nil, err
+	}
+
+	history, err := transcript(ctx, h.events, payload, game.GetParticipants(), utterance.GetDungeonMaster().GetWhisper())
+	if err != nil {
+		h.log.Error("failed to build transcript", info.LoggingContext("error", err)...)
+		close(results)
+		return nil, err
+	}
+
+	reply, err := h.dm.Respond(ctx, generative.DungeonMasterScene{
+		Theme:        game.GetTheme(),
+		Speaker:      speaker,
+		Participants: game.GetParticipants(),
+		Location:     location,
+		History:      history,
+		Utterance:    utterance.GetContent(),
+	})
+	if err != nil {
+		h.log.Error("dungeon master failed to respond", info.LoggingContext("error", err)...)
+		close(results)
+		return nil, err
+	}
+
+	whisper := utterance.GetDungeonMaster().GetWhisper()
+	recipients := []string{speaker.GetUid()}
+	if !whisper {
+		recipients = common.Filter(game.GetParticipants(), func(a *v1.Actor) string {
+			return a.GetUid()
+		})
+	}
+
+	go func() {
+		defer close(results)
+		for paragraph := range reply {
+			if paragraph.Err != nil {
+				if err := failEvent(ctx, h.events, payload, "the dungeon master lost their train of thought", results); err != nil {
+					h.log.Error("failed to record broken reply", info.LoggingContext("error", err)...)
+				}
+				return
+			}
+			receipt := newReceipt(payload)
+			receipt.Effect = &v1.EventReceipt_Utterance{Utterance: &v1.UtteranceEffect{
+				Actor:      auth.DungeonMasterActorId,
+				Content:    paragraph.Content,
+				Whisper:    whisper,
+				Recipients: recipients,
+				Audience:   v1.UtteranceEffect_DUNGEON_MASTER,
+			}}
+			if err := emitReceipt(ctx, h.events, receipt, results); err != nil {
+				h.log.Error("failed to record dungeon master reply", info.LoggingContext("error", err)...)
+			}
+		}
+		h.log.Debug("dungeon master reply delivered", info.LoggingContext("game", payload.GetGameUid())...)
+	}()
+
+	return results, nil
+}
+
+// locateActor finds where the actor is standing, games without a map yet have no location
+func locateActor(ctx context.Context, maps storage.MapStore, gameUid string, actorUid string) (*v1.MapCoordinateDetail, error) {
+	gameMap, err := maps.GetMapForGame(ctx, gameUid)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := maps.GetActorCoordinate(ctx, gameMap.GetUid(), actorUid)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	return location, err
+}
+
+// transcript turns the recent receipts of the game into the conversation the dungeon master remembers,
+// only what the speaker heard is remembered and whispers are left out of replies the whole table hears
+func transcript(ctx context.Context, events storage.EventStore, payload *v1.EventRecord, participants []*v1.Actor, whisper bool) ([]generative.DungeonMasterLine, error) {
+	records, err := events.GetRecentEvents(ctx, payload.GetGameUid(), common.GetConfiguration().DungeonMaster.HistoryLength)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
+	for _, participant := range participants {
+		names[participant.GetUid()] = generative.ActorName(participant)
+	}
+	nameOf := func(uid string) string {
+		if name, ok := names[uid]; ok {
+			return name
+		}
+		return uid
+	}
+
+	speaker := payload.GetPayload().GetActor().GetUid()
+	lines := make([]generative.DungeonMasterLine, 0)
+	for _, record := range records {
+		if record.GetUid() == payload.GetUid() {
+			continue
+		}
+		for _, receipt := range record.GetReceipts() {
+			if !common.IsReceiptVisibleTo(receipt, speaker) || (!whisper && receipt.GetUtterance().GetWhisper()) {
+				continue
+			}
+			if utterance := receipt.GetUtterance(); utterance != nil {
+				lines = append(lines, generative.DungeonMasterLine{
+					FromDungeonMaster: utterance.GetActor() == auth.DungeonMasterActorId,
+					Speaker:           nameOf(utterance.GetActor()),
+					Content:           utterance.GetContent(),
+				})
+			}
+			if movement := receipt.GetGameState().GetMovement(); movement != nil {
+				lines = append(lines, generative.DungeonMasterLine{
+					Speaker: nameOf(movement.GetActor().GetUid()),
+					Content: fmt.Sprintf("*travels %s*", movement.GetDirection()),
+				})
+			}
+		}
+	}
+
+	return lines, nil
+}
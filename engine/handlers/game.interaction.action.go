@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/generative"
+	"overseer/storage"
+	"strings"
+
+	charm "github.com/charmbracelet/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// baseDifficulty is the difficulty of any check the rules have no better opinion on
+	baseDifficulty int64 = 10
+	// difficultTerrainPenalty is added to checks made while standing in difficult terrain
+	difficultTerrainPenalty int64 = 5
+	// attackDamageDie is the die rolled for the damage of a successful attack
+	attackDamageDie int64 = 6
+)
+
+type actionHandler struct {
+	dm     generative.DungeonMasterService
+	events storage.EventStore
+	games  storage.GameStore
+	maps   storage.MapStore
+	log    *charm.Logger
+}
+
+// NewActionHandler resolves free-form actions, the dungeon master classifies and narrates the action while the dice and outcome are decided here
+func NewActionHandler(dm generative.DungeonMasterService, games storage.GameStore, maps storage.MapStore, events storage.EventStore) engine.EventHandler {
+	return actionHandler{
+		dm:     dm,
+		games:  games,
+		maps:   maps,
+		events: events,
+		log:    common.GetLogger("engine.handler.action"),
+	}
+}
+
+func (h actionHandler) Name() string {
+	return "game.interaction.action"
+}
+
+func (h actionHandler) Predicate() engine.EventPredicate {
+	return func(ctx context.Context, event *v1.EventRecord) (bool, error) {
+		if event == nil {
+			return false, status.Error(codes.InvalidArgument, "event is nil")
+		}
+		if event.GetPayload().GetInteraction().GetAction() == nil {
+			return false, nil
+		}
+		return true, nil
+	}
+}
+
+func (h actionHandler) Handle(ctx context.Context, payload *v1.EventRecord) (<-chan *v1.EventReceipt, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	action := payload.GetPayload().GetInteraction().GetAction()
+	actor := payload.GetPayload().GetActor()
+	if actor == nil {
+		err = status.Error(codes.InvalidArgument, "action event has no actor")
+		h.log.Error("failed to handle action", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	h.log.Info("handling action event", info.LoggingContext("actor", actor.GetUid())...)
+
+	results := make(chan *v1.EventReceipt, common.GetConfiguration().ChannelBuffer)
+	if strings.TrimSpace(action.GetAction()) == "" {
+		defer close(results)
+		return results, rejectEvent(ctx, h.events, payload, "you hesitate and do nothing", results)
+	}
+
+	game, err := h.games.GetGame(ctx, payload.GetGameUid())
+	if err != nil {
+		h.log.Error("failed to get game", info.LoggingContext("error", err)...)
+		close(results)
+		return nil, err
+	}
+
+	location, err := locateActor(ctx, h.maps, payload.GetGameUid(), actor.GetUid())
+	if err != nil {
+		h.log.Error("failed to locate actor", info.LoggingContext("error", err)...)
+		close(results)
+		return nil, err
+	}
+
+	// the outcome is narrated to the whole table so whispers stay out of it
+	history, err := transcript(ctx, h.events, payload, game.GetParticipants(), false)
+	if err != nil {
+		h.log.Error("failed to build transcript", info.LoggingContext("error", err)...)
+		close(results)
+		return nil, err
+	}
+
+	scene := generative.DungeonMasterScene{
+		Theme:        game.GetTheme(),
+		Speaker:      actor,
+		Participants: game.GetParticipants(),
+		Location:     location,
+		History:      history,
+		Utterance:    action.GetAction(),
+	}
+
+	classification, err := h.dm.Adjudicate(ctx, scene)
+	if err != nil {
+		h.log.Error("dungeon master failed to adjudicate", info.LoggingContext("error", err)...)
+		close(results)
+		return nil, err
+	}
+	h.log.Debug("action classified", info.LoggingContext("kind", classification.Kind.String(), "ability", classification.Ability.String(), "target", classification.Target)...)
+
+	self := findSprite(location, func(s *v1.Sprite) bool {
+		return s.GetActor().GetUid() == actor.GetUid()
+	})
+	target := findSprite(location, func(s *v1.Sprite) bool {
+		return classification.Target != "" && s.GetUid() == classification.Target
+	})
+	if target == nil && (classification.Kind == v1.ActionEffect_ATTACK || classification.Kind == v1.ActionEffect_INTERACT) {
+		defer close(results)
+		return results, rejectEvent(ctx, h.events, payload, "there is nothing like that here", results)
+	}
+
+	effect := resolveAction(actor, action.GetAction(), classification, location, self, target)
+	if effect.TargetHealth != nil {
+		common.SetCharacteristic(target, v1.Characteristic_HEALTH, effect.GetTargetHealth())
+		if err = h.maps.UpdateCoordinate(ctx, location); err != nil {
+			h.log.Error("failed to record damage", info.LoggingContext("error", err)...)
+			close(results)
+			return nil, err
+		}
+	}
+
+	h.log.Info("action resolved", info.LoggingContext(
+		"kind", effect.GetKind().String(),
+		"roll", effect.GetCheck().GetTotal(),
+		"difficulty", effect.GetDifficulty(),
+		"outcome", effect.GetOutcome().String(),
+	)...)
+
+	receipt := newReceipt(payload)
+	receipt.Effect = &v1.EventReceipt_GameState{GameState: &v1.GameStateEffect{
+		Change: &v1.GameStateEffect_Action{Action: effect},
+	}}
+	if err = emitReceipt(ctx, h.events, receipt, results); err != nil {
+		h.log.Error("failed to record receipt", info.LoggingContext("error", err)...)
+		close(results)
+		return nil, err
+	}
+
+	narration, err := h.dm.Narrate(ctx, scene, effect)
+	if err != nil {
+		// the outcome stands even when the dungeon master cannot describe it
+		h.log.Warn("dungeon master failed to narrate", info.LoggingContext("error", err)...)
+		close(results)
+		return results, nil
+	}
+
+	recipients := common.Filter(game.GetParticipants(), func(a *v1.Actor) string {
+		return a.GetUid()
+	})
+	go func() {
+		defer close(results)
+		for paragraph := range narration {
+			if paragraph.Err != nil {
+				// the outcome stands even when the dungeon master cannot finish describing it
+				if err := failEvent(ctx, h.events, payload, "the dungeon master lost their train of thought", results); err != nil {
+					h.log.Error("failed to record broken narration", info.LoggingContext("error", err)...)
+				}
+				return
+			}
+			receipt := newReceipt(payload)
+			receipt.Effect = &v1.EventReceipt_Utterance{Utterance: &v1.UtteranceEffect{
+				Actor:      auth.DungeonMasterActorId,
+				Content:    paragraph.Content,
+				Recipients: recipients,
+				Audience:   v1.UtteranceEffect_DUNGEON_MASTER,
+			}}
+			if err := emitReceipt(ctx, h.events, receipt, results); err != nil {
+				h.log.Error("failed to record narration", info.LoggingContext("error", err)...)
+			}
+		}
+	}()
+
+	return results, nil
+}
+
+// resolveAction applies the rules to a classified action, nothing here is decided by the model
+func resolveAction(actor *v1.Actor, action string, classification *generative.ActionClassification, location *v1.MapCoordinateDetail, self *v1.Sprite, target *v1.Sprite) *v1.ActionEffect {
+	effect := &v1.ActionEffect{
+		Actor:      actor,
+		Action:     action,
+		Kind:       classification.Kind,
+		Ability:    classification.Ability,
+		Difficulty: baseDifficulty,
+	}
+	if target != nil {
+		uid := target.GetUid()
+		effect.TargetSpriteUid = &uid
+	}
+
+	if classification.Kind == v1.ActionEffect_ATTACK {
+		// attacks are made against the defense of the target rather than the terrain
+		effect.Difficulty += common.CharacteristicModifier(target, v1.Characteristic_DEFENSE)
+	} else if location.GetDifficultTerrain() {
+		effect.Difficulty += difficultTerrainPenalty
+	}
+
+	effect.Check = common.RollDice(1, 20, common.AbilityModifier(self, classification.Ability))
+	effect.Outcome = common.ResolveCheck(effect.Check, effect.Difficulty)
+
+	if classification.Kind == v1.ActionEffect_ATTACK && common.IsSuccessful(effect.Outcome) {
+		dice := int64(1)
+		if effect.Outcome == v1.ActionEffect_CRITICAL_SUCCESS {
+			dice = 2
+		}
+		effect.Damage = common.RollDice(dice, attackDamageDie, common.AbilityModifier(self, v1.ActionEffect_STRENGTH))
+		// a successful hit always hurts
+		effect.Damage.Total = max(effect.Damage.Total, 1)
+		health := max(common.CharacteristicValue(target, v1.Characteristic_HEALTH)-float32(effect.Damage.Total), 0)
+		effect.TargetHealth = &health
+	}
+
+	return effect
+}
+
+// findSprite returns the first sprite on the coordinate matching the predicate
+func findSprite(coordinate *v1.MapCoordinateDetail, fn func(*v1.Sprite) bool) *v1.Sprite {
+	for _, sprite := range coordinate.GetSprites() {
+		if fn(sprite) {
+			return sprite
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/storage"
+
+	charm "github.com/charmbracelet/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type movementHandler struct {
+	events storage.EventStore
+	maps   storage.MapStore
+	log    *charm.Logger
+}
+
+func NewMovementHandler(maps storage.MapStore, events storage.EventStore) engine.EventHandler {
+	return movementHandler{
+		maps:   maps,
+		events: events,
+		log:    common.GetLogger("engine.handler.movement"),
+	}
+}
+
+func (h movementHandler) Name() string {
+	return "game.interaction.movement"
+}
+
+func (h movementHandler) Predicate() engine.EventPredicate {
+	return func(ctx context.Context, event *v1.EventRecord) (bool, error) {
+		if event == nil {
+			return false, status.Error(codes.InvalidArgument, "event is nil")
+		}
+		if event.GetPayload().GetInteraction().GetMovement() == nil {
+			return false, nil
+		}
+		return true, nil
+	}
+}
+
+func (h movementHandler) Handle(ctx context.Context, payload *v1.EventRecord) (<-chan *v1.EventReceipt, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	movement := payload.GetPayload().GetInteraction().GetMovement()
+	actor := payload.GetPayload().GetActor()
+	if actor == nil {
+		err = status.Error(codes.InvalidArgument, "movement event has no actor")
+		h.log.Error("failed to handle movement", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	h.log.Info("handling movement event", info.LoggingContext("direction", movement.GetDirection(), "actor", actor.GetUid())...)
+
+	results := make(chan *v1.EventReceipt, common.GetConfiguration().ChannelBuffer)
+	defer close(results)
+
+	translation, err := common.ParseDirection(movement.GetDirection())
+	if err != nil {
+		h.log.Warn("invalid direction", info.LoggingContext("direction", movement.GetDirection())...)
+		return results, rejectEvent(ctx, h.events, payload, fmt.Sprintf("%s is not a direction you can travel", movement.GetDirection()), results)
+	}
+
+	gameMap, err := h.maps.GetMapForGame(ctx, payload.GetGameUid())
+	if err != nil {
+		h.log.Error("failed to get map for game", info.LoggingContext("error", err, "game", payload.GetGameUid())...)
+		return nil, err
+	}
+
+	position, err := h.maps.GetActorCoordinate(ctx, gameMap.GetUid(), actor.GetUid())
+	if err != nil {
+		h.log.Error("failed to locate actor", info.LoggingContext("error", err, "map", gameMap.GetUid())...)
+		return nil, err
+	}
+
+	target := translation.Apply(position.GetPosition())
+	if !common.IsWithinBounds(target, gameMap.GetMaxX(), gameMap.GetMaxY()) {
+		h.log.Debug("movement out of bounds", info.LoggingContext("x", target.X, "y", target.Y)...)
+		return results, rejectEvent(ctx, h.events, payload, "you cannot travel beyond the edge of the world", results)
+	}
+
+	origin, destination, err := h.maps.MoveActor(ctx, gameMap.GetUid(), actor.GetUid(), target, func(origin *v1.MapCoordinateDetail, destination *v1.MapCoordinateDetail) error {
+		if err := common.CheckMovement(origin, destination, actor.GetUid()); err != nil {
+			return err
+		}
+		common.TransferActor(origin, destination, actor.GetUid())
+		return nil
+	})
+	if status.Code(err) == codes.FailedPrecondition {
+		h.log.Debug("movement not allowed", info.LoggingContext("error", err, "x", target.X, "y", target.Y)...)
+		return results, rejectEvent(ctx, h.events, payload, status.Convert(err).Message(), results)
+	}
+	if err != nil {
+		h.log.Error("failed to move actor", info.LoggingContext("error", err, "x", target.X, "y", target.Y)...)
+		return nil, err
+	}
+
+	// the actor takes in their new surroundings, what they see stays on the map they know
+	if err = storage.Discover(ctx, h.maps, gameMap.GetUid(), actor.GetUid(), destination); err != nil {
+		h.log.Error("failed to discover surroundings", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	cost := common.MovementCost(destination)
+
+	receipt := newReceipt(payload)
+	receipt.Effect = &v1.EventReceipt_GameState{GameState: &v1.GameStateEffect{
+		Change: &v1.GameStateEffect_Movement{Movement: &v1.MovementEffect{
+			Actor:            actor,
+			MapUid:           gameMap.GetUid(),
+			Origin:           origin.GetPosition(),
+			Destination:      destination.GetPosition(),
+			Direction:        common.GetDirection(origin.GetPosition(), destination.GetPosition()),
+			DifficultTerrain: destination.GetDifficultTerrain(),
+			Cost:             cost,
+		}},
+	}}
+
+	h.log.Info("actor moved", info.LoggingContext(
+		"actor", actor.GetUid(),
+		"from_x", origin.GetPosition().GetX(),
+		"from_y", origin.GetPosition().GetY(),
+		"to_x", destination.GetPosition().GetX(),
+		"to_y", destination.GetPosition().GetY(),
+	)...)
+
+	err = emitReceipt(ctx, h.events, receipt, results)
+	if err != nil {
+		h.log.Error("failed to record receipt", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/storage"
+
+	charm "github.com/charmbracelet/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type utteranceHandler struct {
+	events storage.EventStore
+	games  storage.GameStore
+	log    *charm.Logger
+}
+
+func NewUtteranceHandler(games storage.GameStore, events storage.EventStore) engine.EventHandler {
+	return utteranceHandler{
+		games:  games,
+		events: events,
+		log:    common.GetLogger("engine.handler.utterance"),
+	}
+}
+
+func (h utteranceHandler) Name() string {
+	return "game.interaction.utterance"
+}
+
+func (h utteranceHandler) Predicate() engine.EventPredicate {
+	return func(ctx context.Context, event *v1.EventRecord) (bool, error) {
+		if event == nil {
+			return false, status.Error(codes.InvalidArgument, "event is nil")
+		}
+		if event.GetPayload().GetInteraction().GetUtterance() == nil {
+			return false, nil
+		}
+		return true, nil
+	}
+}
+
+func (h utteranceHandler) Handle(ctx context.Context, payload *v1.EventRecord) (<-chan *v1.EventReceipt, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	utterance := payload.GetPayload().GetInteraction().GetUtterance()
+	speaker := payload.GetPayload().GetActor()
+	if speaker == nil {
+		err = status.Error(codes.InvalidArgument, "utterance event has no actor")
+		h.log.Error("failed to handle utterance", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	h.log.Info("handling utterance event", info.LoggingContext("speaker", speaker.GetUid(), "audience", fmt.Sprintf("%T", utterance.GetUtterance()))...)
+
+	results := make(chan *v1.EventReceipt, common.GetConfiguration().ChannelBuffer)
+	defer close(results)
+
+	game, err := h.games.GetGame(ctx, payload.GetGameUid())
+	if err != nil {
+		h.log.Error("failed to get game", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	participants := make(map[string]*v1.Actor)
+	for _, participant := range game.GetParticipants() {
+		participants[participant.GetUid()] = participant
+	}
+
+	effect := &v1.UtteranceEffect{
+		Actor:      speaker.GetUid(),
+		Content:    utterance.GetContent(),
+		Recipients: make([]string, 0),
+	}
+
+	switch u := utterance.GetUtterance().(type) {
+	case *v1.UtteranceInteraction_DungeonMaster:
+		// the dungeon master is not an actor so there are no recipients, a whisper is only visible to the speaker and the DM
+		effect.Audience = v1.UtteranceEffect_DUNGEON_MASTER
+		effect.Whisper = u.DungeonMaster.GetWhisper()
+	case *v1.UtteranceInteraction_Player:
+		target := u.Player.GetTarget()
+		if _, ok := participants[target.GetUid()]; !ok {
+			return results, rejectEvent(ctx, h.events, payload, "there is nobody by that name at the table", results)
+		}
+		if target.GetUid() == speaker.GetUid() {
+			return results, rejectEvent(ctx, h.events, payload, "you mutter quietly to yourself", results)
+		}
+		effect.Audience = v1.UtteranceEffect_PLAYER
+		effect.Whisper = u.Player.GetWhisper()
+		effect.Recipients = append(effect.Recipients, target.GetUid())
+	case *v1.UtteranceInteraction_Players:
+		if len(u.Players.GetTargets()) == 0 {
+			return results, rejectEvent(ctx, h.events, payload, "you must address at least one player", results)
+		}
+		for _, target := range u.Players.GetTargets() {
+			if _, ok := participants[target.GetUid()]; !ok {
+				return results, rejectEvent(ctx, h.events, payload, "there is nobody by that name at the table", results)
+			}
+			if target.GetUid() != speaker.GetUid() {
+				effect.Recipients = append(effect.Recipients, target.GetUid())
+			}
+		}
+		effect.Audience = v1.UtteranceEffect_PLAYERS
+		effect.Whisper = u.Players.GetWhisper()
+	default:
+		// an utterance without an explicit audience is spoken to the whole table
+		effect.Audience = v1.UtteranceEffect_TABLE
+		effect.Whisper = utterance.GetTable().GetWhisper()
+		for _, participant := range game.GetParticipants() {
+			if participant.GetUid() != speaker.GetUid() {
+				effect.Recipients = append(effect.Recipients, participant.GetUid())
+			}
+		}
+	}
+
+	receipt := newReceipt(payload)
+	receipt.Effect = &v1.EventReceipt_Utterance{Utterance: effect}
+
+	err = emitReceipt(ctx, h.events, receipt, results)
+	if err != nil {
+		h.log.Error("failed to record receipt", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	h.log.Debug("utterance routed", info.LoggingContext(
+		"audience", effect.GetAudience().String(),
+		"whisper", effect.GetWhisper(),
+		"recipients", len(effect.GetRecipients()),
+	)...)
+	return results, nil
+}
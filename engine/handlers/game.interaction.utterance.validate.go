@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/storage"
+	"strings"
+
+	charm "github.com/charmbracelet/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// utteranceValidator turns away utterances nobody should hear before anything records or answers them
+type utteranceValidator struct {
+	events storage.EventStore
+	log    *charm.Logger
+}
+
+func NewUtteranceValidator(events storage.EventStore) engine.EventHandler {
+	return utteranceValidator{
+		events: events,
+		log:    common.GetLogger("engine.handler.utterance.validate"),
+	}
+}
+
+func (h utteranceValidator) Name() string {
+	return "game.interaction.utterance.validate"
+}
+
+func (h utteranceValidator) Predicate() engine.EventPredicate {
+	return func(ctx context.Context, event *v1.EventRecord) (bool, error) {
+		if event == nil {
+			return false, status.Error(codes.InvalidArgument, "event is nil")
+		}
+		if event.GetPayload().GetInteraction().GetUtterance() == nil {
+			return false, nil
+		}
+		return true, nil
+	}
+}
+
+func (h utteranceValidator) Handle(ctx context.Context, payload *v1.EventRecord) (<-chan *v1.EventReceipt, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(chan *v1.EventReceipt, common.GetConfiguration().ChannelBuffer)
+	defer close(results)
+
+	if payload.GetPayload().GetActor() == nil {
+		err = status.Error(codes.InvalidArgument, "utterance event has no actor")
+		h.log.Error("failed to validate utterance", info.LoggingContext("error", err)...)
+		engine.StopPropagation(ctx)
+		return nil, err
+	}
+	if strings.TrimSpace(payload.GetPayload().GetInteraction().GetUtterance().GetContent()) == "" {
+		h.log.Debug("empty utterance turned away", info.LoggingContext("event", payload.GetUid())...)
+		engine.StopPropagation(ctx)
+		return results, rejectEvent(ctx, h.events, payload, "you open your mouth but say nothing", results)
+	}
+	return results, nil
+}
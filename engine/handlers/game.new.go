@@ -3,9 +3,11 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"overseer/auth"
 	v1 "overseer/build/go"
 	"overseer/common"
 	"overseer/engine"
+	"overseer/generative"
 	"overseer/storage"
 	"time"
 
@@ -14,15 +16,25 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// progressSteps is how many progress receipts are sent while the map is generated
+const progressSteps int64 = 10
+
+// generationPollInterval is how often the handler checks on the map generating in the background
+const generationPollInterval = 100 * time.Millisecond
+
 type newGameHandler struct {
+	dm     generative.DungeonMasterService
 	events storage.EventStore
 	games  storage.GameStore
+	maps   v1.MapsServer
 	log    *charm.Logger
 }
 
-func NewGameHandler(games storage.GameStore, events storage.EventStore) engine.EventHandler {
+func NewGameHandler(maps v1.MapsServer, dm generative.DungeonMasterService, games storage.GameStore, events storage.EventStore) engine.EventHandler {
 	return newGameHandler{
+		dm:     dm,
 		games:  games,
+		maps:   maps,
 		events: events,
 		log:    common.GetLogger("engine.handler.newgame"),
 	}
@@ -51,9 +63,6 @@ func (h newGameHandler) Handle(ctx context.Context, payload *v1.EventRecord) (<-
 	}
 	h.log.Info("handling new game event", info.LoggingContext()...)
 
-	results := make(chan *v1.EventReceipt, common.GetConfiguration().ChannelBuffer)
-	defer close(results)
-
 	game, err := h.games.GetGame(ctx, payload.GetPayload().GetGameUid())
 	if err != nil {
 		h.log.Error("failed to get game", info.LoggingContext("error", err)...)
@@ -71,40 +80,151 @@ func (h newGameHandler) Handle(ctx context.Context, payload *v1.EventRecord) (<-
 		return nil, err
 	}
 
+	results := make(chan *v1.EventReceipt, common.GetConfiguration().ChannelBuffer)
+	go func() {
+		defer close(results)
+		if err := h.initialize(ctx, payload, game, results); err != nil {
+			h.log.Error("failed to initialize game", info.LoggingContext("error", err, "game", game.GetUid())...)
+			if err := failEvent(ctx, h.events, payload, "the world could not be created", results); err != nil {
+				h.log.Error("failed to record receipt", info.LoggingContext("error", err)...)
+			}
+		}
+	}()
+
+	return results, nil
+}
+
+// initialize builds the world for the game streaming progress as it goes, the game is only marked initialized once everything is in place
+func (h newGameHandler) initialize(ctx context.Context, payload *v1.EventRecord, game *v1.Game, results chan<- *v1.EventReceipt) error {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return err
+	}
+	startTime := time.Now()
+
 	// step 1: create a map
-	// step 2: create an initial codition
-	// step 3: mark game as initialized
+	if err = acknowledgeEvent(ctx, h.events, payload, "the world is being created", results); err != nil {
+		return err
+	}
 
-	game.Initialized = true
+	// the last step is reported even when the map was already complete on the first poll so players always see the world charted
+	var reported int64
+	progress := func(completed int64, total int64) {
+		step := completed * progressSteps / max(total, 1)
+		if step <= reported {
+			return
+		}
+		reported = step
+		message := fmt.Sprintf("the world is being created (%d of %d locations charted)", completed, total)
+		if err := acknowledgeEvent(ctx, h.events, payload, message, results); err != nil {
+			h.log.Warn("failed to record progress", info.LoggingContext("error", err)...)
+		}
+	}
 
-	err = h.games.SaveGame(ctx, game)
+	config := common.GetConfiguration().MapGeneration
+	gameMap, err := h.maps.CreateMap(ctx, &v1.CreateMapRequest{
+		GameUid:                game.GetUid(),
+		Name:                   game.GetName(),
+		MaxX:                   config.DefaultMaxX,
+		MaxY:                   config.DefaultMaxY,
+		Theme:                  game.GetTheme(),
+		Actors:                 game.GetParticipants(),
+		DifficultTerrainChance: config.DefaultDifficultTerrainChance,
+		SpriteDensity:          config.DefaultSpriteDensity,
+		Seed:                   game.Seed,
+	})
 	if err != nil {
-		h.log.Error("failed to save game", info.LoggingContext("error", err)...)
-		return nil, err
+		return err
+	}
+	if err = h.awaitMap(ctx, gameMap, progress); err != nil {
+		return err
 	}
+	h.log.Info("map created for new game", info.LoggingContext("game", game.GetUid(), "map", gameMap.GetUid(), "duration", time.Since(startTime))...)
 
-	message := "game created successfully"
+	// step 2: create an initial condition
+	detail, err := h.maps.GetMapDetail(ctx, &v1.GetMapRequest{Uid: gameMap.GetUid()})
+	if err != nil {
+		return err
+	}
+	if err = h.introduce(ctx, payload, game, startingCoordinate(detail), results); err != nil {
+		return err
+	}
+
+	// step 3: mark game as initialized
+	game.Initialized = true
+	if err = h.games.SaveGame(ctx, game); err != nil {
+		return err
+	}
+
+	h.log.Info("game initialized", info.LoggingContext("game", game.GetUid(), "duration", time.Since(startTime))...)
+	return acknowledgeEvent(ctx, h.events, payload, "game created successfully", results)
+}
+
+// awaitMap waits for the map to finish generating, the game can't start before every location exists
+// progress is reported from the handler's own polling so nothing reports once the handler has returned
+func (h newGameHandler) awaitMap(ctx context.Context, gameMap *v1.Map, progress common.ProgressReporter) error {
+	ticker := time.NewTicker(generationPollInterval)
+	defer ticker.Stop()
+	for {
+		generation, err := h.maps.GetMapGenerationStatus(ctx, &v1.GetMapRequest{Uid: gameMap.GetUid()})
+		if err != nil {
+			return err
+		}
+		progress(generation.GetCompleted(), generation.GetTotal())
+		switch generation.GetState() {
+		case v1.MapGenerationStatus_COMPLETE:
+			return nil
+		case v1.MapGenerationStatus_FAILED:
+			return status.Error(codes.Internal, fmt.Sprintf("failed to create map: %s", generation.GetError()))
+		}
 
-	receipt := v1.EventReceipt{
-		Uid: common.GenerateRandomStringFromSeed(
-			common.GenerateUniqueId(),
-			fmt.Sprintf("%d", time.Now().UTC().Unix()),
-			payload.GameUid,
-		),
-		GameUid:  payload.GetGameUid(),
-		EventUid: payload.GetUid(),
-		Effect: &v1.EventReceipt_Ack{Ack: &v1.Acknowledgement{
-			Message: &message,
-		}},
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
 	}
+}
 
-	err = h.events.RecordReceipt(ctx, &receipt)
+// introduce has the dungeon master narrate the opening scene to every participant
+func (h newGameHandler) introduce(ctx context.Context, payload *v1.EventRecord, game *v1.Game, location *v1.MapCoordinateDetail, results chan<- *v1.EventReceipt) error {
+	opening, err := h.dm.Introduce(ctx, generative.DungeonMasterScene{
+		Theme:        game.GetTheme(),
+		Participants: game.GetParticipants(),
+		Location:     location,
+	})
 	if err != nil {
-		h.log.Error("failed to record receipt", info.LoggingContext("error", err)...)
-		return nil, err
+		return err
 	}
 
-	results <- &receipt
+	recipients := common.Filter(game.GetParticipants(), func(a *v1.Actor) string {
+		return a.GetUid()
+	})
+	for paragraph := range opening {
+		if paragraph.Err != nil {
+			// the world is ready even if its introduction is not, the players can still start exploring
+			return failEvent(ctx, h.events, payload, "the dungeon master lost their train of thought", results)
+		}
+		receipt := newReceipt(payload)
+		receipt.Effect = &v1.EventReceipt_Utterance{Utterance: &v1.UtteranceEffect{
+			Actor:      auth.DungeonMasterActorId,
+			Content:    paragraph.Content,
+			Recipients: recipients,
+			Audience:   v1.UtteranceEffect_DUNGEON_MASTER,
+		}}
+		if err := emitReceipt(ctx, h.events, receipt, results); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	return results, nil
+// startingCoordinate is where the participants were placed when the map was created
+func startingCoordinate(detail *v1.MapDetail) *v1.MapCoordinateDetail {
+	for _, coordinate := range detail.GetCoordinates() {
+		if len(coordinate.GetActors()) > 0 {
+			return coordinate
+		}
+	}
+	return nil
 }
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/storage"
+	"time"
+)
+
+// newReceipt builds a receipt for the event with a freshly generated uid
+func newReceipt(payload *v1.EventRecord) *v1.EventReceipt {
+	return &v1.EventReceipt{
+		Uid: common.GenerateRandomStringFromSeed(
+			common.GenerateUniqueId(),
+			fmt.Sprintf("%d", time.Now().UTC().Unix()),
+			payload.GameUid,
+		),
+		GameUid:  payload.GetGameUid(),
+		EventUid: payload.GetUid(),
+	}
+}
+
+// emitReceipt records the receipt and forwards it to the results channel
+func emitReceipt(ctx context.Context, events storage.EventStore, receipt *v1.EventReceipt, results chan<- *v1.EventReceipt) error {
+	if err := events.RecordReceipt(ctx, receipt); err != nil {
+		return err
+	}
+
+	results <- receipt
+	return nil
+}
+
+// rejectEvent records an invalid interaction as an error receipt so the player learns why nothing happened
+func rejectEvent(ctx context.Context, events storage.EventStore, payload *v1.EventRecord, message string, results chan<- *v1.EventReceipt) error {
+	receipt := newReceipt(payload)
+	receipt.Effect = &v1.EventReceipt_Error{Error: &v1.ErrorEffect{
+		Type:    v1.ErrorEffect_INVALID,
+		Message: message,
+	}}
+
+	return emitReceipt(ctx, events, receipt, results)
+}
+
+// rejected is whether any of the receipts turned the event away as invalid
+func rejected(receipts []*v1.EventReceipt) bool {
+	for _, receipt := range receipts {
+		if receipt.GetError().GetType() == v1.ErrorEffect_INVALID {
+			return true
+		}
+	}
+	return false
+}
+
+// acknowledgeEvent records a plain acknowledgement, used for progress updates and confirmations
+func acknowledgeEvent(ctx context.Context, events storage.EventStore, payload *v1.EventRecord, message string, results chan<- *v1.EventReceipt) error {
+	receipt := newReceipt(payload)
+	receipt.Effect = &v1.EventReceipt_Ack{Ack: &v1.Acknowledgement{
+		Message: &message,
+	}}
+
+	return emitReceipt(ctx, events, receipt, results)
+}
+
+// failEvent records an internal failure that happened after the handler already started streaming receipts
+func failEvent(ctx context.Context, events storage.EventStore, payload *v1.EventRecord, message string, results chan<- *v1.EventReceipt) error {
+	receipt := newReceipt(payload)
+	receipt.Effect = &v1.EventReceipt_Error{Error: &v1.ErrorEffect{
+		Type:    v1.ErrorEffect_INTERNAL,
+		Message: message,
+	}}
+
+	return emitReceipt(ctx, events, receipt, results)
+}
@@ -7,6 +7,9 @@ import (
 
 type EventBus interface {
 	Submit(ctx context.Context, event *v1.Event) (<-chan *v1.EventReceipt, error)
+	// Watch streams every receipt produced for the game until the context is done
+	// the channel is closed early if the watcher falls too far behind
+	Watch(ctx context.Context, gameUid string) (<-chan *v1.EventReceipt, error)
 }
 
 type EventPredicate func(ctx context.Context, event *v1.EventRecord) (bool, error)
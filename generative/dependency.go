@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"overseer/common"
 	"overseer/generative/ollama"
+	"overseer/generative/openai"
 	"sync"
 
 	"google.golang.org/grpc/codes"
@@ -13,28 +14,72 @@ import (
 )
 
 var initializeOllama sync.Once
+var initializeOllamaErr error
 
-func NewMapGenerationService() (MapGenerationService, error) {
+func newOllamaClient() (ollama.Client, error) {
+	base, err := url.Parse(common.GetConfiguration().Ollama.BaseUrl)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid ollama base url: %s", err))
+	}
+	client := ollama.NewOllamaClient(base)
+	initializeOllama.Do(func() {
+		common.GetLogger("generative").Info("Initializing Ollama client")
+		initializeOllamaErr = client.InitializeOllama(context.Background())
+	})
+	if initializeOllamaErr != nil {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to initialize ollama client: %s", initializeOllamaErr))
+	}
+	return client, nil
+}
+
+func newOpenAIClient() (openai.Client, error) {
+	base, err := url.Parse(common.GetConfiguration().OpenAI.BaseUrl)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid openai base url: %s", err))
+	}
+	return openai.NewOpenAIClient(base, common.GetConfiguration().OpenAI.ApiKey), nil
+}
+
+// NewLLM adapts the configured generative features provider
+func NewLLM() (LLM, error) {
 	switch common.GetConfiguration().GenerativeFeaturesProvider {
 	case common.OllamaProvider:
-		base, err := url.Parse(common.GetConfiguration().Ollama.BaseUrl)
-		if err != nil {
-			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid ollama base url: %s", err))
-		}
-		client := ollama.NewOllamaClient(base)
-		initializeOllama.Do(func() {
-			common.GetLogger("generative.map").Info("Initializing Ollama client")
-			err = client.InitializeOllama(context.Background())
-		})
-		tmpl, err := NewTemplatingService()
+		client, err := newOllamaClient()
 		if err != nil {
-			return nil, status.Error(codes.Internal, fmt.Sprintf("failed to initialize templating service: %s", err))
+			return nil, err
 		}
+		return NewOllamaLLM(client), nil
+	case common.OpenAIProvider:
+		client, err := newOpenAIClient()
 		if err != nil {
-			return nil, status.Error(codes.Internal, fmt.Sprintf("failed to initialize ollama client: %s", err))
+			return nil, err
 		}
-		return NewOllamaMapGenerationService(tmpl, client)
+		return NewOpenAILLM(client), nil
 	default:
 		return nil, status.Error(codes.InvalidArgument, "invalid generative feature provider")
 	}
 }
+
+func NewMapGenerationService() (MapGenerationService, error) {
+	llm, err := NewLLM()
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := NewTemplatingService()
+	if err != nil {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to initialize templating service: %s", err))
+	}
+	return NewLLMMapGenerationService(tmpl, llm)
+}
+
+func NewDungeonMasterService() (DungeonMasterService, error) {
+	llm, err := NewLLM()
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := NewTemplatingService()
+	if err != nil {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to initialize templating service: %s", err))
+	}
+	return NewLLMDungeonMasterService(tmpl, llm)
+}
@@ -0,0 +1,51 @@
+package generative
+
+import (
+	"context"
+)
+
+// LLM is everything the generative services ask of a language model, providers are adapted to it
+// so the map generator, the dungeon master and whatever narrates next never depend on a provider
+type LLM interface {
+	// Complete answers a single prompt
+	Complete(ctx context.Context, prompt string) (string, error)
+	// CompleteJSON answers a single prompt with a JSON object, the object is not checked against anything
+	CompleteJSON(ctx context.Context, prompt string) (string, error)
+	// Chat answers the conversation with the whole reply at once
+	Chat(ctx context.Context, messages []Message) (string, error)
+	// ChatStream answers the conversation in chunks as the model produces them, the channel is closed when the reply is complete.
+	// a reply cut short ends with a chunk carrying the error
+	ChatStream(ctx context.Context, messages []Message) (<-chan Chunk, error)
+	// Embed returns a vector for each input in the same order
+	Embed(ctx context.Context, inputs ...string) ([][]float32, error)
+}
+
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+// Chunk is a piece of a streamed reply, a chunk with an error is the last one sent
+type Chunk struct {
+	Content string
+	Err     error
+}
+
+// sendChunk hands a chunk to the reader of a stream, false once the reader has gone away
+func sendChunk(ctx context.Context, results chan<- Chunk, chunk Chunk) bool {
+	select {
+	case results <- chunk:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Message is a single turn of a conversation with the model
+type Message struct {
+	Role    Role
+	Content string
+}
@@ -0,0 +1,114 @@
+package generative
+
+import (
+	"context"
+	"overseer/common"
+	"overseer/generative/ollama"
+	"strings"
+
+	charm "github.com/charmbracelet/log"
+)
+
+type ollamaLLM struct {
+	client ollama.Client
+	log    *charm.Logger
+}
+
+// NewOllamaLLM adapts an ollama client, the configured ollama models are used for every call
+func NewOllamaLLM(client ollama.Client) LLM {
+	return &ollamaLLM{
+		client: client,
+		log:    common.GetLogger("generative.llm.ollama"),
+	}
+}
+
+func (l *ollamaLLM) Complete(ctx context.Context, prompt string) (string, error) {
+	return l.generate(ctx, ollama.GenerateRequest{
+		Model:  common.GetConfiguration().Ollama.Model.String(),
+		Prompt: prompt,
+		Stream: false,
+	})
+}
+
+func (l *ollamaLLM) CompleteJSON(ctx context.Context, prompt string) (string, error) {
+	return l.generate(ctx, ollama.GenerateRequest{
+		Model:  common.GetConfiguration().Ollama.Model.String(),
+		Prompt: prompt,
+		Stream: false,
+		Format: "json",
+	})
+}
+
+func (l *ollamaLLM) generate(ctx context.Context, req ollama.GenerateRequest) (string, error) {
+	results, err := l.client.Generate(ctx, req)
+	if err != nil {
+		l.log.Error("failed to generate", "error", err)
+		return "", err
+	}
+
+	var response strings.Builder
+	for result := range results {
+		l.log.Debug("received result from ollama", "result", result.Response)
+		response.WriteString(result.Response)
+	}
+	return response.String(), nil
+}
+
+func (l *ollamaLLM) Chat(ctx context.Context, messages []Message) (string, error) {
+	responses, err := l.converse(ctx, messages, false)
+	if err != nil {
+		return "", err
+	}
+
+	var reply strings.Builder
+	for response := range responses {
+		if response.Err != nil {
+			return "", response.Err
+		}
+		reply.WriteString(response.Content)
+	}
+	return reply.String(), nil
+}
+
+func (l *ollamaLLM) ChatStream(ctx context.Context, messages []Message) (<-chan Chunk, error) {
+	return l.converse(ctx, messages, true)
+}
+
+func (l *ollamaLLM) converse(ctx context.Context, messages []Message, stream bool) (<-chan Chunk, error) {
+	responses, err := l.client.Converse(ctx, ollama.ConverseRequest{
+		Model:    common.GetConfiguration().Ollama.Model.String(),
+		Messages: common.Filter(messages, toOllamaMessage),
+		Stream:   stream,
+	})
+	if err != nil {
+		l.log.Error("failed to converse", "error", err)
+		return nil, err
+	}
+
+	results := make(chan Chunk, common.GetConfiguration().ChannelBuffer)
+	go func() {
+		defer close(results)
+		for response := range responses {
+			if !sendChunk(ctx, results, Chunk{Content: response.Message.Content}) {
+				return
+			}
+		}
+	}()
+	return results, nil
+}
+
+func (l *ollamaLLM) Embed(ctx context.Context, inputs ...string) ([][]float32, error) {
+	response, err := l.client.Embed(ctx, ollama.EmbedRequest{
+		Model: common.GetConfiguration().Ollama.EmbeddingModel,
+		Input: inputs,
+	})
+	if err != nil {
+		l.log.Error("failed to embed", "error", err)
+		return nil, err
+	}
+	return response.Embeddings, nil
+}
+
+func toOllamaMessage(message Message) ollama.ConversationMessage {
+	return ollama.ConversationMessage{Role: ollama.ConversationActor(message.Role), Content: message.Content}
+}
@@ -0,0 +1,106 @@
+package generative
+
+import (
+	"context"
+	"overseer/common"
+	"overseer/generative/openai"
+
+	charm "github.com/charmbracelet/log"
+)
+
+type openaiLLM struct {
+	client openai.Client
+	log    *charm.Logger
+}
+
+// NewOpenAILLM adapts a client of any OpenAI compatible api, the configured openai models are used for every call
+func NewOpenAILLM(client openai.Client) LLM {
+	return &openaiLLM{
+		client: client,
+		log:    common.GetLogger("generative.llm.openai"),
+	}
+}
+
+// Complete sends the prompt as the only message of the conversation
+func (l *openaiLLM) Complete(ctx context.Context, prompt string) (string, error) {
+	return l.complete(ctx, openai.ChatCompletionRequest{
+		Model:    common.GetConfiguration().OpenAI.Model,
+		Messages: []openai.Message{{Role: openai.User, Content: prompt}},
+	})
+}
+
+func (l *openaiLLM) CompleteJSON(ctx context.Context, prompt string) (string, error) {
+	return l.complete(ctx, openai.ChatCompletionRequest{
+		Model:          common.GetConfiguration().OpenAI.Model,
+		Messages:       []openai.Message{{Role: openai.User, Content: prompt}},
+		ResponseFormat: &openai.ResponseFormat{Type: "json_object"},
+	})
+}
+
+func (l *openaiLLM) Chat(ctx context.Context, messages []Message) (string, error) {
+	return l.complete(ctx, openai.ChatCompletionRequest{
+		Model:    common.GetConfiguration().OpenAI.Model,
+		Messages: common.Filter(messages, toOpenAIMessage),
+	})
+}
+
+func (l *openaiLLM) complete(ctx context.Context, req openai.ChatCompletionRequest) (string, error) {
+	response, err := l.client.ChatCompletion(ctx, req)
+	if err != nil {
+		l.log.Error("failed to complete", "error", err)
+		return "", err
+	}
+	return response.Choices[0].Message.Content, nil
+}
+
+func (l *openaiLLM) ChatStream(ctx context.Context, messages []Message) (<-chan Chunk, error) {
+	chunks, err := l.client.StreamChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:    common.GetConfiguration().OpenAI.Model,
+		Messages: common.Filter(messages, toOpenAIMessage),
+	})
+	if err != nil {
+		l.log.Error("failed to stream completion", "error", err)
+		return nil, err
+	}
+
+	results := make(chan Chunk, common.GetConfiguration().ChannelBuffer)
+	go func() {
+		defer close(results)
+		for chunk := range chunks {
+			if chunk.Err != nil {
+				sendChunk(ctx, results, Chunk{Err: chunk.Err})
+				return
+			}
+			for _, choice := range chunk.Choices {
+				if !sendChunk(ctx, results, Chunk{Content: choice.Delta.Content}) {
+					return
+				}
+			}
+		}
+	}()
+	return results, nil
+}
+
+func (l *openaiLLM) Embed(ctx context.Context, inputs ...string) ([][]float32, error) {
+	response, err := l.client.Embeddings(ctx, openai.EmbeddingsRequest{
+		Model: common.GetConfiguration().OpenAI.EmbeddingModel,
+		Input: inputs,
+	})
+	if err != nil {
+		l.log.Error("failed to embed", "error", err)
+		return nil, err
+	}
+
+	// the data is not guaranteed to be in the order of the inputs
+	embeddings := make([][]float32, len(inputs))
+	for _, embedding := range response.Data {
+		if embedding.Index >= 0 && int(embedding.Index) < len(embeddings) {
+			embeddings[embedding.Index] = embedding.Embedding
+		}
+	}
+	return embeddings, nil
+}
+
+func toOpenAIMessage(message Message) openai.Message {
+	return openai.Message{Role: openai.Role(message.Role), Content: message.Content}
+}
@@ -24,6 +24,7 @@ type Client interface {
 	GetModel(ctx context.Context, req GetModelRequest) (*ModelInformation, error)
 	Generate(ctx context.Context, req GenerateRequest) (chan GenerateResponse, error)
 	Converse(ctx context.Context, req ConverseRequest) (chan ConverseResponse, error)
+	Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error)
 }
 
 func NewOllamaClient(baseUrl *url.URL) Client {
@@ -342,3 +343,46 @@ func (c *defaultClient) handleConverseStream(ctx context.Context, req *http.Requ
 		}
 	}
 }
+
+func (c *defaultClient) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	c.log.Debug("embed request", "model", req.Model, "inputs", len(req.Input))
+	payload, err := json.Marshal(req)
+	if err != nil {
+		c.log.Error("failed to marshal request", "error", err)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to marshal request: %v", err))
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseUrl.String()+"/api/embed", bytes.NewReader(payload))
+	if err != nil {
+		c.log.Error("failed to create request", "error", err)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to create request: %v", err))
+	}
+
+	startTime := time.Now()
+	resp, err := c.client.Do(request)
+	if err != nil {
+		c.log.Error("failed to execute request", "error", err)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to execute request: %v", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		c.log.Error("failed to embed", "status", resp.Status)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to embed: %v", resp.Status))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.log.Error("failed to read response body", "error", err)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to read response body: %v", err))
+	}
+
+	var embeddings EmbedResponse
+	if err := json.Unmarshal(body, &embeddings); err != nil {
+		c.log.Error("failed to unmarshal response body", "error", err)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to unmarshal response body: %v", err))
+	}
+
+	c.log.Debug("embed response", "model", embeddings.Model, "duration", time.Since(startTime))
+	return &embeddings, nil
+}
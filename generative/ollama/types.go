@@ -41,6 +41,8 @@ type GenerateRequest struct {
 	Model  string `json:"model"`
 	Prompt string `json:"prompt"`
 	Stream bool   `json:"stream"`
+	// Format constrains the response, "json" forces the model to respond with a JSON object
+	Format string `json:"format,omitempty"`
 }
 
 type GenerateResponse struct {
@@ -81,3 +83,13 @@ type ConverseResponse struct {
 	EvalCount          int64               `json:"eval_count"`
 	EvalDuration       int64               `json:"eval_duration"`
 }
+
+type EmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type EmbedResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float32 `json:"embeddings"`
+}
@@ -0,0 +1,228 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"overseer/common"
+	"strings"
+	"time"
+
+	charm "github.com/charmbracelet/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Client speaks the chat completions api shared by OpenAI, llama.cpp server, vLLM, LM Studio and friends
+type Client interface {
+	ChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error)
+	// StreamChatCompletion closes the channel once the reply is complete, a stream that broke ends with a chunk carrying the error
+	StreamChatCompletion(ctx context.Context, req ChatCompletionRequest) (chan ChatCompletionChunk, error)
+	Embeddings(ctx context.Context, req EmbeddingsRequest) (*EmbeddingsResponse, error)
+}
+
+// NewOpenAIClient calls {baseUrl}/v1/chat/completions and {baseUrl}/v1/embeddings, the api key is sent as a bearer token when it is set
+func NewOpenAIClient(baseUrl *url.URL, apiKey string) Client {
+	return &defaultClient{
+		baseUrl: baseUrl,
+		apiKey:  apiKey,
+		client:  &http.Client{},
+		log:     common.GetLogger("generative.openai"),
+	}
+}
+
+type defaultClient struct {
+	baseUrl *url.URL
+	apiKey  string
+	client  *http.Client
+	log     *charm.Logger
+}
+
+// streamDone is the data of the event closing a streamed completion
+const streamDone = "[DONE]"
+
+const (
+	chatCompletionsPath = "chat/completions"
+	embeddingsPath      = "embeddings"
+)
+
+func (c *defaultClient) ChatCompletion(ctx context.Context, req ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	c.log.Debug("chat completion request", "model", req.Model)
+	req.Stream = false
+	startTime := time.Now()
+	resp, err := c.do(ctx, chatCompletionsPath, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.log.Error("failed to read response body", "error", err)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to read response body: %v", err))
+	}
+
+	var completion ChatCompletionResponse
+	if err := json.Unmarshal(body, &completion); err != nil {
+		c.log.Error("failed to unmarshal response body", "error", err)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to unmarshal response body: %v", err))
+	}
+	if len(completion.Choices) == 0 {
+		return nil, status.Error(codes.Internal, "chat completion returned no choices")
+	}
+
+	c.log.Debug("chat completion response", "model", completion.Model, "duration", time.Since(startTime))
+	return &completion, nil
+}
+
+func (c *defaultClient) StreamChatCompletion(ctx context.Context, req ChatCompletionRequest) (chan ChatCompletionChunk, error) {
+	c.log.Debug("streamed chat completion request", "model", req.Model)
+	req.Stream = true
+	startTime := time.Now()
+	// the request is made before returning so a rejected request is an error rather than an empty stream
+	resp, err := c.do(ctx, chatCompletionsPath, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(chan ChatCompletionChunk, common.GetConfiguration().ChannelBuffer)
+	go c.handleChatCompletionStream(ctx, resp, results, startTime)
+
+	return results, nil
+}
+
+func (c *defaultClient) handleChatCompletionStream(ctx context.Context, resp *http.Response, results chan ChatCompletionChunk, startTime time.Time) {
+	defer resp.Body.Close()
+	defer close(results)
+
+	// send gives up once the caller has gone away rather than blocking on a channel no one reads
+	send := func(chunk ChatCompletionChunk) bool {
+		select {
+		case results <- chunk:
+			return true
+		case <-ctx.Done():
+			c.log.Debug("chat completion stream cancelled", "duration", time.Since(startTime))
+			return false
+		}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		// server sent events, blank lines separate events and lines starting with a colon are comments
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == streamDone {
+			c.log.Debug("chat completion stream finished", "duration", time.Since(startTime))
+			return
+		}
+		var chunk ChatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			c.log.Error("failed to unmarshal chunk", "error", err)
+			continue
+		}
+		if !send(chunk) {
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		c.log.Error("chat completion stream broke", "error", err, "duration", time.Since(startTime))
+		send(ChatCompletionChunk{Err: status.Error(codes.Unavailable, fmt.Sprintf("chat completion stream broke: %v", err))})
+		return
+	}
+	c.log.Debug("chat completion stream closed", "duration", time.Since(startTime))
+}
+
+func (c *defaultClient) Embeddings(ctx context.Context, req EmbeddingsRequest) (*EmbeddingsResponse, error) {
+	c.log.Debug("embeddings request", "model", req.Model, "inputs", len(req.Input))
+	startTime := time.Now()
+	resp, err := c.do(ctx, embeddingsPath, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.log.Error("failed to read response body", "error", err)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to read response body: %v", err))
+	}
+
+	var embeddings EmbeddingsResponse
+	if err := json.Unmarshal(body, &embeddings); err != nil {
+		c.log.Error("failed to unmarshal response body", "error", err)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to unmarshal response body: %v", err))
+	}
+
+	c.log.Debug("embeddings response", "model", embeddings.Model, "duration", time.Since(startTime))
+	return &embeddings, nil
+}
+
+// do posts the request to {baseUrl}/v1/{path} and hands back the response once the server accepted it
+func (c *defaultClient) do(ctx context.Context, path string, req any, stream bool) (*http.Response, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		c.log.Error("failed to marshal request", "error", err)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to marshal request: %v", err))
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseUrl.JoinPath("v1", path).String(), bytes.NewReader(payload))
+	if err != nil {
+		c.log.Error("failed to create request", "error", err)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to create request: %v", err))
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if stream {
+		request.Header.Set("Accept", "text/event-stream")
+	}
+	if c.apiKey != "" {
+		request.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.client.Do(request)
+	if err != nil {
+		c.log.Error("failed to execute request", "error", err)
+		return nil, status.Error(codes.Unavailable, fmt.Sprintf("failed to execute request: %v", err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, c.describeFailure(resp)
+	}
+	return resp, nil
+}
+
+// describeFailure turns an unsuccessful response into a status, keeping the message the server gave when there is one
+func (c *defaultClient) describeFailure(resp *http.Response) error {
+	message := resp.Status
+	body, _ := io.ReadAll(resp.Body)
+	var failure ErrorResponse
+	if err := json.Unmarshal(body, &failure); err == nil && failure.Error.Message != "" {
+		message = fmt.Sprintf("%s: %s", resp.Status, failure.Error.Message)
+	}
+	c.log.Error("request failed", "status", resp.Status, "message", message)
+
+	code := codes.Internal
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case resp.StatusCode == http.StatusForbidden:
+		code = codes.PermissionDenied
+	case resp.StatusCode == http.StatusNotFound:
+		code = codes.NotFound
+	case resp.StatusCode == http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case resp.StatusCode == http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case resp.StatusCode >= http.StatusInternalServerError:
+		code = codes.Unavailable
+	}
+	return status.Error(code, fmt.Sprintf("request failed: %s", message))
+}
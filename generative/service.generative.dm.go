@@ -0,0 +1,361 @@
+package generative
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/generative/ollama"
+	"overseer/generative/openai"
+	"strings"
+	"time"
+
+	charm "github.com/charmbracelet/log"
+)
+
+// paragraphBreak is where a streamed reply is split into separate messages for the players
+const paragraphBreak = "\n\n"
+
+type dungeonMaster struct {
+	llm        LLM
+	templating TemplatingService
+	log        *charm.Logger
+}
+
+func NewLLMDungeonMasterService(templating TemplatingService, llm LLM) (DungeonMasterService, error) {
+	return &dungeonMaster{
+		llm:        llm,
+		templating: templating,
+		log:        common.GetLogger("service.generative.dm"),
+	}, nil
+}
+
+func NewOllamaDungeonMasterService(templating TemplatingService, client ollama.Client) (DungeonMasterService, error) {
+	return NewLLMDungeonMasterService(templating, NewOllamaLLM(client))
+}
+
+func NewOpenAIDungeonMasterService(templating TemplatingService, client openai.Client) (DungeonMasterService, error) {
+	return NewLLMDungeonMasterService(templating, NewOpenAILLM(client))
+}
+
+type dungeonMasterTemplate struct {
+	Theme            v1.GameTheme
+	Speaker          string
+	Participants     []string
+	HasLocation      bool
+	LocationTheme    v1.MapCoordinateDetail_CoordinateType
+	LocationLore     string
+	DifficultTerrain bool
+	SpriteLores      []string
+}
+
+func (s *dungeonMaster) Respond(ctx context.Context, scene DungeonMasterScene) (<-chan Chunk, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, err
+	}
+
+	messages, err := s.conversation(info, scene)
+	if err != nil {
+		return nil, err
+	}
+	messages = append(messages, Message{
+		Role:    RoleUser,
+		Content: fmt.Sprintf("%s: %s", ActorName(scene.Speaker), scene.Utterance),
+	})
+
+	return s.converse(ctx, info, messages)
+}
+
+type adjudicationTemplate struct {
+	Theme            v1.GameTheme
+	Speaker          string
+	Action           string
+	HasLocation      bool
+	LocationTheme    v1.MapCoordinateDetail_CoordinateType
+	DifficultTerrain bool
+	Sprites          []adjudicationSpriteTemplate
+}
+
+type adjudicationSpriteTemplate struct {
+	Uid  string
+	Lore string
+}
+
+type adjudicationResponse struct {
+	Kind    string `json:"kind"`
+	Ability string `json:"ability"`
+	Target  string `json:"target"`
+}
+
+func (s *dungeonMaster) Adjudicate(ctx context.Context, scene DungeonMasterScene) (*ActionClassification, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, err
+	}
+
+	tmplVals := &adjudicationTemplate{
+		Theme:   scene.Theme,
+		Speaker: ActorName(scene.Speaker),
+		Action:  scene.Utterance,
+		Sprites: make([]adjudicationSpriteTemplate, 0),
+	}
+	if scene.Location != nil {
+		tmplVals.HasLocation = true
+		tmplVals.LocationTheme = scene.Location.GetType()
+		tmplVals.DifficultTerrain = scene.Location.GetDifficultTerrain()
+		for _, sprite := range scene.Location.GetSprites() {
+			if sprite.GetActor().GetUid() == scene.Speaker.GetUid() {
+				continue
+			}
+			lore := sprite.GetLorePublic()
+			if sprite.GetActor() != nil {
+				lore = fmt.Sprintf("the player %s", ActorName(sprite.GetActor()))
+			}
+			tmplVals.Sprites = append(tmplVals.Sprites, adjudicationSpriteTemplate{Uid: sprite.GetUid(), Lore: lore})
+		}
+	}
+
+	var buf bytes.Buffer
+	err = s.templating.AdjudicationTemplate().Execute(&buf, tmplVals)
+	if err != nil {
+		s.log.Error("failed to execute adjudication template", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	raw, err := s.llm.CompleteJSON(ctx, buf.String())
+	if err != nil {
+		s.log.Error("failed to adjudicate action", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	var response adjudicationResponse
+	if err := json.Unmarshal([]byte(raw), &response); err != nil {
+		// the rules still resolve the action so a confused model only costs us the classification
+		s.log.Warn("dungeon master responded with an invalid classification", info.LoggingContext("error", err, "response", raw)...)
+	}
+
+	return sanitizeClassification(response, tmplVals.Sprites), nil
+}
+
+// sanitizeClassification never trusts the model, unknown kinds and abilities fall back to defaults and targets must be present
+func sanitizeClassification(response adjudicationResponse, sprites []adjudicationSpriteTemplate) *ActionClassification {
+	classification := &ActionClassification{
+		Kind:    v1.ActionEffect_SKILL_CHECK,
+		Ability: v1.ActionEffect_ABILITY_UNSPECIFIED,
+	}
+	if kind, ok := v1.ActionEffect_Kind_value[strings.ToUpper(strings.TrimSpace(response.Kind))]; ok && kind != 0 {
+		classification.Kind = v1.ActionEffect_Kind(kind)
+	}
+	if ability, ok := v1.ActionEffect_Ability_value[strings.ToUpper(strings.TrimSpace(response.Ability))]; ok {
+		classification.Ability = v1.ActionEffect_Ability(ability)
+	}
+	if classification.Ability == v1.ActionEffect_ABILITY_UNSPECIFIED {
+		switch classification.Kind {
+		case v1.ActionEffect_ATTACK:
+			classification.Ability = v1.ActionEffect_STRENGTH
+		case v1.ActionEffect_USE_ITEM:
+			classification.Ability = v1.ActionEffect_DEXTERITY
+		case v1.ActionEffect_INTERACT:
+			classification.Ability = v1.ActionEffect_CHARISMA
+		default:
+			classification.Ability = v1.ActionEffect_WISDOM
+		}
+	}
+	target := strings.TrimSpace(response.Target)
+	for _, sprite := range sprites {
+		if sprite.Uid == target {
+			classification.Target = target
+			break
+		}
+	}
+	return classification
+}
+
+type narrationTemplate struct {
+	Speaker    string
+	Action     string
+	Kind       v1.ActionEffect_Kind
+	Ability    v1.ActionEffect_Ability
+	Check      int64
+	Difficulty int64
+	Outcome    v1.ActionEffect_Outcome
+	Target     string
+	Damage     int64
+	Defeated   bool
+}
+
+func (s *dungeonMaster) Narrate(ctx context.Context, scene DungeonMasterScene, resolution *v1.ActionEffect) (<-chan Chunk, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, err
+	}
+
+	tmplVals := &narrationTemplate{
+		Speaker:    ActorName(scene.Speaker),
+		Action:     resolution.GetAction(),
+		Kind:       resolution.GetKind(),
+		Ability:    resolution.GetAbility(),
+		Check:      resolution.GetCheck().GetTotal(),
+		Difficulty: resolution.GetDifficulty(),
+		Outcome:    resolution.GetOutcome(),
+		Damage:     resolution.GetDamage().GetTotal(),
+		Defeated:   resolution.TargetHealth != nil && resolution.GetTargetHealth() <= 0,
+	}
+	for _, sprite := range scene.Location.GetSprites() {
+		if sprite.GetUid() != resolution.GetTargetSpriteUid() {
+			continue
+		}
+		tmplVals.Target = sprite.GetLorePublic()
+		if sprite.GetActor() != nil {
+			tmplVals.Target = ActorName(sprite.GetActor())
+		}
+	}
+
+	var buf bytes.Buffer
+	err = s.templating.NarrationTemplate().Execute(&buf, tmplVals)
+	if err != nil {
+		s.log.Error("failed to execute narration template", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	messages, err := s.conversation(info, scene)
+	if err != nil {
+		return nil, err
+	}
+	messages = append(messages, Message{Role: RoleUser, Content: buf.String()})
+
+	return s.converse(ctx, info, messages)
+}
+
+type introductionTemplate struct {
+	Players []string
+}
+
+func (s *dungeonMaster) Introduce(ctx context.Context, scene DungeonMasterScene) (<-chan Chunk, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = s.templating.IntroductionTemplate().Execute(&buf, &introductionTemplate{
+		Players: common.Filter(scene.Participants, ActorName),
+	})
+	if err != nil {
+		s.log.Error("failed to execute introduction template", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	messages, err := s.conversation(info, scene)
+	if err != nil {
+		return nil, err
+	}
+	messages = append(messages, Message{Role: RoleUser, Content: buf.String()})
+
+	return s.converse(ctx, info, messages)
+}
+
+// conversation builds the system prompt and transcript shared by everything the dungeon master says
+func (s *dungeonMaster) conversation(info *common.OverseerContextInformation, scene DungeonMasterScene) ([]Message, error) {
+	tmplVals := &dungeonMasterTemplate{
+		Theme:        scene.Theme,
+		Speaker:      ActorName(scene.Speaker),
+		Participants: common.Filter(scene.Participants, ActorName),
+	}
+	if scene.Location != nil {
+		tmplVals.HasLocation = true
+		tmplVals.LocationTheme = scene.Location.GetType()
+		tmplVals.LocationLore = scene.Location.GetLore()
+		tmplVals.DifficultTerrain = scene.Location.GetDifficultTerrain()
+		for _, sprite := range scene.Location.GetSprites() {
+			if sprite.GetActor() == nil && sprite.GetLorePublic() != "" {
+				tmplVals.SpriteLores = append(tmplVals.SpriteLores, sprite.GetLorePublic())
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	err := s.templating.DungeonMasterTemplate().Execute(&buf, tmplVals)
+	if err != nil {
+		s.log.Error("failed to execute dungeon master template", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	messages := []Message{{Role: RoleSystem, Content: buf.String()}}
+	for _, line := range scene.History {
+		if line.FromDungeonMaster {
+			messages = append(messages, Message{Role: RoleAssistant, Content: line.Content})
+		} else {
+			messages = append(messages, Message{Role: RoleUser, Content: fmt.Sprintf("%s: %s", line.Speaker, line.Content)})
+		}
+	}
+	return messages, nil
+}
+
+func (s *dungeonMaster) converse(ctx context.Context, info *common.OverseerContextInformation, messages []Message) (<-chan Chunk, error) {
+	s.log.Debug("asking the dungeon master", info.LoggingContext("messages", len(messages))...)
+	responses, err := s.llm.ChatStream(ctx, messages)
+	if err != nil {
+		s.log.Error("failed to converse with the dungeon master", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	results := make(chan Chunk, common.GetConfiguration().ChannelBuffer)
+	go s.streamParagraphs(info, responses, results)
+	return results, nil
+}
+
+// streamParagraphs forwards the reply a paragraph at a time so players are not left waiting on the full response
+func (s *dungeonMaster) streamParagraphs(info *common.OverseerContextInformation, responses <-chan Chunk, results chan<- Chunk) {
+	defer close(results)
+	startTime := time.Now()
+
+	var pending strings.Builder
+	for response := range responses {
+		if response.Err != nil {
+			// what was said before the reply broke off is still worth hearing, the error follows it
+			s.log.Error("dungeon master reply was cut short", info.LoggingContext("error", response.Err)...)
+			if paragraph := strings.TrimSpace(pending.String()); paragraph != "" {
+				results <- Chunk{Content: paragraph}
+			}
+			results <- Chunk{Err: response.Err}
+			return
+		}
+		pending.WriteString(response.Content)
+		for {
+			text := pending.String()
+			idx := strings.Index(text, paragraphBreak)
+			if idx < 0 {
+				break
+			}
+			if paragraph := strings.TrimSpace(text[:idx]); paragraph != "" {
+				results <- Chunk{Content: paragraph}
+			}
+			pending.Reset()
+			pending.WriteString(text[idx+len(paragraphBreak):])
+		}
+	}
+
+	if paragraph := strings.TrimSpace(pending.String()); paragraph != "" {
+		results <- Chunk{Content: paragraph}
+	}
+	s.log.Debug("dungeon master finished responding", info.LoggingContext("duration", time.Since(startTime))...)
+}
+
+// ActorName is how an actor is referred to in prompts and narration
+func ActorName(actor *v1.Actor) string {
+	if actor == nil {
+		return "someone"
+	}
+	if actor.GetSourceIdentity() != "" {
+		return actor.GetSourceIdentity()
+	}
+	return actor.GetUid()
+}
@@ -6,28 +6,39 @@ import (
 	v1 "overseer/build/go"
 	"overseer/common"
 	"overseer/generative/ollama"
+	"overseer/generative/openai"
 	"time"
 
 	charm "github.com/charmbracelet/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-type ollamaMapGeneration struct {
-	client     ollama.Client
+type mapGeneration struct {
+	llm        LLM
 	templating TemplatingService
 	log        *charm.Logger
 }
 
-func NewOllamaMapGenerationService(templating TemplatingService, client ollama.Client) (MapGenerationService, error) {
+func NewLLMMapGenerationService(templating TemplatingService, llm LLM) (MapGenerationService, error) {
 
-	server := &ollamaMapGeneration{
-		client:     client,
+	server := &mapGeneration{
+		llm:        llm,
 		templating: templating,
-		log:        common.GetLogger("service.generative.map.ollama"),
+		log:        common.GetLogger("service.generative.map"),
 	}
 
 	return server, nil
 }
 
+func NewOllamaMapGenerationService(templating TemplatingService, client ollama.Client) (MapGenerationService, error) {
+	return NewLLMMapGenerationService(templating, NewOllamaLLM(client))
+}
+
+func NewOpenAIMapGenerationService(templating TemplatingService, client openai.Client) (MapGenerationService, error) {
+	return NewLLMMapGenerationService(templating, NewOpenAILLM(client))
+}
+
 func readTemplateFile(path string) (string, error) {
 	contents, err := os.ReadFile(path)
 	if err != nil {
@@ -37,17 +48,23 @@ func readTemplateFile(path string) (string, error) {
 	return string(contents), nil
 }
 
-func (s *ollamaMapGeneration) GenerateCoordinate(ctx context.Context, gameTheme v1.GameTheme, spriteDensity float32, coordinate *v1.MapCoordinateDetail, neighbors []*v1.MapCoordinateDetail) (*v1.MapCoordinateDetail, error) {
+func (s *mapGeneration) GenerateCoordinate(ctx context.Context, gameTheme v1.GameTheme, spriteDensity float32, coordinate *v1.MapCoordinateDetail, neighbors []*v1.MapCoordinateDetail) (*v1.MapCoordinateDetail, error) {
 	info, err := common.GetContextInformation(ctx)
 	if err != nil {
 		s.log.Error("failed to get context information", "error", err)
 		return nil, err
 	}
 
+	// the terrain is decided procedurally before the model is asked to describe it
+	if coordinate.Type == v1.MapCoordinateDetail_TYPE_UNSPECIFIED {
+		return nil, status.Error(codes.InvalidArgument, "the terrain of a coordinate has to be decided before it is generated")
+	}
+
 	s.log.Debug("generating new map coordinate",
 		info.LoggingContext(
 			"x", coordinate.Position.X,
 			"y", coordinate.Position.Y,
+			"type", coordinate.Type.String(),
 		)...)
 	generationStartTime := time.Now()
 
@@ -58,20 +75,6 @@ func (s *ollamaMapGeneration) GenerateCoordinate(ctx context.Context, gameTheme
 		coordinate.Sprites = make([]*v1.Sprite, 0)
 	}
 
-	themeGenerationStarted := time.Now()
-	coordType, err := s.chooseTheme(neighbors)
-	if err != nil {
-		s.log.Error("failed to choose theme", info.LoggingContext("error", err)...)
-		return nil, err
-	}
-	s.log.Debug("theme chosen for coordinate", info.LoggingContext(
-		"theme", coordType.String(),
-		"coordinate_x", coordinate.Position.X,
-		"coordinate_y", coordinate.Position.Y,
-		"duration", time.Since(themeGenerationStarted),
-	)...)
-	coordinate.Type = coordType
-
 	spriteGenerationStarted := time.Now()
 	sprites, err := s.generateSprites(ctx, gameTheme, spriteDensity, coordinate, neighbors)
 	if err != nil {
@@ -0,0 +1,159 @@
+package generative
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"time"
+)
+
+func (s *mapGeneration) generateSprites(ctx context.Context, gameTheme v1.GameTheme, spriteDensity float32, coordinate *v1.MapCoordinateDetail, neighbors []*v1.MapCoordinateDetail) ([]*v1.Sprite, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Debug("Generating sprites", info.LoggingContext(
+		"x", coordinate.Position.X,
+		"y", coordinate.Position.Y,
+		"neighbors", len(neighbors),
+		"sprite_density", spriteDensity,
+	)...)
+	startTime := time.Now()
+	sprites := []*v1.Sprite{}
+
+	if len(coordinate.Actors) != 0 {
+		s.log.Debug("actors detected handling their sprites", info.LoggingContext(
+			"x", coordinate.Position.X,
+			"y", coordinate.Position.Y,
+		)...)
+		// todo: this is where we need to look up the DnD beyond account for the associated User and generate their sprite based on that content
+		for _, actor := range coordinate.Actors {
+			sprites = append(sprites, &v1.Sprite{
+				Uid:             common.GenerateUniqueId(),
+				Actor:           actor,
+				Characteristics: make([]*v1.Characteristic, 0),
+				IsObstacle:      true,
+				IsMoveable:      true,
+			})
+		}
+	}
+
+	numberOfSprites := common.RandomizedProgressiveValue(
+		common.Random(ctx),
+		common.GetConfiguration().MapGeneration.MinimumSpriteDensity,
+		spriteDensity,
+		common.GetConfiguration().MapGeneration.MaximumSpriteDensity,
+		common.GetConfiguration().MapGeneration.MaximumSpritesPerCoordinate,
+	)
+	s.log.Debug("generating sprites", info.LoggingContext(
+		"number_of_sprites", numberOfSprites,
+		"x", coordinate.Position.X,
+		"y", coordinate.Position.Y,
+	)...)
+	for i := 0; i < numberOfSprites; i++ {
+		sprite, err := s.generateSprite(ctx, gameTheme, coordinate, sprites, neighbors)
+		if errors.Is(err, errMalformedSprite) {
+			// a confused model only costs us the sprite, the coordinate is still worth keeping
+			s.log.Warn("skipping sprite the model could not describe", info.LoggingContext(
+				"x", coordinate.Position.X,
+				"y", coordinate.Position.Y,
+			)...)
+			continue
+		}
+		if err != nil {
+			s.log.Error("failed to generate sprite", info.LoggingContext(
+				"error", err,
+				"x", coordinate.Position.X,
+				"y", coordinate.Position.Y,
+			)...)
+			return nil, err
+		}
+		sprites = append(sprites, sprite)
+	}
+
+	s.log.Debug("sprite generation completed", info.LoggingContext(
+		"duration", time.Since(startTime),
+		"sprites", len(sprites),
+		"x", coordinate.Position.X,
+		"y", coordinate.Position.Y,
+	)...)
+	return sprites, nil
+}
+
+type mapLoreGenerationTemplate struct {
+	Theme         v1.GameTheme
+	LocationTheme v1.MapCoordinateDetail_CoordinateType
+	SpriteLores   []string
+	NeighborLores []mapGenerationNeighborLoreTemplate
+}
+
+type mapGenerationNeighborLoreTemplate struct {
+	Theme       v1.MapCoordinateDetail_CoordinateType
+	Direction   string
+	Lore        string
+	SpriteLores []string
+}
+
+func (s *mapGeneration) generateLore(ctx context.Context, gameTheme v1.GameTheme, coordinate *v1.MapCoordinateDetail, neighbors []*v1.MapCoordinateDetail) (string, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	s.log.Debug("Generating lore", info.LoggingContext(
+		"x", coordinate.Position.X,
+		"y", coordinate.Position.Y,
+		"neighbors", len(neighbors),
+	)...)
+	startTime := time.Now()
+
+	spriteLores := make([]string, 0)
+	for _, sprite := range coordinate.Sprites {
+		spriteLores = append(spriteLores, sprite.LoreInternal)
+	}
+
+	neighborLores := make([]mapGenerationNeighborLoreTemplate, 0)
+	for _, neighbor := range neighbors {
+		neighborSpriteLores := make([]string, 0)
+		for _, sprite := range neighbor.Sprites {
+			neighborSpriteLores = append(neighborSpriteLores, sprite.LoreInternal)
+		}
+		direction := common.GetDirection(coordinate.Position, neighbor.Position)
+		neighborLores = append(neighborLores, mapGenerationNeighborLoreTemplate{
+			Theme:       neighbor.Type,
+			Direction:   direction,
+			Lore:        neighbor.Lore,
+			SpriteLores: neighborSpriteLores,
+		})
+	}
+
+	templateVals := &mapLoreGenerationTemplate{
+		Theme:         gameTheme,
+		LocationTheme: coordinate.Type,
+		SpriteLores:   spriteLores,
+		NeighborLores: neighborLores,
+	}
+
+	var buf bytes.Buffer
+	err = s.templating.CoordinateLoreTemplate().Execute(&buf, templateVals)
+	if err != nil {
+		s.log.Error("failed to execute lore template", info.LoggingContext("error", err)...)
+		return "", err
+	}
+
+	lore, err := s.llm.Complete(ctx, buf.String())
+	if err != nil {
+		s.log.Error("failed to generate lore", info.LoggingContext("error", err)...)
+		return "", err
+	}
+
+	s.log.Debug("lore generation completed", info.LoggingContext(
+		"duration", time.Since(startTime),
+		"x", coordinate.Position.X,
+		"y", coordinate.Position.Y,
+	)...)
+	return lore, nil
+}
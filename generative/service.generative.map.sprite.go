@@ -0,0 +1,233 @@
+package generative
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errMalformedSprite is returned once the model used up its attempts without answering with a valid sprite
+var errMalformedSprite = status.Error(codes.Internal, "model did not respond with a valid sprite")
+
+// defaultCharacteristicRange applies to characteristics without a configured range
+var defaultCharacteristicRange = common.CharacteristicRange{Minimum: 0, Maximum: 100}
+
+type spriteGenerationTemplate struct {
+	Theme            v1.GameTheme
+	LocationTheme    v1.MapCoordinateDetail_CoordinateType
+	DifficultTerrain string
+	ExistingSprites  []string
+	Characteristics  []spriteCharacteristicTemplate
+	Schema           string
+}
+
+type spriteCharacteristicTemplate struct {
+	Name    string
+	Minimum float32
+	Maximum float32
+}
+
+// generatedSprite is what the model answers with, pointers tell a missing field from a zero value
+type generatedSprite struct {
+	Name         string              `json:"name"`
+	Stats        map[string]*float32 `json:"stats"`
+	IsObstacle   *bool               `json:"is_obstacle"`
+	IsMoveable   *bool               `json:"is_moveable"`
+	LoreInternal string              `json:"lore_internal"`
+	LorePublic   string              `json:"lore_public"`
+}
+
+func (s *mapGeneration) generateSprite(ctx context.Context, gameTheme v1.GameTheme, coordinate *v1.MapCoordinateDetail, localSprites []*v1.Sprite, neighbors []*v1.MapCoordinateDetail) (*v1.Sprite, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Debug("Generating sprite", info.LoggingContext(
+		"x", coordinate.Position.X,
+		"y", coordinate.Position.Y,
+		"neighbors", len(neighbors),
+		"prexisting_sprites", len(localSprites),
+	)...)
+
+	types := characteristicTypes()
+	schema, err := spriteSchema(types)
+	if err != nil {
+		s.log.Error("failed to build sprite schema", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	difficultTerrain := "Normal"
+	if coordinate.DifficultTerrain {
+		difficultTerrain = "Difficult"
+	}
+
+	tmplVals := &spriteGenerationTemplate{
+		Theme:            gameTheme,
+		LocationTheme:    coordinate.Type,
+		DifficultTerrain: difficultTerrain,
+		ExistingSprites:  make([]string, 0),
+		Characteristics:  make([]spriteCharacteristicTemplate, 0, len(types)),
+		Schema:           schema,
+	}
+	for _, sprite := range localSprites {
+		if sprite.Actor == nil && sprite.Name != "" {
+			tmplVals.ExistingSprites = append(tmplVals.ExistingSprites, sprite.Name)
+		}
+	}
+	for _, characteristic := range types {
+		limits := characteristicRange(characteristic)
+		tmplVals.Characteristics = append(tmplVals.Characteristics, spriteCharacteristicTemplate{
+			Name:    characteristicName(characteristic),
+			Minimum: limits.Minimum,
+			Maximum: limits.Maximum,
+		})
+	}
+
+	var buf bytes.Buffer
+	err = s.templating.SpriteTemplate().Execute(&buf, tmplVals)
+	if err != nil {
+		s.log.Error("failed to execute sprite template", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	prompt := buf.String()
+	attempts := max(common.GetConfiguration().MapGeneration.SpriteGenerationAttempts, 1)
+	for attempt := 1; attempt <= attempts; attempt++ {
+		raw, err := s.llm.CompleteJSON(ctx, prompt)
+		if err != nil {
+			s.log.Error("failed to generate sprite", info.LoggingContext("error", err)...)
+			return nil, err
+		}
+
+		generated, err := parseSprite(raw, types)
+		if err != nil {
+			s.log.Warn("model responded with a malformed sprite", info.LoggingContext(
+				"error", err,
+				"attempt", attempt,
+				"response", raw,
+			)...)
+			// telling the model what was wrong makes the next attempt far more likely to succeed
+			prompt = fmt.Sprintf("%s\n\nYour previous response was rejected because %s. Respond only with a JSON object matching the schema.", buf.String(), err)
+			continue
+		}
+
+		return generated.toProto(types), nil
+	}
+	return nil, errMalformedSprite
+}
+
+// parseSprite checks the response against the sprite schema, the error describes the first problem found
+func parseSprite(raw string, types []v1.Characteristic_Type) (*generatedSprite, error) {
+	var generated generatedSprite
+	if err := json.Unmarshal([]byte(raw), &generated); err != nil {
+		return nil, fmt.Errorf("it is not a valid sprite object: %v", err)
+	}
+	switch {
+	case strings.TrimSpace(generated.Name) == "":
+		return nil, fmt.Errorf("name is missing")
+	case generated.IsObstacle == nil:
+		return nil, fmt.Errorf("is_obstacle is missing")
+	case generated.IsMoveable == nil:
+		return nil, fmt.Errorf("is_moveable is missing")
+	case strings.TrimSpace(generated.LoreInternal) == "":
+		return nil, fmt.Errorf("lore_internal is missing")
+	case strings.TrimSpace(generated.LorePublic) == "":
+		return nil, fmt.Errorf("lore_public is missing")
+	}
+
+	stats := make(map[string]*float32, len(generated.Stats))
+	for name, value := range generated.Stats {
+		stats[strings.ToLower(name)] = value
+	}
+	for _, characteristic := range types {
+		if stats[characteristicName(characteristic)] == nil {
+			return nil, fmt.Errorf("stats.%s is missing", characteristicName(characteristic))
+		}
+	}
+	generated.Stats = stats
+	return &generated, nil
+}
+
+// toProto clamps the stats into their configured ranges, the model is asked to respect them but never trusted to
+func (g *generatedSprite) toProto(types []v1.Characteristic_Type) *v1.Sprite {
+	characteristics := make([]*v1.Characteristic, 0, len(types))
+	for _, characteristic := range types {
+		limits := characteristicRange(characteristic)
+		value := min(max(*g.Stats[characteristicName(characteristic)], limits.Minimum), limits.Maximum)
+		characteristics = append(characteristics, &v1.Characteristic{Type: characteristic, Value: value})
+	}
+
+	return &v1.Sprite{
+		Uid:             common.GenerateUniqueId(),
+		Name:            strings.TrimSpace(g.Name),
+		Characteristics: characteristics,
+		IsObstacle:      *g.IsObstacle,
+		IsMoveable:      *g.IsMoveable,
+		LoreInternal:    strings.TrimSpace(g.LoreInternal),
+		LorePublic:      strings.TrimSpace(g.LorePublic),
+	}
+}
+
+// spriteSchema is the JSON schema shown to the model, parseSprite enforces the same shape
+func spriteSchema(types []v1.Characteristic_Type) (string, error) {
+	stats := make(map[string]any, len(types))
+	required := make([]string, 0, len(types))
+	for _, characteristic := range types {
+		limits := characteristicRange(characteristic)
+		stats[characteristicName(characteristic)] = map[string]any{
+			"type":    "number",
+			"minimum": limits.Minimum,
+			"maximum": limits.Maximum,
+		}
+		required = append(required, characteristicName(characteristic))
+	}
+
+	schema, err := json.MarshalIndent(map[string]any{
+		"type":     "object",
+		"required": []string{"name", "stats", "is_obstacle", "is_moveable", "lore_internal", "lore_public"},
+		"properties": map[string]any{
+			"name":          map[string]any{"type": "string"},
+			"stats":         map[string]any{"type": "object", "required": required, "properties": stats},
+			"is_obstacle":   map[string]any{"type": "boolean"},
+			"is_moveable":   map[string]any{"type": "boolean"},
+			"lore_internal": map[string]any{"type": "string"},
+			"lore_public":   map[string]any{"type": "string"},
+		},
+	}, "", "  ")
+	if err != nil {
+		return "", status.Error(codes.Internal, fmt.Sprintf("failed to marshal sprite schema: %v", err))
+	}
+	return string(schema), nil
+}
+
+// characteristicTypes are every characteristic a sprite has, in the order of the enum
+func characteristicTypes() []v1.Characteristic_Type {
+	types := make([]v1.Characteristic_Type, 0, len(v1.Characteristic_Type_name))
+	for value := range v1.Characteristic_Type_name {
+		if value != int32(v1.Characteristic_TYPE_UNSPECIFIED) {
+			types = append(types, v1.Characteristic_Type(value))
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+func characteristicName(characteristic v1.Characteristic_Type) string {
+	return strings.ToLower(characteristic.String())
+}
+
+func characteristicRange(characteristic v1.Characteristic_Type) common.CharacteristicRange {
+	if limits, ok := common.GetConfiguration().MapGeneration.CharacteristicRanges[characteristicName(characteristic)]; ok && limits.Minimum <= limits.Maximum {
+		return limits
+	}
+	return defaultCharacteristicRange
+}
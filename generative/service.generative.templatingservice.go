@@ -7,54 +7,99 @@ import (
 )
 
 type defaultTemplatingService struct {
-	spriteInternalTemplate *template.Template
-	spriteExternalTemplate *template.Template
-	coordinateTemplate     *template.Template
+	spriteTemplate        *template.Template
+	coordinateTemplate    *template.Template
+	dungeonMasterTemplate *template.Template
+	adjudicationTemplate  *template.Template
+	narrationTemplate     *template.Template
+	introductionTemplate  *template.Template
 }
 
 func NewTemplatingService() (TemplatingService, error) {
-	tmpl, err := readTemplateFile(path.Join(common.GetConfiguration().Templating.TemplateBasePath, "map", "ollama", "spriteLore.internal.tmpl"))
+	tmpl, err := readTemplateFile(path.Join(common.GetConfiguration().Templating.TemplateBasePath, "map", "ollama", "sprite.tmpl"))
 	if err != nil {
 		return nil, err
 	}
-	spriteInternalTmpl, err := template.New("spriteInternalGeneration").Parse(tmpl)
+	spriteTmpl, err := template.New("spriteGeneration").Parse(tmpl)
 	if err != nil {
 		return nil, err
 	}
 
-	tmpl, err = readTemplateFile(path.Join(common.GetConfiguration().Templating.TemplateBasePath, "map", "ollama", "spriteLore.external.tmpl"))
+	tmpl, err = readTemplateFile(path.Join(common.GetConfiguration().Templating.TemplateBasePath, "map", "ollama", "coordinateLore.tmpl"))
 	if err != nil {
 		return nil, err
 	}
-	spriteExternalTmpl, err := template.New("spriteExternalGeneration").Parse(tmpl)
+	coordinateLoreTmpl, err := template.New("coordinateLoreGeneration").Parse(tmpl)
 	if err != nil {
 		return nil, err
 	}
 
-	tmpl, err = readTemplateFile(path.Join(common.GetConfiguration().Templating.TemplateBasePath, "map", "ollama", "coordinateLore.tmpl"))
+	tmpl, err = readTemplateFile(path.Join(common.GetConfiguration().Templating.TemplateBasePath, "dm", "ollama", "system.tmpl"))
 	if err != nil {
 		return nil, err
 	}
-	coordinateLoreTmpl, err := template.New("coordinateLoreGeneration").Parse(tmpl)
+	dungeonMasterTmpl, err := template.New("dungeonMasterSystem").Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err = readTemplateFile(path.Join(common.GetConfiguration().Templating.TemplateBasePath, "dm", "ollama", "adjudicate.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	adjudicationTmpl, err := template.New("dungeonMasterAdjudication").Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err = readTemplateFile(path.Join(common.GetConfiguration().Templating.TemplateBasePath, "dm", "ollama", "narrate.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	narrationTmpl, err := template.New("dungeonMasterNarration").Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err = readTemplateFile(path.Join(common.GetConfiguration().Templating.TemplateBasePath, "dm", "ollama", "introduce.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	introductionTmpl, err := template.New("dungeonMasterIntroduction").Parse(tmpl)
 	if err != nil {
 		return nil, err
 	}
 
 	return &defaultTemplatingService{
-		spriteInternalTemplate: spriteInternalTmpl,
-		spriteExternalTemplate: spriteExternalTmpl,
-		coordinateTemplate:     coordinateLoreTmpl,
+		spriteTemplate:        spriteTmpl,
+		coordinateTemplate:    coordinateLoreTmpl,
+		dungeonMasterTemplate: dungeonMasterTmpl,
+		adjudicationTemplate:  adjudicationTmpl,
+		narrationTemplate:     narrationTmpl,
+		introductionTemplate:  introductionTmpl,
 	}, nil
 }
 
-func (s *defaultTemplatingService) InternalLoreTemplate() *template.Template {
-	return s.spriteInternalTemplate
-}
-
-func (s *defaultTemplatingService) PublicLoreTemplate() *template.Template {
-	return s.spriteExternalTemplate
+func (s *defaultTemplatingService) SpriteTemplate() *template.Template {
+	return s.spriteTemplate
 }
 
 func (s *defaultTemplatingService) CoordinateLoreTemplate() *template.Template {
 	return s.coordinateTemplate
 }
+
+func (s *defaultTemplatingService) DungeonMasterTemplate() *template.Template {
+	return s.dungeonMasterTemplate
+}
+
+func (s *defaultTemplatingService) AdjudicationTemplate() *template.Template {
+	return s.adjudicationTemplate
+}
+
+func (s *defaultTemplatingService) NarrationTemplate() *template.Template {
+	return s.narrationTemplate
+}
+
+func (s *defaultTemplatingService) IntroductionTemplate() *template.Template {
+	return s.introductionTemplate
+}
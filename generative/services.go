@@ -7,11 +7,53 @@ import (
 )
 
 type MapGenerationService interface {
+	// GenerateCoordinate writes the sprites and lore of a coordinate whose terrain type is already decided
 	GenerateCoordinate(ctx context.Context, gameTheme v1.GameTheme, spriteDensity float32, coordinate *v1.MapCoordinateDetail, neighbors []*v1.MapCoordinateDetail) (*v1.MapCoordinateDetail, error)
 }
 
+// DungeonMasterService voices the dungeon master, replies are streamed back a paragraph per chunk as the model produces them
+// and a reply cut short ends with a chunk carrying the error
+type DungeonMasterService interface {
+	Respond(ctx context.Context, scene DungeonMasterScene) (<-chan Chunk, error)
+	// Adjudicate classifies the free-form action in the scene's utterance, it never decides the outcome
+	Adjudicate(ctx context.Context, scene DungeonMasterScene) (*ActionClassification, error)
+	// Introduce sets the opening scene for the players of a new game
+	Introduce(ctx context.Context, scene DungeonMasterScene) (<-chan Chunk, error)
+	// Narrate describes an action whose outcome has already been resolved by the game rules
+	Narrate(ctx context.Context, scene DungeonMasterScene, resolution *v1.ActionEffect) (<-chan Chunk, error)
+}
+
+// ActionClassification is how the dungeon master interpreted a free-form action
+type ActionClassification struct {
+	Kind    v1.ActionEffect_Kind
+	Ability v1.ActionEffect_Ability
+	// Target is the uid of the sprite the action is aimed at, empty when there is none
+	Target string
+}
+
+// DungeonMasterScene is everything the dungeon master knows when answering a player
+type DungeonMasterScene struct {
+	Theme        v1.GameTheme
+	Speaker      *v1.Actor
+	Participants []*v1.Actor
+	Location     *v1.MapCoordinateDetail
+	History      []DungeonMasterLine
+	Utterance    string
+}
+
+// DungeonMasterLine is a single line of the game transcript provided to the dungeon master as context
+type DungeonMasterLine struct {
+	FromDungeonMaster bool
+	Speaker           string
+	Content           string
+}
+
 type TemplatingService interface {
-	InternalLoreTemplate() *template.Template
-	PublicLoreTemplate() *template.Template
+	// SpriteTemplate asks for a sprite as a JSON object matching the schema it is given
+	SpriteTemplate() *template.Template
 	CoordinateLoreTemplate() *template.Template
+	DungeonMasterTemplate() *template.Template
+	AdjudicationTemplate() *template.Template
+	NarrationTemplate() *template.Template
+	IntroductionTemplate() *template.Template
 }
@@ -4,30 +4,106 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"overseer/auth"
 	v1 "overseer/build/go"
 	"overseer/common"
 	"overseer/engine"
+	"overseer/storage"
 
 	charm "github.com/charmbracelet/log"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+const (
+	defaultEventPageSize int32 = 50
+	maximumEventPageSize int32 = 500
+)
+
 type defaultEventServer struct {
-	bus engine.EventBus
-	log *charm.Logger
+	bus    engine.EventBus
+	events storage.EventStore
+	games  storage.GameStore
+	log    *charm.Logger
 	v1.UnimplementedEventsServer
 }
 
-func NewEventServer(bus engine.EventBus) v1.EventsServer {
+func NewEventServer(bus engine.EventBus, events storage.EventStore, games storage.GameStore) v1.EventsServer {
 	return &defaultEventServer{
-		bus: bus,
-		log: common.GetLogger("server.event"),
+		bus:    bus,
+		events: events,
+		games:  games,
+		log:    common.GetLogger("server.event"),
 	}
 }
 
 func (s *defaultEventServer) GetEvent(ctx context.Context, req *v1.GetEventRequest) (*v1.EventRecord, error) {
-	return nil, status.Error(codes.Unimplemented, "method GetEvent not implemented")
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, err
+	}
+
+	if req.GetUid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "event uid is required")
+	}
+
+	s.log.Debug("getting event", info.LoggingContext("event", req.GetUid())...)
+	record, err := s.events.GetEvent(ctx, req.GetUid())
+	if err != nil {
+		s.log.Warn("failed to get event", info.LoggingContext("error", err, "event", req.GetUid())...)
+		return nil, err
+	}
+
+	// the policy cannot scope the request until the event is loaded, so membership of its game is checked here
+	if info.User.GetUid() != auth.SystemUserId {
+		game, err := s.games.GetGame(ctx, record.GetGameUid())
+		if err != nil {
+			s.log.Warn("failed to get game of event", info.LoggingContext("error", err, "event", req.GetUid())...)
+			return nil, err
+		}
+		if !auth.IsGameMember(info, game) {
+			s.log.Warn("event requested from outside its game", info.LoggingContext("event", req.GetUid(), "game", record.GetGameUid())...)
+			return nil, status.Error(codes.PermissionDenied, "permission denied")
+		}
+	}
+
+	record.Receipts = visibleReceipts(info, record.GetReceipts())
+	return record, nil
+}
+
+func (s *defaultEventServer) ListEvents(ctx context.Context, req *v1.ListEventsRequest) (*v1.ListEventsResponse, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, err
+	}
+
+	if req.GetGameUid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "game uid is required")
+	}
+	if req.GetPageSize() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page size cannot be negative")
+	}
+	if req.CreatedAfter != nil && req.CreatedBefore != nil && req.GetCreatedAfter() >= req.GetCreatedBefore() {
+		return nil, status.Error(codes.InvalidArgument, "created after must be before created before")
+	}
+	if req.GetPageSize() == 0 {
+		req.PageSize = defaultEventPageSize
+	}
+	req.PageSize = min(req.GetPageSize(), maximumEventPageSize)
+
+	s.log.Debug("listing events", info.LoggingContext("game", req.GetGameUid(), "page_size", req.GetPageSize(), "page_token", req.GetPageToken())...)
+	response, err := s.events.ListEvents(ctx, req)
+	if err != nil {
+		s.log.Warn("failed to list events", info.LoggingContext("error", err, "game", req.GetGameUid())...)
+		return nil, err
+	}
+
+	for _, record := range response.GetEvents() {
+		record.Receipts = visibleReceipts(info, record.GetReceipts())
+	}
+	return response, nil
 }
 
 func (s *defaultEventServer) Submit(ctx context.Context, event *v1.Event) (*v1.EventReceipts, error) {
@@ -39,6 +115,10 @@ func (s *defaultEventServer) Submit(ctx context.Context, event *v1.Event) (*v1.E
 
 	s.log.Debug("submitting event", info.LoggingContext("event", event)...)
 	results, err := s.bus.Submit(ctx, event)
+	if code := status.Code(err); code == codes.InvalidArgument || code == codes.PermissionDenied {
+		s.log.Warn("event rejected", info.LoggingContext("error", err)...)
+		return nil, err
+	}
 	if err != nil {
 		s.log.Error("failed to submit event", info.LoggingContext("error", err)...)
 		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to submit event: %v", err))
@@ -100,3 +180,95 @@ func (s *defaultEventServer) transmitResults(stream v1.Events_SubscribeServer, r
 		}
 	}
 }
+
+func (s *defaultEventServer) WatchGame(req *v1.WatchGameRequest, stream v1.Events_WatchGameServer) error {
+	ctx := stream.Context()
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return err
+	}
+
+	if req.GetGameUid() == "" {
+		return status.Error(codes.InvalidArgument, "game uid is required")
+	}
+
+	// subscribe before replaying so nothing recorded in between is missed
+	live, err := s.bus.Watch(ctx, req.GetGameUid())
+	if err != nil {
+		s.log.Warn("failed to watch game", info.LoggingContext("error", err, "game", req.GetGameUid())...)
+		return err
+	}
+	s.log.Info("client watching game", info.LoggingContext("game", req.GetGameUid(), "resume", req.GetAfterReceiptUid())...)
+	// headers tell the client the subscription is live
+	if err := stream.SendHeader(nil); err != nil {
+		s.log.Warn("failed to send headers", info.LoggingContext("error", err)...)
+		return err
+	}
+
+	replayed := make(map[string]bool)
+	if req.AfterReceiptUid != nil {
+		backlog, err := s.events.GetReceiptsAfter(ctx, req.GetGameUid(), req.GetAfterReceiptUid())
+		if err != nil {
+			s.log.Warn("failed to replay receipts", info.LoggingContext("error", err, "game", req.GetGameUid())...)
+			return err
+		}
+		for _, receipt := range backlog {
+			replayed[receipt.GetUid()] = true
+			if err := s.sendVisible(info, stream, receipt); err != nil {
+				return err
+			}
+		}
+		s.log.Debug("receipts replayed", info.LoggingContext("count", len(backlog))...)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Debug("watch stream context done", info.LoggingContext("game", req.GetGameUid())...)
+			return nil
+		case receipt, ok := <-live:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return status.Error(codes.Unavailable, "watcher fell behind, resume from the last receipt received")
+			}
+			if replayed[receipt.GetUid()] {
+				continue
+			}
+			if err := s.sendVisible(info, stream, receipt); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sendVisible forwards the receipt unless it is a whisper the watching actor is not part of,
+// the system sees every whisper so front ends can deliver them to their targets
+func (s *defaultEventServer) sendVisible(info *common.OverseerContextInformation, stream v1.Events_WatchGameServer, receipt *v1.EventReceipt) error {
+	if !isReceiptVisible(info, receipt) {
+		return nil
+	}
+	if err := stream.Send(receipt); err != nil {
+		s.log.Error("failed to send receipt to watcher", info.LoggingContext("error", err)...)
+		return err
+	}
+	return nil
+}
+
+// isReceiptVisible hides whispers from everyone but the speaker and their recipients, the system sees them all
+func isReceiptVisible(info *common.OverseerContextInformation, receipt *v1.EventReceipt) bool {
+	return info.User.GetUid() == auth.SystemUserId || info.Actor == nil || common.IsReceiptVisibleTo(receipt, info.Actor.GetUid())
+}
+
+// visibleReceipts drops the receipts the caller may not see from a history they read
+func visibleReceipts(info *common.OverseerContextInformation, receipts []*v1.EventReceipt) []*v1.EventReceipt {
+	visible := make([]*v1.EventReceipt, 0, len(receipts))
+	for _, receipt := range receipts {
+		if isReceiptVisible(info, receipt) {
+			visible = append(visible, receipt)
+		}
+	}
+	return visible
+}
@@ -18,17 +18,19 @@ import (
 type defaultGameServer struct {
 	locks storage.LockStore
 	games storage.GameStore
+	maps  storage.MapStore
 	// todo: replace this with a client to avoid loopback dependencies
 	users v1.UsersServer
 	log   *charm.Logger
 	v1.UnimplementedGamesServer
 }
 
-func NewGameServer(users v1.UsersServer, locks storage.LockStore, games storage.GameStore) v1.GamesServer {
+func NewGameServer(users v1.UsersServer, locks storage.LockStore, games storage.GameStore, maps storage.MapStore) v1.GamesServer {
 	return &defaultGameServer{
 		users: users,
 		locks: locks,
 		games: games,
+		maps:  maps,
 		log:   common.GetLogger("server.game"),
 	}
 }
@@ -50,6 +52,18 @@ func (s *defaultGameServer) CreateGame(ctx context.Context, req *v1.CreateGameRe
 		s.log.Error("failed to validate actors", info.LoggingContext("error", err)...)
 		return nil, err
 	}
+	for _, spectator := range req.Spectators {
+		if _, err = s.users.GetActor(ctx, &v1.GetActorRequest{ActorId: spectator.Uid}); err != nil {
+			s.log.Error("failed to validate spectator", info.LoggingContext("error", err, "spectator", spectator.Uid)...)
+			return nil, err
+		}
+	}
+	if req.DungeonMasterUid != nil {
+		if _, err = s.users.GetActor(ctx, &v1.GetActorRequest{ActorId: req.GetDungeonMasterUid()}); err != nil {
+			s.log.Error("failed to validate dungeon master", info.LoggingContext("error", err)...)
+			return nil, err
+		}
+	}
 
 	game := &v1.Game{
 		Uid: common.GenerateRandomStringFromSeed(
@@ -57,10 +71,19 @@ func (s *defaultGameServer) CreateGame(ctx context.Context, req *v1.CreateGameRe
 			common.GenerateUniqueId(),
 			fmt.Sprintf("%d", time.Now().UTC().UnixMilli()),
 		),
-		Name:         req.Name,
-		Theme:        req.Theme,
-		ActiveActor:  info.Actor,
-		Participants: req.Participants,
+		Name:             req.Name,
+		Theme:            req.Theme,
+		ActiveActor:      info.Actor,
+		Participants:     req.Participants,
+		Spectators:       req.Spectators,
+		OwnerUid:         info.Actor.GetUid(),
+		DungeonMasterUid: req.DungeonMasterUid,
+		Seed:             req.Seed,
+	}
+	// the seed is recorded with the game so its world can be made again
+	if game.Seed == nil {
+		seed := common.NewSeed()
+		game.Seed = &seed
 	}
 	err = s.games.CreateGame(ctx, game)
 	if err != nil {
@@ -123,6 +146,10 @@ func (s *defaultGameServer) GetGame(ctx context.Context, req *v1.GetGameRequest)
 		return nil, status.Error(codes.NotFound, "game not found")
 	}
 
+	// spectators and the dungeon master may see the game without taking part in it
+	if len(auth.GameRoles(info, game)) > 0 {
+		return game, nil
+	}
 	err = s.validateActors(ctx, game.Participants)
 	if err == nil {
 		return game, nil
@@ -175,10 +202,92 @@ func (s *defaultGameServer) LockGame(ctx context.Context, req *v1.LockGameReques
 		}, err
 	}
 
-	s.log.Info("game lock resulted", info.LoggingContext("game", req.GameUid, "result", result)...)
+	s.log.Info("game lock resulted", info.LoggingContext("game", req.GameUid, "result", result != nil)...)
 	return &v1.LockGameResponse{
-		Success: result,
+		Success: result != nil,
+		GameUid: req.GameUid,
+		Lock:    result,
+	}, nil
+}
+
+func (s *defaultGameServer) RenewLock(ctx context.Context, req *v1.RenewLockRequest) (*v1.LockGameResponse, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("error getting context information", err)
+		return nil, err
+	}
+
+	game, err := s.games.GetGame(ctx, req.GameUid)
+	if err != nil {
+		s.log.Error("failed to get game", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	if game == nil {
+		s.log.Error("game not found", info.LoggingContext("game", req.GameUid)...)
+		return nil, status.Error(codes.NotFound, "game not found")
+	}
+
+	err = s.validateActors(ctx, game.Participants)
+	if err != nil {
+		s.log.Error("actor is not part of the game", info.LoggingContext("game", req.GameUid)...)
+		return nil, status.Error(codes.PermissionDenied, "actor is not part of the game")
+	}
+
+	result, err := s.locks.RenewLock(ctx, req)
+	if err != nil {
+		s.log.Error("failed to renew lock", info.LoggingContext("error", err, "game", req.GameUid)...)
+		return nil, err
+	}
+
+	return &v1.LockGameResponse{
+		Success: true,
+		GameUid: req.GameUid,
+		Lock:    result,
+	}, nil
+}
+
+func (s *defaultGameServer) ListLocks(ctx context.Context, req *v1.ListLocksRequest) (*v1.ListLocksResponse, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("error getting context information", err)
+		return nil, err
+	}
+	if info.User.GetUid() != auth.SystemUserId {
+		s.log.Error("only the system may list locks", info.LoggingContext()...)
+		return nil, status.Error(codes.PermissionDenied, "only the system may list locks")
+	}
+
+	locks, err := s.locks.ListLocks(ctx, req.GetGameUid())
+	if err != nil {
+		s.log.Error("failed to list locks", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	return &v1.ListLocksResponse{Locks: locks}, nil
+}
+
+func (s *defaultGameServer) BreakLock(ctx context.Context, req *v1.BreakLockRequest) (*v1.BreakLockResponse, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("error getting context information", err)
+		return nil, err
+	}
+	if info.User.GetUid() != auth.SystemUserId {
+		s.log.Error("only the system may break locks", info.LoggingContext("game", req.GameUid)...)
+		return nil, status.Error(codes.PermissionDenied, "only the system may break locks")
+	}
+
+	broken, err := s.locks.BreakLock(ctx, req.GameUid)
+	if err != nil {
+		s.log.Error("failed to break lock", info.LoggingContext("error", err, "game", req.GameUid)...)
+		return nil, err
+	}
+
+	s.log.Warn("game lock broken", info.LoggingContext("game", req.GameUid, "result", broken != nil)...)
+	return &v1.BreakLockResponse{
 		GameUid: req.GameUid,
+		Success: broken != nil,
+		Broken:  broken,
 	}, nil
 }
 
@@ -231,6 +340,88 @@ func (s *defaultGameServer) UnlockGame(ctx context.Context, req *v1.UnlockGameRe
 	}, nil
 }
 
+func (s *defaultGameServer) JoinGame(ctx context.Context, req *v1.JoinGameRequest) (*v1.Game, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("error getting context information", err)
+		return nil, err
+	}
+
+	if info.Actor == nil || info.User.GetUid() == auth.SystemUserId {
+		return nil, status.Error(codes.InvalidArgument, "only actors may join a game")
+	}
+
+	game, err := s.games.GetGame(ctx, req.GetGameUid())
+	if err != nil {
+		s.log.Error("failed to get game", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	if game.GetCompleted() {
+		return nil, status.Error(codes.FailedPrecondition, "game has already ended")
+	}
+
+	roles := auth.GameRoles(info, game)
+	if slices.Contains(roles, auth.RoleParticipant) || (req.GetSpectator() && slices.Contains(roles, auth.RoleSpectator)) {
+		s.log.Debug("actor already in game", info.LoggingContext("game", game.Uid)...)
+		return game, nil
+	}
+
+	// a spectator joining as a player stops spectating
+	game.Spectators = slices.DeleteFunc(game.Spectators, func(a *v1.Actor) bool {
+		return a.GetUid() == info.Actor.GetUid()
+	})
+	if req.GetSpectator() {
+		game.Spectators = append(game.Spectators, info.Actor)
+	} else {
+		game.Participants = append(game.Participants, info.Actor)
+	}
+
+	if err = s.games.SaveGame(ctx, game); err != nil {
+		s.log.Error("failed to save game", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	s.log.Info("actor joined game", info.LoggingContext("game", game.Uid, "spectator", req.GetSpectator())...)
+	if !req.GetSpectator() {
+		// the actor has joined either way, a player left off the map can still be placed by the dungeon master
+		if err = s.arrive(ctx, game, info.Actor); err != nil {
+			s.log.Error("failed to place actor on the map", info.LoggingContext("error", err, "game", game.Uid)...)
+		}
+	}
+	return game, nil
+}
+
+// arrive places a player joining late where the game began and lets them see around them,
+// a map still being generated has nowhere to arrive at yet
+func (s *defaultGameServer) arrive(ctx context.Context, game *v1.Game, actor *v1.Actor) error {
+	gameMap, err := s.maps.GetMapForGame(ctx, game.Uid)
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	job, err := s.maps.GetMapGeneration(ctx, gameMap.Uid)
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if job.State != v1.MapGenerationStatus_COMPLETE {
+		return nil
+	}
+
+	start, err := s.maps.PlaceActor(ctx, gameMap.Uid, actor, job.Start)
+	if status.Code(err) == codes.AlreadyExists {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return storage.Discover(ctx, s.maps, gameMap.Uid, actor.GetUid(), start)
+}
+
 func (s *defaultGameServer) EndGame(ctx context.Context, req *v1.EndGameRequest) (*v1.EndGameResponse, error) {
 	info, err := common.GetContextInformation(ctx)
 	if err != nil {
@@ -253,11 +444,12 @@ func (s *defaultGameServer) EndGame(ctx context.Context, req *v1.EndGameRequest)
 		}, status.Error(codes.NotFound, "game not found")
 	}
 
-	if !slices.Contains(game.Participants, info.Actor) {
-		s.log.Error("actor is not part of the game", info.LoggingContext("game", req.GameUid)...)
+	roles := auth.GameRoles(info, game)
+	if info.User.GetUid() != auth.SystemUserId && !slices.Contains(roles, auth.RoleGameOwner) && !slices.Contains(roles, auth.RoleDungeonMaster) {
+		s.log.Error("only the owner or dungeon master may end the game", info.LoggingContext("game", req.GameUid)...)
 		return &v1.EndGameResponse{
 			GameUid: req.GameUid,
-		}, status.Error(codes.PermissionDenied, "actor is not part of the game")
+		}, status.Error(codes.PermissionDenied, "only the owner or dungeon master may end the game")
 	}
 
 	s.log.Info("ending game", info.LoggingContext("game", req.GameUid)...)
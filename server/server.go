@@ -19,6 +19,22 @@ import (
 const sqliteDBPath = "overseer.db"
 
 func NewServer() (*grpc.Server, error) {
+	// dependencies
+	db, err := storage.NewSqliteDB(sqliteDBPath, true)
+	if err != nil {
+		common.GetLogger("server").Error("failed to create db", "error", err)
+		return nil, err
+	}
+	eventStore := storage.NewSqlEventStore(db)
+	userStore := storage.NewSqlUserStore(db)
+	gameStore := storage.NewSqlGameStore(db, userStore)
+	mapStore := storage.NewSqlMapStore(db)
+	lockStore := storage.NewSqlLockStore(db)
+	apiKeyStore := storage.NewSqlApiKeyStore(db)
+	tokenSigner := overseerAuth.NewTokenSignerFromConfiguration()
+	authenticator := overseerAuth.NewAuthenticator(apiKeyStore, userStore, tokenSigner)
+	authorizer := overseerAuth.NewAuthorizer(gameStore, mapStore)
+
 	server := grpc.NewServer(
 		grpc.ChainStreamInterceptor(
 			recovery.StreamServerInterceptor(
@@ -33,7 +49,8 @@ func NewServer() (*grpc.Server, error) {
 					return status.Error(codes.Internal, "unrecoverable error")
 				}),
 			),
-			overseerAuth.StreamServerAuthFunc,
+			authenticator.StreamServerAuthFunc,
+			authorizer.StreamServerAuthorizeFunc,
 		),
 		grpc.ChainUnaryInterceptor(
 			recovery.UnaryServerInterceptor(
@@ -48,35 +65,44 @@ func NewServer() (*grpc.Server, error) {
 					return status.Error(codes.Internal, "unrecoverable error")
 				}),
 			),
-			overseerAuth.UnaryServerAuthFunc,
+			authenticator.UnaryServerAuthFunc,
+			authorizer.UnaryServerAuthorizeFunc,
 		),
 	)
 
-	// dependencies
-	db, err := storage.NewSqliteDB(sqliteDBPath, true)
+	mapGeneration, err := generative.NewMapGenerationService()
 	if err != nil {
-		common.GetLogger("server").Error("failed to create db", "error", err)
+		common.GetLogger("server").Error("failed to create map generation service", "error", err)
 		return nil, err
 	}
-	eventStore := storage.NewSqlEventStore(db)
-	userStore := storage.NewSqlUserStore(db)
-	gameStore := storage.NewSqlGameStore(db, userStore)
-	mapStore := storage.NewSqlMapStore(db)
-	lockStore := storage.NewSqlLockStore(db)
 
-	mapGeneration, err := generative.NewMapGenerationService()
+	dungeonMaster, err := generative.NewDungeonMasterService()
 	if err != nil {
-		common.GetLogger("server").Error("failed to create map generation service", "error", err)
+		common.GetLogger("server").Error("failed to create dungeon master service", "error", err)
 		return nil, err
 	}
 
-	userServer := NewUserServer(userStore)
-	gameServer := NewGameServer(userServer, lockStore, gameStore)
+	userServer := NewUserServer(userStore, apiKeyStore, tokenSigner)
+	gameServer := NewGameServer(userServer, lockStore, gameStore, mapStore)
+	receipts := engine.NewReceiptBroker()
+	mapServer := NewMapServer(mapStore, eventStore, lockStore, receipts, mapGeneration)
+	systemCtx, err := overseerAuth.SystemContext(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if err = mapServer.ResumeMapGeneration(systemCtx); err != nil {
+		common.GetLogger("server").Error("failed to resume map generation", "error", err)
+		return nil, err
+	}
 	bus := engine.NewEventBus([]engine.EventHandler{
-		handlers.NewGameHandler(gameStore, eventStore),
-	}, gameServer, userServer, eventStore)
-	eventServer := NewEventServer(bus)
-	mapServer := NewMapServer(mapStore, mapGeneration)
+		engine.Register(handlers.NewUtteranceValidator(eventStore), engine.StageValidate, 0),
+		engine.Register(handlers.NewGameHandler(mapServer, dungeonMaster, gameStore, eventStore), engine.StageMutate, 0),
+		engine.Register(handlers.NewMovementHandler(mapStore, eventStore), engine.StageMutate, 0),
+		engine.Register(handlers.NewActionHandler(dungeonMaster, gameStore, mapStore, eventStore), engine.StageMutate, 0),
+		engine.Register(handlers.NewUtteranceHandler(gameStore, eventStore), engine.StageMutate, 0),
+		engine.Register(handlers.NewDungeonMasterHandler(dungeonMaster, gameStore, mapStore, eventStore), engine.StageNarrate, 0),
+	}, gameServer, userServer, eventStore, receipts)
+	eventServer := NewEventServer(bus, eventStore, gameStore)
 
 	v1.RegisterEventsServer(server, eventServer)
 	v1.RegisterUsersServer(server, userServer)
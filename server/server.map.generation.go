@@ -0,0 +1,269 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/storage"
+	"overseer/terrain"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// generatedCoordinate is what a worker hands back to the scheduler
+type generatedCoordinate struct {
+	coordinate *v1.MapCoordinateDetail
+	err        error
+}
+
+func (s *defaultMapServer) ResumeMapGeneration(ctx context.Context) error {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return err
+	}
+
+	jobs, err := s.mapsDb.ListMapGenerations(ctx, v1.MapGenerationStatus_GENERATING)
+	if err != nil {
+		s.log.Error("failed to list unfinished map generations", info.LoggingContext("error", err)...)
+		return err
+	}
+	for _, job := range jobs {
+		s.log.Info("resuming map generation", info.LoggingContext("map", job.MapUid, "game", job.GameUid)...)
+		s.startGeneration(ctx, job)
+	}
+	return nil
+}
+
+// startGeneration runs the job in the background and records how it ended, unless the job is already running
+func (s *defaultMapServer) startGeneration(ctx context.Context, job *v1.MapGenerationJob) {
+	if _, running := s.running.LoadOrStore(job.MapUid, struct{}{}); running {
+		return
+	}
+
+	go func() {
+		defer s.running.Delete(job.MapUid)
+		info, err := common.GetContextInformation(ctx)
+		if err != nil {
+			s.log.Error("map generation has no context information", "error", err, "map", job.MapUid)
+			return
+		}
+		startTime := time.Now()
+
+		job.State = v1.MapGenerationStatus_COMPLETE
+		if err := s.generate(ctx, job); err != nil {
+			s.log.Error("map generation failed", info.LoggingContext("error", err, "map", job.MapUid, "game", job.GameUid)...)
+			message := status.Convert(err).Message()
+			job.State = v1.MapGenerationStatus_FAILED
+			job.Error = &message
+		}
+		if err := s.mapsDb.SaveMapGeneration(ctx, job); err != nil {
+			s.log.Error("failed to record the end of map generation", info.LoggingContext("error", err, "map", job.MapUid)...)
+			return
+		}
+
+		s.log.Info("map generation finished", info.LoggingContext(
+			"map", job.MapUid,
+			"game", job.GameUid,
+			"state", job.State,
+			"duration", time.Since(startTime),
+		)...)
+	}()
+}
+
+// generate fills in every coordinate of the map missing from the store, a coordinate is generated once the
+// neighbors it depends on exist and up to the configured number are generated at the same time
+// coordinates are persisted as they finish so a restarted server picks up where this one stopped
+func (s *defaultMapServer) generate(ctx context.Context, job *v1.MapGenerationJob) error {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return err
+	}
+	req := job.Request
+
+	existing, err := s.mapsDb.GetCoordinates(ctx, job.MapUid)
+	if err != nil {
+		return err
+	}
+	grid := make(map[string]*v1.MapCoordinateDetail, job.Total)
+	for _, coordinate := range existing {
+		grid[positionKey(coordinate.Position)] = coordinate
+	}
+	if len(existing) > 0 {
+		s.log.Info("map generation resumed", info.LoggingContext("map", job.MapUid, "existing", len(existing), "total", job.Total)...)
+	}
+
+	// the terrain is decided up front, the generator only writes lore and sprites for it
+	generator, err := terrain.NewTerrainGenerator(req.TerrainGenerator, job.Seed)
+	if err != nil {
+		return err
+	}
+	land, err := generator.Generate(req.MaxX, req.MaxY)
+	if err != nil {
+		return err
+	}
+
+	// waiting counts the unfinished dependencies of every position, dependents are released as a position finishes
+	waiting := make(map[string]int)
+	dependents := make(map[string][]*v1.MapPosition)
+	ready := make([]*v1.MapPosition, 0)
+	for y := -req.MaxY; y <= req.MaxY; y++ {
+		for x := -req.MaxX; x <= req.MaxX; x++ {
+			position := &v1.MapPosition{X: x, Y: y}
+			key := positionKey(position)
+			if grid[key] != nil {
+				continue
+			}
+			for _, dependency := range generationDependencies(position, req.MaxX, req.MaxY) {
+				if grid[positionKey(dependency)] == nil {
+					waiting[key]++
+					dependents[positionKey(dependency)] = append(dependents[positionKey(dependency)], position)
+				}
+			}
+			if waiting[key] == 0 {
+				ready = append(ready, position)
+			}
+		}
+	}
+
+	completed := int64(len(grid))
+	common.ReportProgress(ctx, completed, job.Total)
+
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan generatedCoordinate)
+	concurrency := max(common.GetConfiguration().MapGeneration.Concurrency, 1)
+	inFlight := 0
+	var failure error
+	for {
+		for failure == nil && inFlight < concurrency && len(ready) > 0 {
+			position := ready[0]
+			ready = ready[1:]
+			neighbors := make([]*v1.MapCoordinateDetail, 0)
+			for _, dependency := range generationDependencies(position, req.MaxX, req.MaxY) {
+				neighbors = append(neighbors, grid[positionKey(dependency)])
+			}
+			inFlight++
+			go func() {
+				coordinate, err := s.generateCoordinate(workCtx, job, land.At(position), position, neighbors)
+				results <- generatedCoordinate{coordinate: coordinate, err: err}
+			}()
+		}
+		if inFlight == 0 {
+			break
+		}
+
+		result := <-results
+		inFlight--
+		if result.err == nil {
+			// coordinates are only written from here so the store never sees concurrent writes from one map
+			result.err = s.mapsDb.CreateCoordinate(ctx, result.coordinate)
+		}
+		if result.err != nil {
+			if failure == nil {
+				failure = result.err
+				cancel()
+			}
+			continue
+		}
+
+		key := positionKey(result.coordinate.Position)
+		grid[key] = result.coordinate
+		completed++
+		common.ReportProgress(ctx, completed, job.Total)
+		for _, dependent := range dependents[key] {
+			waiting[positionKey(dependent)]--
+			if waiting[positionKey(dependent)] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if failure != nil {
+		return failure
+	}
+	if completed != job.Total {
+		s.log.Error("map generation stalled", info.LoggingContext("map", job.MapUid, "completed", completed, "total", job.Total)...)
+		return status.Error(codes.Internal, "failed to create map -- generation stalled")
+	}
+
+	// the players arrive seeing what is around them, the map is complete even when that can't be recorded
+	for _, actor := range req.Actors {
+		if err := storage.Discover(ctx, s.mapsDb, job.MapUid, actor.GetUid(), grid[positionKey(job.Start)]); err != nil {
+			s.log.Error("failed to discover starting surroundings", info.LoggingContext("error", err, "map", job.MapUid, "actor", actor.GetUid())...)
+		}
+	}
+	return nil
+}
+
+func (s *defaultMapServer) generateCoordinate(ctx context.Context, job *v1.MapGenerationJob, coordinateType v1.MapCoordinateDetail_CoordinateType, position *v1.MapPosition, neighbors []*v1.MapCoordinateDetail) (*v1.MapCoordinateDetail, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req := job.Request
+
+	coord := &v1.MapCoordinateDetail{
+		Uid:      common.GenerateUniqueId(),
+		GameUid:  job.GameUid,
+		MapUid:   job.MapUid,
+		Position: position,
+		Type:     coordinateType,
+		Actors:   make([]*v1.Actor, 0),
+		Sprites:  make([]*v1.Sprite, 0),
+	}
+	if position.X == job.Start.X && position.Y == job.Start.Y {
+		for _, actor := range req.Actors {
+			coord.Actors = append(coord.Actors, actor)
+			coord.Sprites = append(coord.Sprites, &v1.Sprite{Actor: actor})
+		}
+	}
+
+	// each coordinate draws from its own generator so the order the workers finish in doesn't change the map
+	r := common.SeededRandom(job.Seed, position.X, position.Y)
+	coord, err = s.mapGenerator.GenerateCoordinate(common.WithRandom(ctx, r), req.Theme, req.SpriteDensity, coord, neighbors)
+	if err != nil {
+		s.log.Error("failed to generate coordinate", info.LoggingContext("error", err, "x", position.X, "y", position.Y)...)
+		return nil, err
+	}
+
+	// generate a random value between zero and one
+	// hack: this should be more dynamic and algoritmic to determine the difficulty of the terrain based on a coefficient
+	// bug: values larger than 1 will always be true right now ALL THE TIME
+	coord.DifficultTerrain = r.Float32() < req.DifficultTerrainChance
+
+	s.log.Debug("map coordinate generated", info.LoggingContext(
+		"x", coord.Position.X,
+		"y", coord.Position.Y,
+		"actors", len(coord.Actors),
+		"sprites", len(coord.Sprites),
+		"game", job.GameUid,
+		"type", coord.Type.String(),
+		"difficult_terrain", coord.DifficultTerrain,
+	)...)
+	return coord, nil
+}
+
+// generationDependencies are the neighbors generated before a position, those in the row below and the one to its west,
+// their lore is written into the lore of the position so they have to exist first while everything else can be generated in parallel
+func generationDependencies(position *v1.MapPosition, maxX int64, maxY int64) []*v1.MapPosition {
+	candidates := []*v1.MapPosition{
+		{X: position.X - 1, Y: position.Y},
+		{X: position.X - 1, Y: position.Y - 1},
+		{X: position.X, Y: position.Y - 1},
+		{X: position.X + 1, Y: position.Y - 1},
+	}
+	dependencies := make([]*v1.MapPosition, 0, len(candidates))
+	for _, candidate := range candidates {
+		if common.IsWithinBounds(candidate, maxX, maxY) {
+			dependencies = append(dependencies, candidate)
+		}
+	}
+	return dependencies
+}
+
+func positionKey(position *v1.MapPosition) string {
+	return fmt.Sprintf("%d:%d", position.X, position.Y)
+}
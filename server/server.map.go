@@ -4,36 +4,48 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"overseer/auth"
 	v1 "overseer/build/go"
 	"overseer/common"
+	"overseer/engine"
 	"overseer/generative"
 	"overseer/storage"
+	"sync"
 	"time"
 
 	charm "github.com/charmbracelet/log"
-	"golang.org/x/exp/maps"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
+// MapServer serves the maps rpcs and owns the background jobs generating their coordinates
+type MapServer interface {
+	v1.MapsServer
+	// ResumeMapGeneration restarts every map generation a previous server left unfinished
+	ResumeMapGeneration(ctx context.Context) error
+}
+
 type defaultMapServer struct {
 	mapGenerator generative.MapGenerationService
 	mapsDb       storage.MapStore
-	log          *charm.Logger
+	eventsDb     storage.EventStore
+	// locks is shared with the game server so movements made here wait their turn behind the event bus
+	locks storage.LockStore
+	// receipts is shared with the event bus so movements made here reach whoever is watching the game
+	receipts *engine.ReceiptBroker
+	// running holds the uid of every map this server is generating so a job is never run twice
+	running sync.Map
+	log     *charm.Logger
 	v1.UnimplementedMapsServer
 }
 
-type cardinalDirection string
-
-const (
-	north cardinalDirection = "north"
-	south cardinalDirection = "south"
-	east  cardinalDirection = "east"
-)
-
-func NewMapServer(mapsDb storage.MapStore, mapGenerator generative.MapGenerationService) v1.MapsServer {
+func NewMapServer(mapsDb storage.MapStore, eventsDb storage.EventStore, locks storage.LockStore, receipts *engine.ReceiptBroker, mapGenerator generative.MapGenerationService) MapServer {
 	return &defaultMapServer{
 		mapsDb:       mapsDb,
+		eventsDb:     eventsDb,
+		locks:        locks,
+		receipts:     receipts,
 		mapGenerator: mapGenerator,
 		log:          common.GetLogger("server.map"),
 	}
@@ -51,238 +63,79 @@ func (s *defaultMapServer) CreateMap(ctx context.Context, req *v1.CreateMapReque
 		return nil, err
 	}
 
-	generationStartTime := time.Now()
+	// every random choice made for the map is drawn from its seed so the same seed makes the same world
+	if req.Seed == nil {
+		seed := common.NewSeed()
+		req.Seed = &seed
+	}
+
 	newMap, err := s.mapsDb.CreateMap(ctx, req)
 	if err != nil {
 		s.log.Error("failed to persist map", info.LoggingContext("error", err)...)
 		return nil, err
 	}
-	s.log.Info("creating new map", info.LoggingContext(
-		"game", req.GameUid,
-		"map", newMap.Uid,
-	)...)
-
-	max_positive_y := req.MaxY
-	max_negative_y := req.MaxY * -1
-	max_positive_x := req.MaxX
-	max_negative_x := req.MaxX * -1
-	// total coordinates are the positive and negative values of the max x and y multiplied by 2 (for negative and positive) PLUS the zero axis on the x an Y demension plus 1 (for map center)
-	total_coordinates := ((req.MaxX*2)*(req.MaxY*2) + (req.MaxX * 2) + (req.MaxY * 2)) + 1
-	s.log.Debug("map geometry determined", info.LoggingContext(
-		"max_y_positive", max_positive_y,
-		"max_y_negative", max_negative_y,
-		"max_x_positive", max_positive_x,
-		"max_x_negative", max_negative_x,
-		"total_coordinates", total_coordinates,
-		"game", req.GameUid)...,
-	)
-
-	// map[X:Y] = &v1.MapCoordinateDetail{X: X, Y: Y}
-	grid := make(map[string]*v1.MapCoordinateDetail)
-	for x := max_negative_x; x <= max_positive_x; x++ {
-		for y := max_negative_y; y <= max_positive_y; y++ {
-			grid[fmt.Sprintf("%d:%d", x, y)] = nil
-		}
-	}
-
-	if len(grid) != int(total_coordinates) {
-		s.log.Error("failed to create map", info.LoggingContext(
-			"game", req.GameUid,
-			"total_coordinates", total_coordinates,
-			"max_positive_y", max_positive_y,
-			"max_negative_y", max_negative_y,
-			"max_positive_x", max_positive_x,
-			"max_negative_x", max_negative_x,
-			"grid", len(grid),
-			"keys", maps.Keys(grid),
-		)...)
-		return nil, status.Error(codes.Internal, "failed to create map")
-	}
 
 	// randomly determine where the actors will start on the map
-	startX, startY := s.randomCoordinate(max_positive_x, max_positive_y)
-	s.log.Debug("starting position", info.LoggingContext("x", startX, "y", startY)...)
-	if _, ok := grid[fmt.Sprintf("%d:%d", startX, startY)]; !ok {
-		s.log.Error("failed to find starting position", info.LoggingContext("x", startX, "y", startY)...)
-		return nil, status.Error(codes.Internal, "failed to create map starting position")
-	}
-	startingCoordinate := fmt.Sprintf("%d:%d", startX, startY)
-	startingCoordinateDetail := &v1.MapCoordinateDetail{
-		Uid:     common.GenerateUniqueId(),
-		GameUid: req.GameUid,
+	startX, startY := s.randomCoordinate(common.SeededRandom(req.GetSeed()), req.MaxX, req.MaxY)
+	job := &v1.MapGenerationJob{
 		MapUid:  newMap.Uid,
-		Position: &v1.MapPosition{
-			X: startX,
-			Y: startY,
-		},
-		Actors:  make([]*v1.Actor, 0),
-		Sprites: make([]*v1.Sprite, 0),
-	}
-	grid[startingCoordinate] = startingCoordinateDetail
-	// generate their sprites
-	for _, actor := range req.Actors {
-		sprite := &v1.Sprite{
-			Actor: actor,
-		}
-		startingCoordinateDetail.Actors = append(grid[fmt.Sprintf("%d:%d", startX, startY)].Actors, actor)
-		startingCoordinateDetail.Sprites = append(grid[fmt.Sprintf("%d:%d", startX, startY)].Sprites, sprite)
-	}
-	grid[startingCoordinate] = startingCoordinateDetail
-	// move iteratively through the map in a sweep pattern generating sprites for each coordinate determined by the previous coordiate
-	stillWalking := true
-	currentX, currentY := max_negative_x, max_negative_y
-	directionOfMovement := north
-	for stillWalking {
-		potentialPos := fmt.Sprintf("%d:%d", currentX, currentY)
-		s.log.Debug("walking", info.LoggingContext("x", currentX, "y", currentY, "coordinate", potentialPos, "game", req.GameUid)...)
-
-		// check if we have visited this coordinate
-		if _, ok := grid[potentialPos]; !ok {
-			s.log.Error("coordinate out of bounds", info.LoggingContext("position", potentialPos, "game", req.GameUid)...)
-			return nil, status.Error(codes.Internal, "failed to create map -- generation ran out of bounds")
-		}
-
-		coord, err := s.generateCoordinate(ctx, req.GameUid, newMap.Uid, req.Theme, req.SpriteDensity, req.DifficultTerrainChance, currentX, currentY, grid)
-		if err != nil {
-			s.log.Error("failed to generate coordinate", info.LoggingContext("error", err)...)
-			return nil, err
-		}
-
-		s.log.Debug("map coordinate generated", info.LoggingContext(
-			"x", coord.Position.X,
-			"y", coord.Position.Y,
-			"actors", len(coord.Actors),
-			"sprites", len(coord.Sprites),
-			"game", req.GameUid,
-			"type", v1.MapCoordinateDetail_CoordinateType_name[int32(coord.Type)],
-		)...)
-
-		grid[potentialPos] = coord
-
-		// check if we have visited all coordinates
-		remaining := common.Reduce(maps.Keys(grid), func(k string) bool {
-			return grid[k] == nil
-		})
-		if len(remaining) == 0 {
-			stillWalking = false
-		} else {
-			var action string
-			// determine the next coordinate
-			switch directionOfMovement {
-			case north:
-				if currentY == max_positive_y {
-					directionOfMovement = east
-					currentX++
-					action = "turning east from top of map"
-				} else {
-					currentY++
-					action = "moving north"
-				}
-			case south:
-				if currentY == max_negative_y {
-					directionOfMovement = east
-					currentX++
-					action = "turning east from bottom of map"
-				} else {
-					currentY--
-					action = "moving south"
-				}
-			case east:
-				if currentY == max_positive_y {
-					directionOfMovement = south
-					currentY--
-					action = "turning south from top map"
-				} else if currentY == max_negative_y {
-					directionOfMovement = north
-					currentY++
-					action = "turning north from bottom map"
-				} else {
-					s.log.Error("invalid direction of movement -- trying to turn outside of maximums", info.LoggingContext("direction", directionOfMovement)...)
-					return nil, status.Error(codes.Internal, "failed to create map -- invalid direction of movement during map generation")
-				}
-			default:
-				s.log.Error("invalid direction of movement", info.LoggingContext("direction", directionOfMovement)...)
-				return nil, status.Error(codes.Internal, "failed to create map -- invalid direction of movement during map generation")
-			}
-			s.log.Debug("still walking", info.LoggingContext("remaining", len(remaining), "action", action)...)
-		}
+		GameUid: req.GameUid,
+		Request: req,
+		Start:   &v1.MapPosition{X: startX, Y: startY},
+		// the positive and negative values of each axis plus the zero axis
+		Total: (req.MaxX*2 + 1) * (req.MaxY*2 + 1),
+		State: v1.MapGenerationStatus_GENERATING,
+		Seed:  req.GetSeed(),
 	}
-
-	s.log.Info("map generation complete", info.LoggingContext("game", req.GameUid, "duration", time.Since(generationStartTime), "size", len(grid))...)
-	// persist the map to the database
-	startPersistence := time.Now()
-
-	for _, coord := range grid {
-		err = s.mapsDb.CreateCoordinate(ctx, coord)
-		if err != nil {
-			s.log.Error("failed to persist map coordinate", info.LoggingContext("error", err)...)
-			return nil, err
-		}
+	if err = s.mapsDb.SaveMapGeneration(ctx, job); err != nil {
+		s.log.Error("failed to persist map generation", info.LoggingContext("error", err, "map", newMap.Uid)...)
+		return nil, err
 	}
-
-	s.log.Info("map created", info.LoggingContext(
+	s.log.Info("creating new map", info.LoggingContext(
 		"game", req.GameUid,
 		"map", newMap.Uid,
-		"persistence_duration", time.Since(startPersistence),
-		"total_duration", time.Since(generationStartTime),
+		"total_coordinates", job.Total,
+		"terrain_generator", req.TerrainGenerator.String(),
+		"seed", req.GetSeed(),
+		"start_x", startX,
+		"start_y", startY,
 	)...)
 
+	// the job keeps the caller's identity but outlives the request, so it must not report progress to a caller that may be gone
+	s.startGeneration(common.WithProgressReporter(context.WithoutCancel(ctx), nil), job)
 	return newMap, nil
 }
 
-func (s *defaultMapServer) randomCoordinate(x int64, y int64) (int64, int64) {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	randX := r.Int63n(2*x+1) - x
-	randY := r.Int63n(2*y+1) - y
-	return randX, randY
-}
-
-func (s *defaultMapServer) generateCoordinate(ctx context.Context, gameUid string, mapUid string, gameTheme v1.GameTheme, terrainDifficultyChance float32, spriteDensity float32, x int64, y int64, grid map[string]*v1.MapCoordinateDetail) (*v1.MapCoordinateDetail, error) {
+func (s *defaultMapServer) GetMapGenerationStatus(ctx context.Context, req *v1.GetMapRequest) (*v1.MapGenerationStatus, error) {
 	info, err := common.GetContextInformation(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	coord, ok := grid[fmt.Sprintf("%d:%d", x, y)]
-	if !ok || coord == nil {
-		coord = &v1.MapCoordinateDetail{
-			Uid:     common.GenerateUniqueId(),
-			GameUid: gameUid,
-			MapUid:  mapUid,
-			Position: &v1.MapPosition{
-				X: x,
-				Y: y,
-			},
-			Actors:  make([]*v1.Actor, 0),
-			Sprites: make([]*v1.Sprite, 0),
-		}
-	} else {
-		s.log.Info("pre-existing coordinate available on the grid", info.LoggingContext("x", coord.Position.X, "y", coord.Position.Y)...)
+	job, err := s.mapsDb.GetMapGeneration(ctx, req.Uid)
+	if err != nil {
+		s.log.Warn("failed to get map generation", info.LoggingContext("error", err, "map", req.Uid)...)
+		return nil, err
 	}
-
-	neighbors := common.FindNeighbors(coord.Position, grid)
-	s.log.Debug("neighbors found", info.LoggingContext("neighbors", len(neighbors))...)
-	coord, err = s.mapGenerator.GenerateCoordinate(ctx, gameTheme, spriteDensity, coord, neighbors)
+	completed, err := s.mapsDb.CountCoordinates(ctx, req.Uid)
 	if err != nil {
-		s.log.Error("failed to generate coordinate", info.LoggingContext("error", err)...)
 		return nil, err
 	}
 
-	// generate a random value between zero and one
-	// hack: this should be more dynamic and algoritmic to determine the difficulty of the terrain based on a coefficient
-	// bug: values larger than 1 will always be true right now ALL THE TIME
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	if r.Float32() < terrainDifficultyChance {
-		s.log.Debug("difficult terrain selected", info.LoggingContext(
-			"x", x,
-			"y", y,
-		)...)
-		coord.DifficultTerrain = true
-	} else {
-		coord.DifficultTerrain = false
-	}
+	return &v1.MapGenerationStatus{
+		MapUid:    job.MapUid,
+		GameUid:   job.GameUid,
+		State:     job.State,
+		Total:     job.Total,
+		Completed: min(completed, job.Total),
+		Error:     job.Error,
+	}, nil
+}
 
-	return coord, nil
+func (s *defaultMapServer) randomCoordinate(r *rand.Rand, x int64, y int64) (int64, int64) {
+	randX := r.Int63n(2*x+1) - x
+	randY := r.Int63n(2*y+1) - y
+	return randX, randY
 }
 
 func (s *defaultMapServer) validateCreateMapRequest(req *v1.CreateMapRequest) error {
@@ -308,6 +161,10 @@ func (s *defaultMapServer) validateCreateMapRequest(req *v1.CreateMapRequest) er
 		return status.Error(codes.InvalidArgument, "There are no players in the game")
 	}
 
+	if _, ok := v1.TerrainGenerator_name[int32(req.TerrainGenerator)]; !ok {
+		return status.Error(codes.InvalidArgument, "unknown terrain generator")
+	}
+
 	return nil
 }
 
@@ -359,7 +216,21 @@ func (s *defaultMapServer) GetPosition(ctx context.Context, req *v1.Actor) (*v1.
 	}
 
 	s.log.Info("getting position", info.LoggingContext("actor", req.Uid)...)
-	return nil, status.Error(codes.Unimplemented, "method GetPosition not implemented")
+	if req.GetUid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "actor uid is required")
+	}
+	if req.GetUid() != info.Actor.GetUid() && info.User.GetUid() != auth.SystemUserId {
+		s.log.Warn("actor asked for another actor's position", info.LoggingContext("actor", req.Uid)...)
+		return nil, status.Error(codes.PermissionDenied, "you may only ask for your own position")
+	}
+
+	position, err := s.mapsDb.GetActorPosition(ctx, req.Uid)
+	if err != nil {
+		s.log.Error("failed to get position", info.LoggingContext("error", err, "actor", req.Uid)...)
+		return nil, err
+	}
+
+	return position, nil
 }
 
 func (s *defaultMapServer) PeekCoordinate(ctx context.Context, req *v1.PeekCoordinateRequest) (*v1.MapCoordinateDetail, error) {
@@ -368,8 +239,46 @@ func (s *defaultMapServer) PeekCoordinate(ctx context.Context, req *v1.PeekCoord
 		return nil, err
 	}
 
-	s.log.Info("peeking coordinate", info.LoggingContext("x", req.Coordinate.X, "y", req.Coordinate.Y, "game", req.GameUid, "map", req.GameUid)...)
-	return nil, status.Error(codes.Unimplemented, "method PeekCoordinate not implemented")
+	s.log.Info("peeking coordinate", info.LoggingContext("x", req.GetCoordinate().GetX(), "y", req.GetCoordinate().GetY(), "game", req.GameUid, "map", req.MapUid)...)
+	if req.Coordinate == nil {
+		return nil, status.Error(codes.InvalidArgument, "coordinate is required")
+	}
+
+	gameMap, err := s.gameMap(ctx, req.GameUid, req.MapUid)
+	if err != nil {
+		s.log.Error("failed to get map to peek at", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	if !common.IsWithinBounds(req.Coordinate, gameMap.MaxX, gameMap.MaxY) {
+		return nil, status.Error(codes.InvalidArgument, "coordinate is beyond the edge of the map")
+	}
+
+	coordinate, err := s.mapsDb.GetCoordinate(ctx, gameMap.GameUid, gameMap.Uid, req.Coordinate.X, req.Coordinate.Y)
+	if err != nil {
+		s.log.Error("failed to get coordinate", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	peeked := proto.Clone(coordinate).(*v1.MapCoordinateDetail)
+	if info.User.GetUid() == auth.SystemUserId || auth.HasRole(ctx, auth.RoleDungeonMaster, auth.RoleGameOwner) {
+		return peeked, nil
+	}
+
+	// players only peek at what they can see or remember, the same as their known map
+	sight, err := s.sightOf(ctx, gameMap.Uid, info.Actor.GetUid(), coordinate)
+	if err != nil {
+		s.log.Error("failed to work out what the actor knows", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	switch sight {
+	case v1.KnownCoordinate_VISIBLE:
+	case v1.KnownCoordinate_REMEMBERED:
+		peeked.Actors = nil
+		peeked.Sprites = nil
+	default:
+		return nil, status.Error(codes.PermissionDenied, "you have not discovered that place")
+	}
+	return peeked, nil
 }
 
 func (s *defaultMapServer) PlayerMovement(ctx context.Context, req *v1.PlayerMovementRequest) (*v1.MovementResult, error) {
@@ -379,5 +288,173 @@ func (s *defaultMapServer) PlayerMovement(ctx context.Context, req *v1.PlayerMov
 	}
 
 	s.log.Info("player movement", info.LoggingContext("actor", req.ActorUid, "x", req.X, "y", req.Y, "game", req.GameUid)...)
-	return nil, status.Error(codes.Unimplemented, "method PlayerMovement not implemented")
+	if req.ActorUid == "" {
+		return nil, status.Error(codes.InvalidArgument, "actor uid is required")
+	}
+	if req.ActorUid != info.Actor.GetUid() && info.User.GetUid() != auth.SystemUserId && !auth.HasRole(ctx, auth.RoleDungeonMaster) {
+		s.log.Warn("actor tried to move another actor", info.LoggingContext("actor", req.ActorUid)...)
+		return nil, status.Error(codes.PermissionDenied, "only the dungeon master may move another player")
+	}
+
+	gameMap, err := s.mapsDb.GetMapForGame(ctx, req.GameUid)
+	if err != nil {
+		s.log.Error("failed to get map for game", info.LoggingContext("error", err, "game", req.GameUid)...)
+		return nil, err
+	}
+
+	target := &v1.MapPosition{X: req.X, Y: req.Y}
+	if !common.IsWithinBounds(target, gameMap.MaxX, gameMap.MaxY) {
+		return refuseMovement("you cannot travel beyond the edge of the world"), nil
+	}
+
+	// the move is made under the game lease the same as a movement submitted as an event,
+	// so it cannot interleave with a handler and storage refuses it once the game has moved on
+	ctx, unlock, err := s.lockGame(ctx, req.GameUid, req.ActorUid)
+	if err != nil {
+		s.log.Error("failed to lock game", info.LoggingContext("error", err, "game", req.GameUid)...)
+		return nil, err
+	}
+	defer unlock()
+
+	origin, destination, err := s.mapsDb.MoveActor(ctx, gameMap.Uid, req.ActorUid, target, func(origin *v1.MapCoordinateDetail, destination *v1.MapCoordinateDetail) error {
+		if req.SpriteUid != "" && common.ActorSprite(origin, req.ActorUid).GetUid() != req.SpriteUid {
+			return status.Error(codes.InvalidArgument, "the sprite is not the actor's")
+		}
+		if err := common.CheckMovement(origin, destination, req.ActorUid); err != nil {
+			return err
+		}
+		common.TransferActor(origin, destination, req.ActorUid)
+		return nil
+	})
+	if status.Code(err) == codes.FailedPrecondition {
+		s.log.Debug("movement not allowed", info.LoggingContext("error", err, "x", req.X, "y", req.Y)...)
+		return refuseMovement(status.Convert(err).Message()), nil
+	}
+	if err != nil {
+		s.log.Error("failed to move actor", info.LoggingContext("error", err, "x", req.X, "y", req.Y)...)
+		return nil, err
+	}
+
+	if err = storage.Discover(ctx, s.mapsDb, gameMap.Uid, req.ActorUid, destination); err != nil {
+		s.log.Error("failed to discover the surroundings", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	cost := common.MovementCost(destination)
+	if err = s.recordMovement(ctx, gameMap, req.ActorUid, origin, destination, cost); err != nil {
+		s.log.Error("failed to record movement", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	s.log.Info("actor moved", info.LoggingContext(
+		"actor", req.ActorUid,
+		"from_x", origin.Position.X,
+		"from_y", origin.Position.Y,
+		"to_x", destination.Position.X,
+		"to_y", destination.Position.Y,
+	)...)
+	return &v1.MovementResult{
+		Success:     true,
+		Cost:        cost,
+		Destination: destination.Position,
+	}, nil
+}
+
+// lockGame waits for the game lease and adds it to the context, the returned function releases it
+func (s *defaultMapServer) lockGame(ctx context.Context, gameId string, actorId string) (context.Context, func(), error) {
+	claimId := common.GenerateRandomStringFromSeed(
+		"server.map",
+		gameId,
+		actorId,
+		fmt.Sprintf("%d", time.Now().UTC().UnixNano()),
+	)
+	lock, err := s.locks.LockGame(ctx, &v1.LockGameRequest{
+		GameUid:  gameId,
+		ClaimUid: claimId,
+		Wait:     true,
+	})
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	unlock := func() {
+		if _, err := s.locks.UnlockGame(ctx, &v1.UnlockGameRequest{
+			GameUid:      gameId,
+			ClaimUid:     claimId,
+			FencingToken: lock.GetFencingToken(),
+		}); err != nil {
+			s.log.Error("failed to unlock game", "error", err, "game", gameId, "claim", claimId)
+		}
+	}
+	return common.WithGameLease(ctx, gameId, lock.GetFencingToken()), unlock, nil
+}
+
+// gameMap is the map the request names, or the map of the game when it names none
+func (s *defaultMapServer) gameMap(ctx context.Context, gameId string, mapId string) (*v1.Map, error) {
+	if mapId == "" {
+		return s.mapsDb.GetMapForGame(ctx, gameId)
+	}
+
+	gameMap, err := s.mapsDb.GetMap(ctx, mapId)
+	if err != nil {
+		return nil, err
+	}
+	if gameMap.GameUid != gameId {
+		return nil, status.Error(codes.NotFound, "map not found for game")
+	}
+	return gameMap, nil
+}
+
+// recordMovement adds the step to the history of the game the same as a movement submitted as an event
+func (s *defaultMapServer) recordMovement(ctx context.Context, gameMap *v1.Map, actorId string, origin *v1.MapCoordinateDetail, destination *v1.MapCoordinateDetail, cost int64) error {
+	actor := &v1.Actor{Uid: actorId}
+	for _, a := range destination.Actors {
+		if a.GetUid() == actorId {
+			actor = a
+		}
+	}
+	direction := common.GetDirection(origin.Position, destination.Position)
+
+	record, err := s.eventsDb.RecordEvent(ctx, &v1.Event{
+		GameUid: gameMap.GameUid,
+		Actor:   actor,
+		Origin:  &v1.Event_System{System: &v1.EventOriginSystem{}},
+		Payload: &v1.Event_Interaction{Interaction: &v1.InteractionEvent{
+			Interaction: &v1.InteractionEvent_Movement{Movement: &v1.MovementInteraction{Direction: direction}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	receipt := &v1.EventReceipt{
+		Uid:      common.GenerateUniqueId(),
+		GameUid:  gameMap.GameUid,
+		EventUid: record.Uid,
+		Effect: &v1.EventReceipt_GameState{GameState: &v1.GameStateEffect{
+			Change: &v1.GameStateEffect_Movement{Movement: &v1.MovementEffect{
+				Actor:            actor,
+				MapUid:           gameMap.Uid,
+				Origin:           origin.Position,
+				Destination:      destination.Position,
+				Direction:        direction,
+				DifficultTerrain: destination.DifficultTerrain,
+				Cost:             cost,
+			}},
+		}},
+	}
+	err = s.eventsDb.RecordReceipt(ctx, receipt)
+	if err != nil {
+		return err
+	}
+	s.receipts.Publish(receipt)
+	return nil
+}
+
+// refuseMovement is the answer to a step the rules don't allow, the message is meant for the player
+func refuseMovement(message string) *v1.MovementResult {
+	return &v1.MovementResult{
+		Success: false,
+		Message: &message,
+	}
 }
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/storage"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *defaultMapServer) GetKnownMap(ctx context.Context, req *v1.GetKnownMapRequest) (*v1.KnownMap, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	actorId := req.ActorUid
+	if actorId == "" {
+		actorId = info.Actor.GetUid()
+	}
+	s.log.Info("getting known map", info.LoggingContext("game", req.GameUid, "map", req.MapUid, "actor", actorId)...)
+	if actorId != info.Actor.GetUid() && info.User.GetUid() != auth.SystemUserId && !auth.HasRole(ctx, auth.RoleDungeonMaster) {
+		s.log.Warn("actor asked for another actor's known map", info.LoggingContext("actor", actorId)...)
+		return nil, status.Error(codes.PermissionDenied, "only the dungeon master may see what another player knows")
+	}
+
+	gameMap, err := s.gameMap(ctx, req.GameUid, req.MapUid)
+	if err != nil {
+		s.log.Error("failed to get map", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	known, err := s.mapsDb.GetDiscoveredCoordinates(ctx, gameMap.Uid, actorId)
+	if err != nil {
+		s.log.Error("failed to get discovered coordinates", info.LoggingContext("error", err, "actor", actorId)...)
+		return nil, err
+	}
+
+	result := &v1.KnownMap{Map: gameMap, ActorUid: actorId}
+	visible := make(map[string]*v1.MapCoordinateDetail)
+	standing, err := s.mapsDb.GetActorCoordinate(ctx, gameMap.Uid, actorId)
+	switch {
+	case status.Code(err) == codes.NotFound:
+		// spectators and the fallen see nothing, they only remember
+	case err != nil:
+		s.log.Error("failed to locate actor", info.LoggingContext("error", err, "actor", actorId)...)
+		return nil, err
+	default:
+		result.Position = standing.Position
+		seen, err := storage.VisibleFrom(ctx, s.mapsDb, gameMap.Uid, standing)
+		if err != nil {
+			s.log.Error("failed to work out what the actor sees", info.LoggingContext("error", err, "actor", actorId)...)
+			return nil, err
+		}
+		for _, coordinate := range seen {
+			visible[coordinate.Uid] = coordinate
+		}
+	}
+
+	for _, coordinate := range known {
+		if _, ok := visible[coordinate.Coordinate.Uid]; ok {
+			delete(visible, coordinate.Coordinate.Uid)
+			coordinate.Visibility = v1.KnownCoordinate_VISIBLE
+			continue
+		}
+		// the land is remembered but whoever was there may have moved on
+		coordinate.Coordinate.Actors = nil
+		coordinate.Coordinate.Sprites = nil
+	}
+	// what is in sight is known even when it was never recorded as discovered
+	for _, coordinate := range visible {
+		known = append(known, &v1.KnownCoordinate{
+			Coordinate: coordinate,
+			Visibility: v1.KnownCoordinate_VISIBLE,
+			Visited:    coordinate.Uid == standing.GetUid(),
+		})
+	}
+	result.Coordinates = known
+
+	return result, nil
+}
+
+// sightOf is how the actor knows the coordinate, what they never discovered and cannot see is unspecified
+func (s *defaultMapServer) sightOf(ctx context.Context, mapId string, actorId string, coordinate *v1.MapCoordinateDetail) (v1.KnownCoordinate_Visibility, error) {
+	standing, err := s.mapsDb.GetActorCoordinate(ctx, mapId, actorId)
+	switch {
+	case status.Code(err) == codes.NotFound:
+	case err != nil:
+		return v1.KnownCoordinate_VISIBILITY_UNSPECIFIED, err
+	default:
+		seen, err := storage.VisibleFrom(ctx, s.mapsDb, mapId, standing)
+		if err != nil {
+			return v1.KnownCoordinate_VISIBILITY_UNSPECIFIED, err
+		}
+		for _, visible := range seen {
+			if visible.Uid == coordinate.Uid {
+				return v1.KnownCoordinate_VISIBLE, nil
+			}
+		}
+	}
+
+	discovered, err := s.mapsDb.HasDiscovered(ctx, mapId, actorId, coordinate.Uid)
+	if err != nil {
+		return v1.KnownCoordinate_VISIBILITY_UNSPECIFIED, err
+	}
+	if discovered {
+		return v1.KnownCoordinate_REMEMBERED, nil
+	}
+	return v1.KnownCoordinate_VISIBILITY_UNSPECIFIED, nil
+}
@@ -6,6 +6,8 @@ import (
 	v1 "overseer/build/go"
 	"overseer/common"
 	"overseer/storage"
+	"strings"
+	"time"
 
 	charm "github.com/charmbracelet/log"
 	"google.golang.org/grpc/codes"
@@ -13,15 +15,19 @@ import (
 )
 
 type defaultUserServer struct {
-	users storage.UserStore
-	log   *charm.Logger
+	users  storage.UserStore
+	keys   storage.ApiKeyStore
+	tokens *auth.TokenSigner
+	log    *charm.Logger
 	v1.UnimplementedUsersServer
 }
 
-func NewUserServer(users storage.UserStore) v1.UsersServer {
+func NewUserServer(users storage.UserStore, keys storage.ApiKeyStore, tokens *auth.TokenSigner) v1.UsersServer {
 	return &defaultUserServer{
-		users: users,
-		log:   common.GetLogger("server.user"),
+		users:  users,
+		keys:   keys,
+		tokens: tokens,
+		log:    common.GetLogger("server.user"),
 	}
 }
 
@@ -57,6 +63,11 @@ func (s *defaultUserServer) RegisterActor(ctx context.Context, req *v1.RegisterA
 		s.log.Error("user id is reserved", info.LoggingContext("uid", req.GetUserId())...)
 		return nil, status.Error(codes.InvalidArgument, "user id is reserved and cannot register new actors")
 	}
+	// front ends find their players by source identity, only they can vouch for who owns one
+	if req.GetSource() != v1.Actor_SYSTEM && info.User.GetUid() != auth.SystemUserId {
+		s.log.Warn("front end actor registered by a player", info.LoggingContext("source", req.GetSource().String())...)
+		return nil, status.Error(codes.PermissionDenied, "actors of a front end can only be registered by the front end")
+	}
 
 	actor := &v1.Actor{
 		Uid: common.GenerateRandomStringFromSeed(
@@ -67,6 +78,7 @@ func (s *defaultUserServer) RegisterActor(ctx context.Context, req *v1.RegisterA
 			common.GenerateUniqueId(),
 		),
 		SourceIdentity: req.GetSourceIdentity(),
+		Source:         req.GetSource(),
 		Metadata:       req.GetMetadata(),
 	}
 
@@ -134,3 +146,282 @@ func (s *defaultUserServer) GetActors(ctx context.Context, user *v1.User) (*v1.A
 
 	return &v1.Actors{Actors: actors}, nil
 }
+
+func (s *defaultUserServer) GetActorBySource(ctx context.Context, req *v1.GetActorBySourceRequest) (*v1.Actor, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, status.Error(codes.Unauthenticated, "failed to get context information")
+	}
+
+	if req.GetSourceIdentity() == "" {
+		return nil, status.Error(codes.InvalidArgument, "source identity is required")
+	}
+
+	actor, err := s.users.GetActorBySource(ctx, req.GetSource(), req.GetSourceIdentity())
+	if err != nil {
+		s.log.Warn("failed to get actor by source", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	return actor, nil
+}
+
+func (s *defaultUserServer) IssueApiKey(ctx context.Context, req *v1.IssueApiKeyRequest) (*v1.IssueApiKeyResponse, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, status.Error(codes.Unauthenticated, "failed to get context information")
+	}
+
+	if req.GetUserId() == auth.SystemUserId {
+		s.log.Error("user id is reserved", info.LoggingContext("uid", req.GetUserId())...)
+		return nil, status.Error(codes.InvalidArgument, "the system user cannot hold api keys")
+	}
+	if info.Credential == common.CredentialBearerToken {
+		s.log.Error("bearer tokens cannot issue api keys", info.LoggingContext()...)
+		return nil, status.Error(codes.PermissionDenied, "api keys cannot be issued with a bearer token")
+	}
+	if !actsFor(info, req.GetUserId()) {
+		s.log.Error("api keys may only be issued to yourself", info.LoggingContext("uid", req.GetUserId())...)
+		return nil, status.Error(codes.PermissionDenied, "api keys may only be issued to yourself")
+	}
+
+	if _, err = s.users.GetUser(ctx, req.GetUserId()); err != nil {
+		s.log.Error("failed to get user", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	if req.ActorId != nil {
+		owner, err := s.users.GetUserForActor(ctx, req.GetActorId())
+		if err != nil {
+			s.log.Error("failed to get actor owner", info.LoggingContext("error", err)...)
+			return nil, err
+		}
+		if owner.GetUid() != req.GetUserId() {
+			return nil, status.Error(codes.InvalidArgument, "actor does not belong to user")
+		}
+	}
+
+	secret, err := auth.GenerateApiKeySecret()
+	if err != nil {
+		s.log.Error("failed to generate api key", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	key := &v1.ApiKey{
+		Uid:     common.GenerateRandomStringFromSeed("apikey", req.GetUserId(), common.GenerateUniqueId()),
+		UserId:  req.GetUserId(),
+		Name:    req.GetName(),
+		ActorId: req.ActorId,
+	}
+	if err = s.keys.CreateApiKey(ctx, key, auth.HashApiKeySecret(secret)); err != nil {
+		s.log.Error("failed to create api key", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	return &v1.IssueApiKeyResponse{Key: key, Secret: secret}, nil
+}
+
+func (s *defaultUserServer) RevokeApiKey(ctx context.Context, req *v1.RevokeApiKeyRequest) (*v1.RevokeApiKeyResponse, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, status.Error(codes.Unauthenticated, "failed to get context information")
+	}
+
+	key, err := s.keys.GetApiKey(ctx, req.GetKeyUid())
+	if err != nil {
+		s.log.Error("failed to get api key", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	if !actsFor(info, key.GetUserId()) {
+		s.log.Error("api keys may only be revoked by their owner", info.LoggingContext("key", key.GetUid())...)
+		return nil, status.Error(codes.PermissionDenied, "api keys may only be revoked by their owner")
+	}
+
+	revoked, err := s.keys.RevokeApiKey(ctx, key.GetUid())
+	if err != nil {
+		s.log.Error("failed to revoke api key", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	return &v1.RevokeApiKeyResponse{KeyUid: key.GetUid(), Success: revoked}, nil
+}
+
+func (s *defaultUserServer) IssueToken(ctx context.Context, req *v1.IssueTokenRequest) (*v1.IssueTokenResponse, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, status.Error(codes.Unauthenticated, "failed to get context information")
+	}
+
+	if info.User.GetUid() == auth.SystemUserId {
+		return nil, status.Error(codes.InvalidArgument, "the system user authenticates with the system token")
+	}
+	// a token that could mint its successor would never expire, so tokens are only issued against a lasting credential
+	if info.Credential == common.CredentialBearerToken {
+		s.log.Error("bearer tokens cannot issue tokens", info.LoggingContext()...)
+		return nil, status.Error(codes.PermissionDenied, "tokens can only be issued with an api key")
+	}
+
+	actorId := info.Actor.GetUid()
+	if req.ActorId != nil && req.GetActorId() != actorId {
+		owner, err := s.users.GetUserForActor(ctx, req.GetActorId())
+		if err != nil {
+			s.log.Error("failed to get actor owner", info.LoggingContext("error", err)...)
+			return nil, err
+		}
+		if owner.GetUid() != info.User.GetUid() {
+			return nil, status.Error(codes.PermissionDenied, "actor does not belong to you")
+		}
+		actorId = req.GetActorId()
+	}
+
+	token, claims, err := s.tokens.Issue(info.User.GetUid(), actorId, time.Duration(req.GetTtlSeconds())*time.Second)
+	if err != nil {
+		s.log.Error("failed to issue token", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	s.log.Info("token issued", info.LoggingContext("token_actor", actorId, "expires_at", claims.ExpiresAt)...)
+	return &v1.IssueTokenResponse{Token: token, ExpiresAt: claims.ExpiresAt}, nil
+}
+
+// actsFor reports whether the caller may manage credentials belonging to the user
+func actsFor(info *common.OverseerContextInformation, userId string) bool {
+	return info.User.GetUid() == auth.SystemUserId || info.User.GetUid() == userId
+}
+
+func (s *defaultUserServer) CreateLinkCode(ctx context.Context, req *v1.CreateLinkCodeRequest) (*v1.LinkCode, error) {
+	info, err := linkingActor(ctx)
+	if err != nil {
+		s.log.Error("failed to create link code", "error", err)
+		return nil, err
+	}
+
+	code, err := auth.GenerateLinkCode()
+	if err != nil {
+		s.log.Error("failed to generate link code", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	ttl := time.Duration(common.GetConfiguration().Server.LinkCodeTtlSeconds) * time.Second
+	linkCode := &v1.LinkCode{
+		Code:      code,
+		UserId:    info.User.GetUid(),
+		ActorId:   info.Actor.GetUid(),
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}
+	if err = s.users.CreateLinkCode(ctx, linkCode); err != nil {
+		s.log.Error("failed to create link code", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	s.log.Info("link code created", info.LoggingContext("expires_at", linkCode.GetExpiresAt())...)
+	return linkCode, nil
+}
+
+func (s *defaultUserServer) RedeemLinkCode(ctx context.Context, req *v1.RedeemLinkCodeRequest) (*v1.User, error) {
+	info, err := linkingActor(ctx)
+	if err != nil {
+		s.log.Error("failed to redeem link code", "error", err)
+		return nil, err
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(req.GetCode()))
+	if code == "" {
+		return nil, status.Error(codes.InvalidArgument, "code is required")
+	}
+	linkCode, err := s.users.ConsumeLinkCode(ctx, code)
+	if err != nil {
+		s.log.Warn("failed to consume link code", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	if linkCode.GetUserId() == info.User.GetUid() {
+		return info.User, nil
+	}
+
+	// the caller's user is folded into the user that created the code so the actors of both follow the player
+	if err = s.users.MergeUsers(ctx, info.User.GetUid(), linkCode.GetUserId()); err != nil {
+		s.log.Error("failed to merge users", info.LoggingContext("error", err, "into", linkCode.GetUserId())...)
+		return nil, err
+	}
+
+	s.log.Info("actors linked", info.LoggingContext("into", linkCode.GetUserId(), "linked_from", linkCode.GetActorId())...)
+	return &v1.User{Uid: linkCode.GetUserId()}, nil
+}
+
+func (s *defaultUserServer) UnlinkActor(ctx context.Context, req *v1.UnlinkActorRequest) (*v1.User, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, status.Error(codes.Unauthenticated, "failed to get context information")
+	}
+
+	actorId := req.GetActorId()
+	if actorId == "" {
+		actorId = info.Actor.GetUid()
+	}
+	if actorId == "" || actorId == auth.SystemActorId {
+		return nil, status.Error(codes.InvalidArgument, "actor id is required")
+	}
+
+	owner, err := s.users.GetUserForActor(ctx, actorId)
+	if err != nil {
+		s.log.Error("failed to get actor owner", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	if !actsFor(info, owner.GetUid()) {
+		s.log.Error("actors may only be unlinked by their owner", info.LoggingContext("unlink_actor", actorId)...)
+		return nil, status.Error(codes.PermissionDenied, "actors may only be unlinked by their owner")
+	}
+	actors, err := s.users.GetActors(ctx, owner.GetUid())
+	if err != nil {
+		s.log.Error("failed to get actors", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	if len(actors) < 2 {
+		return nil, status.Error(codes.FailedPrecondition, "the only actor of a user cannot be unlinked")
+	}
+
+	user := &v1.User{Uid: common.GenerateRandomStringFromSeed("user", actorId, common.GenerateUniqueId())}
+	if err = s.users.UpsertUser(ctx, user); err != nil {
+		s.log.Error("failed to create user", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	if err = s.users.MoveActor(ctx, actorId, user.GetUid()); err != nil {
+		s.log.Error("failed to move actor", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+
+	s.log.Info("actor unlinked", info.LoggingContext("unlink_actor", actorId, "from", owner.GetUid(), "into", user.GetUid())...)
+	return user, nil
+}
+
+func (s *defaultUserServer) ListLinkedActors(ctx context.Context, req *v1.ListLinkedActorsRequest) (*v1.Actors, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, status.Error(codes.Unauthenticated, "failed to get context information")
+	}
+	if info.User.GetUid() == auth.SystemUserId {
+		return nil, status.Error(codes.InvalidArgument, "the system user has no linked actors")
+	}
+
+	actors, err := s.users.GetActors(ctx, info.User.GetUid())
+	if err != nil {
+		s.log.Error("failed to get actors", info.LoggingContext("error", err)...)
+		return nil, err
+	}
+	return &v1.Actors{Actors: actors}, nil
+}
+
+// linkingActor returns the caller, only actors of real users can be linked
+func linkingActor(ctx context.Context) (*common.OverseerContextInformation, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "failed to get context information")
+	}
+	if info.Actor == nil || info.User.GetUid() == auth.SystemUserId {
+		return nil, status.Error(codes.InvalidArgument, "linking requires calling as an actor")
+	}
+	return info, nil
+}
@@ -0,0 +1,416 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	v1 "overseer/build/go"
+	"overseer/client"
+	"overseer/common"
+	"regexp"
+	"strings"
+
+	slackapi "github.com/slack-go/slack"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// commands mirror the discord slash commands, slack passes everything after the command as free text
+var commands = map[string]func(b *Bot, ctx context.Context, command slackapi.SlashCommand){
+	"register": (*Bot).registerCommand,
+	"game":     (*Bot).gameCommand,
+	"move":     (*Bot).moveCommand,
+	"say":      (*Bot).sayCommand,
+	"whisper":  (*Bot).whisperCommand,
+	"ask-dm":   (*Bot).askDmCommand,
+	"act":      (*Bot).actCommand,
+	"link":     (*Bot).linkCommand,
+}
+
+// mentionPattern matches an escaped user mention such as <@U024BE7LH|bob> at the start of the text
+var mentionPattern = regexp.MustCompile(`^<@([A-Z0-9]+)(?:\|[^>]*)?>\s*(.*)$`)
+
+// privateFlag keeps a question to the dungeon master between the player and the dungeon master
+const privateFlag = "--private"
+
+func (b *Bot) registerCommand(ctx context.Context, command slackapi.SlashCommand) {
+	if info, err := common.GetContextInformation(ctx); err == nil {
+		b.reply(ctx, command, fmt.Sprintf("You are already registered as %s", info.Actor.GetUid()))
+		return
+	}
+
+	user, err := b.overseer.Users.RegisterUser(ctx, &v1.User{
+		Uid: common.SourceUserId(v1.Actor_APP_SLACK, command.TeamID, command.UserID),
+	})
+	if err != nil {
+		b.log.Error("failed to register user", "error", err, "user", command.UserID, "team", command.TeamID)
+		b.reply(ctx, command, "Registration failed, please try again later")
+		return
+	}
+
+	actor, err := b.overseer.Users.RegisterActor(ctx, &v1.RegisterActorRequest{
+		UserId:         user.GetUid(),
+		SourceIdentity: command.UserID,
+		Source:         v1.Actor_APP_SLACK,
+		Metadata: &v1.ActorMetadata{
+			Value: &v1.ActorMetadata_Slack{
+				Slack: &v1.ActorSourceSlack{
+					Team:    command.TeamID,
+					Channel: command.ChannelID,
+				},
+			},
+		},
+	})
+	if err != nil {
+		b.log.Error("failed to register actor", "error", err, "user", command.UserID, "team", command.TeamID)
+		b.reply(ctx, command, "Registration failed, please try again later")
+		return
+	}
+
+	b.log.Info("registered user", "user", user.GetUid(), "actor", actor.GetUid(), "team", command.TeamID)
+	b.reply(ctx, command, fmt.Sprintf("Welcome %s, you are registered as %s", command.UserName, actor.GetUid()))
+}
+
+func (b *Bot) gameCommand(ctx context.Context, command slackapi.SlashCommand) {
+	info, ok := b.requireActor(ctx, command)
+	if !ok {
+		return
+	}
+
+	subcommand, rest, _ := strings.Cut(strings.TrimSpace(command.Text), " ")
+	rest = strings.TrimSpace(rest)
+	switch subcommand {
+	case "new":
+		b.gameNew(ctx, command, info, rest)
+	case "join":
+		b.gameJoin(ctx, command, info, rest)
+	case "status":
+		b.gameStatus(ctx, command, info, rest)
+	case "end":
+		b.gameEnd(ctx, command, info, rest)
+	default:
+		b.reply(ctx, command, "Usage: `/game new <name>`, `/game join [game] [spectate]`, `/game status [game]` or `/game end [game]`")
+	}
+}
+
+func (b *Bot) gameNew(ctx context.Context, command slackapi.SlashCommand, info *common.OverseerContextInformation, name string) {
+	if name == "" {
+		b.reply(ctx, command, "Usage: `/game new <name>`")
+		return
+	}
+
+	game, err := b.overseer.Games.CreateGame(ctx, &v1.CreateGameRequest{
+		Name:         name,
+		Theme:        v1.GameTheme_DEFAULT,
+		Participants: []*v1.Actor{info.Actor},
+	})
+	if err != nil {
+		b.log.Error("failed to create game", info.LoggingContext("error", err)...)
+		b.reply(ctx, command, client.DescribeError(err))
+		return
+	}
+	if _, err = b.bind(ctx, command.ChannelID, game); err != nil {
+		b.reply(ctx, command, client.DescribeError(err))
+		return
+	}
+
+	// the world is generated while the new game event is handled, the receipts are posted to the thread
+	_, err = b.overseer.Events.Submit(ctx, &v1.Event{
+		GameUid: game.GetUid(),
+		Actor:   info.Actor,
+		Origin:  eventOrigin(command.TeamID, command.ChannelID),
+		Payload: &v1.Event_NewGame{
+			NewGame: &v1.NewGameEvent{
+				Theme:        game.GetTheme(),
+				Name:         game.GetName(),
+				Participants: game.GetParticipants(),
+			},
+		},
+	})
+	if err != nil {
+		b.log.Error("failed to start game", info.LoggingContext("error", err, "game", game.GetUid())...)
+		b.reply(ctx, command, client.DescribeError(err))
+		return
+	}
+	b.log.Info("game started", info.LoggingContext("game", game.GetUid(), "channel", command.ChannelID)...)
+}
+
+func (b *Bot) gameJoin(ctx context.Context, command slackapi.SlashCommand, info *common.OverseerContextInformation, text string) {
+	spectate := false
+	fields := strings.Fields(text)
+	gameUid := ""
+	for _, field := range fields {
+		if field == "spectate" {
+			spectate = true
+		} else {
+			gameUid = field
+		}
+	}
+	gameUid, ok := b.resolveGame(ctx, command, gameUid)
+	if !ok {
+		return
+	}
+
+	game, err := b.overseer.Games.JoinGame(ctx, &v1.JoinGameRequest{GameUid: gameUid, Spectator: spectate})
+	if err != nil {
+		b.log.Error("failed to join game", info.LoggingContext("error", err, "game", gameUid)...)
+		b.reply(ctx, command, client.DescribeError(err))
+		return
+	}
+	bound, err := b.bind(ctx, command.ChannelID, game)
+	if err != nil {
+		b.reply(ctx, command, client.DescribeError(err))
+		return
+	}
+
+	verb := "joined"
+	if spectate {
+		verb = "is watching"
+	}
+	if _, _, err = b.api.PostMessageContext(ctx, command.ChannelID, slackapi.MsgOptionText(fmt.Sprintf("<@%s> %s *%s*", command.UserID, verb, game.GetName()), false), slackapi.MsgOptionTS(bound.ThreadId)); err != nil {
+		b.log.Error("failed to announce join", "error", err, "channel", command.ChannelID)
+	}
+}
+
+func (b *Bot) gameStatus(ctx context.Context, command slackapi.SlashCommand, info *common.OverseerContextInformation, text string) {
+	gameUid, ok := b.resolveGame(ctx, command, text)
+	if !ok {
+		return
+	}
+
+	game, err := b.overseer.Games.GetGame(ctx, &v1.GetGameRequest{GameUid: gameUid})
+	if err != nil {
+		b.log.Error("failed to get game", info.LoggingContext("error", err, "game", gameUid)...)
+		b.reply(ctx, command, client.DescribeError(err))
+		return
+	}
+
+	state := "being created"
+	if game.GetCompleted() {
+		state = "over"
+	} else if game.GetInitialized() {
+		state = "in progress"
+	}
+	participants := make([]string, 0, len(game.GetParticipants()))
+	for _, participant := range game.GetParticipants() {
+		participants = append(participants, b.name(ctx, participant.GetUid()))
+	}
+
+	b.reply(ctx, command, fmt.Sprintf("*%s* (`%s`) is %s\nPlayers: %s\nSpectators: %d",
+		game.GetName(),
+		game.GetUid(),
+		state,
+		strings.Join(participants, ", "),
+		len(game.GetSpectators()),
+	))
+}
+
+func (b *Bot) gameEnd(ctx context.Context, command slackapi.SlashCommand, info *common.OverseerContextInformation, text string) {
+	gameUid, ok := b.resolveGame(ctx, command, text)
+	if !ok {
+		return
+	}
+
+	if _, err := b.overseer.Games.EndGame(ctx, &v1.EndGameRequest{GameUid: gameUid}); err != nil {
+		b.log.Error("failed to end game", info.LoggingContext("error", err, "game", gameUid)...)
+		b.reply(ctx, command, client.DescribeError(err))
+		return
+	}
+	if bound, ok := b.bindings.Get(command.ChannelID); ok && bound.GameUid == gameUid {
+		if _, _, err := b.api.PostMessageContext(ctx, command.ChannelID, slackapi.MsgOptionText("The game has ended", false), slackapi.MsgOptionTS(bound.ThreadId)); err != nil {
+			b.log.Error("failed to announce end of game", "error", err, "channel", command.ChannelID)
+		}
+	}
+	b.bindings.UnbindGame(gameUid)
+	b.log.Info("game ended", info.LoggingContext("game", gameUid)...)
+}
+
+func (b *Bot) moveCommand(ctx context.Context, command slackapi.SlashCommand) {
+	direction := strings.TrimSpace(command.Text)
+	if _, err := common.ParseDirection(direction); err != nil {
+		b.reply(ctx, command, "Usage: `/move <north|north-east|east|south-east|south|south-west|west|north-west>`")
+		return
+	}
+	b.submitInteraction(ctx, command, &v1.InteractionEvent{
+		Interaction: &v1.InteractionEvent_Movement{
+			Movement: &v1.MovementInteraction{Direction: direction},
+		},
+	})
+}
+
+func (b *Bot) sayCommand(ctx context.Context, command slackapi.SlashCommand) {
+	if strings.TrimSpace(command.Text) == "" {
+		b.reply(ctx, command, "Usage: `/say <message>`")
+		return
+	}
+	b.submitInteraction(ctx, command, &v1.InteractionEvent{
+		Interaction: &v1.InteractionEvent_Utterance{
+			Utterance: &v1.UtteranceInteraction{
+				Content:   command.Text,
+				Utterance: &v1.UtteranceInteraction_Table{Table: &v1.TableUtterance{}},
+			},
+		},
+	})
+}
+
+func (b *Bot) whisperCommand(ctx context.Context, command slackapi.SlashCommand) {
+	match := mentionPattern.FindStringSubmatch(strings.TrimSpace(command.Text))
+	if match == nil || strings.TrimSpace(match[2]) == "" {
+		b.reply(ctx, command, "Usage: `/whisper @player <message>`")
+		return
+	}
+
+	target, err := b.overseer.Users.GetActorBySource(client.AsSystem(ctx), &v1.GetActorBySourceRequest{
+		Source:         v1.Actor_APP_SLACK,
+		SourceIdentity: match[1],
+	})
+	if status.Code(err) == codes.NotFound {
+		b.reply(ctx, command, "That player has not registered")
+		return
+	}
+	if err != nil {
+		b.log.Error("failed to resolve whisper target", "error", err, "target", match[1])
+		b.reply(ctx, command, client.DescribeError(err))
+		return
+	}
+
+	b.submitInteraction(ctx, command, &v1.InteractionEvent{
+		Interaction: &v1.InteractionEvent_Utterance{
+			Utterance: &v1.UtteranceInteraction{
+				Content: match[2],
+				Utterance: &v1.UtteranceInteraction_Player{
+					Player: &v1.PlayerUtterance{Target: target, Whisper: true},
+				},
+			},
+		},
+	})
+}
+
+func (b *Bot) askDmCommand(ctx context.Context, command slackapi.SlashCommand) {
+	question := strings.TrimSpace(command.Text)
+	question, private := strings.CutPrefix(question, privateFlag)
+	question = strings.TrimSpace(question)
+	if question == "" {
+		b.reply(ctx, command, "Usage: `/ask-dm [--private] <question>`")
+		return
+	}
+	b.submitInteraction(ctx, command, &v1.InteractionEvent{
+		Interaction: &v1.InteractionEvent_Utterance{
+			Utterance: &v1.UtteranceInteraction{
+				Content: question,
+				Utterance: &v1.UtteranceInteraction_DungeonMaster{
+					DungeonMaster: &v1.DungeonMasterUtterance{Whisper: private},
+				},
+			},
+		},
+	})
+}
+
+func (b *Bot) actCommand(ctx context.Context, command slackapi.SlashCommand) {
+	if strings.TrimSpace(command.Text) == "" {
+		b.reply(ctx, command, "Usage: `/act <what your character does>`")
+		return
+	}
+	b.submitInteraction(ctx, command, &v1.InteractionEvent{
+		Interaction: &v1.InteractionEvent_Action{
+			Action: &v1.ActionInteraction{Action: command.Text},
+		},
+	})
+}
+
+func (b *Bot) linkCommand(ctx context.Context, command slackapi.SlashCommand) {
+	info, ok := b.requireActor(ctx, command)
+	if !ok {
+		return
+	}
+
+	subcommand, rest, _ := strings.Cut(strings.TrimSpace(command.Text), " ")
+	switch subcommand {
+	case "create":
+		code, err := b.overseer.Users.CreateLinkCode(ctx, &v1.CreateLinkCodeRequest{})
+		if err != nil {
+			b.log.Error("failed to create link code", info.LoggingContext("error", err)...)
+			b.reply(ctx, command, client.DescribeError(err))
+			return
+		}
+		b.reply(ctx, command, fmt.Sprintf("Redeem `%s` from your other account before <!date^%d^{time}|it expires>", code.GetCode(), code.GetExpiresAt()))
+	case "redeem":
+		if _, err := b.overseer.Users.RedeemLinkCode(ctx, &v1.RedeemLinkCodeRequest{Code: rest}); err != nil {
+			b.log.Warn("failed to redeem link code", info.LoggingContext("error", err)...)
+			b.reply(ctx, command, client.DescribeError(err))
+			return
+		}
+		b.reply(ctx, command, "Your accounts are linked")
+	case "list":
+		actors, err := b.overseer.Users.ListLinkedActors(ctx, &v1.ListLinkedActorsRequest{})
+		if err != nil {
+			b.log.Error("failed to list linked actors", info.LoggingContext("error", err)...)
+			b.reply(ctx, command, client.DescribeError(err))
+			return
+		}
+		lines := make([]string, 0, len(actors.GetActors()))
+		for _, actor := range actors.GetActors() {
+			source := strings.ToLower(strings.TrimPrefix(actor.GetSource().String(), "APP_"))
+			if actor.GetSource() == v1.Actor_APP_SLACK {
+				lines = append(lines, fmt.Sprintf("• %s <@%s>", source, actor.GetSourceIdentity()))
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("• %s %s", source, actor.GetSourceIdentity()))
+		}
+		b.reply(ctx, command, fmt.Sprintf("Linked accounts:\n%s", strings.Join(lines, "\n")))
+	case "unlink":
+		if _, err := b.overseer.Users.UnlinkActor(ctx, &v1.UnlinkActorRequest{}); err != nil {
+			b.log.Warn("failed to unlink actor", info.LoggingContext("error", err)...)
+			b.reply(ctx, command, client.DescribeError(err))
+			return
+		}
+		b.reply(ctx, command, "This slack account is no longer linked to your other accounts")
+	default:
+		b.reply(ctx, command, "Usage: `/link create`, `/link redeem <code>`, `/link list` or `/link unlink`")
+	}
+}
+
+// requireActor returns who is calling, unregistered slack users are told to register first
+func (b *Bot) requireActor(ctx context.Context, command slackapi.SlashCommand) (*common.OverseerContextInformation, bool) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil || info.Actor == nil {
+		b.reply(ctx, command, "You need to `/register` before you can play")
+		return nil, false
+	}
+	return info, true
+}
+
+// resolveGame returns the game named in the command or the game bound to the channel
+func (b *Bot) resolveGame(ctx context.Context, command slackapi.SlashCommand, gameUid string) (string, bool) {
+	if gameUid = strings.TrimSpace(gameUid); gameUid != "" {
+		return gameUid, true
+	}
+	if bound, ok := b.bindings.Get(command.ChannelID); ok {
+		return bound.GameUid, true
+	}
+	b.reply(ctx, command, "There is no game in this channel, start one with `/game new <name>`")
+	return "", false
+}
+
+// submitInteraction submits an interaction by the caller to the game bound to the channel,
+// the receipts are posted to the thread of the game so only failures are replied to
+func (b *Bot) submitInteraction(ctx context.Context, command slackapi.SlashCommand, interaction *v1.InteractionEvent) {
+	info, ok := b.requireActor(ctx, command)
+	if !ok {
+		return
+	}
+	gameUid, ok := b.resolveGame(ctx, command, "")
+	if !ok {
+		return
+	}
+
+	_, err := b.overseer.Events.Submit(ctx, &v1.Event{
+		GameUid: gameUid,
+		Actor:   info.Actor,
+		Origin:  eventOrigin(command.TeamID, command.ChannelID),
+		Payload: &v1.Event_Interaction{Interaction: interaction},
+	})
+	if err != nil {
+		b.log.Error("failed to submit interaction", info.LoggingContext("error", err, "game", gameUid)...)
+		b.reply(ctx, command, client.DescribeError(err))
+	}
+}
@@ -0,0 +1,125 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	v1 "overseer/build/go"
+	"overseer/client"
+	"overseer/common"
+	"strings"
+	"sync"
+
+	charm "github.com/charmbracelet/log"
+	slackapi "github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// Bot turns slack slash commands and thread replies into overseer events and posts the receipts back to slack,
+// it is kept apart from the socket mode connection so it can be driven against any slack api
+type Bot struct {
+	api      *slackapi.Client
+	overseer *client.OverseerClient
+	// bindings are games played in a thread of a channel, the thread is kept as the binding's thread id
+	bindings *client.Bindings
+	actors   sync.Map
+	log      *charm.Logger
+}
+
+// NewBot keeps the bindings in the file so they survive the bot restarting, an empty file name keeps them in memory only
+func NewBot(api *slackapi.Client, overseer *client.OverseerClient, file string) *Bot {
+	b := &Bot{
+		api:      api,
+		overseer: overseer,
+		log:      common.GetLogger("slack.bot"),
+	}
+	b.bindings = client.NewBindings(overseer, file, b.deliver)
+	return b
+}
+
+// HandleCommand runs a slash command, the reply is only shown to whoever ran it
+func (b *Bot) HandleCommand(ctx context.Context, command slackapi.SlashCommand) {
+	b.log.Info("command received", "command", command.Command, "user", command.UserID, "team", command.TeamID, "channel", command.ChannelID)
+
+	ctx, err := b.overseer.ResolveIdentity(ctx, v1.Actor_APP_SLACK, command.UserID)
+	if err != nil {
+		b.log.Error("failed to resolve identity", "error", err, "user", command.UserID)
+		b.reply(ctx, command, client.DescribeError(err))
+		return
+	}
+
+	handler, ok := commands[strings.TrimPrefix(command.Command, "/")]
+	if !ok {
+		b.log.Error("no handler found for command", "command", command.Command)
+		b.reply(ctx, command, fmt.Sprintf("Unknown command %s", command.Command))
+		return
+	}
+	handler(b, ctx, command)
+}
+
+// HandleMessage turns replies in the thread of a bound game into utterances to the table
+func (b *Bot) HandleMessage(ctx context.Context, teamId string, message *slackevents.MessageEvent) {
+	if message.BotID != "" || message.SubType != "" || message.User == "" || strings.TrimSpace(message.Text) == "" {
+		return
+	}
+	bound, ok := b.bindings.Get(message.Channel)
+	if !ok || message.ThreadTimeStamp != bound.ThreadId {
+		return
+	}
+
+	ctx, err := b.overseer.ResolveIdentity(ctx, v1.Actor_APP_SLACK, message.User)
+	if err != nil {
+		b.log.Error("failed to resolve identity", "error", err, "user", message.User)
+		return
+	}
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		b.whisper(ctx, message.Channel, bound.ThreadId, message.User, "You need to `/register` before you can play")
+		return
+	}
+
+	_, err = b.overseer.Events.Submit(ctx, &v1.Event{
+		GameUid: bound.GameUid,
+		Actor:   info.Actor,
+		Origin:  eventOrigin(teamId, message.Channel),
+		Payload: &v1.Event_Interaction{
+			Interaction: &v1.InteractionEvent{
+				Interaction: &v1.InteractionEvent_Utterance{
+					Utterance: &v1.UtteranceInteraction{
+						Content:   message.Text,
+						Utterance: &v1.UtteranceInteraction_Table{Table: &v1.TableUtterance{}},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		b.log.Error("failed to submit utterance", info.LoggingContext("error", err, "game", bound.GameUid)...)
+		b.whisper(ctx, message.Channel, bound.ThreadId, message.User, client.DescribeError(err))
+	}
+}
+
+// reply answers a command with a message only the caller can see
+func (b *Bot) reply(ctx context.Context, command slackapi.SlashCommand, text string) {
+	b.whisper(ctx, command.ChannelID, "", command.UserID, text)
+}
+
+// whisper posts an ephemeral message, in the thread when one is given
+func (b *Bot) whisper(ctx context.Context, channelId string, threadTs string, slackUser string, text string) {
+	options := []slackapi.MsgOption{slackapi.MsgOptionText(text, false)}
+	if threadTs != "" {
+		options = append(options, slackapi.MsgOptionTS(threadTs))
+	}
+	if _, err := b.api.PostEphemeralContext(ctx, channelId, slackUser, options...); err != nil {
+		b.log.Error("failed to post ephemeral message", "error", err, "channel", channelId, "user", slackUser)
+	}
+}
+
+// eventOrigin records where in slack an event came from
+func eventOrigin(teamId string, channelId string) *v1.Event_Slack {
+	return &v1.Event_Slack{
+		Slack: &v1.EventOriginSlack{
+			Team:    teamId,
+			Channel: channelId,
+		},
+	}
+}
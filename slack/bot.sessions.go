@@ -0,0 +1,92 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/client"
+
+	slackapi "github.com/slack-go/slack"
+)
+
+// bind starts a thread for the game in the channel and posts the receipts of the game to it from now on
+func (b *Bot) bind(ctx context.Context, channelId string, game *v1.Game) (client.Binding, error) {
+	if bound, ok := b.bindings.Get(channelId); ok && bound.GameUid == game.GetUid() {
+		return bound, nil
+	}
+
+	_, threadTs, err := b.api.PostMessageContext(ctx, channelId, slackapi.MsgOptionText(
+		fmt.Sprintf("*%s* (`%s`) is played in this thread, reply here to talk to the table", game.GetName(), game.GetUid()),
+		false,
+	))
+	if err != nil {
+		b.log.Error("failed to start thread", "error", err, "game", game.GetUid(), "channel", channelId)
+		return client.Binding{}, err
+	}
+
+	bound := client.Binding{ChannelId: channelId, GameUid: game.GetUid(), ThreadId: threadTs}
+	if err = b.bindings.Bind(bound); err != nil {
+		return client.Binding{}, err
+	}
+	return bound, nil
+}
+
+// Restore binds the threads saved by a previous run again
+func (b *Bot) Restore() error {
+	return b.bindings.Restore()
+}
+
+// Close stops watching every game, the bindings stay saved for the next run
+func (b *Bot) Close() {
+	b.bindings.Close()
+}
+
+// deliver posts the receipt to the thread of every channel bound to the game, whispers are posted once as
+// ephemeral messages only their targets can see in the first of those threads
+func (b *Bot) deliver(ctx context.Context, bindings []client.Binding, receipt *v1.EventReceipt) {
+	text := b.describe(ctx, receipt)
+	if text == "" || len(bindings) == 0 {
+		return
+	}
+
+	utterance := receipt.GetUtterance()
+	if utterance == nil || !utterance.GetWhisper() {
+		for _, bound := range bindings {
+			if _, _, err := b.api.PostMessageContext(ctx, bound.ChannelId, slackapi.MsgOptionText(text, false), slackapi.MsgOptionTS(bound.ThreadId)); err != nil {
+				b.log.Error("failed to post receipt", "error", err, "receipt", receipt.GetUid(), "channel", bound.ChannelId)
+			}
+		}
+		return
+	}
+
+	// slash commands have no reply of their own so the speaker is shown their whisper as well
+	for _, actorUid := range append([]string{utterance.GetActor()}, utterance.GetRecipients()...) {
+		if actorUid == auth.DungeonMasterActorId {
+			continue
+		}
+		actor, err := b.actor(ctx, actorUid)
+		if err != nil {
+			b.log.Error("failed to get whisper target", "error", err, "receipt", receipt.GetUid(), "target", actorUid)
+			continue
+		}
+		// targets on other front ends receive the whisper there
+		if actor.GetSource() != v1.Actor_APP_SLACK {
+			continue
+		}
+		b.whisper(ctx, bindings[0].ChannelId, bindings[0].ThreadId, actor.GetSourceIdentity(), text)
+	}
+}
+
+// actor looks up an actor, actors never change so they are cached
+func (b *Bot) actor(ctx context.Context, actorUid string) (*v1.Actor, error) {
+	if cached, ok := b.actors.Load(actorUid); ok {
+		return cached.(*v1.Actor), nil
+	}
+	actor, err := b.overseer.Users.GetActor(client.AsSystem(ctx), &v1.GetActorRequest{ActorId: actorUid})
+	if err != nil {
+		return nil, err
+	}
+	b.actors.Store(actorUid, actor)
+	return actor, nil
+}
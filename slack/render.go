@@ -0,0 +1,66 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"strings"
+)
+
+// describe renders a receipt as slack mrkdwn, empty when there is nothing to show
+func (b *Bot) describe(ctx context.Context, receipt *v1.EventReceipt) string {
+	switch effect := receipt.GetEffect().(type) {
+	case *v1.EventReceipt_Ack:
+		return effect.Ack.GetMessage()
+	case *v1.EventReceipt_Error:
+		return fmt.Sprintf(":warning: %s", effect.Error.GetMessage())
+	case *v1.EventReceipt_Utterance:
+		if effect.Utterance.GetWhisper() {
+			return fmt.Sprintf("_%s whispers:_ %s", b.name(ctx, effect.Utterance.GetActor()), effect.Utterance.GetContent())
+		}
+		return fmt.Sprintf("%s: %s", b.name(ctx, effect.Utterance.GetActor()), effect.Utterance.GetContent())
+	case *v1.EventReceipt_GameState:
+		if movement := effect.GameState.GetMovement(); movement != nil {
+			text := fmt.Sprintf("%s moves %s to (%d, %d)",
+				b.name(ctx, movement.GetActor().GetUid()),
+				movement.GetDirection(),
+				movement.GetDestination().GetX(),
+				movement.GetDestination().GetY(),
+			)
+			if movement.GetDifficultTerrain() {
+				text += " through difficult terrain"
+			}
+			return text
+		}
+		if action := effect.GameState.GetAction(); action != nil {
+			text := fmt.Sprintf("%s attempts to %s: rolled %d against %d, *%s*",
+				b.name(ctx, action.GetActor().GetUid()),
+				action.GetAction(),
+				action.GetCheck().GetTotal(),
+				action.GetDifficulty(),
+				strings.ToLower(strings.ReplaceAll(action.GetOutcome().String(), "_", " ")),
+			)
+			if action.Damage != nil {
+				text += fmt.Sprintf(" for %d damage", action.GetDamage().GetTotal())
+			}
+			return text
+		}
+	}
+	return ""
+}
+
+// name is how an actor is shown, slack actors are mentioned
+func (b *Bot) name(ctx context.Context, actorUid string) string {
+	if actorUid == auth.DungeonMasterActorId {
+		return "*Dungeon Master*"
+	}
+	actor, err := b.actor(ctx, actorUid)
+	if err != nil {
+		return fmt.Sprintf("*%s*", actorUid)
+	}
+	if actor.GetSource() == v1.Actor_APP_SLACK {
+		return fmt.Sprintf("<@%s>", actor.GetSourceIdentity())
+	}
+	return fmt.Sprintf("*%s*", actor.GetSourceIdentity())
+}
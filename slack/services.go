@@ -0,0 +1,8 @@
+package slack
+
+// SlackServer is the top level interface to operate a slack bot within overseer
+type SlackServer interface {
+	// Connect will open a socket mode connection to slack and handle commands and messages.
+	// This function will block until the connection is closed.
+	Connect() error
+}
@@ -0,0 +1,17 @@
+package slack
+
+import (
+	"overseer/client"
+	"overseer/common"
+
+	slackapi "github.com/slack-go/slack"
+)
+
+// NewSlackServer runs the bot over socket mode, options are passed to the slack api client
+func NewSlackServer(appToken string, botToken string, overseer *client.OverseerClient, options ...slackapi.Option) SlackServer {
+	return &defaultSlackServer{
+		api:      slackapi.New(botToken, append([]slackapi.Option{slackapi.OptionAppLevelToken(appToken)}, options...)...),
+		overseer: overseer,
+		log:      common.GetLogger("slack.server"),
+	}
+}
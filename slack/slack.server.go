@@ -0,0 +1,81 @@
+package slack
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"overseer/client"
+	"overseer/common"
+	"syscall"
+
+	charm "github.com/charmbracelet/log"
+	slackapi "github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+type defaultSlackServer struct {
+	api      *slackapi.Client
+	overseer *client.OverseerClient
+	log      *charm.Logger
+}
+
+func (s *defaultSlackServer) Connect() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	bot := NewBot(s.api, s.overseer, common.GetConfiguration().Slack.BindingsFile)
+	defer bot.Close()
+	// games bound before a restart keep being posted to their threads
+	if err := bot.Restore(); err != nil {
+		s.log.Error("failed to restore bindings", "error", err)
+	}
+	socket := socketmode.New(s.api)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-socket.Events:
+				s.handle(ctx, socket, bot, event)
+			}
+		}
+	}()
+
+	s.log.Info("server waiting for events")
+	if err := socket.RunContext(ctx); err != nil && ctx.Err() == nil {
+		s.log.Error("socket mode connection failed", "error", err)
+		return err
+	}
+	s.log.Info("shutting down slack server")
+	return nil
+}
+
+// handle acknowledges every request straight away, slack only waits three seconds and the work happens afterwards
+func (s *defaultSlackServer) handle(ctx context.Context, socket *socketmode.Client, bot *Bot, event socketmode.Event) {
+	switch event.Type {
+	case socketmode.EventTypeConnected:
+		s.log.Info("connected to slack")
+	case socketmode.EventTypeSlashCommand:
+		command, ok := event.Data.(slackapi.SlashCommand)
+		if !ok {
+			s.log.Error("unexpected slash command payload", "type", event.Type)
+			return
+		}
+		socket.Ack(*event.Request)
+		go bot.HandleCommand(ctx, command)
+	case socketmode.EventTypeEventsAPI:
+		payload, ok := event.Data.(slackevents.EventsAPIEvent)
+		if !ok {
+			s.log.Error("unexpected events api payload", "type", event.Type)
+			return
+		}
+		socket.Ack(*event.Request)
+		if message, ok := payload.InnerEvent.Data.(*slackevents.MessageEvent); ok {
+			go bot.HandleMessage(ctx, payload.TeamID, message)
+		}
+	default:
+		s.log.Debug("ignoring event", "type", event.Type)
+	}
+}
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"context"
+	v1 "overseer/build/go"
+	"overseer/common"
+)
+
+// VisibleFrom is every coordinate of the map that can be seen from where the actor stands
+func VisibleFrom(ctx context.Context, maps MapStore, mapId string, standing *v1.MapCoordinateDetail) ([]*v1.MapCoordinateDetail, error) {
+	window, err := maps.GetCoordinatesWithin(ctx, mapId, standing.GetPosition(), common.SightRadiusMaximum)
+	if err != nil {
+		return nil, err
+	}
+	return common.VisibleFrom(standing, window), nil
+}
+
+// Discover remembers everything the actor sees from where they stand, and where they stand as visited
+func Discover(ctx context.Context, maps MapStore, mapId string, actorId string, standing *v1.MapCoordinateDetail) error {
+	seen, err := VisibleFrom(ctx, maps, mapId, standing)
+	if err != nil {
+		return err
+	}
+	return maps.DiscoverCoordinates(ctx, mapId, actorId, seen, standing)
+}
@@ -19,9 +19,13 @@ func NewSqliteDB(databaseName string, migrate bool) (*gorm.DB, error) {
 	}
 
 	common.GetLogger("storage.NewSqliteDB").Info("migrating models")
+	// the position index is only filled as coordinates are written so a database from before it existed has to be indexed once
+	indexPositions := !db.Migrator().HasTable(&actorPosition{}) && db.Migrator().HasTable(&mapCoordinate{})
 	err = db.AutoMigrate(
 		&actor{},
 		&user{},
+		&apiKey{},
+		&linkCode{},
 		&game{},
 		&gameParticipant{},
 		&eventRow{},
@@ -29,6 +33,9 @@ func NewSqliteDB(databaseName string, migrate bool) (*gorm.DB, error) {
 		&lock{},
 		&gameMap{},
 		&mapCoordinate{},
+		&actorPosition{},
+		&discoveredCoordinate{},
+		&mapGenerationJob{},
 	)
 	common.GetLogger("storage.NewSqliteDB").Info("migrated models")
 
@@ -37,5 +44,18 @@ func NewSqliteDB(databaseName string, migrate bool) (*gorm.DB, error) {
 		return nil, err
 	}
 
+	if err = backfillActorOwners(db); err != nil {
+		common.GetLogger("storage.NewSqliteDB").Error("failed to link actors to their users", "error", err)
+		return nil, err
+	}
+
+	if indexPositions {
+		common.GetLogger("storage.NewSqliteDB").Info("indexing actor positions")
+		if err = backfillActorPositions(db); err != nil {
+			common.GetLogger("storage.NewSqliteDB").Error("failed to index actor positions", "error", err)
+			return nil, err
+		}
+	}
+
 	return db, nil
 }
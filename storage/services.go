@@ -5,9 +5,13 @@ import (
 	v1 "overseer/build/go"
 )
 
+// LockStore hands out leases on games, a nil lock without an error means the game is held and the caller chose not to wait
 type LockStore interface {
-	LockGame(ctx context.Context, request *v1.LockGameRequest) (bool, error)
+	LockGame(ctx context.Context, request *v1.LockGameRequest) (*v1.GameLock, error)
+	RenewLock(ctx context.Context, request *v1.RenewLockRequest) (*v1.GameLock, error)
 	UnlockGame(ctx context.Context, request *v1.UnlockGameRequest) (bool, error)
+	ListLocks(ctx context.Context, gameId string) ([]*v1.GameLock, error)
+	BreakLock(ctx context.Context, gameId string) (*v1.GameLock, error)
 }
 
 type GameStore interface {
@@ -23,21 +27,69 @@ type UserStore interface {
 	GetUserForActor(ctx context.Context, actorID string) (*v1.User, error)
 	GetActor(ctx context.Context, id string) (*v1.Actor, error)
 	GetActors(ctx context.Context, id string) ([]*v1.Actor, error)
+	GetActorBySource(ctx context.Context, source v1.Actor_Source, sourceIdentity string) (*v1.Actor, error)
 	DeleteUser(ctx context.Context, id string) error
 	DeleteActor(ctx context.Context, id string) error
+	// MergeUsers moves every actor and api key of one user to another
+	MergeUsers(ctx context.Context, fromUserId string, toUserId string) error
+	// MoveActor moves an actor and the api keys bound to it to another user
+	MoveActor(ctx context.Context, actorId string, toUserId string) error
+	CreateLinkCode(ctx context.Context, code *v1.LinkCode) error
+	// ConsumeLinkCode marks a code redeemed, codes that are expired or already redeemed are not found
+	ConsumeLinkCode(ctx context.Context, code string) (*v1.LinkCode, error)
+}
+
+// ApiKeyStore only ever sees the hash of a key, the secret itself is never stored
+type ApiKeyStore interface {
+	CreateApiKey(ctx context.Context, key *v1.ApiKey, secretHash string) error
+	GetApiKey(ctx context.Context, id string) (*v1.ApiKey, error)
+	GetApiKeyBySecretHash(ctx context.Context, secretHash string) (*v1.ApiKey, error)
+	RevokeApiKey(ctx context.Context, id string) (bool, error)
 }
 
 type EventStore interface {
 	RecordEvent(ctx context.Context, event *v1.Event) (*v1.EventRecord, error)
 	RecordReceipt(ctx context.Context, receipt *v1.EventReceipt) error
 	GetEvent(ctx context.Context, id string) (*v1.EventRecord, error)
+	GetRecentEvents(ctx context.Context, gameId string, limit int) ([]*v1.EventRecord, error)
+	ListEvents(ctx context.Context, request *v1.ListEventsRequest) (*v1.ListEventsResponse, error)
+	GetReceiptsAfter(ctx context.Context, gameId string, receiptId string) ([]*v1.EventReceipt, error)
 }
 
 type MapStore interface {
 	CreateMap(ctx context.Context, request *v1.CreateMapRequest) (*v1.Map, error)
 	GetMap(ctx context.Context, uid string) (*v1.Map, error)
+	GetMapForGame(ctx context.Context, gameId string) (*v1.Map, error)
 	CreateCoordinate(ctx context.Context, coordinate *v1.MapCoordinateDetail) error
 	GetCoordinates(ctx context.Context, mapId string) ([]*v1.MapCoordinateDetail, error)
+	// UpdateCoordinate saves the coordinate except for who stands on it, the actors and their sprites are kept as stored
 	UpdateCoordinate(ctx context.Context, coordinate *v1.MapCoordinateDetail) error
 	GetCoordinate(ctx context.Context, gameId string, mapId string, x int64, y int64) (*v1.MapCoordinateDetail, error)
+	GetActorCoordinate(ctx context.Context, mapId string, actorId string) (*v1.MapCoordinateDetail, error)
+	// GetActorPosition is where the actor stands on the map they moved on most recently
+	GetActorPosition(ctx context.Context, actorId string) (*v1.MapPosition, error)
+	// MoveActor loads the coordinate the actor stands on and the target, lets move change them and saves both together,
+	// nothing is saved when move returns an error
+	MoveActor(
+		ctx context.Context,
+		mapId string,
+		actorId string,
+		target *v1.MapPosition,
+		move func(origin *v1.MapCoordinateDetail, destination *v1.MapCoordinateDetail) error,
+	) (*v1.MapCoordinateDetail, *v1.MapCoordinateDetail, error)
+	// PlaceActor puts an actor who is not on the map yet, and a sprite for them, on the target coordinate
+	PlaceActor(ctx context.Context, mapId string, actor *v1.Actor, target *v1.MapPosition) (*v1.MapCoordinateDetail, error)
+	CountCoordinates(ctx context.Context, mapId string) (int64, error)
+	// GetCoordinatesWithin is the square of coordinates at most radius steps from the center
+	GetCoordinatesWithin(ctx context.Context, mapId string, center *v1.MapPosition, radius int64) ([]*v1.MapCoordinateDetail, error)
+	// DiscoverCoordinates remembers the coordinates the actor has seen, and the one they stand on as visited
+	DiscoverCoordinates(ctx context.Context, mapId string, actorId string, seen []*v1.MapCoordinateDetail, visited *v1.MapCoordinateDetail) error
+	// GetDiscoveredCoordinates is every coordinate the actor has discovered on the map, all of them remembered
+	GetDiscoveredCoordinates(ctx context.Context, mapId string, actorId string) ([]*v1.KnownCoordinate, error)
+	// HasDiscovered is whether the actor has ever seen the coordinate
+	HasDiscovered(ctx context.Context, mapId string, actorId string, coordinateId string) (bool, error)
+	// SaveMapGeneration creates or replaces the generation job of a map
+	SaveMapGeneration(ctx context.Context, job *v1.MapGenerationJob) error
+	GetMapGeneration(ctx context.Context, mapId string) (*v1.MapGenerationJob, error)
+	ListMapGenerations(ctx context.Context, state v1.MapGenerationStatus_State) ([]*v1.MapGenerationJob, error)
 }
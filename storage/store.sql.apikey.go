@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"time"
+
+	charm "github.com/charmbracelet/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+type sqlApiKeyStore struct {
+	db  *gorm.DB
+	log *charm.Logger
+}
+
+func NewSqlApiKeyStore(db *gorm.DB) ApiKeyStore {
+	return &sqlApiKeyStore{
+		db:  db,
+		log: common.GetLogger("store.sql.apikey"),
+	}
+}
+
+func (s *sqlApiKeyStore) CreateApiKey(ctx context.Context, key *v1.ApiKey, secretHash string) error {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return err
+	}
+
+	row := &apiKey{
+		ID:         key.GetUid(),
+		UserID:     key.GetUserId(),
+		ActorID:    key.ActorId,
+		Name:       key.GetName(),
+		SecretHash: secretHash,
+	}
+	if err = s.db.WithContext(ctx).Create(row).Error; err != nil {
+		s.log.Error("failed to create api key", info.LoggingContext("error", err, "key", key.GetUid())...)
+		return status.Error(codes.Internal, "failed to create api key")
+	}
+
+	key.CreatedAt = row.CreatedAt.Unix()
+	s.log.Info("api key created", info.LoggingContext("key", key.GetUid(), "owner", key.GetUserId())...)
+	return nil
+}
+
+func (s *sqlApiKeyStore) GetApiKey(ctx context.Context, id string) (*v1.ApiKey, error) {
+	row := &apiKey{}
+	err := s.db.WithContext(ctx).Where("id = ?", id).First(row).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, status.Error(codes.NotFound, "api key not found")
+		}
+		s.log.Error("failed to get api key", "error", err, "key", id)
+		return nil, status.Error(codes.Internal, "failed to get api key")
+	}
+	return row.toProto(), nil
+}
+
+// GetApiKeyBySecretHash is used while authenticating so it cannot rely on context information
+func (s *sqlApiKeyStore) GetApiKeyBySecretHash(ctx context.Context, secretHash string) (*v1.ApiKey, error) {
+	row := &apiKey{}
+	err := s.db.WithContext(ctx).Where("secret_hash = ?", secretHash).First(row).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, status.Error(codes.NotFound, "api key not found")
+		}
+		s.log.Error("failed to get api key", "error", err)
+		return nil, status.Error(codes.Internal, "failed to get api key")
+	}
+	return row.toProto(), nil
+}
+
+func (s *sqlApiKeyStore) RevokeApiKey(ctx context.Context, id string) (bool, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return false, err
+	}
+
+	result := s.db.WithContext(ctx).Model(&apiKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		s.log.Error("failed to revoke api key", info.LoggingContext("error", result.Error, "key", id)...)
+		return false, status.Error(codes.Internal, "failed to revoke api key")
+	}
+
+	s.log.Info("api key revoked", info.LoggingContext("key", id, "revoked", result.RowsAffected > 0)...)
+	return result.RowsAffected > 0, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	v1 "overseer/build/go"
 	"overseer/common"
+	"slices"
 	"time"
 
 	"google.golang.org/grpc/codes"
@@ -30,6 +31,7 @@ func NewSqlEventStore(db *gorm.DB) EventStore {
 func (s *sqlEventStore) RecordEvent(ctx context.Context, event *v1.Event) (*v1.EventRecord, error) {
 	recordId := common.GenerateRandomStringFromSeed(
 		event.Actor.Uid,
+		common.GenerateUniqueId(),
 		fmt.Sprintf("%d", time.Now().UTC().Unix()),
 	)
 
@@ -77,6 +79,7 @@ func (s *sqlEventStore) RecordEvent(ctx context.Context, event *v1.Event) (*v1.E
 		s.log.Error("failed to record eventRow", "error", err)
 		return nil, err
 	}
+	record.CreatedAt = evt.CreatedAt.Unix()
 
 	return record, nil
 }
@@ -134,11 +137,21 @@ func (s *sqlEventStore) RecordReceipt(ctx context.Context, receipt *v1.EventRece
 	db := s.db.WithContext(ctx)
 
 	err = db.Transaction(func(tx *gorm.DB) error {
+		if txErr := checkFence(ctx, tx); txErr != nil {
+			return txErr
+		}
 		if txErr := tx.Create(record).Error; txErr != nil {
 			return txErr
 		}
 		return nil
 	})
+	if status.Code(err) == codes.FailedPrecondition {
+		s.log.Warn("refused receipt written under a lost lease",
+			"receipt_id", receipt.Uid,
+			"event_id", receipt.EventUid,
+		)
+		return err
+	}
 	if err != nil {
 		s.log.Error("failed to record receipt",
 			"error", err,
@@ -165,5 +178,174 @@ func (s *sqlEventStore) receiptExists(ctx context.Context, id string) (bool, err
 }
 
 func (s *sqlEventStore) GetEvent(ctx context.Context, id string) (*v1.EventRecord, error) {
-	return nil, status.Error(codes.Unimplemented, "method GetEvent not implemented")
+	db := s.db.WithContext(ctx)
+
+	var rows []eventRow
+	err := db.Where("id = ?", id).Limit(1).Find(&rows).Error
+	if err != nil {
+		s.log.Error("failed to get event", "error", err, "event_id", id)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to get event: %s", err))
+	}
+	if len(rows) == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("event not found: %s", id))
+	}
+
+	records, err := s.toEventRecords(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+	return records[0], nil
+}
+
+// ListEvents pages through events oldest first, the page token is the uid of the last event of the previous page
+func (s *sqlEventStore) ListEvents(ctx context.Context, request *v1.ListEventsRequest) (*v1.ListEventsResponse, error) {
+	query := s.db.WithContext(ctx).Model(&eventRow{}).Where("game_id = ?", request.GetGameUid())
+	if request.ActorUid != nil {
+		query = query.Where("actor_id = ?", request.GetActorUid())
+	}
+	switch request.GetPayloadType() {
+	case v1.ListEventsRequest_NEW_GAME:
+		query = query.Where("payload_type = ?", payloadTypeNewGame)
+	case v1.ListEventsRequest_INTERACTION:
+		query = query.Where("payload_type = ?", payloadTypeInteraction)
+	}
+	if request.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", time.Unix(request.GetCreatedAfter(), 0).UTC())
+	}
+	if request.CreatedBefore != nil {
+		query = query.Where("created_at < ?", time.Unix(request.GetCreatedBefore(), 0).UTC())
+	}
+	if request.GetPageToken() != "" {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&eventRow{}).Where("id = ? AND game_id = ?", request.GetPageToken(), request.GetGameUid()).Count(&count).Error; err != nil {
+			s.log.Error("failed to resolve page token", "error", err, "game_id", request.GetGameUid())
+			return nil, status.Error(codes.Internal, fmt.Sprintf("failed to resolve page token: %s", err))
+		}
+		if count == 0 {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+		// compare against the cursor row itself so timestamps never round trip through go
+		query = query.Where("(created_at, id) > (SELECT created_at, id FROM event_rows WHERE id = ?)", request.GetPageToken())
+	}
+
+	// one extra row is fetched to learn whether there is another page
+	limit := int(request.GetPageSize())
+	var rows []eventRow
+	err := query.Order("created_at asc").Order("id asc").Limit(limit + 1).Find(&rows).Error
+	if err != nil {
+		s.log.Error("failed to list events", "error", err, "game_id", request.GetGameUid())
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to list events: %s", err))
+	}
+
+	response := &v1.ListEventsResponse{}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		response.NextPageToken = rows[len(rows)-1].ID
+	}
+
+	response.Events, err = s.toEventRecords(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (s *sqlEventStore) GetRecentEvents(ctx context.Context, gameId string, limit int) ([]*v1.EventRecord, error) {
+	db := s.db.WithContext(ctx)
+
+	var rows []eventRow
+	err := db.Where("game_id = ?", gameId).Order("created_at desc").Limit(limit).Find(&rows).Error
+	if err != nil {
+		s.log.Error("failed to get recent events", "error", err, "game_id", gameId)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to get recent events: %s", err))
+	}
+
+	// rows are fetched newest first to honor the limit but returned oldest first so they read as a transcript
+	slices.Reverse(rows)
+	return s.toEventRecords(ctx, rows)
+}
+
+// GetReceiptsAfter returns every receipt of the game recorded after the given receipt oldest first
+func (s *sqlEventStore) GetReceiptsAfter(ctx context.Context, gameId string, receiptId string) ([]*v1.EventReceipt, error) {
+	db := s.db.WithContext(ctx)
+
+	var count int64
+	if err := db.Model(&eventReceipt{}).Where("id = ? AND game_id = ?", receiptId, gameId).Count(&count).Error; err != nil {
+		s.log.Error("failed to find receipt", "error", err, "receipt_id", receiptId)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to find receipt: %s", err))
+	}
+	if count == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("receipt not found: %s", receiptId))
+	}
+
+	var rows []eventReceipt
+	err := db.Where("game_id = ?", gameId).
+		Where("(created_at, id) > (SELECT created_at, id FROM event_receipts WHERE id = ?)", receiptId).
+		Order("created_at asc").Order("id asc").
+		Find(&rows).Error
+	if err != nil {
+		s.log.Error("failed to get receipts", "error", err, "game_id", gameId)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to get receipts: %s", err))
+	}
+
+	receipts := make([]*v1.EventReceipt, 0, len(rows))
+	for _, row := range rows {
+		receipt := &v1.EventReceipt{}
+		if err := proto.Unmarshal(row.Raw, receipt); err != nil {
+			s.log.Error("failed to unmarshal receipt", "error", err, "receipt_id", row.ID)
+			return nil, status.Error(codes.Internal, fmt.Sprintf("failed to unmarshal receipt: %s", err))
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
+
+func (s *sqlEventStore) toEventRecords(ctx context.Context, rows []eventRow) ([]*v1.EventRecord, error) {
+	records := make([]*v1.EventRecord, 0, len(rows))
+	if len(rows) == 0 {
+		return records, nil
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+
+	var receiptRows []eventReceipt
+	err := s.db.WithContext(ctx).Where("event_id IN ?", ids).Order("created_at asc").Find(&receiptRows).Error
+	if err != nil {
+		s.log.Error("failed to get receipts for events", "error", err)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to get receipts: %s", err))
+	}
+
+	receipts := make(map[string][]*v1.EventReceipt)
+	for _, row := range receiptRows {
+		receipt := &v1.EventReceipt{}
+		if err := proto.Unmarshal(row.Raw, receipt); err != nil {
+			s.log.Error("failed to unmarshal receipt", "error", err, "receipt_id", row.ID)
+			return nil, status.Error(codes.Internal, fmt.Sprintf("failed to unmarshal receipt: %s", err))
+		}
+		receipts[row.EventID] = append(receipts[row.EventID], receipt)
+	}
+
+	for _, row := range rows {
+		event := &v1.Event{}
+		if err := proto.Unmarshal(row.Raw, event); err != nil {
+			s.log.Error("failed to unmarshal event", "error", err, "event_id", row.ID)
+			return nil, status.Error(codes.Internal, fmt.Sprintf("failed to unmarshal event: %s", err))
+		}
+		eventReceipts, ok := receipts[row.ID]
+		if !ok {
+			eventReceipts = make([]*v1.EventReceipt, 0)
+		}
+		records = append(records, &v1.EventRecord{
+			Uid:       row.ID,
+			GameUid:   row.GameID,
+			Payload:   event,
+			Receipts:  eventReceipts,
+			CreatedAt: row.CreatedAt.Unix(),
+		})
+	}
+
+	return records, nil
 }
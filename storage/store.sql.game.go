@@ -34,16 +34,19 @@ func (s sqlGameStore) CreateGame(ctx context.Context, gameObj *v1.Game) error {
 
 	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Save(&game{
-			ID:        gameObj.Uid,
-			Name:      gameObj.Name,
-			ActorID:   gameObj.ActiveActor.Uid,
-			Completed: gameObj.Completed,
+			ID:              gameObj.Uid,
+			Name:            gameObj.Name,
+			ActorID:         gameObj.ActiveActor.Uid,
+			OwnerID:         gameObj.OwnerUid,
+			DungeonMasterID: gameObj.DungeonMasterUid,
+			Seed:            gameObj.Seed,
+			Completed:       gameObj.Completed,
 		}).Error; err != nil {
 			s.log.Error("failed to create game", "error", err)
 			return err
 		}
 
-		return nil
+		return s.saveMembers(tx, gameObj)
 	})
 
 	if err != nil {
@@ -51,21 +54,33 @@ func (s sqlGameStore) CreateGame(ctx context.Context, gameObj *v1.Game) error {
 		return status.Error(codes.Internal, "failed to create game")
 	}
 
+	return nil
+}
+
+// saveMembers replaces the participants and spectators recorded for the game
+func (s sqlGameStore) saveMembers(tx *gorm.DB, gameObj *v1.Game) error {
+	if err := tx.Unscoped().Where("game_id = ?", gameObj.Uid).Delete(&gameParticipant{}).Error; err != nil {
+		s.log.Error("failed to clear game participants", "error", err)
+		return err
+	}
+
 	for _, participant := range gameObj.Participants {
-		err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-			if err := tx.Save(&gameParticipant{
-				GameID:  gameObj.Uid,
-				ActorID: participant.Uid,
-			}).Error; err != nil {
-				s.log.Error("failed to create game participant", "error", err)
-				return err
-			}
-
-			return nil
-		})
-		if err != nil {
-			s.log.Error("failed to create game participant", info.LoggingContext("error", err)...)
-			return status.Error(codes.Internal, "failed to create game participant")
+		if err := tx.Save(&gameParticipant{
+			GameID:  gameObj.Uid,
+			ActorID: participant.Uid,
+		}).Error; err != nil {
+			s.log.Error("failed to save game participant", "error", err)
+			return err
+		}
+	}
+	for _, spectator := range gameObj.Spectators {
+		if err := tx.Save(&gameParticipant{
+			GameID:    gameObj.Uid,
+			ActorID:   spectator.Uid,
+			Spectator: true,
+		}).Error; err != nil {
+			s.log.Error("failed to save game spectator", "error", err)
+			return err
 		}
 	}
 
@@ -104,6 +119,7 @@ func (s sqlGameStore) GetGame(ctx context.Context, uid string) (*v1.Game, error)
 	}
 
 	participantsRet := make([]*v1.Actor, 0)
+	spectatorsRet := make([]*v1.Actor, 0)
 	for _, participant := range participants {
 		actor, err := s.users.GetActor(ctx, participant.ActorID)
 		if err != nil {
@@ -114,16 +130,24 @@ func (s sqlGameStore) GetGame(ctx context.Context, uid string) (*v1.Game, error)
 			s.log.Error("actor not found for game", info.LoggingContext("game", uid, "actor", participant.ActorID)...)
 			return nil, status.Error(codes.NotFound, "failed to get game participant")
 		}
+		if participant.Spectator {
+			spectatorsRet = append(spectatorsRet, actor)
+			continue
+		}
 		participantsRet = append(participantsRet, actor)
 	}
 
 	gameRet := &v1.Game{
-		Uid:          gameObj.ID,
-		Name:         gameObj.Name,
-		Initialized:  gameObj.Initialized,
-		Completed:    gameObj.Completed,
-		ActiveActor:  active,
-		Participants: participantsRet,
+		Uid:              gameObj.ID,
+		Name:             gameObj.Name,
+		Initialized:      gameObj.Initialized,
+		Completed:        gameObj.Completed,
+		ActiveActor:      active,
+		Participants:     participantsRet,
+		Spectators:       spectatorsRet,
+		OwnerUid:         gameObj.OwnerID,
+		DungeonMasterUid: gameObj.DungeonMasterID,
+		Seed:             gameObj.Seed,
 	}
 
 	return gameRet, nil
@@ -137,11 +161,14 @@ func (s sqlGameStore) SaveGame(ctx context.Context, gameObj *v1.Game) error {
 	}
 
 	gameRecord := &game{
-		ID:          gameObj.Uid,
-		Name:        gameObj.Name,
-		ActorID:     gameObj.ActiveActor.Uid,
-		Initialized: gameObj.Initialized,
-		Completed:   gameObj.Completed,
+		ID:              gameObj.Uid,
+		Name:            gameObj.Name,
+		ActorID:         gameObj.ActiveActor.Uid,
+		OwnerID:         gameObj.OwnerUid,
+		DungeonMasterID: gameObj.DungeonMasterUid,
+		Seed:            gameObj.Seed,
+		Initialized:     gameObj.Initialized,
+		Completed:       gameObj.Completed,
 	}
 
 	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
@@ -150,7 +177,7 @@ func (s sqlGameStore) SaveGame(ctx context.Context, gameObj *v1.Game) error {
 			return err
 		}
 
-		return nil
+		return s.saveMembers(tx, gameObj)
 	})
 
 	if err != nil {
@@ -158,23 +185,5 @@ func (s sqlGameStore) SaveGame(ctx context.Context, gameObj *v1.Game) error {
 		return status.Error(codes.Internal, "failed to save game")
 	}
 
-	for _, p := range gameObj.Participants {
-		err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-			if err := tx.Save(&gameParticipant{
-				GameID:  gameObj.Uid,
-				ActorID: p.Uid,
-			}).Error; err != nil {
-				s.log.Error("failed to save game participant", "error", err)
-				return err
-			}
-
-			return nil
-		})
-		if err != nil {
-			s.log.Error("failed to save game participant", info.LoggingContext("error", err)...)
-			return status.Error(codes.Internal, "failed to save game participant")
-		}
-	}
-
 	return nil
 }
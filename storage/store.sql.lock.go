@@ -2,8 +2,10 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	v1 "overseer/build/go"
 	"overseer/common"
+	"sync"
 	"time"
 
 	charm "github.com/charmbracelet/log"
@@ -12,155 +14,357 @@ import (
 	"gorm.io/gorm"
 )
 
-// this sucks plz replace with something robust
+// sqlLockStore hands out per game leases. Waiting and expiry are coordinated in process through channels and timers
+// while every lease is recorded in the database so fencing tokens keep increasing across restarts.
 type sqlLockStore struct {
-	db  *gorm.DB
-	log *charm.Logger
+	db    *gorm.DB
+	mu    sync.Mutex
+	games map[string]*gameLeases
+	log   *charm.Logger
+}
+
+// gameLeases is the lock state of a single game, waiters are granted the lease in the order they asked for it
+type gameLeases struct {
+	holder    *lease
+	waiters   []*leaseWaiter
+	lastToken int64
+}
+
+type lease struct {
+	id       string
+	gameId   string
+	claimId  string
+	token    int64
+	acquired time.Time
+	expires  time.Time
+	timer    *time.Timer
+}
+
+type leaseWaiter struct {
+	claimId string
+	ttl     time.Duration
+	granted chan *lease
 }
 
 func NewSqlLockStore(db *gorm.DB) LockStore {
 	return &sqlLockStore{
-		db:  db,
-		log: common.GetLogger("store.stub.lock"),
+		db:    db,
+		games: make(map[string]*gameLeases),
+		log:   common.GetLogger("store.sql.lock"),
 	}
 }
 
-func (s *sqlLockStore) LockGame(ctx context.Context, request *v1.LockGameRequest) (bool, error) {
+func (s *sqlLockStore) LockGame(ctx context.Context, request *v1.LockGameRequest) (*v1.GameLock, error) {
 	info, err := common.GetContextInformation(ctx)
 	if err != nil {
 		s.log.Error("failed to get context information", "error", err)
-		return false, err
+		return nil, err
 	}
 
-	err = s.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(&lock{
-			ID:        request.ClaimUid,
-			GameID:    request.GameUid,
-			Completed: false,
-		}).Error; err != nil {
-			s.log.Error("failed to create lock", info.LoggingContext("error", err)...)
-			return err
-		}
-		return nil
-	})
+	ttl := leaseDuration(request.GetTtlSeconds())
 
+	s.mu.Lock()
+	game, err := s.gameLeases(ctx, request.GetGameUid())
 	if err != nil {
-		s.log.Error("failed to create lock", info.LoggingContext("error", err)...)
-		return false, err
+		s.mu.Unlock()
+		return nil, err
 	}
 
-	var pending int64
-	var myLock lock
-
-	err = s.db.Where(&lock{GameID: request.GameUid, ID: request.ClaimUid}).First(&myLock).Error
-	if err != nil {
-		s.log.Error("failed to get lock", info.LoggingContext("error", err)...)
-		return false, err
+	if game.holder != nil && game.holder.claimId == request.GetClaimUid() {
+		// locking again with the same claim is idempotent
+		held := s.toGameLock(game, game.holder)
+		s.mu.Unlock()
+		return held, nil
 	}
 
-	pending, err = s.getPendingLocks(ctx, request.GameUid, myLock.CreatedAt)
-	if err != nil {
-		s.log.Error("failed to get pending locks", info.LoggingContext("error", err)...)
-		return false, err
+	if game.holder == nil && len(game.waiters) == 0 {
+		granted, err := s.grant(ctx, game, request.GetGameUid(), request.GetClaimUid(), ttl)
+		if err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+		held := s.toGameLock(game, granted)
+		s.mu.Unlock()
+		s.log.Info("locked game", info.LoggingContext("game", request.GetGameUid(), "claim", request.GetClaimUid(), "token", granted.token)...)
+		return held, nil
 	}
 
-	waiting := pending > 0
-
-	if waiting && !request.Wait {
-		s.log.Warn("game is locked", info.LoggingContext("game", request.GameUid)...)
-		return false, nil
+	if !request.GetWait() {
+		s.mu.Unlock()
+		s.log.Warn("game is locked", info.LoggingContext("game", request.GetGameUid())...)
+		return nil, nil
 	}
 
-	for waiting {
-		select {
-		case <-ctx.Done():
-			s.log.Warn("context cancelled", info.LoggingContext("game", request.GameUid)...)
-			return false, ctx.Err()
-		default:
-			pending, err = s.getPendingLocks(ctx, request.GameUid, myLock.CreatedAt)
-			if err != nil {
-				s.log.Error("failed to get pending locks", info.LoggingContext("error", err)...)
-				return false, err
-			}
+	waiter := &leaseWaiter{
+		claimId: request.GetClaimUid(),
+		ttl:     ttl,
+		granted: make(chan *lease, 1),
+	}
+	game.waiters = append(game.waiters, waiter)
+	s.log.Info("waiting for lock", info.LoggingContext("game", request.GetGameUid(), "claim", request.GetClaimUid(), "position", len(game.waiters))...)
+	s.mu.Unlock()
 
-			if pending == 0 {
-				waiting = false
+	select {
+	case granted := <-waiter.granted:
+		if granted == nil {
+			return nil, status.Error(codes.Internal, "failed to grant lock")
+		}
+		s.mu.Lock()
+		held := s.toGameLock(game, granted)
+		s.mu.Unlock()
+		s.log.Info("locked game", info.LoggingContext("game", request.GetGameUid(), "claim", request.GetClaimUid(), "token", granted.token)...)
+		return held, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for idx, w := range game.waiters {
+			if w == waiter {
+				game.waiters = append(game.waiters[:idx], game.waiters[idx+1:]...)
+				s.log.Warn("stopped waiting for lock", info.LoggingContext("game", request.GetGameUid(), "claim", request.GetClaimUid())...)
+				return nil, ctx.Err()
 			}
 		}
-
-		if waiting {
-			s.log.Info("waiting for lock", info.LoggingContext("game", request.GameUid, "claim", request.ClaimUid, "pending", pending)...)
-			time.Sleep(100 * time.Second)
-		} else {
-			break
+		// the lease was granted as we gave up so hand it straight to the next in line
+		if granted := <-waiter.granted; granted != nil {
+			s.release(game, granted, false)
 		}
+		return nil, ctx.Err()
 	}
+}
 
-	s.log.Info("locking game", info.LoggingContext("game", request.GameUid, "claim", request.ClaimUid, "period", myLock.CreatedAt)...)
+func (s *sqlLockStore) RenewLock(ctx context.Context, request *v1.RenewLockRequest) (*v1.GameLock, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, err
+	}
 
-	err = s.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Model(&lock{}).Where("game_id = ? AND id = ?", request.GameUid, request.ClaimUid).Update("locked", true).Error; err != nil {
-			s.log.Error("failed to complete lock", info.LoggingContext("error", err)...)
-			return err
-		}
-		return nil
-	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, ok := s.games[request.GetGameUid()]
+	if !ok || !holds(game, request.GetClaimUid(), request.GetFencingToken()) {
+		s.log.Warn("lease lost before renewal", info.LoggingContext("game", request.GetGameUid(), "claim", request.GetClaimUid())...)
+		return nil, status.Error(codes.FailedPrecondition, "lock is no longer held")
+	}
+
+	held := game.holder
+	held.expires = time.Now().Add(leaseDuration(request.GetTtlSeconds()))
+	held.timer.Reset(time.Until(held.expires))
+	err = s.db.WithContext(ctx).Model(&lock{}).Where("id = ?", held.id).Update("expires_at", held.expires).Error
 	if err != nil {
-		s.log.Error("failed to complete lock", info.LoggingContext("error", err, "game", request.GameUid, "claim", request.ClaimUid)...)
-		return false, err
+		s.log.Error("failed to renew lock", info.LoggingContext("error", err, "game", request.GetGameUid())...)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to renew lock: %s", err))
 	}
 
-	return true, nil
+	s.log.Debug("renewed lock", info.LoggingContext("game", request.GetGameUid(), "claim", request.GetClaimUid(), "expires", held.expires)...)
+	return s.toGameLock(game, held), nil
 }
 
-func (s *sqlLockStore) getPendingLocks(ctx context.Context, gameId string, period time.Time) (int64, error) {
+func (s *sqlLockStore) UnlockGame(ctx context.Context, request *v1.UnlockGameRequest) (bool, error) {
 	info, err := common.GetContextInformation(ctx)
 	if err != nil {
 		s.log.Error("failed to get context information", "error", err)
-		return 0, err
+		return false, err
 	}
 
-	s.log.Debug("getting pending locks", info.LoggingContext("game", gameId, "period", period)...)
-	var count int64
-	err = s.db.Model(&lock{}).Where("game_id = ? AND created_at < ? AND completed = false", gameId, period).Count(&count).Error
+	s.log.Debug("unlocking game", info.LoggingContext("game", request.GameUid, "claim", request.ClaimUid)...)
 
-	if err != nil {
-		s.log.Error("failed to get pending locks", info.LoggingContext("error", err, "game", gameId)...)
-		return 0, status.Error(codes.Internal, "failed to get pending locks")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, ok := s.games[request.GetGameUid()]
+	if !ok || !holds(game, request.GetClaimUid(), request.GetFencingToken()) {
+		s.log.Warn("unlock requested for a lease that is not held", info.LoggingContext("game", request.GetGameUid(), "claim", request.GetClaimUid())...)
+		return false, nil
 	}
 
-	return count, nil
+	s.release(game, game.holder, false)
+	s.log.Info("unlocked game", info.LoggingContext("game", request.GameUid, "claim", request.ClaimUid)...)
+
+	return true, nil
 }
 
-func (s *sqlLockStore) UnlockGame(ctx context.Context, request *v1.UnlockGameRequest) (bool, error) {
+func (s *sqlLockStore) ListLocks(ctx context.Context, gameId string) ([]*v1.GameLock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	locks := make([]*v1.GameLock, 0)
+	for id, game := range s.games {
+		if gameId != "" && id != gameId {
+			continue
+		}
+		if game.holder != nil {
+			locks = append(locks, s.toGameLock(game, game.holder))
+		}
+	}
+	return locks, nil
+}
+
+func (s *sqlLockStore) BreakLock(ctx context.Context, gameId string) (*v1.GameLock, error) {
 	info, err := common.GetContextInformation(ctx)
 	if err != nil {
 		s.log.Error("failed to get context information", "error", err)
-		return false, err
+		return nil, err
 	}
 
-	s.log.Debug("unlocking game", info.LoggingContext("game", request.GameUid, "claim", request.ClaimUid)...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, ok := s.games[gameId]
+	if !ok || game.holder == nil {
+		return nil, nil
+	}
+
+	broken := s.toGameLock(game, game.holder)
+	s.release(game, game.holder, true)
+	s.log.Warn("lock broken", info.LoggingContext("game", gameId, "claim", broken.GetClaimUid(), "token", broken.GetFencingToken())...)
+	return broken, nil
+}
+
+// gameLeases loads the lock state for a game, the first time a game is seen any leases left behind by a previous process are closed
+// must be called with the mutex held
+func (s *sqlLockStore) gameLeases(ctx context.Context, gameId string) (*gameLeases, error) {
+	if game, ok := s.games[gameId]; ok {
+		return game, nil
+	}
 
 	db := s.db.WithContext(ctx)
+	var lastToken int64
+	err := db.Model(&lock{}).Where("game_id = ?", gameId).Select("COALESCE(MAX(fencing_token), 0)").Scan(&lastToken).Error
+	if err != nil {
+		s.log.Error("failed to load fencing token", "error", err, "game", gameId)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to load locks: %s", err))
+	}
+	err = db.Model(&lock{}).Where("game_id = ? AND completed = false", gameId).Updates(map[string]interface{}{"locked": false, "completed": true}).Error
+	if err != nil {
+		s.log.Error("failed to clear stale locks", "error", err, "game", gameId)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to clear stale locks: %s", err))
+	}
 
-	err = db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Save(&lock{
-			ID:        request.ClaimUid,
-			GameID:    request.GameUid,
-			Completed: true,
-			Locked:    false,
-		}).Error; err != nil {
-			s.log.Error("failed to unlock game", info.LoggingContext("error", err, "game", request.GameUid, "claim", request.ClaimUid)...)
-			return err
-		}
-		return nil
+	game := &gameLeases{lastToken: lastToken, waiters: make([]*leaseWaiter, 0)}
+	s.games[gameId] = game
+	return game, nil
+}
+
+// grant hands the game to the claim with the next fencing token, must be called with the mutex held
+func (s *sqlLockStore) grant(ctx context.Context, game *gameLeases, gameId string, claimId string, ttl time.Duration) (*lease, error) {
+	now := time.Now()
+	granted := &lease{
+		gameId:   gameId,
+		claimId:  claimId,
+		token:    game.lastToken + 1,
+		acquired: now,
+		expires:  now.Add(ttl),
+	}
+	granted.id = common.GenerateRandomStringFromSeed(gameId, claimId, fmt.Sprintf("%d", granted.token))
+
+	err := s.db.WithContext(ctx).Create(&lock{
+		ID:           granted.id,
+		GameID:       gameId,
+		ClaimID:      claimId,
+		FencingToken: granted.token,
+		ExpiresAt:    granted.expires,
+		Locked:       true,
+	}).Error
+	if err != nil {
+		s.log.Error("failed to record lock", "error", err, "game", gameId, "claim", claimId)
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to record lock: %s", err))
+	}
+
+	game.lastToken = granted.token
+	game.holder = granted
+	granted.timer = time.AfterFunc(ttl, func() {
+		s.expire(granted)
 	})
+	return granted, nil
+}
+
+// release ends the lease and wakes the next waiter, must be called with the mutex held
+func (s *sqlLockStore) release(game *gameLeases, held *lease, broken bool) {
+	if game.holder != held {
+		return
+	}
+	held.timer.Stop()
+	game.holder = nil
+
+	err := s.db.Model(&lock{}).Where("id = ?", held.id).Updates(map[string]interface{}{
+		"locked":    false,
+		"completed": true,
+		"broken":    broken,
+	}).Error
 	if err != nil {
-		s.log.Error("failed to unlock game", info.LoggingContext("error", err, "game", request.GameUid, "claim", request.ClaimUid)...)
-		return false, err
+		// the in process state is authoritative, the row is only history
+		s.log.Error("failed to record release", "error", err, "game", held.gameId, "claim", held.claimId)
 	}
 
-	s.log.Info("unlocked game", info.LoggingContext("game", request.GameUid, "claim", request.ClaimUid)...)
+	for len(game.waiters) > 0 {
+		next := game.waiters[0]
+		game.waiters = game.waiters[1:]
+		granted, err := s.grant(context.Background(), game, held.gameId, next.claimId, next.ttl)
+		if err != nil {
+			next.granted <- nil
+			continue
+		}
+		next.granted <- granted
+		return
+	}
+}
 
-	return true, nil
+func (s *sqlLockStore) expire(held *lease) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, ok := s.games[held.gameId]
+	if !ok || game.holder != held || time.Now().Before(held.expires) {
+		return
+	}
+	s.log.Warn("lease expired", "game", held.gameId, "claim", held.claimId, "token", held.token)
+	s.release(game, held, false)
+}
+
+// toGameLock must be called with the mutex held
+func (s *sqlLockStore) toGameLock(game *gameLeases, held *lease) *v1.GameLock {
+	return &v1.GameLock{
+		GameUid:      held.gameId,
+		ClaimUid:     held.claimId,
+		FencingToken: held.token,
+		AcquiredAtMs: held.acquired.UnixMilli(),
+		ExpiresAtMs:  held.expires.UnixMilli(),
+		Waiting:      int32(len(game.waiters)),
+	}
+}
+
+// holds reports whether the claim (and token when provided) is the current holder of the game
+func holds(game *gameLeases, claimId string, token int64) bool {
+	if game.holder == nil || game.holder.claimId != claimId {
+		return false
+	}
+	return token == 0 || game.holder.token == token
+}
+
+func leaseDuration(ttlSeconds int64) time.Duration {
+	if ttlSeconds <= 0 {
+		ttlSeconds = common.GetConfiguration().Locks.LeaseSeconds
+	}
+	return time.Duration(ttlSeconds) * time.Second
+}
+
+// checkFence refuses a write made under a lease once the game has been granted to a newer one,
+// writes made outside the event bus carry no lease and are not fenced
+func checkFence(ctx context.Context, tx *gorm.DB) error {
+	held, ok := common.GetGameLease(ctx)
+	if !ok {
+		return nil
+	}
+
+	var lastToken int64
+	err := tx.Model(&lock{}).Where("game_id = ?", held.GameId).Select("COALESCE(MAX(fencing_token), 0)").Scan(&lastToken).Error
+	if err != nil {
+		return err
+	}
+	if lastToken > held.FencingToken {
+		return status.Error(codes.FailedPrecondition, "the game lock was lost")
+	}
+	return nil
 }
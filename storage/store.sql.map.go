@@ -8,7 +8,9 @@ import (
 	charm "github.com/charmbracelet/log"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type sqlMapStore struct {
@@ -42,6 +44,7 @@ func (s *sqlMapStore) CreateMap(ctx context.Context, req *v1.CreateMapRequest) (
 		Name:    req.Name,
 		MaxX:    req.MaxX,
 		MaxY:    req.MaxY,
+		Seed:    req.GetSeed(),
 	}
 	record, err := MapRecordFromProto(newMap)
 	if err != nil {
@@ -125,7 +128,12 @@ func (s *sqlMapStore) CreateCoordinate(ctx context.Context, coordinate *v1.MapCo
 		return status.Error(codes.Internal, "failed to make new map coordinate record")
 	}
 
-	err = s.db.Create(&record).Error
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		return indexActors(tx, coordinate)
+	})
 	if err != nil {
 		s.log.Error("failed to create map coordinate", info.LoggingContext(
 			"error", err,
@@ -183,10 +191,647 @@ func (s *sqlMapStore) GetCoordinates(ctx context.Context, mapId string) ([]*v1.M
 	return pb, nil
 }
 
+func (s *sqlMapStore) GetMapForGame(ctx context.Context, gameId string) (*v1.Map, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Debug("fetching map for game", info.LoggingContext(
+		"game", gameId,
+	)...)
+	var record gameMap
+	err = s.db.WithContext(ctx).Where("game_id = ?", gameId).Order("created_at desc").First(&record).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, status.Error(codes.NotFound, "map not found for game")
+		}
+		s.log.Error("failed to fetch map for game", info.LoggingContext(
+			"error", err,
+			"game", gameId,
+		)...)
+		return nil, status.Error(codes.Internal, "failed to fetch map for game")
+	}
+
+	return record.ToProto()
+}
+
 func (s *sqlMapStore) UpdateCoordinate(ctx context.Context, coordinate *v1.MapCoordinateDetail) error {
-	return status.Error(codes.Unimplemented, "method UpdateSprite not implemented")
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.log.Debug("updating map coordinate", info.LoggingContext(
+		"game", coordinate.GameUid,
+		"map", coordinate.MapUid,
+		"coordinate", coordinate.Uid,
+	)...)
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkFence(ctx, tx); err != nil {
+			return err
+		}
+		var current mapCoordinate
+		err := tx.Where("id = ?", coordinate.Uid).First(&current).Error
+		if err == gorm.ErrRecordNotFound {
+			return status.Error(codes.NotFound, "map coordinate not found")
+		}
+		if err != nil {
+			return err
+		}
+		stored, err := current.ToProto()
+		if err != nil {
+			return err
+		}
+
+		update := keepOccupants(coordinate, stored)
+		record, err := MapCoordinateRecordFromProto(update)
+		if err != nil {
+			return err
+		}
+		return saveCoordinate(tx, record, update)
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		s.log.Error("failed to update map coordinate", info.LoggingContext(
+			"error", err,
+			"coordinate", coordinate.Uid,
+		)...)
+		return status.Error(codes.Internal, "failed to update map coordinate")
+	}
+
+	return nil
+}
+
+// keepOccupants takes who stands on the coordinate from what is stored rather than the update,
+// actors only come and go through MoveActor so a copy read before they moved cannot put them back
+func keepOccupants(update *v1.MapCoordinateDetail, stored *v1.MapCoordinateDetail) *v1.MapCoordinateDetail {
+	merged := proto.Clone(update).(*v1.MapCoordinateDetail)
+	merged.Actors = stored.Actors
+
+	present := make(map[string]bool)
+	for _, actor := range stored.Actors {
+		present[actor.GetUid()] = true
+	}
+	sprites := make([]*v1.Sprite, 0, len(merged.Sprites))
+	updated := make(map[string]bool)
+	for _, sprite := range merged.Sprites {
+		if sprite.GetActor() != nil && !present[sprite.GetActor().GetUid()] {
+			continue
+		}
+		updated[sprite.GetUid()] = true
+		sprites = append(sprites, sprite)
+	}
+	// actors who arrived since the update was read bring their sprites with them
+	for _, sprite := range stored.Sprites {
+		if sprite.GetActor() != nil && !updated[sprite.GetUid()] {
+			sprites = append(sprites, sprite)
+		}
+	}
+	merged.Sprites = sprites
+	return merged
+}
+
+// saveCoordinate writes the coordinate and the positions of the actors standing on it
+func saveCoordinate(tx *gorm.DB, record *mapCoordinate, coordinate *v1.MapCoordinateDetail) error {
+	result := tx.Model(&mapCoordinate{}).Where("id = ?", record.ID).Updates(map[string]interface{}{
+		"type":              record.Type,
+		"difficult_terrain": record.DifficultTerrain,
+		"lore":              record.Lore,
+		"raw":               record.Raw,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return status.Error(codes.NotFound, "map coordinate not found")
+	}
+
+	// actors that left the coordinate are dropped, those still on it are written again below
+	err := tx.Unscoped().Where("coordinate_id = ?", record.ID).Delete(&actorPosition{}).Error
+	if err != nil {
+		return err
+	}
+	return indexActors(tx, coordinate)
+}
+
+// indexActors records the actors of the coordinate as standing on it, replacing wherever they stood on the map before
+func indexActors(tx *gorm.DB, coordinate *v1.MapCoordinateDetail) error {
+	positions := actorPositionsFromProto(coordinate)
+	if len(positions) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "game_map_id"}, {Name: "actor_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"game_id", "coordinate_id", "x", "y", "updated_at", "deleted_at"}),
+	}).Create(&positions).Error
+}
+
+// backfillActorPositions indexes every actor standing on a coordinate written before the position index existed
+func backfillActorPositions(db *gorm.DB) error {
+	var batch []mapCoordinate
+	return db.FindInBatches(&batch, 500, func(_ *gorm.DB, _ int) error {
+		for _, record := range batch {
+			coordinate, err := record.ToProto()
+			if err != nil {
+				return err
+			}
+			if err = indexActors(db, coordinate); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
 }
 
 func (s *sqlMapStore) GetCoordinate(ctx context.Context, gameId string, mapId string, x int64, y int64) (*v1.MapCoordinateDetail, error) {
-	return nil, status.Error(codes.Unimplemented, "method GetCoordinate not implemented")
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Debug("fetching map coordinate", info.LoggingContext(
+		"game", gameId,
+		"map", mapId,
+		"x", x,
+		"y", y,
+	)...)
+	var record mapCoordinate
+	err = s.db.WithContext(ctx).Where("game_id = ? AND game_map_id = ? AND x = ? AND y = ?", gameId, mapId, x, y).First(&record).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, status.Error(codes.NotFound, "map coordinate not found")
+		}
+		s.log.Error("failed to fetch map coordinate", info.LoggingContext(
+			"error", err,
+			"map", mapId,
+			"x", x,
+			"y", y,
+		)...)
+		return nil, status.Error(codes.Internal, "failed to fetch map coordinate")
+	}
+
+	return record.ToProto()
+}
+
+func (s *sqlMapStore) GetActorCoordinate(ctx context.Context, mapId string, actorId string) (*v1.MapCoordinateDetail, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Debug("fetching actor coordinate", info.LoggingContext(
+		"map", mapId,
+		"actor", actorId,
+	)...)
+	var record mapCoordinate
+	err = s.db.WithContext(ctx).
+		Joins("JOIN actor_positions ON actor_positions.coordinate_id = map_coordinates.id AND actor_positions.deleted_at IS NULL").
+		Where("actor_positions.game_map_id = ? AND actor_positions.actor_id = ?", mapId, actorId).
+		First(&record).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			s.log.Warn("actor not found on map", info.LoggingContext(
+				"map", mapId,
+				"actor", actorId,
+			)...)
+			return nil, status.Error(codes.NotFound, "actor not found on map")
+		}
+		s.log.Error("failed to fetch actor coordinate", info.LoggingContext(
+			"error", err,
+			"map", mapId,
+			"actor", actorId,
+		)...)
+		return nil, status.Error(codes.Internal, "failed to fetch actor coordinate")
+	}
+
+	return record.ToProto()
+}
+
+func (s *sqlMapStore) GetActorPosition(ctx context.Context, actorId string) (*v1.MapPosition, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Debug("fetching actor position", info.LoggingContext("actor", actorId)...)
+	var record actorPosition
+	err = s.db.WithContext(ctx).Where("actor_id = ?", actorId).Order("updated_at desc").First(&record).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, status.Error(codes.NotFound, "actor is not on a map")
+		}
+		s.log.Error("failed to fetch actor position", info.LoggingContext(
+			"error", err,
+			"actor", actorId,
+		)...)
+		return nil, status.Error(codes.Internal, "failed to fetch actor position")
+	}
+
+	return record.ToProto(), nil
+}
+
+func (s *sqlMapStore) MoveActor(
+	ctx context.Context,
+	mapId string,
+	actorId string,
+	target *v1.MapPosition,
+	move func(origin *v1.MapCoordinateDetail, destination *v1.MapCoordinateDetail) error,
+) (*v1.MapCoordinateDetail, *v1.MapCoordinateDetail, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.log.Debug("moving actor", info.LoggingContext(
+		"map", mapId,
+		"actor", actorId,
+		"x", target.GetX(),
+		"y", target.GetY(),
+	)...)
+	var origin, destination *v1.MapCoordinateDetail
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkFence(ctx, tx); err != nil {
+			return err
+		}
+		var position actorPosition
+		err := tx.Where("game_map_id = ? AND actor_id = ?", mapId, actorId).First(&position).Error
+		if err == gorm.ErrRecordNotFound {
+			return status.Error(codes.NotFound, "actor not found on map")
+		}
+		if err != nil {
+			return err
+		}
+
+		var originRecord, destinationRecord mapCoordinate
+		if err = tx.Where("id = ?", position.CoordinateID).First(&originRecord).Error; err != nil {
+			return err
+		}
+		err = tx.Where("game_map_id = ? AND x = ? AND y = ?", mapId, target.GetX(), target.GetY()).First(&destinationRecord).Error
+		if err == gorm.ErrRecordNotFound {
+			return status.Error(codes.NotFound, "map coordinate not found")
+		}
+		if err != nil {
+			return err
+		}
+
+		if origin, err = originRecord.ToProto(); err != nil {
+			return err
+		}
+		if destination, err = destinationRecord.ToProto(); err != nil {
+			return err
+		}
+		if err = move(origin, destination); err != nil {
+			return err
+		}
+
+		for _, coordinate := range []*v1.MapCoordinateDetail{origin, destination} {
+			record, err := MapCoordinateRecordFromProto(coordinate)
+			if err != nil {
+				return err
+			}
+			if err = saveCoordinate(tx, record, coordinate); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, nil, err
+		}
+		s.log.Error("failed to move actor", info.LoggingContext(
+			"error", err,
+			"map", mapId,
+			"actor", actorId,
+		)...)
+		return nil, nil, status.Error(codes.Internal, "failed to move actor")
+	}
+
+	return origin, destination, nil
+}
+
+func (s *sqlMapStore) PlaceActor(ctx context.Context, mapId string, actor *v1.Actor, target *v1.MapPosition) (*v1.MapCoordinateDetail, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Debug("placing actor", info.LoggingContext(
+		"map", mapId,
+		"actor", actor.GetUid(),
+		"x", target.GetX(),
+		"y", target.GetY(),
+	)...)
+	var destination *v1.MapCoordinateDetail
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkFence(ctx, tx); err != nil {
+			return err
+		}
+		var placed int64
+		err := tx.Model(&actorPosition{}).Where("game_map_id = ? AND actor_id = ?", mapId, actor.GetUid()).Count(&placed).Error
+		if err != nil {
+			return err
+		}
+		if placed > 0 {
+			return status.Error(codes.AlreadyExists, "actor is already on the map")
+		}
+
+		var record mapCoordinate
+		err = tx.Where("game_map_id = ? AND x = ? AND y = ?", mapId, target.GetX(), target.GetY()).First(&record).Error
+		if err == gorm.ErrRecordNotFound {
+			return status.Error(codes.NotFound, "map coordinate not found")
+		}
+		if err != nil {
+			return err
+		}
+		if destination, err = record.ToProto(); err != nil {
+			return err
+		}
+
+		// the actor arrives from nowhere, transferring them gives them a sprite the same as any other step
+		common.TransferActor(&v1.MapCoordinateDetail{Actors: []*v1.Actor{actor}}, destination, actor.GetUid())
+		updated, err := MapCoordinateRecordFromProto(destination)
+		if err != nil {
+			return err
+		}
+		return saveCoordinate(tx, updated, destination)
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		s.log.Error("failed to place actor", info.LoggingContext(
+			"error", err,
+			"map", mapId,
+			"actor", actor.GetUid(),
+		)...)
+		return nil, status.Error(codes.Internal, "failed to place actor")
+	}
+
+	return destination, nil
+}
+
+func (s *sqlMapStore) CountCoordinates(ctx context.Context, mapId string) (int64, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = s.db.WithContext(ctx).Model(&mapCoordinate{}).Where("game_map_id = ?", mapId).Count(&count).Error
+	if err != nil {
+		s.log.Error("failed to count map coordinates", info.LoggingContext(
+			"error", err,
+			"map", mapId,
+		)...)
+		return 0, status.Error(codes.Internal, "failed to count map coordinates")
+	}
+
+	return count, nil
+}
+
+func (s *sqlMapStore) GetCoordinatesWithin(ctx context.Context, mapId string, center *v1.MapPosition, radius int64) ([]*v1.MapCoordinateDetail, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []mapCoordinate
+	err = s.db.WithContext(ctx).
+		Where("game_map_id = ? AND x BETWEEN ? AND ? AND y BETWEEN ? AND ?",
+			mapId, center.GetX()-radius, center.GetX()+radius, center.GetY()-radius, center.GetY()+radius).
+		Find(&records).Error
+	if err != nil {
+		s.log.Error("failed to fetch map coordinates within radius", info.LoggingContext(
+			"error", err,
+			"map", mapId,
+			"x", center.GetX(),
+			"y", center.GetY(),
+			"radius", radius,
+		)...)
+		return nil, status.Error(codes.Internal, "failed to fetch map coordinates")
+	}
+
+	coordinates := make([]*v1.MapCoordinateDetail, 0, len(records))
+	for _, record := range records {
+		coordinate, err := record.ToProto()
+		if err != nil {
+			return nil, err
+		}
+		coordinates = append(coordinates, coordinate)
+	}
+	return coordinates, nil
+}
+
+func (s *sqlMapStore) DiscoverCoordinates(ctx context.Context, mapId string, actorId string, seen []*v1.MapCoordinateDetail, visited *v1.MapCoordinateDetail) error {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.log.Debug("discovering map coordinates", info.LoggingContext(
+		"map", mapId,
+		"actor", actorId,
+		"seen", len(seen),
+	)...)
+	discovered := make([]discoveredCoordinate, 0, len(seen)+1)
+	includesVisited := false
+	for _, coordinate := range seen {
+		isVisited := visited != nil && coordinate.GetUid() == visited.GetUid()
+		includesVisited = includesVisited || isVisited
+		discovered = append(discovered, discoveredCoordinate{
+			GameMapID:    mapId,
+			ActorID:      actorId,
+			CoordinateID: coordinate.GetUid(),
+			Visited:      isVisited,
+		})
+	}
+	if visited != nil && !includesVisited {
+		discovered = append(discovered, discoveredCoordinate{
+			GameMapID:    mapId,
+			ActorID:      actorId,
+			CoordinateID: visited.GetUid(),
+			Visited:      true,
+		})
+	}
+	if len(discovered) == 0 {
+		return nil
+	}
+
+	// a coordinate once visited stays visited however often it is only seen afterwards
+	err = s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "game_map_id"}, {Name: "actor_id"}, {Name: "coordinate_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"visited":    gorm.Expr("discovered_coordinates.visited OR excluded.visited"),
+			"updated_at": gorm.Expr("excluded.updated_at"),
+		}),
+	}).Create(&discovered).Error
+	if err != nil {
+		s.log.Error("failed to discover map coordinates", info.LoggingContext(
+			"error", err,
+			"map", mapId,
+			"actor", actorId,
+		)...)
+		return status.Error(codes.Internal, "failed to discover map coordinates")
+	}
+
+	return nil
+}
+
+func (s *sqlMapStore) GetDiscoveredCoordinates(ctx context.Context, mapId string, actorId string) ([]*v1.KnownCoordinate, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []struct {
+		Raw     []byte
+		Visited bool
+	}
+	err = s.db.WithContext(ctx).Model(&mapCoordinate{}).
+		Select("map_coordinates.raw, discovered_coordinates.visited").
+		Joins("JOIN discovered_coordinates ON discovered_coordinates.coordinate_id = map_coordinates.id AND discovered_coordinates.deleted_at IS NULL").
+		Where("discovered_coordinates.game_map_id = ? AND discovered_coordinates.actor_id = ?", mapId, actorId).
+		Order("map_coordinates.y asc").Order("map_coordinates.x asc").
+		Find(&records).Error
+	if err != nil {
+		s.log.Error("failed to fetch discovered map coordinates", info.LoggingContext(
+			"error", err,
+			"map", mapId,
+			"actor", actorId,
+		)...)
+		return nil, status.Error(codes.Internal, "failed to fetch discovered map coordinates")
+	}
+
+	known := make([]*v1.KnownCoordinate, 0, len(records))
+	for _, record := range records {
+		coordinate, err := (&mapCoordinate{Raw: record.Raw}).ToProto()
+		if err != nil {
+			return nil, err
+		}
+		known = append(known, &v1.KnownCoordinate{
+			Coordinate: coordinate,
+			Visibility: v1.KnownCoordinate_REMEMBERED,
+			Visited:    record.Visited,
+		})
+	}
+	return known, nil
+}
+
+func (s *sqlMapStore) HasDiscovered(ctx context.Context, mapId string, actorId string, coordinateId string) (bool, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	err = s.db.WithContext(ctx).Model(&discoveredCoordinate{}).
+		Where("game_map_id = ? AND actor_id = ? AND coordinate_id = ?", mapId, actorId, coordinateId).
+		Count(&count).Error
+	if err != nil {
+		s.log.Error("failed to check discovered map coordinate", info.LoggingContext(
+			"error", err,
+			"map", mapId,
+			"actor", actorId,
+			"coordinate", coordinateId,
+		)...)
+		return false, status.Error(codes.Internal, "failed to check discovered map coordinate")
+	}
+	return count > 0, nil
+}
+
+func (s *sqlMapStore) SaveMapGeneration(ctx context.Context, job *v1.MapGenerationJob) error {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.log.Debug("saving map generation", info.LoggingContext(
+		"map", job.MapUid,
+		"state", job.State,
+	)...)
+	record, err := MapGenerationJobRecordFromProto(job)
+	if err != nil {
+		s.log.Error("failed to make map generation record", info.LoggingContext(
+			"error", err,
+			"map", job.MapUid,
+		)...)
+		return err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing mapGenerationJob
+		err := tx.Where("id = ?", record.ID).First(&existing).Error
+		if err == gorm.ErrRecordNotFound {
+			return tx.Create(record).Error
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&existing).Updates(map[string]interface{}{
+			"state": record.State,
+			"raw":   record.Raw,
+		}).Error
+	})
+	if err != nil {
+		s.log.Error("failed to save map generation", info.LoggingContext(
+			"error", err,
+			"map", job.MapUid,
+		)...)
+		return status.Error(codes.Internal, "failed to save map generation")
+	}
+
+	return nil
+}
+
+func (s *sqlMapStore) GetMapGeneration(ctx context.Context, mapId string) (*v1.MapGenerationJob, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var record mapGenerationJob
+	err = s.db.WithContext(ctx).Where("id = ?", mapId).First(&record).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, status.Error(codes.NotFound, "map generation not found")
+		}
+		s.log.Error("failed to fetch map generation", info.LoggingContext(
+			"error", err,
+			"map", mapId,
+		)...)
+		return nil, status.Error(codes.Internal, "failed to fetch map generation")
+	}
+
+	return record.ToProto()
+}
+
+func (s *sqlMapStore) ListMapGenerations(ctx context.Context, state v1.MapGenerationStatus_State) ([]*v1.MapGenerationJob, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []mapGenerationJob
+	err = s.db.WithContext(ctx).Where("state = ?", state.String()).Order("created_at asc").Find(&records).Error
+	if err != nil {
+		s.log.Error("failed to list map generations", info.LoggingContext(
+			"error", err,
+			"state", state,
+		)...)
+		return nil, status.Error(codes.Internal, "failed to list map generations")
+	}
+
+	jobs := make([]*v1.MapGenerationJob, 0, len(records))
+	for _, record := range records {
+		job, err := record.ToProto()
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	v1 "overseer/build/go"
 	"overseer/common"
+	"time"
 
 	charm "github.com/charmbracelet/log"
 	"google.golang.org/grpc/codes"
@@ -71,6 +72,7 @@ func (s *sqlUserStore) UpsertActor(ctx context.Context, userId string, actorMsg
 
 	row := &actor{
 		ID:             actorMsg.GetUid(),
+		UserID:         user.GetUid(),
 		SourceIdentity: actorMsg.GetSourceIdentity(),
 		Source:         actorMsg.GetSource(),
 		Raw:            raw,
@@ -111,7 +113,29 @@ func (s *sqlUserStore) GetUser(ctx context.Context, id string) (*v1.User, error)
 }
 
 func (s *sqlUserStore) GetUserForActor(ctx context.Context, actorID string) (*v1.User, error) {
-	return nil, status.Error(codes.Unimplemented, "GetUserForActor not implemented")
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, status.Error(codes.Unauthenticated, "failed to get context information")
+	}
+
+	s.log.Info("getting user for actor", info.LoggingContext("actor_id", actorID)...)
+
+	row := &actor{}
+	err = s.db.Where("id = ?", actorID).First(&row).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, status.Error(codes.NotFound, "actor not found")
+		}
+
+		s.log.Error("failed to get actor", info.LoggingContext("error", err)...)
+		return nil, status.Error(codes.Internal, "failed to get actor")
+	}
+	if row.UserID == "" {
+		return nil, status.Error(codes.NotFound, "actor has no user")
+	}
+
+	return s.GetUser(ctx, row.UserID)
 }
 
 func (s *sqlUserStore) GetActor(ctx context.Context, id string) (*v1.Actor, error) {
@@ -145,7 +169,62 @@ func (s *sqlUserStore) GetActor(ctx context.Context, id string) (*v1.Actor, erro
 }
 
 func (s *sqlUserStore) GetActors(ctx context.Context, id string) ([]*v1.Actor, error) {
-	return nil, status.Error(codes.Unimplemented, "GetActors not implemented")
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, status.Error(codes.Unauthenticated, "failed to get context information")
+	}
+
+	s.log.Info("getting actors", info.LoggingContext("user_id", id)...)
+
+	rows := make([]actor, 0)
+	err = s.db.Where("user_id = ?", id).Order("created_at ASC").Find(&rows).Error
+	if err != nil {
+		s.log.Error("failed to get actors", info.LoggingContext("error", err)...)
+		return nil, status.Error(codes.Internal, "failed to get actors")
+	}
+
+	actors := make([]*v1.Actor, 0, len(rows))
+	for _, row := range rows {
+		actorMsg := &v1.Actor{}
+		if err = proto.Unmarshal(row.Raw, actorMsg); err != nil {
+			s.log.Error("failed to unmarshal actor", info.LoggingContext("error", err)...)
+			return nil, status.Error(codes.Internal, "failed to unmarshal actor")
+		}
+		actors = append(actors, actorMsg)
+	}
+
+	return actors, nil
+}
+
+func (s *sqlUserStore) GetActorBySource(ctx context.Context, source v1.Actor_Source, sourceIdentity string) (*v1.Actor, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, status.Error(codes.Unauthenticated, "failed to get context information")
+	}
+
+	s.log.Info("getting actor by source", info.LoggingContext("actor_source", source.String(), "actor_source_identity", sourceIdentity)...)
+
+	row := &actor{}
+	err = s.db.Where("source = ? AND source_identity = ?", source, sourceIdentity).Order("created_at ASC").First(&row).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, status.Error(codes.NotFound, "actor not found")
+		}
+
+		s.log.Error("failed to get actor", info.LoggingContext("error", err)...)
+		return nil, status.Error(codes.Internal, "failed to get actor")
+	}
+
+	actorMsg := &v1.Actor{}
+	err = proto.Unmarshal(row.Raw, actorMsg)
+	if err != nil {
+		s.log.Error("failed to unmarshal actor", info.LoggingContext("error", err)...)
+		return nil, status.Error(codes.Internal, "failed to unmarshal actor")
+	}
+
+	return actorMsg, nil
 }
 
 func (s *sqlUserStore) DeleteUser(ctx context.Context, id string) error {
@@ -155,3 +234,151 @@ func (s *sqlUserStore) DeleteUser(ctx context.Context, id string) error {
 func (s *sqlUserStore) DeleteActor(ctx context.Context, id string) error {
 	return status.Error(codes.Unimplemented, "DeleteActor not implemented")
 }
+
+func (s *sqlUserStore) MergeUsers(ctx context.Context, fromUserId string, toUserId string) error {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return status.Error(codes.Unauthenticated, "failed to get context information")
+	}
+
+	s.log.Info("merging users", info.LoggingContext("from_user_id", fromUserId, "to_user_id", toUserId)...)
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&actor{}).Where("user_id = ?", fromUserId).Update("user_id", toUserId).Error; err != nil {
+			return err
+		}
+		return tx.Model(&apiKey{}).Where("user_id = ?", fromUserId).Update("user_id", toUserId).Error
+	})
+	if err != nil {
+		s.log.Error("failed to merge users", info.LoggingContext("error", err)...)
+		return status.Error(codes.Internal, "failed to merge users")
+	}
+
+	return nil
+}
+
+func (s *sqlUserStore) MoveActor(ctx context.Context, actorId string, toUserId string) error {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return status.Error(codes.Unauthenticated, "failed to get context information")
+	}
+
+	s.log.Info("moving actor", info.LoggingContext("actor_id", actorId, "to_user_id", toUserId)...)
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&actor{}).Where("id = ?", actorId).Update("user_id", toUserId)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Model(&apiKey{}).Where("actor_id = ?", actorId).Update("user_id", toUserId).Error
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return status.Error(codes.NotFound, "actor not found")
+		}
+		s.log.Error("failed to move actor", info.LoggingContext("error", err)...)
+		return status.Error(codes.Internal, "failed to move actor")
+	}
+
+	return nil
+}
+
+func (s *sqlUserStore) CreateLinkCode(ctx context.Context, code *v1.LinkCode) error {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return status.Error(codes.Unauthenticated, "failed to get context information")
+	}
+
+	s.log.Info("creating link code", info.LoggingContext("user_id", code.GetUserId(), "actor_id", code.GetActorId())...)
+
+	row := &linkCode{
+		Code:      code.GetCode(),
+		UserID:    code.GetUserId(),
+		ActorID:   code.GetActorId(),
+		ExpiresAt: time.Unix(code.GetExpiresAt(), 0),
+	}
+	if err = s.db.WithContext(ctx).Create(row).Error; err != nil {
+		s.log.Error("failed to create link code", info.LoggingContext("error", err)...)
+		return status.Error(codes.Internal, "failed to create link code")
+	}
+
+	return nil
+}
+
+func (s *sqlUserStore) ConsumeLinkCode(ctx context.Context, code string) (*v1.LinkCode, error) {
+	info, err := common.GetContextInformation(ctx)
+	if err != nil {
+		s.log.Error("failed to get context information", "error", err)
+		return nil, status.Error(codes.Unauthenticated, "failed to get context information")
+	}
+
+	s.log.Info("consuming link code", info.LoggingContext()...)
+
+	row := &linkCode{}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Where("code = ? AND redeemed_at IS NULL AND expires_at > ?", code, now).First(row).Error; err != nil {
+			return err
+		}
+		// the redeemed_at guard stops two redemptions racing each other from both succeeding
+		result := tx.Model(&linkCode{}).Where("id = ? AND redeemed_at IS NULL", row.ID).Update("redeemed_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, status.Error(codes.NotFound, "link code not found or expired")
+		}
+		s.log.Error("failed to consume link code", info.LoggingContext("error", err)...)
+		return nil, status.Error(codes.Internal, "failed to consume link code")
+	}
+
+	return row.toProto(), nil
+}
+
+// backfillActorOwners links actors registered before their user was recorded to the user their front end registers for them
+func backfillActorOwners(db *gorm.DB) error {
+	var orphans []actor
+	if err := db.Where("user_id = ?", "").Find(&orphans).Error; err != nil {
+		return err
+	}
+
+	for _, row := range orphans {
+		actorMsg := &v1.Actor{}
+		if err := proto.Unmarshal(row.Raw, actorMsg); err != nil {
+			return err
+		}
+		var owner string
+		switch actorMsg.GetSource() {
+		case v1.Actor_APP_DISCORD, v1.Actor_APP_TELNET:
+			owner = common.SourceUserId(actorMsg.GetSource(), actorMsg.GetSourceIdentity())
+		case v1.Actor_APP_SLACK:
+			owner = common.SourceUserId(actorMsg.GetSource(), actorMsg.GetMetadata().GetSlack().GetTeam(), actorMsg.GetSourceIdentity())
+		default:
+			continue
+		}
+
+		var users int64
+		if err := db.Model(&user{}).Where("id = ?", owner).Count(&users).Error; err != nil {
+			return err
+		}
+		if users == 0 {
+			continue
+		}
+		if err := db.Model(&actor{}).Where("id = ?", row.ID).Update("user_id", owner).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
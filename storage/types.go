@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	v1 "overseer/build/go"
+	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -15,8 +16,9 @@ import (
 type actor struct {
 	gorm.Model
 	ID             string
-	SourceIdentity string
-	Source         v1.Actor_Source
+	UserID         string          `gorm:"index"`
+	SourceIdentity string          `gorm:"index:idx_actor_source"`
+	Source         v1.Actor_Source `gorm:"index:idx_actor_source"`
 	Raw            []byte
 }
 
@@ -25,20 +27,67 @@ type user struct {
 	ID string
 }
 
+type apiKey struct {
+	gorm.Model
+	ID         string
+	UserID     string `gorm:"index"`
+	ActorID    *string
+	Name       string
+	SecretHash string `gorm:"uniqueIndex"`
+	RevokedAt  *time.Time
+}
+
+func (k *apiKey) toProto() *v1.ApiKey {
+	key := &v1.ApiKey{
+		Uid:       k.ID,
+		UserId:    k.UserID,
+		Name:      k.Name,
+		ActorId:   k.ActorID,
+		CreatedAt: k.CreatedAt.Unix(),
+	}
+	if k.RevokedAt != nil {
+		revoked := k.RevokedAt.Unix()
+		key.RevokedAt = &revoked
+	}
+	return key
+}
+
+type linkCode struct {
+	gorm.Model
+	Code       string `gorm:"uniqueIndex"`
+	UserID     string
+	ActorID    string
+	ExpiresAt  time.Time
+	RedeemedAt *time.Time
+}
+
+func (c *linkCode) toProto() *v1.LinkCode {
+	return &v1.LinkCode{
+		Code:      c.Code,
+		UserId:    c.UserID,
+		ActorId:   c.ActorID,
+		ExpiresAt: c.ExpiresAt.Unix(),
+	}
+}
+
 type game struct {
 	gorm.Model
-	ID          string
-	Name        string
-	ActorID     string
-	Initialized bool
-	Completed   bool
-	Raw         []byte
+	ID              string
+	Name            string
+	ActorID         string
+	OwnerID         string
+	DungeonMasterID *string
+	Seed            *int64
+	Initialized     bool
+	Completed       bool
+	Raw             []byte
 }
 
 type gameParticipant struct {
 	gorm.Model
-	GameID  string
-	ActorID string
+	GameID    string `gorm:"index"`
+	ActorID   string
+	Spectator bool
 }
 
 type eventRow struct {
@@ -56,12 +105,18 @@ type eventOrigin string
 const (
 	eventOriginSystem  eventOrigin = "system"
 	eventOriginDiscord eventOrigin = "discord"
+	eventOriginSlack   eventOrigin = "slack"
+	eventOriginTelnet  eventOrigin = "telnet"
 )
 
 func getEventOrigin(event *v1.Event) (eventOrigin, error) {
 	switch event.Origin.(type) {
 	case *v1.Event_Discord:
 		return eventOriginDiscord, nil
+	case *v1.Event_Slack:
+		return eventOriginSlack, nil
+	case *v1.Event_Telnet:
+		return eventOriginTelnet, nil
 	case *v1.Event_System:
 		return eventOriginSystem, nil
 	default:
@@ -173,10 +228,14 @@ func (t recieptEffectType) Value() (driver.Value, error) {
 
 type lock struct {
 	gorm.Model
-	ID        string
-	GameID    string
-	Locked    bool
-	Completed bool
+	ID           string
+	GameID       string `gorm:"index"`
+	ClaimID      string
+	FencingToken int64
+	ExpiresAt    time.Time
+	Locked       bool
+	Completed    bool
+	Broken       bool
 }
 
 type gameMap struct {
@@ -217,9 +276,9 @@ type mapCoordinate struct {
 	gorm.Model
 	ID               string
 	GameID           string
-	GameMapID        string
-	X                int64
-	Y                int64
+	GameMapID        string `gorm:"index:idx_coordinate_position"`
+	X                int64  `gorm:"index:idx_coordinate_position"`
+	Y                int64  `gorm:"index:idx_coordinate_position"`
 	Type             string
 	DifficultTerrain bool
 	Lore             string
@@ -254,3 +313,72 @@ func (c *mapCoordinate) ToProto() (*v1.MapCoordinateDetail, error) {
 
 	return &pb, nil
 }
+
+// actorPosition indexes which coordinate of a map each actor stands on so they can be found without reading every coordinate
+type actorPosition struct {
+	gorm.Model
+	GameMapID    string `gorm:"uniqueIndex:idx_actor_position"`
+	ActorID      string `gorm:"uniqueIndex:idx_actor_position"`
+	GameID       string
+	CoordinateID string
+	X            int64
+	Y            int64
+}
+
+func actorPositionsFromProto(src *v1.MapCoordinateDetail) []actorPosition {
+	positions := make([]actorPosition, 0, len(src.GetActors()))
+	for _, actor := range src.GetActors() {
+		positions = append(positions, actorPosition{
+			GameMapID:    src.MapUid,
+			ActorID:      actor.GetUid(),
+			GameID:       src.GameUid,
+			CoordinateID: src.Uid,
+			X:            src.GetPosition().GetX(),
+			Y:            src.GetPosition().GetY(),
+		})
+	}
+	return positions
+}
+
+func (p *actorPosition) ToProto() *v1.MapPosition {
+	return &v1.MapPosition{X: p.X, Y: p.Y}
+}
+
+// discoveredCoordinate is a coordinate an actor has seen, visited once they have stood on it
+type discoveredCoordinate struct {
+	gorm.Model
+	GameMapID    string `gorm:"uniqueIndex:idx_discovered_coordinate"`
+	ActorID      string `gorm:"uniqueIndex:idx_discovered_coordinate"`
+	CoordinateID string `gorm:"uniqueIndex:idx_discovered_coordinate"`
+	Visited      bool
+}
+
+type mapGenerationJob struct {
+	gorm.Model
+	ID     string
+	GameID string `gorm:"index"`
+	State  string `gorm:"index"`
+	Raw    []byte
+}
+
+func MapGenerationJobRecordFromProto(src *v1.MapGenerationJob) (*mapGenerationJob, error) {
+	raw, err := proto.Marshal(src)
+	if err != nil {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to marshal map generation job: %v", err))
+	}
+	return &mapGenerationJob{
+		ID:     src.MapUid,
+		GameID: src.GameUid,
+		State:  src.State.String(),
+		Raw:    raw,
+	}, nil
+}
+
+func (j *mapGenerationJob) ToProto() (*v1.MapGenerationJob, error) {
+	var pb v1.MapGenerationJob
+	err := proto.Unmarshal(j.Raw, &pb)
+	if err != nil {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to unmarshal map generation job: %v", err))
+	}
+	return &pb, nil
+}
@@ -0,0 +1,126 @@
+package telnet
+
+import (
+	"fmt"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"strings"
+)
+
+// describe renders a receipt as plain text, empty when there is nothing to show
+func (s *session) describe(receipt *v1.EventReceipt) string {
+	switch effect := receipt.GetEffect().(type) {
+	case *v1.EventReceipt_Ack:
+		return effect.Ack.GetMessage()
+	case *v1.EventReceipt_Error:
+		return fmt.Sprintf("! %s", effect.Error.GetMessage())
+	case *v1.EventReceipt_Utterance:
+		return s.describeUtterance(effect.Utterance)
+	case *v1.EventReceipt_GameState:
+		if movement := effect.GameState.GetMovement(); movement != nil {
+			who := s.name(movement.GetActor().GetUid()) + " moves"
+			if movement.GetActor().GetUid() == s.actor.GetUid() {
+				who = "You move"
+			}
+			text := fmt.Sprintf("%s %s to (%d, %d)", who, movement.GetDirection(), movement.GetDestination().GetX(), movement.GetDestination().GetY())
+			if movement.GetDifficultTerrain() {
+				text += " through difficult terrain"
+			}
+			return text
+		}
+		if action := effect.GameState.GetAction(); action != nil {
+			text := fmt.Sprintf("%s attempts to %s: rolled %d against %d, %s",
+				s.name(action.GetActor().GetUid()),
+				action.GetAction(),
+				action.GetCheck().GetTotal(),
+				action.GetDifficulty(),
+				strings.ToLower(strings.ReplaceAll(action.GetOutcome().String(), "_", " ")),
+			)
+			if action.Damage != nil {
+				text += fmt.Sprintf(" for %d damage", action.GetDamage().GetTotal())
+			}
+			return text
+		}
+	}
+	return ""
+}
+
+func (s *session) describeUtterance(utterance *v1.UtteranceEffect) string {
+	speaker := s.name(utterance.GetActor())
+	if utterance.GetActor() == s.actor.GetUid() {
+		if utterance.GetWhisper() {
+			return fmt.Sprintf("You whisper to %s: %s", s.names(utterance.GetRecipients()), utterance.GetContent())
+		}
+		return fmt.Sprintf("You say: %s", utterance.GetContent())
+	}
+	if utterance.GetWhisper() {
+		return fmt.Sprintf("%s whispers to you: %s", speaker, utterance.GetContent())
+	}
+	return fmt.Sprintf("%s says: %s", speaker, utterance.GetContent())
+}
+
+// describeCoordinate renders what a player standing on the coordinate can see
+func (s *session) describeCoordinate(coordinate *v1.MapCoordinateDetail) string {
+	lines := []string{fmt.Sprintf("You are in %s at (%d, %d)",
+		strings.ToLower(strings.ReplaceAll(coordinate.GetType().String(), "_", " ")),
+		coordinate.GetPosition().GetX(),
+		coordinate.GetPosition().GetY(),
+	)}
+	if coordinate.GetDifficultTerrain() {
+		lines = append(lines, "The going is difficult here")
+	}
+	if coordinate.GetLore() != "" {
+		lines = append(lines, coordinate.GetLore())
+	}
+	for _, sprite := range coordinate.GetSprites() {
+		if sprite.Actor == nil && sprite.GetLorePublic() != "" {
+			lines = append(lines, fmt.Sprintf("- %s", sprite.GetLorePublic()))
+		}
+	}
+	others := make([]string, 0, len(coordinate.GetActors()))
+	for _, actor := range coordinate.GetActors() {
+		if actor.GetUid() != s.actor.GetUid() {
+			others = append(others, s.name(actor.GetUid()))
+		}
+	}
+	if len(others) > 0 {
+		lines = append(lines, fmt.Sprintf("%s are here", strings.Join(others, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// name is how an actor is shown, telnet players by the name they log in with and everyone else by where they play from
+func (s *session) name(actorUid string) string {
+	if actorUid == auth.DungeonMasterActorId {
+		return "The Dungeon Master"
+	}
+	actor, err := s.lookup(actorUid)
+	if err != nil {
+		return actorUid
+	}
+	if actor.GetSource() == v1.Actor_APP_TELNET {
+		return actor.GetSourceIdentity()
+	}
+	return fmt.Sprintf("%s:%s", strings.ToLower(strings.TrimPrefix(actor.GetSource().String(), "APP_")), actor.GetSourceIdentity())
+}
+
+func (s *session) names(actorUids []string) string {
+	names := make([]string, 0, len(actorUids))
+	for _, actorUid := range actorUids {
+		names = append(names, s.name(actorUid))
+	}
+	return strings.Join(names, ", ")
+}
+
+// lookup gets an actor as the player, actors never change so they are cached
+func (s *session) lookup(actorUid string) (*v1.Actor, error) {
+	if cached, ok := s.actors.Load(actorUid); ok {
+		return cached.(*v1.Actor), nil
+	}
+	actor, err := s.player.Users.GetActor(s.ctx, &v1.GetActorRequest{ActorId: actorUid})
+	if err != nil {
+		return nil, err
+	}
+	s.actors.Store(actorUid, actor)
+	return actor, nil
+}
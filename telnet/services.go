@@ -0,0 +1,15 @@
+package telnet
+
+import (
+	"context"
+	"net"
+)
+
+// TelnetServer is the top level interface to operate the text front end within overseer
+type TelnetServer interface {
+	// Connect will listen for telnet connections and serve a session to each of them.
+	// This function will block until the server is shut down.
+	Connect() error
+	// Handle serves a single session on the connection until the player quits or the connection drops
+	Handle(ctx context.Context, conn net.Conn)
+}
@@ -0,0 +1,286 @@
+package telnet
+
+import (
+	v1 "overseer/build/go"
+	"overseer/client"
+	"overseer/common"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// verbs are the first word of a line, the rest of the line is handed to the verb
+var verbs = map[string]func(s *session, rest string){
+	"help":    (*session).help,
+	"join":    (*session).join,
+	"look":    (*session).look,
+	"l":       (*session).look,
+	"go":      (*session).move,
+	"say":     (*session).say,
+	"whisper": (*session).whisper,
+	"tell":    (*session).tell,
+	"link":    (*session).link,
+}
+
+const helpText = `join <game> [spectate]   join a game, from discord use /game status to find its id
+look                     look around
+go <direction>           move, north, ne, south west and so on also work on their own
+say <message>            say something to the table
+whisper <name> <message> whisper to another player
+tell dm <question>       ask the dungeon master privately
+link <create|redeem <code>|list|unlink>
+                         link this character with your discord or slack account
+quit                     leave`
+
+// execute runs a line of input, it reports whether the player wants to leave
+func (s *session) execute(line string) bool {
+	verb, rest, _ := strings.Cut(line, " ")
+	verb = strings.ToLower(verb)
+	rest = strings.TrimSpace(rest)
+
+	switch {
+	case verb == "":
+		return false
+	case verb == "quit" || verb == "exit":
+		return true
+	}
+	if handler, ok := verbs[verb]; ok {
+		handler(s, rest)
+		return false
+	}
+	// a bare direction is a shorthand for go
+	if _, err := common.ParseDirection(line); err == nil {
+		s.move(line)
+		return false
+	}
+	s.printf("I don't know how to %s, type `help` to see what you can do\n", verb)
+	return false
+}
+
+func (s *session) help(string) {
+	s.println(helpText)
+}
+
+func (s *session) join(rest string) {
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		s.println("Join which game? `join <game> [spectate]`")
+		return
+	}
+	spectate := len(fields) > 1 && strings.EqualFold(fields[1], "spectate")
+
+	game, err := s.player.Games.JoinGame(s.ctx, &v1.JoinGameRequest{GameUid: fields[0], Spectator: spectate})
+	if err != nil {
+		s.log.Warn("failed to join game", "error", err, "game", fields[0], "actor", s.actor.GetUid())
+		s.println(client.DescribeError(err))
+		return
+	}
+	if err = s.follow(game); err != nil {
+		s.log.Error("failed to watch game", "error", err, "game", game.GetUid(), "actor", s.actor.GetUid())
+		s.println(client.DescribeError(err))
+		return
+	}
+
+	if spectate {
+		s.printf("You are watching %s\n", game.GetName())
+		return
+	}
+	s.printf("You joined %s\n", game.GetName())
+}
+
+func (s *session) look(string) {
+	game, ok := s.requireGame()
+	if !ok {
+		return
+	}
+	game, err := s.player.Games.GetGame(s.ctx, &v1.GetGameRequest{GameUid: game.GetUid()})
+	if err != nil {
+		s.println(client.DescribeError(err))
+		return
+	}
+
+	players := make([]string, 0, len(game.GetParticipants()))
+	for _, participant := range game.GetParticipants() {
+		players = append(players, s.name(participant.GetUid()))
+	}
+	s.printf("You are playing %s with %s\n", game.GetName(), strings.Join(players, ", "))
+
+	position, err := s.player.Maps.GetPosition(s.ctx, s.actor)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			s.println("You can't make out where you are")
+			return
+		}
+		s.println(client.DescribeError(err))
+		return
+	}
+	coordinate, err := s.player.Maps.PeekCoordinate(s.ctx, &v1.PeekCoordinateRequest{GameUid: game.GetUid(), Coordinate: position})
+	if err != nil {
+		s.println(client.DescribeError(err))
+		return
+	}
+	s.println(s.describeCoordinate(coordinate))
+}
+
+func (s *session) move(direction string) {
+	if _, err := common.ParseDirection(direction); err != nil {
+		s.println("Go where? north, north-east, east, south-east, south, south-west, west or north-west")
+		return
+	}
+	s.submit(&v1.InteractionEvent{
+		Interaction: &v1.InteractionEvent_Movement{
+			Movement: &v1.MovementInteraction{Direction: direction},
+		},
+	})
+}
+
+func (s *session) say(message string) {
+	if message == "" {
+		s.println("Say what?")
+		return
+	}
+	s.submit(&v1.InteractionEvent{
+		Interaction: &v1.InteractionEvent_Utterance{
+			Utterance: &v1.UtteranceInteraction{
+				Content:   message,
+				Utterance: &v1.UtteranceInteraction_Table{Table: &v1.TableUtterance{}},
+			},
+		},
+	})
+}
+
+func (s *session) whisper(rest string) {
+	name, message, _ := strings.Cut(rest, " ")
+	message = strings.TrimSpace(message)
+	if name == "" || message == "" {
+		s.println("Whisper what to whom? `whisper <name> <message>`")
+		return
+	}
+	game, ok := s.requireGame()
+	if !ok {
+		return
+	}
+	game, err := s.player.Games.GetGame(s.ctx, &v1.GetGameRequest{GameUid: game.GetUid()})
+	if err != nil {
+		s.println(client.DescribeError(err))
+		return
+	}
+
+	var target *v1.Actor
+	for _, participant := range game.GetParticipants() {
+		if strings.EqualFold(s.name(participant.GetUid()), name) || participant.GetUid() == name {
+			target = participant
+			break
+		}
+	}
+	if target == nil {
+		s.printf("There is no one called %s in %s\n", name, game.GetName())
+		return
+	}
+
+	s.submit(&v1.InteractionEvent{
+		Interaction: &v1.InteractionEvent_Utterance{
+			Utterance: &v1.UtteranceInteraction{
+				Content: message,
+				Utterance: &v1.UtteranceInteraction_Player{
+					Player: &v1.PlayerUtterance{Target: target, Whisper: true},
+				},
+			},
+		},
+	})
+}
+
+// tell only knows the dungeon master, players are whispered to
+func (s *session) tell(rest string) {
+	who, question, _ := strings.Cut(rest, " ")
+	question = strings.TrimSpace(question)
+	if !strings.EqualFold(who, "dm") {
+		s.println("You can only tell the dungeon master things, `tell dm <question>`, use whisper for players")
+		return
+	}
+	if question == "" {
+		s.println("Tell the dungeon master what?")
+		return
+	}
+	s.submit(&v1.InteractionEvent{
+		Interaction: &v1.InteractionEvent_Utterance{
+			Utterance: &v1.UtteranceInteraction{
+				Content: question,
+				Utterance: &v1.UtteranceInteraction_DungeonMaster{
+					DungeonMaster: &v1.DungeonMasterUtterance{Whisper: true},
+				},
+			},
+		},
+	})
+}
+
+func (s *session) link(rest string) {
+	subcommand, code, _ := strings.Cut(rest, " ")
+	switch strings.ToLower(subcommand) {
+	case "create":
+		linkCode, err := s.player.Users.CreateLinkCode(s.ctx, &v1.CreateLinkCodeRequest{})
+		if err != nil {
+			s.println(client.DescribeError(err))
+			return
+		}
+		s.printf("Redeem %s from your other account within %s\n", linkCode.GetCode(), time.Until(time.Unix(linkCode.GetExpiresAt(), 0)).Round(time.Minute))
+	case "redeem":
+		user, err := s.player.Users.RedeemLinkCode(s.ctx, &v1.RedeemLinkCodeRequest{Code: code})
+		if err != nil {
+			s.println(client.DescribeError(err))
+			return
+		}
+		s.relink(user)
+		s.println("Your accounts are linked")
+	case "list":
+		actors, err := s.player.Users.ListLinkedActors(s.ctx, &v1.ListLinkedActorsRequest{})
+		if err != nil {
+			s.println(client.DescribeError(err))
+			return
+		}
+		for _, actor := range actors.GetActors() {
+			s.printf("- %s\n", s.name(actor.GetUid()))
+		}
+	case "unlink":
+		user, err := s.player.Users.UnlinkActor(s.ctx, &v1.UnlinkActorRequest{})
+		if err != nil {
+			s.println(client.DescribeError(err))
+			return
+		}
+		s.relink(user)
+		s.println("This character is no longer linked to your other accounts")
+	default:
+		s.println("Link how? `link create`, `link redeem <code>`, `link list` or `link unlink`")
+	}
+}
+
+// submit sends an interaction to the joined game, the outcome is printed when its receipts arrive
+func (s *session) submit(interaction *v1.InteractionEvent) {
+	game, ok := s.requireGame()
+	if !ok {
+		return
+	}
+
+	_, err := s.player.Events.Submit(s.ctx, &v1.Event{
+		GameUid: game.GetUid(),
+		Actor:   s.actor,
+		Origin: &v1.Event_Telnet{
+			Telnet: &v1.EventOriginTelnet{RemoteAddress: s.conn.RemoteAddr().String()},
+		},
+		Payload: &v1.Event_Interaction{Interaction: interaction},
+	})
+	if err != nil {
+		s.log.Warn("failed to submit interaction", "error", err, "game", game.GetUid(), "actor", s.actor.GetUid())
+		s.println(client.DescribeError(err))
+	}
+}
+
+func (s *session) requireGame() (*v1.Game, bool) {
+	if s.game == nil {
+		s.println("You are not in a game, `join <game>` first")
+		return nil, false
+	}
+	return s.game, true
+}
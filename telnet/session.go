@@ -0,0 +1,335 @@
+package telnet
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	v1 "overseer/build/go"
+	"overseer/client"
+	"overseer/common"
+	"regexp"
+	"strings"
+	"sync"
+
+	charm "github.com/charmbracelet/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// telnet commands, the session only asks the client to stop echoing while a key is typed and ignores everything else
+const (
+	iac  byte = 255
+	will byte = 251
+	wont byte = 252
+	do   byte = 253
+	dont byte = 254
+	sb   byte = 250
+	se   byte = 240
+	echo byte = 1
+)
+
+// loginAttempts is how many keys a player may try before the connection is closed
+const loginAttempts = 3
+
+var namePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{1,23}$`)
+
+// session is a single player connected over telnet, input lines are turned into events submitted as the player
+// and the receipts of the game they joined are printed as they arrive
+type session struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	writeMu  sync.Mutex
+	overseer *client.OverseerClient
+	dial     Dialer
+	// player calls the server with the api key of the logged in player, ctx carries their actor
+	player *client.OverseerClient
+	ctx    context.Context
+	actor  *v1.Actor
+	game   *v1.Game
+	watch  context.CancelFunc
+	actors sync.Map
+	log    *charm.Logger
+}
+
+func newSession(conn net.Conn, overseer *client.OverseerClient, dial Dialer) *session {
+	return &session{
+		conn:     conn,
+		reader:   bufio.NewReader(conn),
+		overseer: overseer,
+		dial:     dial,
+		log:      common.GetLogger("telnet.session"),
+	}
+}
+
+func (s *session) run(ctx context.Context) {
+	defer s.close()
+
+	s.println("Welcome to Overseer")
+	if err := s.login(ctx); err != nil {
+		if err != io.EOF {
+			s.log.Warn("login failed", "error", err, "remote", s.conn.RemoteAddr().String())
+		}
+		return
+	}
+	s.log.Info("player logged in", "actor", s.actor.GetUid(), "remote", s.conn.RemoteAddr().String())
+	s.printf("Hello %s, type `help` to see what you can do\n", s.actor.GetSourceIdentity())
+
+	for {
+		s.prompt()
+		line, err := s.readLine()
+		if err != nil {
+			return
+		}
+		if quit := s.execute(line); quit {
+			s.println("Farewell")
+			return
+		}
+	}
+}
+
+// login asks for a name and either registers a new character or checks the key of an existing one
+func (s *session) login(ctx context.Context) error {
+	for {
+		s.print("name: ")
+		name, err := s.readLine()
+		if err != nil {
+			return err
+		}
+		if !namePattern.MatchString(name) {
+			s.println("Names start with a letter and are 2 to 24 letters, digits, dashes or underscores")
+			continue
+		}
+
+		actor, err := s.overseer.Users.GetActorBySource(client.AsSystem(ctx), &v1.GetActorBySourceRequest{
+			Source:         v1.Actor_APP_TELNET,
+			SourceIdentity: name,
+		})
+		if status.Code(err) == codes.NotFound {
+			registered, err := s.register(ctx, name)
+			if err != nil {
+				return err
+			}
+			if !registered {
+				continue
+			}
+			return nil
+		}
+		if err != nil {
+			s.println(client.DescribeError(err))
+			return err
+		}
+		return s.authenticate(ctx, actor)
+	}
+}
+
+// register creates a user and actor for a new character and hands the player the key to log in with again
+func (s *session) register(ctx context.Context, name string) (bool, error) {
+	s.printf("No one called %s has been here before, create them? [y/n] ", name)
+	answer, err := s.readLine()
+	if err != nil {
+		return false, err
+	}
+	if !strings.HasPrefix(strings.ToLower(answer), "y") {
+		return false, nil
+	}
+
+	systemCtx := client.AsSystem(ctx)
+	user, err := s.overseer.Users.RegisterUser(systemCtx, &v1.User{
+		Uid: common.SourceUserId(v1.Actor_APP_TELNET, name),
+	})
+	if err != nil {
+		s.println(client.DescribeError(err))
+		return false, err
+	}
+	actor, err := s.overseer.Users.RegisterActor(systemCtx, &v1.RegisterActorRequest{
+		UserId:         user.GetUid(),
+		SourceIdentity: name,
+		Source:         v1.Actor_APP_TELNET,
+		Metadata: &v1.ActorMetadata{
+			Value: &v1.ActorMetadata_Telnet{
+				Telnet: &v1.ActorSourceTelnet{RemoteAddress: s.conn.RemoteAddr().String()},
+			},
+		},
+	})
+	if err != nil {
+		s.println(client.DescribeError(err))
+		return false, err
+	}
+	key, err := s.overseer.Users.IssueApiKey(systemCtx, &v1.IssueApiKeyRequest{
+		UserId:  user.GetUid(),
+		Name:    "telnet",
+		ActorId: &actor.Uid,
+	})
+	if err != nil {
+		s.println(client.DescribeError(err))
+		return false, err
+	}
+
+	if err = s.start(ctx, user, actor, key.GetSecret()); err != nil {
+		return false, err
+	}
+	s.log.Info("character registered", "user", user.GetUid(), "actor", actor.GetUid())
+	s.printf("Your key is %s\nKeep it safe, you need it to log in as %s again\n", key.GetSecret(), name)
+	return true, nil
+}
+
+// authenticate asks for the key of an existing character, the key is checked by the server when it is first used
+func (s *session) authenticate(ctx context.Context, actor *v1.Actor) error {
+	owner, err := s.overseer.Users.GetUser(client.AsSystem(ctx), actor)
+	if err != nil {
+		s.println(client.DescribeError(err))
+		return err
+	}
+
+	for attempt := 0; attempt < loginAttempts; attempt++ {
+		s.print("key: ")
+		s.write([]byte{iac, will, echo})
+		secret, err := s.readLine()
+		s.write([]byte{iac, wont, echo})
+		s.println("")
+		if err != nil {
+			return err
+		}
+
+		if err = s.start(ctx, owner, actor, secret); err == nil {
+			// only the owner of the character may list its actors
+			if _, err = s.player.Users.GetActors(s.ctx, owner); err == nil {
+				return nil
+			}
+			s.player.Close()
+			s.player = nil
+		}
+		s.println("That key does not fit")
+	}
+	return status.Error(codes.Unauthenticated, "too many failed logins")
+}
+
+// start opens the client the player calls the server with from now on
+func (s *session) start(ctx context.Context, user *v1.User, actor *v1.Actor, secret string) error {
+	player, err := s.dial(strings.TrimSpace(secret))
+	if err != nil {
+		s.log.Error("failed to dial overseer", "error", err)
+		return err
+	}
+	playerCtx, err := common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actor})
+	if err != nil {
+		player.Close()
+		return err
+	}
+	s.player = player
+	s.ctx = playerCtx
+	s.actor = actor
+	return nil
+}
+
+// relink records the user the character belongs to after linking moved it
+func (s *session) relink(user *v1.User) {
+	s.ctx, _ = common.SetContextInformation(s.ctx, &common.OverseerContextInformation{User: user, Actor: s.actor})
+}
+
+// follow prints the receipts of the game from now on, only one game is followed at a time
+func (s *session) follow(game *v1.Game) error {
+	watchCtx, cancel := context.WithCancel(s.ctx)
+	if err := s.player.Watch(watchCtx, game.GetUid(), s.deliver); err != nil {
+		cancel()
+		return err
+	}
+	if s.watch != nil {
+		s.watch()
+	}
+	s.watch = cancel
+	s.game = game
+	return nil
+}
+
+func (s *session) deliver(receipt *v1.EventReceipt) {
+	if text := s.describe(receipt); text != "" {
+		s.printf("\n%s\n", text)
+		s.prompt()
+	}
+}
+
+func (s *session) close() {
+	if s.watch != nil {
+		s.watch()
+	}
+	if s.player != nil {
+		s.player.Close()
+	}
+	s.conn.Close()
+}
+
+// readLine reads a line of input without the telnet negotiation the client mixes into it
+func (s *session) readLine() (string, error) {
+	line := make([]byte, 0, 128)
+	for {
+		b, err := s.reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case b == iac:
+			if err = s.skipCommand(); err != nil {
+				return "", err
+			}
+		case b == '\n':
+			return strings.TrimSpace(string(line)), nil
+		case b == '\r' || b == 0:
+		default:
+			line = append(line, b)
+		}
+	}
+}
+
+// skipCommand drops the telnet command following IAC, including its option or subnegotiation
+func (s *session) skipCommand() error {
+	command, err := s.reader.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch command {
+	case will, wont, do, dont:
+		_, err = s.reader.ReadByte()
+		return err
+	case sb:
+		for {
+			b, err := s.reader.ReadByte()
+			if err != nil {
+				return err
+			}
+			if b == iac {
+				if next, err := s.reader.ReadByte(); err != nil || next == se {
+					return err
+				}
+			}
+		}
+	default:
+		return nil
+	}
+}
+
+func (s *session) prompt() {
+	s.print("> ")
+}
+
+func (s *session) print(text string) {
+	s.write([]byte(text))
+}
+
+func (s *session) println(text string) {
+	s.write([]byte(strings.ReplaceAll(text, "\n", "\r\n") + "\r\n"))
+}
+
+func (s *session) printf(format string, args ...any) {
+	s.write([]byte(strings.ReplaceAll(fmt.Sprintf(format, args...), "\n", "\r\n")))
+}
+
+func (s *session) write(b []byte) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, err := s.conn.Write(b); err != nil {
+		s.log.Debug("failed to write to connection", "error", err)
+	}
+}
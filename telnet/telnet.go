@@ -0,0 +1,20 @@
+package telnet
+
+import (
+	"overseer/client"
+	"overseer/common"
+)
+
+// Dialer opens a client that calls the overseer server as the owner of the api key
+type Dialer func(apiKey string) (*client.OverseerClient, error)
+
+// NewTelnetServer serves sessions on the listen address, overseer holds the system token and is only used to
+// look up and register characters, each logged in player calls the server with a client from dial
+func NewTelnetServer(listenAddress string, overseer *client.OverseerClient, dial Dialer) TelnetServer {
+	return &defaultTelnetServer{
+		listenAddress: listenAddress,
+		overseer:      overseer,
+		dial:          dial,
+		log:           common.GetLogger("telnet.server"),
+	}
+}
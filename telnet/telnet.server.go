@@ -0,0 +1,63 @@
+package telnet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/signal"
+	"overseer/client"
+	"syscall"
+
+	charm "github.com/charmbracelet/log"
+)
+
+type defaultTelnetServer struct {
+	listenAddress string
+	overseer      *client.OverseerClient
+	dial          Dialer
+	log           *charm.Logger
+}
+
+func (s *defaultTelnetServer) Connect() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", s.listenAddress)
+	if err != nil {
+		s.log.Error("failed to listen", "error", err, "address", s.listenAddress)
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	s.log.Info("server waiting for connections", "address", listener.Addr().String())
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				s.log.Info("shutting down telnet server")
+				return nil
+			}
+			s.log.Error("failed to accept connection", "error", err)
+			return err
+		}
+		go s.Handle(ctx, conn)
+	}
+}
+
+func (s *defaultTelnetServer) Handle(ctx context.Context, conn net.Conn) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// closing the connection unblocks any pending read once the server shuts down
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	s.log.Info("session opened", "remote", conn.RemoteAddr().String())
+	newSession(conn, s.overseer, s.dial).run(ctx)
+	s.log.Info("session closed", "remote", conn.RemoteAddr().String())
+}
@@ -0,0 +1,70 @@
+package terrain
+
+import (
+	"math/rand"
+	v1 "overseer/build/go"
+)
+
+const (
+	// cavesFill is the chance a coordinate starts out as rock
+	cavesFill = 0.45
+	// cavesSteps is how often the rock is smoothed, more steps make rounder caverns
+	cavesSteps = 5
+	// cavesLakeChance is the chance an open cavern floor holds an underground lake
+	cavesLakeChance = 0.15
+)
+
+type cellularAutomataGenerator struct {
+	seed int64
+}
+
+// NewCellularAutomataGenerator carves caves out of mountains, random rock is smoothed until caverns form
+func NewCellularAutomataGenerator(seed int64) TerrainGenerator {
+	return &cellularAutomataGenerator{seed: seed}
+}
+
+func (g *cellularAutomataGenerator) Generate(maxX int64, maxY int64) (*Terrain, error) {
+	r := rand.New(rand.NewSource(g.seed))
+
+	terrain := newTerrain(maxX, maxY)
+	terrain.each(func(x int64, y int64) {
+		if r.Float64() < cavesFill {
+			terrain.set(x, y, v1.MapCoordinateDetail_MOUNTAIN)
+		} else {
+			terrain.set(x, y, v1.MapCoordinateDetail_CAVE)
+		}
+	})
+
+	for step := 0; step < cavesSteps; step++ {
+		next := newTerrain(maxX, maxY)
+		terrain.each(func(x int64, y int64) {
+			rock := g.rockAround(terrain, x, y)
+			// rock needs four rocky neighbors to stay and five to fill in a cavern
+			if rock >= 5 || (rock == 4 && terrain.get(x, y) == v1.MapCoordinateDetail_MOUNTAIN) {
+				next.set(x, y, v1.MapCoordinateDetail_MOUNTAIN)
+			} else {
+				next.set(x, y, v1.MapCoordinateDetail_CAVE)
+			}
+		})
+		terrain = next
+	}
+
+	terrain.each(func(x int64, y int64) {
+		if terrain.get(x, y) == v1.MapCoordinateDetail_CAVE && g.rockAround(terrain, x, y) == 0 && r.Float64() < cavesLakeChance {
+			terrain.set(x, y, v1.MapCoordinateDetail_SEA)
+		}
+	})
+	return terrain, nil
+}
+
+// rockAround counts the rocky neighbors of a coordinate, the edge of the map is solid rock
+func (g *cellularAutomataGenerator) rockAround(terrain *Terrain, x int64, y int64) int {
+	rock := 0
+	for _, offset := range neighborOffsets {
+		neighbor := terrain.get(x+offset[0], y+offset[1])
+		if neighbor == v1.MapCoordinateDetail_MOUNTAIN || neighbor == v1.MapCoordinateDetail_TYPE_UNSPECIFIED {
+			rock++
+		}
+	}
+	return rock
+}
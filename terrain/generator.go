@@ -0,0 +1,79 @@
+package terrain
+
+import (
+	"fmt"
+	v1 "overseer/build/go"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TerrainGenerator decides the type of every coordinate of a map, the same seed always produces the same terrain
+type TerrainGenerator interface {
+	Generate(maxX int64, maxY int64) (*Terrain, error)
+}
+
+// NewTerrainGenerator returns the generator a map asked for, noise when it didn't ask
+func NewTerrainGenerator(kind v1.TerrainGenerator, seed int64) (TerrainGenerator, error) {
+	switch kind {
+	case v1.TerrainGenerator_TERRAIN_GENERATOR_UNSPECIFIED, v1.TerrainGenerator_NOISE:
+		return NewNoiseGenerator(seed), nil
+	case v1.TerrainGenerator_CELLULAR_AUTOMATA:
+		return NewCellularAutomataGenerator(seed), nil
+	case v1.TerrainGenerator_WAVE_FUNCTION_COLLAPSE:
+		return NewWaveFunctionCollapseGenerator(seed), nil
+	default:
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("unknown terrain generator: %s", kind))
+	}
+}
+
+// Terrain is the type of every coordinate on a map spanning -maxX..maxX and -maxY..maxY
+type Terrain struct {
+	maxX  int64
+	maxY  int64
+	types []v1.MapCoordinateDetail_CoordinateType
+}
+
+func newTerrain(maxX int64, maxY int64) *Terrain {
+	return &Terrain{
+		maxX:  maxX,
+		maxY:  maxY,
+		types: make([]v1.MapCoordinateDetail_CoordinateType, (2*maxX+1)*(2*maxY+1)),
+	}
+}
+
+// At is the type of the coordinate at the position, unspecified off the map
+func (t *Terrain) At(position *v1.MapPosition) v1.MapCoordinateDetail_CoordinateType {
+	return t.get(position.X, position.Y)
+}
+
+func (t *Terrain) get(x int64, y int64) v1.MapCoordinateDetail_CoordinateType {
+	if !t.contains(x, y) {
+		return v1.MapCoordinateDetail_TYPE_UNSPECIFIED
+	}
+	return t.types[t.index(x, y)]
+}
+
+func (t *Terrain) set(x int64, y int64, coordinateType v1.MapCoordinateDetail_CoordinateType) {
+	t.types[t.index(x, y)] = coordinateType
+}
+
+func (t *Terrain) contains(x int64, y int64) bool {
+	return x >= -t.maxX && x <= t.maxX && y >= -t.maxY && y <= t.maxY
+}
+
+func (t *Terrain) index(x int64, y int64) int64 {
+	return (y+t.maxY)*(2*t.maxX+1) + x + t.maxX
+}
+
+// each visits every position of the terrain row by row
+func (t *Terrain) each(visit func(x int64, y int64)) {
+	for y := -t.maxY; y <= t.maxY; y++ {
+		for x := -t.maxX; x <= t.maxX; x++ {
+			visit(x, y)
+		}
+	}
+}
+
+// neighborOffsets are the eight coordinates a player can move to
+var neighborOffsets = [][2]int64{{-1, -1}, {0, -1}, {1, -1}, {-1, 0}, {1, 0}, {-1, 1}, {0, 1}, {1, 1}}
@@ -0,0 +1,155 @@
+package terrain
+
+import (
+	"math"
+	"math/rand"
+	v1 "overseer/build/go"
+)
+
+const (
+	// noiseScale is roughly how many coordinates a hill or a lake spans
+	noiseScale = 8.0
+	// noiseOctaves layers finer noise over the broad shapes so coastlines aren't smooth curves
+	noiseOctaves = 4
+	// noiseContrast stretches the noise, gradient noise rarely strays far from zero
+	noiseContrast = 2.0
+	// settlementArea is how many coordinates share a city or castle
+	settlementArea = 60
+)
+
+type noiseGenerator struct {
+	seed int64
+}
+
+// NewNoiseGenerator maps perlin noise elevation and moisture to biomes, a desert is dry lowland and a forest wet
+func NewNoiseGenerator(seed int64) TerrainGenerator {
+	return &noiseGenerator{seed: seed}
+}
+
+func (g *noiseGenerator) Generate(maxX int64, maxY int64) (*Terrain, error) {
+	r := rand.New(rand.NewSource(g.seed))
+	elevation := newPerlin(r)
+	moisture := newPerlin(r)
+
+	terrain := newTerrain(maxX, maxY)
+	settlements := make([][2]int64, 0)
+	terrain.each(func(x int64, y int64) {
+		biome := noiseBiome(
+			elevation.fractal(float64(x)/noiseScale, float64(y)/noiseScale),
+			moisture.fractal(float64(x)/noiseScale, float64(y)/noiseScale),
+		)
+		terrain.set(x, y, biome)
+		if biome == v1.MapCoordinateDetail_OPEN_FIELD || biome == v1.MapCoordinateDetail_FOREST {
+			settlements = append(settlements, [2]int64{x, y})
+		}
+	})
+
+	// people settle where the land is kind, cities in the fields and castles in the woods
+	for count := max(len(terrain.types)/settlementArea, 1); count > 0 && len(settlements) > 0; count-- {
+		pick := r.Intn(len(settlements))
+		x, y := settlements[pick][0], settlements[pick][1]
+		settlements = append(settlements[:pick], settlements[pick+1:]...)
+		if terrain.get(x, y) == v1.MapCoordinateDetail_FOREST {
+			terrain.set(x, y, v1.MapCoordinateDetail_CASTLE)
+		} else {
+			terrain.set(x, y, v1.MapCoordinateDetail_CITY)
+		}
+	}
+	return terrain, nil
+}
+
+// noiseBiome picks the biome of an elevation and moisture between zero and one
+func noiseBiome(elevation float64, moisture float64) v1.MapCoordinateDetail_CoordinateType {
+	switch {
+	case elevation < 0.38:
+		return v1.MapCoordinateDetail_SEA
+	case elevation > 0.7 && moisture > 0.6:
+		return v1.MapCoordinateDetail_CAVE
+	case elevation > 0.64:
+		return v1.MapCoordinateDetail_MOUNTAIN
+	case moisture < 0.4:
+		return v1.MapCoordinateDetail_DESERT
+	case moisture > 0.56:
+		return v1.MapCoordinateDetail_FOREST
+	default:
+		return v1.MapCoordinateDetail_OPEN_FIELD
+	}
+}
+
+// perlin is classic gradient noise over a shuffled permutation table
+type perlin struct {
+	permutation [512]int
+	offsetX     float64
+	offsetY     float64
+}
+
+func newPerlin(r *rand.Rand) *perlin {
+	// the offset keeps the origin off the lattice where the noise is always zero
+	p := &perlin{offsetX: r.Float64() * 256, offsetY: r.Float64() * 256}
+	order := r.Perm(256)
+	for i := range p.permutation {
+		p.permutation[i] = order[i%256]
+	}
+	return p
+}
+
+// fractal sums octaves of noise into a value between zero and one
+func (p *perlin) fractal(x float64, y float64) float64 {
+	total, amplitude, frequency, norm := 0.0, 1.0, 1.0, 0.0
+	for octave := 0; octave < noiseOctaves; octave++ {
+		total += p.noise(x*frequency, y*frequency) * amplitude
+		norm += amplitude
+		amplitude /= 2
+		frequency *= 2
+	}
+	return math.Max(0, math.Min(1, (total/norm*noiseContrast+1)/2))
+}
+
+func (p *perlin) noise(x float64, y float64) float64 {
+	x += p.offsetX
+	y += p.offsetY
+	floorX, floorY := math.Floor(x), math.Floor(y)
+	xi, yi := int(floorX)&255, int(floorY)&255
+	xf, yf := x-floorX, y-floorY
+	u, v := fade(xf), fade(yf)
+
+	aa := p.permutation[p.permutation[xi]+yi]
+	ab := p.permutation[p.permutation[xi]+yi+1]
+	ba := p.permutation[p.permutation[xi+1]+yi]
+	bb := p.permutation[p.permutation[xi+1]+yi+1]
+
+	return lerp(
+		lerp(gradient(aa, xf, yf), gradient(ba, xf-1, yf), u),
+		lerp(gradient(ab, xf, yf-1), gradient(bb, xf-1, yf-1), u),
+		v,
+	)
+}
+
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func lerp(a float64, b float64, t float64) float64 {
+	return a + t*(b-a)
+}
+
+func gradient(hash int, x float64, y float64) float64 {
+	switch hash & 7 {
+	case 0:
+		return x + y
+	case 1:
+		return -x + y
+	case 2:
+		return x - y
+	case 3:
+		return -x - y
+	case 4:
+		return x
+	case 5:
+		return -x
+	case 6:
+		return y
+	default:
+		return -y
+	}
+}
@@ -0,0 +1,227 @@
+package terrain
+
+import (
+	"math/bits"
+	"math/rand"
+	v1 "overseer/build/go"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// wfcAttempts is how often the collapse starts over after painting itself into a corner
+	wfcAttempts = 10
+	// wfcCohesion favors the types already around a coordinate so regions form instead of speckles
+	wfcCohesion = 4.0
+)
+
+// adjacency is which types may border each other in any of the eight directions, the rules are symmetric
+var adjacency = map[v1.MapCoordinateDetail_CoordinateType][]v1.MapCoordinateDetail_CoordinateType{
+	v1.MapCoordinateDetail_OPEN_FIELD: {
+		v1.MapCoordinateDetail_OPEN_FIELD, v1.MapCoordinateDetail_FOREST, v1.MapCoordinateDetail_MOUNTAIN, v1.MapCoordinateDetail_DESERT,
+		v1.MapCoordinateDetail_SEA, v1.MapCoordinateDetail_CASTLE, v1.MapCoordinateDetail_CITY,
+	},
+	v1.MapCoordinateDetail_FOREST: {
+		v1.MapCoordinateDetail_OPEN_FIELD, v1.MapCoordinateDetail_FOREST, v1.MapCoordinateDetail_MOUNTAIN, v1.MapCoordinateDetail_SEA,
+		v1.MapCoordinateDetail_CASTLE, v1.MapCoordinateDetail_CITY,
+	},
+	v1.MapCoordinateDetail_MOUNTAIN: {
+		v1.MapCoordinateDetail_OPEN_FIELD, v1.MapCoordinateDetail_FOREST, v1.MapCoordinateDetail_MOUNTAIN, v1.MapCoordinateDetail_DESERT,
+		v1.MapCoordinateDetail_SEA, v1.MapCoordinateDetail_CAVE, v1.MapCoordinateDetail_CASTLE,
+	},
+	v1.MapCoordinateDetail_DESERT: {
+		v1.MapCoordinateDetail_OPEN_FIELD, v1.MapCoordinateDetail_MOUNTAIN, v1.MapCoordinateDetail_DESERT, v1.MapCoordinateDetail_CITY,
+	},
+	v1.MapCoordinateDetail_SEA: {
+		v1.MapCoordinateDetail_OPEN_FIELD, v1.MapCoordinateDetail_FOREST, v1.MapCoordinateDetail_MOUNTAIN, v1.MapCoordinateDetail_SEA,
+		v1.MapCoordinateDetail_CITY,
+	},
+	v1.MapCoordinateDetail_CAVE: {
+		v1.MapCoordinateDetail_MOUNTAIN, v1.MapCoordinateDetail_CAVE,
+	},
+	v1.MapCoordinateDetail_CASTLE: {
+		v1.MapCoordinateDetail_OPEN_FIELD, v1.MapCoordinateDetail_FOREST, v1.MapCoordinateDetail_MOUNTAIN,
+	},
+	v1.MapCoordinateDetail_CITY: {
+		v1.MapCoordinateDetail_OPEN_FIELD, v1.MapCoordinateDetail_FOREST, v1.MapCoordinateDetail_DESERT, v1.MapCoordinateDetail_SEA,
+	},
+}
+
+// wfcWeights is how common each type is when the rules leave a choice
+var wfcWeights = map[v1.MapCoordinateDetail_CoordinateType]float64{
+	v1.MapCoordinateDetail_OPEN_FIELD: 10,
+	v1.MapCoordinateDetail_FOREST:     8,
+	v1.MapCoordinateDetail_MOUNTAIN:   5,
+	v1.MapCoordinateDetail_DESERT:     4,
+	v1.MapCoordinateDetail_SEA:        6,
+	v1.MapCoordinateDetail_CAVE:       1,
+	v1.MapCoordinateDetail_CASTLE:     0.3,
+	v1.MapCoordinateDetail_CITY:       0.5,
+}
+
+// CanBorder reports whether the adjacency rules let the two types sit next to each other
+func CanBorder(a v1.MapCoordinateDetail_CoordinateType, b v1.MapCoordinateDetail_CoordinateType) bool {
+	for _, allowed := range adjacency[a] {
+		if allowed == b {
+			return true
+		}
+	}
+	return false
+}
+
+// tileSet is the types a coordinate could still become, one bit per type
+type tileSet uint16
+
+func tileOf(coordinateType v1.MapCoordinateDetail_CoordinateType) tileSet {
+	return 1 << tileSet(coordinateType)
+}
+
+// allTiles and allowedBeside are derived from the adjacency rules once
+var allTiles, allowedBeside = func() (tileSet, map[v1.MapCoordinateDetail_CoordinateType]tileSet) {
+	all := tileSet(0)
+	beside := make(map[v1.MapCoordinateDetail_CoordinateType]tileSet, len(adjacency))
+	for coordinateType, neighbors := range adjacency {
+		all |= tileOf(coordinateType)
+		for _, neighbor := range neighbors {
+			beside[coordinateType] |= tileOf(neighbor)
+		}
+	}
+	return all, beside
+}()
+
+type waveFunctionCollapseGenerator struct {
+	seed int64
+}
+
+// NewWaveFunctionCollapseGenerator places types one at a time so no two neighbors break the adjacency rules,
+// the sea never touches the desert and caves only open onto mountains
+func NewWaveFunctionCollapseGenerator(seed int64) TerrainGenerator {
+	return &waveFunctionCollapseGenerator{seed: seed}
+}
+
+func (g *waveFunctionCollapseGenerator) Generate(maxX int64, maxY int64) (*Terrain, error) {
+	r := rand.New(rand.NewSource(g.seed))
+	for attempt := 0; attempt < wfcAttempts; attempt++ {
+		if terrain, ok := g.collapse(r, maxX, maxY); ok {
+			return terrain, nil
+		}
+	}
+	return nil, status.Error(codes.Internal, "failed to generate terrain -- the adjacency rules could not be satisfied")
+}
+
+// collapse fills the terrain or reports a contradiction, a coordinate nothing can be placed on
+func (g *waveFunctionCollapseGenerator) collapse(r *rand.Rand, maxX int64, maxY int64) (*Terrain, bool) {
+	terrain := newTerrain(maxX, maxY)
+	width := 2*maxX + 1
+	options := make([]tileSet, len(terrain.types))
+	for i := range options {
+		options[i] = allTiles
+	}
+
+	for {
+		// the coordinate with the fewest options left is collapsed next, ties are broken at random
+		next, fewest, ties := -1, bits.OnesCount16(uint16(allTiles))+1, 0
+		for i, option := range options {
+			count := bits.OnesCount16(uint16(option))
+			switch {
+			case count <= 1:
+			case count < fewest:
+				next, fewest, ties = i, count, 1
+			case count == fewest:
+				ties++
+				if r.Intn(ties) == 0 {
+					next = i
+				}
+			}
+		}
+		if next == -1 {
+			break
+		}
+
+		options[next] = tileOf(g.choose(r, options[next], g.around(options, next, width, maxX, maxY)))
+		if !g.propagate(options, next, width, maxX, maxY) {
+			return nil, false
+		}
+	}
+
+	for i, option := range options {
+		terrain.types[i] = v1.MapCoordinateDetail_CoordinateType(bits.TrailingZeros16(uint16(option)))
+	}
+	return terrain, true
+}
+
+// choose picks one of the options weighted by how common each type is and how often it is already around
+func (g *waveFunctionCollapseGenerator) choose(r *rand.Rand, options tileSet, around map[v1.MapCoordinateDetail_CoordinateType]int) v1.MapCoordinateDetail_CoordinateType {
+	// the candidates are walked lowest type first so a seed always makes the same choice
+	candidates := make([]v1.MapCoordinateDetail_CoordinateType, 0, bits.OnesCount16(uint16(options)))
+	weights := make([]float64, 0, cap(candidates))
+	total := 0.0
+	for remaining := options; remaining != 0; remaining &= remaining - 1 {
+		candidate := v1.MapCoordinateDetail_CoordinateType(bits.TrailingZeros16(uint16(remaining)))
+		weight := wfcWeights[candidate] * (1 + wfcCohesion*float64(around[candidate]))
+		candidates = append(candidates, candidate)
+		weights = append(weights, weight)
+		total += weight
+	}
+
+	roll := r.Float64() * total
+	for i, candidate := range candidates {
+		roll -= weights[i]
+		if roll < 0 {
+			return candidate
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// around counts the types of the neighbors already collapsed
+func (g *waveFunctionCollapseGenerator) around(options []tileSet, index int, width int64, maxX int64, maxY int64) map[v1.MapCoordinateDetail_CoordinateType]int {
+	counts := make(map[v1.MapCoordinateDetail_CoordinateType]int)
+	x, y := int64(index)%width-maxX, int64(index)/width-maxY
+	for _, offset := range neighborOffsets {
+		nx, ny := x+offset[0], y+offset[1]
+		if nx < -maxX || nx > maxX || ny < -maxY || ny > maxY {
+			continue
+		}
+		option := options[(ny+maxY)*width+nx+maxX]
+		if bits.OnesCount16(uint16(option)) == 1 {
+			counts[v1.MapCoordinateDetail_CoordinateType(bits.TrailingZeros16(uint16(option)))]++
+		}
+	}
+	return counts
+}
+
+// propagate removes the options the collapsed coordinate rules out from its neighbors, and theirs in turn
+func (g *waveFunctionCollapseGenerator) propagate(options []tileSet, start int, width int64, maxX int64, maxY int64) bool {
+	stack := []int{start}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		allowed := tileSet(0)
+		for coordinateType, beside := range allowedBeside {
+			if options[current]&tileOf(coordinateType) != 0 {
+				allowed |= beside
+			}
+		}
+
+		x, y := int64(current)%width-maxX, int64(current)/width-maxY
+		for _, offset := range neighborOffsets {
+			nx, ny := x+offset[0], y+offset[1]
+			if nx < -maxX || nx > maxX || ny < -maxY || ny > maxY {
+				continue
+			}
+			neighbor := int((ny+maxY)*width + nx + maxX)
+			narrowed := options[neighbor] & allowed
+			if narrowed == 0 {
+				return false
+			}
+			if narrowed != options[neighbor] {
+				options[neighbor] = narrowed
+				stack = append(stack, neighbor)
+			}
+		}
+	}
+	return true
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"overseer/auth"
 	v1 "overseer/build/go"
 	"overseer/common"
 	"overseer/engine"
@@ -42,9 +43,9 @@ func (s *EventBusSuite) TestEventBus_EmptyHandlers() {
 	userStore := storage.NewSqlUserStore(s.db)
 	gamesStore := storage.NewSqlGameStore(s.db, userStore)
 	lockStore := storage.NewSqlLockStore(s.db)
-	usersSrv := server.NewUserServer(userStore)
-	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore)
-	eventBus := engine.NewEventBus([]engine.EventHandler{}, gamesSrv, usersSrv, storage.NewSqlEventStore(s.db))
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, storage.NewSqlMapStore(s.db))
+	eventBus := engine.NewEventBus([]engine.EventHandler{}, gamesSrv, usersSrv, storage.NewSqlEventStore(s.db), engine.NewReceiptBroker())
 	ctx, err := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{
 		User: &v1.User{Uid: "test"},
 	})
@@ -53,3 +54,102 @@ func (s *EventBusSuite) TestEventBus_EmptyHandlers() {
 	_, err = eventBus.Submit(ctx, &v1.Event{})
 	s.Error(err, "without handlers the event bus should always error")
 }
+
+// recordingHandler emits a single acknowledgement naming itself and remembers what ran before it
+type recordingHandler struct {
+	name  string
+	stop  bool
+	order *[]string
+	prior *[]int
+}
+
+func (h recordingHandler) Name() string {
+	return h.name
+}
+
+func (h recordingHandler) Predicate() engine.EventPredicate {
+	return func(ctx context.Context, event *v1.EventRecord) (bool, error) {
+		return true, nil
+	}
+}
+
+func (h recordingHandler) Handle(ctx context.Context, event *v1.EventRecord) (<-chan *v1.EventReceipt, error) {
+	*h.order = append(*h.order, h.name)
+	*h.prior = append(*h.prior, len(engine.PriorReceipts(ctx)))
+	if h.stop {
+		engine.StopPropagation(ctx)
+	}
+	results := make(chan *v1.EventReceipt, 1)
+	results <- &v1.EventReceipt{
+		Uid:      h.name,
+		GameUid:  event.GameUid,
+		EventUid: event.Uid,
+		Effect:   &v1.EventReceipt_Ack{Ack: &v1.Acknowledgement{Message: &h.name}},
+	}
+	close(results)
+	return results, nil
+}
+
+func (s *EventBusSuite) TestEventBus_PipelineOrder() {
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	lockStore := storage.NewSqlLockStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, storage.NewSqlMapStore(s.db))
+
+	user := &v1.User{Uid: "test"}
+	ctx, err := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	s.Require().NoError(err)
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err = usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err)
+	actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{
+		UserId:         user.Uid,
+		SourceIdentity: "frodo",
+		Source:         v1.Actor_APP_DISCORD,
+	})
+	s.Require().NoError(err)
+	ctx, err = common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actor})
+	s.Require().NoError(err)
+	game, err := gamesSrv.CreateGame(ctx, &v1.CreateGameRequest{Name: "test game", Participants: []*v1.Actor{actor}})
+	s.Require().NoError(err)
+
+	run := func(handlers ...engine.EventHandler) []*v1.EventReceipt {
+		eventBus := engine.NewEventBus(handlers, gamesSrv, usersSrv, storage.NewSqlEventStore(s.db), engine.NewReceiptBroker())
+		results, err := eventBus.Submit(ctx, &v1.Event{
+			GameUid: game.Uid,
+			Actor:   actor,
+			Origin:  &v1.Event_System{System: &v1.EventOriginSystem{}},
+			Payload: &v1.Event_Interaction{Interaction: &v1.InteractionEvent{}},
+		})
+		s.Require().NoError(err)
+		receipts := make([]*v1.EventReceipt, 0)
+		for r := range results {
+			receipts = append(receipts, r)
+		}
+		return receipts
+	}
+
+	order, prior := make([]string, 0), make([]int, 0)
+	handler := func(name string, stop bool) recordingHandler {
+		return recordingHandler{name: name, stop: stop, order: &order, prior: &prior}
+	}
+
+	run(
+		engine.Register(handler("notify", false), engine.StageNotify, 0),
+		engine.Register(handler("narrate", false), engine.StageNarrate, 0),
+		handler("mutate-default", false),
+		engine.Register(handler("mutate-first", false), engine.StageMutate, 10),
+		engine.Register(handler("validate", false), engine.StageValidate, 0),
+	)
+	s.Equal([]string{"validate", "mutate-first", "mutate-default", "narrate", "notify"}, order, "handlers should run by stage then priority")
+	s.Equal([]int{0, 1, 2, 3, 4}, prior, "later handlers should see the receipts of earlier ones")
+
+	order, prior = order[:0], prior[:0]
+	receipts := run(
+		engine.Register(handler("narrate", false), engine.StageNarrate, 0),
+		engine.Register(handler("validate", true), engine.StageValidate, 0),
+	)
+	s.Equal([]string{"validate"}, order, "stopping propagation should skip the remaining handlers")
+	s.Len(receipts, 1, "the receipts of the stopping handler should still be delivered")
+}
@@ -0,0 +1,194 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/engine/handlers"
+	"overseer/generative"
+	"overseer/generative/ollama"
+	"overseer/server"
+	"overseer/storage"
+	"path"
+	"testing"
+	"text/template"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type ActionTest struct {
+	db     *gorm.DB
+	dbFile string
+	suite.Suite
+}
+
+func TestAction(t *testing.T) {
+	suite.Run(t, new(ActionTest))
+}
+
+func (s *ActionTest) SetupTest() {
+	s.dbFile = path.Join(os.TempDir(), fmt.Sprintf("test-%s.db", uuid.NewString()))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *ActionTest) TearDownTest() {
+	s.Require().NoError(os.Remove(s.dbFile), "failed to remove test database")
+	s.db = nil
+}
+
+func generateStream(response string) chan ollama.GenerateResponse {
+	stream := make(chan ollama.GenerateResponse, 1)
+	stream <- ollama.GenerateResponse{Response: response, Done: true}
+	close(stream)
+	return stream
+}
+
+func (s *ActionTest) TestResolvingActions() {
+	mockOllama := new(MockOllamaClient)
+	mockTemplatingClient := new(MockTemplatingClient)
+	mockTemplatingClient.On("DungeonMasterTemplate").Return(template.Must(template.New("mock").Parse("you are the dungeon master")))
+	mockTemplatingClient.On("AdjudicationTemplate").Return(template.Must(template.New("mock").Parse("classify {{.Action}}")))
+	mockTemplatingClient.On("NarrationTemplate").Return(template.Must(template.New("mock").Parse("narrate {{.Outcome}}")))
+	dm, err := generative.NewOllamaDungeonMasterService(mockTemplatingClient, mockOllama)
+	s.Require().NoError(err)
+
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	eventStore := storage.NewSqlEventStore(s.db)
+	lockStore := storage.NewSqlLockStore(s.db)
+	mapStore := storage.NewSqlMapStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, mapStore)
+	eventBus := engine.NewEventBus(
+		[]engine.EventHandler{
+			handlers.NewActionHandler(dm, gamesStore, mapStore, eventStore),
+		},
+		gamesSrv,
+		usersSrv,
+		eventStore,
+		engine.NewReceiptBroker(),
+	)
+	eventSrv := server.NewEventServer(eventBus, eventStore, gamesStore)
+	user := &v1.User{
+		Uid: "test",
+	}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{
+		User: user,
+	})
+
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err = usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err, "error should be nil on creating user")
+
+	actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{
+		UserId: user.Uid,
+		Source: v1.Actor_APP_DISCORD,
+	})
+	s.Require().NoError(err, "error should be nil on creating actor")
+
+	ctx, _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{
+		User:  user,
+		Actor: actor,
+	})
+
+	game, err := gamesSrv.CreateGame(ctx, &v1.CreateGameRequest{
+		Name:         "test game",
+		Participants: []*v1.Actor{actor},
+	})
+	s.Require().NoError(err, "error should be nil")
+
+	// a single coordinate shared by the player and a frail goblin
+	gameMap, err := mapStore.CreateMap(ctx, &v1.CreateMapRequest{
+		GameUid: game.Uid,
+		Name:    "test map",
+	})
+	s.Require().NoError(err, "error should be nil on creating map")
+	s.Require().NoError(mapStore.CreateCoordinate(ctx, &v1.MapCoordinateDetail{
+		Uid:      common.GenerateUniqueId(),
+		GameUid:  game.Uid,
+		MapUid:   gameMap.Uid,
+		Position: &v1.MapPosition{X: 0, Y: 0},
+		Type:     v1.MapCoordinateDetail_CAVE,
+		Actors:   []*v1.Actor{actor},
+		Sprites: []*v1.Sprite{
+			{Uid: "player", Actor: actor, IsObstacle: true, IsMoveable: true},
+			{Uid: "goblin", IsObstacle: true, IsMoveable: true, LorePublic: "a frail goblin", Characteristics: []*v1.Characteristic{
+				{Type: v1.Characteristic_HEALTH, Value: 3},
+				{Type: v1.Characteristic_DEFENSE, Value: 0},
+			}},
+		},
+	}))
+
+	var adjudications []ollama.GenerateRequest
+	mockOllama.On("Generate", mock.Anything, mock.Anything).Return(generateStream(`{"kind": "attack", "ability": "strength", "target": "goblin"}`), nil).Run(func(args mock.Arguments) {
+		adjudications = append(adjudications, args.Get(1).(ollama.GenerateRequest))
+	}).Once()
+	mockOllama.On("Generate", mock.Anything, mock.Anything).Return(generateStream(`{"kind": "attack", "ability": "strength", "target": "dragon"}`), nil).Once()
+	mockOllama.On("Generate", mock.Anything, mock.Anything).Return(generateStream(`the goblin is no match for you`), nil).Once()
+	mockOllama.On("Converse", mock.Anything, mock.Anything).Return(converseStream("The blade swings."), nil).Once()
+	mockOllama.On("Converse", mock.Anything, mock.Anything).Return(converseStream("You see a goblin."), nil).Once()
+
+	act := func(action string) []*v1.EventReceipt {
+		receipts, err := eventSrv.Submit(ctx, &v1.Event{
+			GameUid: game.Uid,
+			Actor:   actor,
+			Origin:  &v1.Event_Discord{Discord: &v1.EventOriginDiscord{Guild: "test", Channel: "test"}},
+			Payload: &v1.Event_Interaction{Interaction: &v1.InteractionEvent{
+				Interaction: &v1.InteractionEvent_Action{Action: &v1.ActionInteraction{Action: action}},
+			}},
+		})
+		s.Require().NoError(err, "error should be nil on submitting an action")
+		return receipts.Receipts
+	}
+
+	receipts := act("I stab the goblin")
+	s.Require().Len(receipts, 2, "an action should be resolved then narrated")
+	s.Require().Len(adjudications, 1)
+	s.Equal("json", adjudications[0].Format, "classification should be requested as json")
+
+	attack := receipts[0].GetGameState().GetAction()
+	s.Require().NotNil(attack, "first receipt should be the resolved action: ", receipts[0])
+	s.Equal(v1.ActionEffect_ATTACK, attack.GetKind())
+	s.Equal(v1.ActionEffect_STRENGTH, attack.GetAbility())
+	s.Equal("goblin", attack.GetTargetSpriteUid())
+	s.Equal(int64(5), attack.GetDifficulty(), "a goblin without defense should be easy to hit")
+	s.Equal(common.ResolveCheck(attack.GetCheck(), attack.GetDifficulty()), attack.GetOutcome(), "the outcome must follow the dice")
+	if common.IsSuccessful(attack.GetOutcome()) {
+		s.Require().NotNil(attack.Damage)
+		s.GreaterOrEqual(attack.GetDamage().GetTotal(), int64(1))
+		s.Equal(max(float32(3)-float32(attack.GetDamage().GetTotal()), 0), attack.GetTargetHealth())
+
+		location, err := mapStore.GetActorCoordinate(ctx, gameMap.Uid, actor.Uid)
+		s.Require().NoError(err)
+		for _, sprite := range location.GetSprites() {
+			if sprite.GetUid() == "goblin" {
+				s.Equal(attack.GetTargetHealth(), common.CharacteristicValue(sprite, v1.Characteristic_HEALTH), "damage should be persisted")
+			}
+		}
+	} else {
+		s.Nil(attack.Damage, "a missed attack deals no damage")
+	}
+
+	s.Equal(auth.DungeonMasterActorId, receipts[1].GetUtterance().GetActor())
+	s.Equal("The blade swings.", receipts[1].GetUtterance().GetContent())
+
+	receipts = act("I stab the dragon")
+	s.Require().Len(receipts, 1)
+	s.Equal(v1.ErrorEffect_INVALID, receipts[0].GetError().GetType(), "attacking something that is not here should be rejected")
+
+	receipts = act("I look around")
+	s.Require().Len(receipts, 2)
+	check := receipts[0].GetGameState().GetAction()
+	s.Require().NotNil(check)
+	s.Equal(v1.ActionEffect_SKILL_CHECK, check.GetKind(), "an unreadable classification should fall back to a skill check")
+	s.Equal(v1.ActionEffect_WISDOM, check.GetAbility())
+}
@@ -0,0 +1,270 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/server"
+	"overseer/storage"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+type AuthenticationTest struct {
+	db     *gorm.DB
+	dbFile string
+	suite.Suite
+}
+
+func TestAuthentication(t *testing.T) {
+	suite.Run(t, new(AuthenticationTest))
+}
+
+func (s *AuthenticationTest) SetupTest() {
+	s.dbFile = path.Join(os.TempDir(), fmt.Sprintf("test-%s.db", uuid.NewString()))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *AuthenticationTest) TearDownTest() {
+	s.Require().NoError(os.Remove(s.dbFile), "failed to remove test database")
+	s.db = nil
+}
+
+func (s *AuthenticationTest) TestApiKeysAndBearerTokens() {
+	userStore := storage.NewSqlUserStore(s.db)
+	keyStore := storage.NewSqlApiKeyStore(s.db)
+	signer := auth.NewTokenSigner("test-secret", time.Minute, time.Hour)
+	usersSrv := server.NewUserServer(userStore, keyStore, signer)
+	authenticator := auth.NewAuthenticator(keyStore, userStore, signer)
+
+	register := func(uid string, identity string) (*v1.User, *v1.Actor, context.Context) {
+		user := &v1.User{Uid: uid}
+		ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+		systemCtx, _ := auth.SystemContext(context.Background())
+		_, err := usersSrv.RegisterUser(ctx, user)
+		s.Require().NoError(err)
+		actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: uid, SourceIdentity: identity, Source: v1.Actor_APP_DISCORD})
+		s.Require().NoError(err)
+		ctx, _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actor})
+		return user, actor, ctx
+	}
+	frodoUser, frodo, frodoCtx := register("frodo-user", "frodo")
+	_, sam, _ := register("sam-user", "sam")
+
+	// authenticate runs a unary call through the interceptor and returns who the server believed was calling
+	authenticate := func(kv ...string) (*common.OverseerContextInformation, error) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(kv...))
+		ctx = peer.NewContext(ctx, &peer.Peer{})
+		var caller *common.OverseerContextInformation
+		_, err := authenticator.UnaryServerAuthFunc(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test"}, func(ctx context.Context, req any) (any, error) {
+			info, err := common.GetContextInformation(ctx)
+			caller = info
+			return nil, err
+		})
+		return caller, err
+	}
+
+	_, err := authenticate()
+	s.Equal(codes.Unauthenticated, status.Code(err), "calls without credentials should be rejected")
+
+	_, err = usersSrv.IssueApiKey(frodoCtx, &v1.IssueApiKeyRequest{UserId: "sam-user", Name: "stolen"})
+	s.Equal(codes.PermissionDenied, status.Code(err), "keys should only be issued to yourself")
+
+	issued, err := usersSrv.IssueApiKey(frodoCtx, &v1.IssueApiKeyRequest{UserId: frodoUser.Uid, Name: "bot"})
+	s.Require().NoError(err)
+	s.NotEmpty(issued.Secret)
+	var stored int64
+	s.Require().NoError(s.db.Table("api_keys").Where("secret_hash = ?", issued.Secret).Count(&stored).Error)
+	s.Zero(stored, "the secret should never be stored in the clear")
+
+	caller, err := authenticate("x-api-key", issued.Secret, "x-actor", frodo.Uid)
+	s.Require().NoError(err)
+	s.Equal(frodoUser.Uid, caller.User.Uid)
+	s.Equal(frodo.Uid, caller.Actor.Uid)
+	s.Equal(common.CredentialApiKey, caller.Credential)
+	apiKeyCtx, _ := common.SetContextInformation(context.Background(), caller)
+	_, err = usersSrv.IssueToken(apiKeyCtx, &v1.IssueTokenRequest{})
+	s.NoError(err, "api keys should issue tokens")
+
+	_, err = authenticate("x-api-key", issued.Secret, "x-actor", sam.Uid)
+	s.Equal(codes.Unauthenticated, status.Code(err), "a key should not act as another user's actor")
+
+	token, err := usersSrv.IssueToken(frodoCtx, &v1.IssueTokenRequest{})
+	s.Require().NoError(err)
+	caller, err = authenticate("authorization", "Bearer "+token.Token)
+	s.Require().NoError(err)
+	s.Equal(frodoUser.Uid, caller.User.Uid)
+	s.Equal(frodo.Uid, caller.Actor.Uid)
+	s.Equal(common.CredentialBearerToken, caller.Credential)
+
+	// a leaked token must not be able to renew itself
+	bearerCtx, _ := common.SetContextInformation(context.Background(), caller)
+	_, err = usersSrv.IssueToken(bearerCtx, &v1.IssueTokenRequest{})
+	s.Equal(codes.PermissionDenied, status.Code(err), "bearer tokens should not issue more tokens")
+	_, err = usersSrv.IssueApiKey(bearerCtx, &v1.IssueApiKeyRequest{UserId: frodoUser.Uid, Name: "lasting"})
+	s.Equal(codes.PermissionDenied, status.Code(err), "bearer tokens should not issue api keys")
+
+	_, err = usersSrv.IssueToken(frodoCtx, &v1.IssueTokenRequest{TtlSeconds: int64((2 * time.Hour).Seconds())})
+	s.Equal(codes.InvalidArgument, status.Code(err), "tokens should not outlive the configured maximum")
+
+	forged, _, err := auth.NewTokenSigner("wrong-secret", time.Minute, time.Hour).Issue(frodoUser.Uid, frodo.Uid, 0)
+	s.Require().NoError(err)
+	_, err = authenticate("authorization", "Bearer "+forged)
+	s.Equal(codes.Unauthenticated, status.Code(err), "tokens signed with another secret should be rejected")
+
+	expired, _, err := auth.NewTokenSigner("test-secret", -time.Minute, time.Hour).Issue(frodoUser.Uid, frodo.Uid, 0)
+	s.Require().NoError(err)
+	_, err = authenticate("authorization", "Bearer "+expired)
+	s.Equal(codes.Unauthenticated, status.Code(err), "expired tokens should be rejected")
+
+	revoked, err := usersSrv.RevokeApiKey(frodoCtx, &v1.RevokeApiKeyRequest{KeyUid: issued.Key.Uid})
+	s.Require().NoError(err)
+	s.True(revoked.Success)
+	_, err = authenticate("x-api-key", issued.Secret, "x-actor", frodo.Uid)
+	s.Equal(codes.Unauthenticated, status.Code(err), "revoked keys should be rejected")
+}
+
+func (s *AuthenticationTest) TestActorBySource() {
+	userStore := storage.NewSqlUserStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+
+	user := &v1.User{Uid: "discord-user"}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err := usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err)
+
+	registered, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{
+		UserId:         user.Uid,
+		SourceIdentity: "1234",
+		Source:         v1.Actor_APP_DISCORD,
+		Metadata: &v1.ActorMetadata{Value: &v1.ActorMetadata_Discord{
+			Discord: &v1.ActorSourceDiscord{Guild: "guild", Channel: "channel"},
+		}},
+	})
+	s.Require().NoError(err)
+	s.Equal(v1.Actor_APP_DISCORD, registered.Source)
+
+	found, err := usersSrv.GetActorBySource(ctx, &v1.GetActorBySourceRequest{Source: v1.Actor_APP_DISCORD, SourceIdentity: "1234"})
+	s.Require().NoError(err)
+	s.Equal(registered.Uid, found.Uid)
+	s.Equal("guild", found.GetMetadata().GetDiscord().GetGuild())
+
+	_, err = usersSrv.GetActorBySource(ctx, &v1.GetActorBySourceRequest{Source: v1.Actor_SYSTEM, SourceIdentity: "1234"})
+	s.Equal(codes.NotFound, status.Code(err), "the same identity from another source is a different actor")
+}
+
+func (s *AuthenticationTest) TestLinkingActors() {
+	userStore := storage.NewSqlUserStore(s.db)
+	keyStore := storage.NewSqlApiKeyStore(s.db)
+	usersSrv := server.NewUserServer(userStore, keyStore, nil)
+	authenticator := auth.NewAuthenticator(keyStore, userStore, nil)
+
+	register := func(uid string, identity string, source v1.Actor_Source) (*v1.User, *v1.Actor, context.Context) {
+		user := &v1.User{Uid: uid}
+		ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+		systemCtx, _ := auth.SystemContext(context.Background())
+		_, err := usersSrv.RegisterUser(ctx, user)
+		s.Require().NoError(err)
+		actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: uid, SourceIdentity: identity, Source: source})
+		s.Require().NoError(err)
+		ctx, _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actor})
+		return user, actor, ctx
+	}
+	frodoUser, frodo, frodoCtx := register("frodo-discord", "frodo", v1.Actor_APP_DISCORD)
+	terminalUser, terminal, terminalCtx := register("frodo-telnet", "frodo", v1.Actor_APP_TELNET)
+	_, _, samCtx := register("sam-discord", "sam", v1.Actor_APP_DISCORD)
+	key, err := usersSrv.IssueApiKey(terminalCtx, &v1.IssueApiKeyRequest{UserId: terminalUser.Uid, Name: "telnet", ActorId: &terminal.Uid})
+	s.Require().NoError(err)
+
+	code, err := usersSrv.CreateLinkCode(frodoCtx, &v1.CreateLinkCodeRequest{})
+	s.Require().NoError(err)
+	s.Len(code.Code, 8)
+	s.Equal(frodoUser.Uid, code.UserId)
+
+	_, err = usersSrv.RedeemLinkCode(terminalCtx, &v1.RedeemLinkCodeRequest{Code: "NOTACODE"})
+	s.Equal(codes.NotFound, status.Code(err), "unknown codes should not link anything")
+
+	linked, err := usersSrv.RedeemLinkCode(terminalCtx, &v1.RedeemLinkCodeRequest{Code: " " + strings.ToLower(code.Code) + " "})
+	s.Require().NoError(err, "codes should be forgiving about how they are typed")
+	s.Equal(frodoUser.Uid, linked.Uid)
+	owner, err := userStore.GetUserForActor(frodoCtx, terminal.Uid)
+	s.Require().NoError(err)
+	s.Equal(frodoUser.Uid, owner.Uid, "the redeeming actor should move to the user that created the code")
+
+	frodoCtx, _ = common.SetContextInformation(frodoCtx, &common.OverseerContextInformation{User: frodoUser, Actor: frodo})
+	actors, err := usersSrv.ListLinkedActors(frodoCtx, &v1.ListLinkedActorsRequest{})
+	s.Require().NoError(err)
+	s.ElementsMatch([]string{frodo.Uid, terminal.Uid}, common.Filter(actors.Actors, func(a *v1.Actor) string { return a.Uid }))
+
+	ctx := peer.NewContext(metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", key.Secret)), &peer.Peer{})
+	_, err = authenticator.UnaryServerAuthFunc(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test"}, func(ctx context.Context, req any) (any, error) {
+		info, err := common.GetContextInformation(ctx)
+		s.Require().NoError(err)
+		s.Equal(frodoUser.Uid, info.User.Uid, "api keys should follow their actors")
+		s.Equal(terminal.Uid, info.Actor.Uid)
+		return nil, nil
+	})
+	s.Require().NoError(err)
+
+	_, err = usersSrv.RedeemLinkCode(samCtx, &v1.RedeemLinkCodeRequest{Code: code.Code})
+	s.Equal(codes.NotFound, status.Code(err), "codes should only be redeemed once")
+
+	s.Require().NoError(userStore.CreateLinkCode(frodoCtx, &v1.LinkCode{Code: "EXPIRED2", UserId: frodoUser.Uid, ActorId: frodo.Uid, ExpiresAt: time.Now().Add(-time.Minute).Unix()}))
+	_, err = usersSrv.RedeemLinkCode(samCtx, &v1.RedeemLinkCodeRequest{Code: "EXPIRED2"})
+	s.Equal(codes.NotFound, status.Code(err), "expired codes should not link anything")
+
+	_, err = usersSrv.UnlinkActor(samCtx, &v1.UnlinkActorRequest{ActorId: terminal.Uid})
+	s.Equal(codes.PermissionDenied, status.Code(err), "only the owner should unlink an actor")
+
+	unlinked, err := usersSrv.UnlinkActor(frodoCtx, &v1.UnlinkActorRequest{ActorId: terminal.Uid})
+	s.Require().NoError(err)
+	s.NotEqual(frodoUser.Uid, unlinked.Uid)
+	owner, err = userStore.GetUserForActor(frodoCtx, terminal.Uid)
+	s.Require().NoError(err)
+	s.Equal(unlinked.Uid, owner.Uid, "the unlinked actor should belong to a user of its own")
+
+	_, err = usersSrv.UnlinkActor(frodoCtx, &v1.UnlinkActorRequest{})
+	s.Equal(codes.FailedPrecondition, status.Code(err), "the last actor of a user cannot be unlinked")
+}
+
+func (s *AuthenticationTest) TestLinkingActorsRegisteredBeforeOwnership() {
+	usersSrv := server.NewUserServer(storage.NewSqlUserStore(s.db), storage.NewSqlApiKeyStore(s.db), nil)
+	user := &v1.User{Uid: common.SourceUserId(v1.Actor_APP_DISCORD, "frodo")}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err := usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err)
+	frodo, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: user.Uid, SourceIdentity: "frodo", Source: v1.Actor_APP_DISCORD})
+	s.Require().NoError(err)
+	stranger, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: user.Uid, SourceIdentity: "stranger", Source: v1.Actor_APP_DISCORD})
+	s.Require().NoError(err)
+
+	// a database from before actors recorded their user
+	s.Require().NoError(s.db.Exec("UPDATE actors SET user_id = ''").Error)
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	userStore := storage.NewSqlUserStore(db)
+
+	owner, err := userStore.GetUserForActor(ctx, frodo.Uid)
+	s.Require().NoError(err, "migrating should link the actor to the user its front end registered")
+	s.Equal(user.Uid, owner.Uid)
+	_, err = userStore.GetUserForActor(ctx, stranger.Uid)
+	s.Equal(codes.NotFound, status.Code(err), "an actor whose user cannot be worked out should stay unlinked")
+}
@@ -0,0 +1,219 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/server"
+	"overseer/storage"
+	"path"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+type AuthorizationTest struct {
+	db     *gorm.DB
+	dbFile string
+	suite.Suite
+}
+
+func TestAuthorization(t *testing.T) {
+	suite.Run(t, new(AuthorizationTest))
+}
+
+func (s *AuthorizationTest) SetupTest() {
+	s.dbFile = path.Join(os.TempDir(), fmt.Sprintf("test-%s.db", uuid.NewString()))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *AuthorizationTest) TearDownTest() {
+	s.Require().NoError(os.Remove(s.dbFile), "failed to remove test database")
+	s.db = nil
+}
+
+func (s *AuthorizationTest) TestRolesAndRedaction() {
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	mapStore := storage.NewSqlMapStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	lockStore := storage.NewSqlLockStore(s.db)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, mapStore)
+	mapsSrv := server.NewMapServer(mapStore, storage.NewSqlEventStore(s.db), lockStore, engine.NewReceiptBroker(), nil)
+	authorizer := auth.NewAuthorizer(gamesStore, mapStore)
+
+	user := &v1.User{Uid: "test"}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err := usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err)
+
+	contexts := make(map[string]context.Context)
+	actors := make(map[string]*v1.Actor)
+	for _, name := range []string{"frodo", "sam", "gandalf", "gollum"} {
+		actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: user.Uid, SourceIdentity: name, Source: v1.Actor_APP_DISCORD})
+		s.Require().NoError(err)
+		actors[name] = actor
+		contexts[name], _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actor})
+	}
+
+	game, err := gamesSrv.CreateGame(contexts["frodo"], &v1.CreateGameRequest{
+		Name:             "test game",
+		Participants:     []*v1.Actor{actors["frodo"]},
+		Spectators:       []*v1.Actor{actors["sam"]},
+		DungeonMasterUid: &actors["gandalf"].Uid,
+	})
+	s.Require().NoError(err)
+
+	gameMap, err := mapStore.CreateMap(contexts["frodo"], &v1.CreateMapRequest{GameUid: game.Uid, Name: "shire", MaxX: 1, MaxY: 1})
+	s.Require().NoError(err)
+	s.Require().NoError(mapStore.CreateCoordinate(contexts["frodo"], &v1.MapCoordinateDetail{
+		Uid:      "bag-end",
+		GameUid:  game.Uid,
+		MapUid:   gameMap.Uid,
+		Position: &v1.MapPosition{X: 0, Y: 0},
+		Actors:   []*v1.Actor{actors["frodo"]},
+		Sprites: []*v1.Sprite{{
+			Uid:          "ring",
+			LoreInternal: "the one ring",
+			LorePublic:   "a plain gold ring",
+		}},
+	}))
+
+	// call runs a request through the authorizer the way the grpc server would
+	call := func(name string, method string, req any, handler grpc.UnaryHandler) (any, error) {
+		return authorizer.UnaryServerAuthorizeFunc(contexts[name], req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	}
+	detail := func(name string) (*v1.MapDetail, error) {
+		res, err := call(name, v1.Maps_GetMapDetail_FullMethodName, &v1.GetMapRequest{Uid: gameMap.Uid}, func(ctx context.Context, req any) (any, error) {
+			return mapsSrv.GetMapDetail(ctx, req.(*v1.GetMapRequest))
+		})
+		if err != nil {
+			return nil, err
+		}
+		return res.(*v1.MapDetail), nil
+	}
+
+	_, err = detail("gollum")
+	s.Equal(codes.PermissionDenied, status.Code(err), "outsiders should not see the map")
+	_, err = detail("sam")
+	s.Equal(codes.PermissionDenied, status.Code(err), "spectators should only see what they have discovered")
+
+	seen, err := detail("frodo")
+	s.Require().NoError(err, "the owner should see the whole map")
+	ring := seen.Coordinates[0].Sprites[0]
+	s.Empty(ring.LoreInternal, "the owner should not read hidden lore")
+	s.Equal("a plain gold ring", ring.LorePublic)
+
+	seen, err = detail("gandalf")
+	s.Require().NoError(err)
+	s.Equal("the one ring", seen.Coordinates[0].Sprites[0].LoreInternal, "the dungeon master should read hidden lore")
+
+	known, err := call("frodo", v1.Maps_GetKnownMap_FullMethodName, &v1.GetKnownMapRequest{GameUid: game.Uid}, func(ctx context.Context, req any) (any, error) {
+		return mapsSrv.GetKnownMap(ctx, req.(*v1.GetKnownMapRequest))
+	})
+	s.Require().NoError(err)
+	s.Require().Len(known.(*v1.KnownMap).Coordinates, 1, "frodo should see where they stand")
+	s.Empty(known.(*v1.KnownMap).Coordinates[0].Coordinate.Sprites[0].LoreInternal, "the known map should only show public lore")
+
+	peeked, err := call("frodo", v1.Maps_PeekCoordinate_FullMethodName, &v1.PeekCoordinateRequest{GameUid: game.Uid, Coordinate: &v1.MapPosition{X: 0, Y: 0}}, func(ctx context.Context, req any) (any, error) {
+		return mapsSrv.PeekCoordinate(ctx, req.(*v1.PeekCoordinateRequest))
+	})
+	s.Require().NoError(err)
+	s.Empty(peeked.(*v1.MapCoordinateDetail).Sprites[0].LoreInternal, "a peek should only show public lore")
+
+	coordinates, err := mapStore.GetCoordinates(contexts["frodo"], gameMap.Uid)
+	s.Require().NoError(err)
+	s.Equal("the one ring", coordinates[0].Sprites[0].LoreInternal, "redaction should never touch stored lore")
+
+	endGame := func(ctx context.Context, req any) (any, error) {
+		return gamesSrv.EndGame(ctx, req.(*v1.EndGameRequest))
+	}
+	_, err = call("sam", v1.Games_EndGame_FullMethodName, &v1.EndGameRequest{GameUid: game.Uid}, endGame)
+	s.Equal(codes.PermissionDenied, status.Code(err), "spectators should not end the game")
+	_, err = call("frodo", v1.Games_EndGame_FullMethodName, &v1.EndGameRequest{GameUid: game.Uid}, endGame)
+	s.NoError(err, "the owner should be able to end the game")
+
+	_, err = call("frodo", v1.Users_RegisterActor_FullMethodName, &v1.RegisterActorRequest{UserId: "someone-else"}, func(ctx context.Context, req any) (any, error) {
+		return usersSrv.RegisterActor(ctx, req.(*v1.RegisterActorRequest))
+	})
+	s.Equal(codes.PermissionDenied, status.Code(err), "actors should only be registered for yourself")
+	registerActor := func(ctx context.Context, req any) (any, error) {
+		return usersSrv.RegisterActor(ctx, req.(*v1.RegisterActorRequest))
+	}
+	for _, source := range []v1.Actor_Source{v1.Actor_APP_DISCORD, v1.Actor_APP_SLACK, v1.Actor_APP_TELNET} {
+		_, err = call("frodo", v1.Users_RegisterActor_FullMethodName, &v1.RegisterActorRequest{UserId: user.Uid, SourceIdentity: "sam", Source: source}, registerActor)
+		s.Equal(codes.PermissionDenied, status.Code(err), "players should not claim an identity of %s", source)
+	}
+	_, err = call("frodo", v1.Users_RegisterActor_FullMethodName, &v1.RegisterActorRequest{UserId: user.Uid, SourceIdentity: "frodo", Source: v1.Actor_SYSTEM}, registerActor)
+	s.NoError(err, "players should be able to register actors of their own")
+	resolved, err := usersSrv.GetActorBySource(systemCtx, &v1.GetActorBySourceRequest{Source: v1.Actor_APP_DISCORD, SourceIdentity: "sam"})
+	s.Require().NoError(err)
+	s.Equal(actors["sam"].Uid, resolved.Uid, "the identity should still resolve to the actor the front end registered")
+
+	_, err = call("frodo", "/overseer.v1.Unknown/Method", &v1.User{}, func(ctx context.Context, req any) (any, error) {
+		return nil, nil
+	})
+	s.Equal(codes.PermissionDenied, status.Code(err), "methods without a policy should be denied")
+}
+
+func (s *AuthorizationTest) TestJoinGame() {
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	gamesSrv := server.NewGameServer(usersSrv, storage.NewSqlLockStore(s.db), gamesStore, storage.NewSqlMapStore(s.db))
+
+	user := &v1.User{Uid: "test"}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err := usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err)
+
+	contexts := make(map[string]context.Context)
+	actors := make(map[string]*v1.Actor)
+	for _, name := range []string{"frodo", "sam", "merry"} {
+		actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: user.Uid, SourceIdentity: name, Source: v1.Actor_APP_DISCORD})
+		s.Require().NoError(err)
+		actors[name] = actor
+		contexts[name], _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actor})
+	}
+
+	game, err := gamesSrv.CreateGame(contexts["frodo"], &v1.CreateGameRequest{Name: "test game", Participants: []*v1.Actor{actors["frodo"]}})
+	s.Require().NoError(err)
+
+	joined, err := gamesSrv.JoinGame(contexts["sam"], &v1.JoinGameRequest{GameUid: game.Uid})
+	s.Require().NoError(err)
+	s.Len(joined.Participants, 2)
+
+	joined, err = gamesSrv.JoinGame(contexts["sam"], &v1.JoinGameRequest{GameUid: game.Uid})
+	s.Require().NoError(err)
+	s.Len(joined.Participants, 2, "joining twice should not add the actor twice")
+
+	joined, err = gamesSrv.JoinGame(contexts["merry"], &v1.JoinGameRequest{GameUid: game.Uid, Spectator: true})
+	s.Require().NoError(err)
+	s.Len(joined.Spectators, 1)
+	joined, err = gamesSrv.JoinGame(contexts["merry"], &v1.JoinGameRequest{GameUid: game.Uid})
+	s.Require().NoError(err)
+	s.Len(joined.Participants, 3)
+	s.Empty(joined.Spectators, "a spectator joining as a player should stop spectating")
+
+	stored, err := gamesSrv.GetGame(contexts["frodo"], &v1.GetGameRequest{GameUid: game.Uid})
+	s.Require().NoError(err)
+	s.Len(stored.Participants, 3)
+
+	_, err = gamesSrv.EndGame(contexts["frodo"], &v1.EndGameRequest{GameUid: game.Uid})
+	s.Require().NoError(err)
+	_, err = gamesSrv.JoinGame(contexts["sam"], &v1.JoinGameRequest{GameUid: game.Uid, Spectator: true})
+	s.Equal(codes.FailedPrecondition, status.Code(err), "ended games cannot be joined")
+}
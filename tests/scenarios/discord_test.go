@@ -0,0 +1,583 @@
+package scenarios
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/client"
+	"overseer/common"
+	discordhandlers "overseer/discord/handlers"
+	"overseer/discord/sessions"
+	"overseer/engine"
+	"overseer/server"
+	"overseer/storage"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+)
+
+type DiscordTest struct {
+	db     *gorm.DB
+	dbFile string
+	suite.Suite
+}
+
+func TestDiscord(t *testing.T) {
+	suite.Run(t, new(DiscordTest))
+}
+
+func (s *DiscordTest) SetupTest() {
+	s.dbFile = path.Join(os.TempDir(), fmt.Sprintf("test-%s.db", uuid.NewString()))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *DiscordTest) TearDownTest() {
+	s.Require().NoError(os.Remove(s.dbFile), "failed to remove test database")
+	s.db = nil
+}
+
+// discordResponse is an answer the bot gave to an interaction through the fake discord api
+type discordResponse struct {
+	interaction string
+	// deferred responses are answered later by editing them
+	deferred  bool
+	edit      bool
+	ephemeral bool
+	content   string
+}
+
+// discordPost is an embed the bot posted to a channel through the fake discord api
+type discordPost struct {
+	channel string
+	embed   *discordgo.MessageEmbed
+}
+
+// fakeDiscordApi stands in for the discord rest api, it records the responses to interactions and the embeds posted to channels.
+// direct message channels are named after their recipient and everything else is accepted
+type fakeDiscordApi struct {
+	mu        sync.Mutex
+	responses []discordResponse
+	posts     []discordPost
+}
+
+func (f *fakeDiscordApi) RoundTrip(r *http.Request) (*http.Response, error) {
+	var body struct {
+		Type    discordgo.InteractionResponseType `json:"type"`
+		Content *string                           `json:"content"`
+		Data    *struct {
+			Content string                 `json:"content"`
+			Flags   discordgo.MessageFlags `json:"flags"`
+		} `json:"data"`
+		Embeds      []*discordgo.MessageEmbed `json:"embeds"`
+		RecipientId string                    `json:"recipient_id"`
+	}
+	if r.Body != nil {
+		contents, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(contents) > 0 {
+			if err = json.Unmarshal(contents, &body); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	reply := "{}"
+	f.mu.Lock()
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/users/@me/channels"):
+		reply = fmt.Sprintf(`{"id":"dm-%s","type":1}`, body.RecipientId)
+	case r.Method == http.MethodPost && segments[len(segments)-1] == "messages" && segments[len(segments)-3] == "channels":
+		for _, embed := range body.Embeds {
+			f.posts = append(f.posts, discordPost{channel: segments[len(segments)-2], embed: embed})
+		}
+	case r.Method == http.MethodPost && segments[len(segments)-1] == "callback":
+		response := discordResponse{
+			interaction: segments[len(segments)-3],
+			deferred:    body.Type == discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		}
+		if body.Data != nil {
+			response.content = body.Data.Content
+			response.ephemeral = body.Data.Flags&discordgo.MessageFlagsEphemeral != 0
+		}
+		f.responses = append(f.responses, response)
+	case r.Method == http.MethodPatch && segments[len(segments)-1] == "@original":
+		response := discordResponse{interaction: segments[len(segments)-3], edit: true}
+		if body.Content != nil {
+			response.content = *body.Content
+		}
+		f.responses = append(f.responses, response)
+	}
+	f.mu.Unlock()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(reply)),
+		Request:    r,
+	}, nil
+}
+
+// answers returns what the bot finally told the caller of each interaction, an edit replaces the deferred response
+func (f *fakeDiscordApi) answers() []discordResponse {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	answers := make([]discordResponse, 0)
+	for _, response := range f.responses {
+		if response.edit && len(answers) > 0 && answers[len(answers)-1].deferred {
+			answers[len(answers)-1].content = response.content
+			answers[len(answers)-1].deferred = false
+			continue
+		}
+		answers = append(answers, response)
+	}
+	return answers
+}
+
+// channels returns the channels the embeds whose description contains the content were posted to, in order
+func (f *fakeDiscordApi) channels(content string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	channels := make([]string, 0)
+	for _, post := range f.posts {
+		if strings.Contains(post.embed.Description, content) {
+			channels = append(channels, post.channel)
+		}
+	}
+	return channels
+}
+
+// slashCommand builds the interaction discord sends when a member of the guild uses a command
+func slashCommand(userId string, name string, options ...*discordgo.ApplicationCommandInteractionDataOption) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:        uuid.NewString(),
+		AppID:     "app",
+		Token:     "token",
+		Type:      discordgo.InteractionApplicationCommand,
+		GuildID:   "G1",
+		ChannelID: "C1",
+		Member:    &discordgo.Member{User: &discordgo.User{ID: userId, Username: userId}},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name:    name,
+			Options: options,
+		},
+	}}
+}
+
+// discordBot is a bot talking to a served overseer through the fake discord api
+type discordBot struct {
+	dispatch func(*discordgo.Session, *discordgo.InteractionCreate)
+	session  *discordgo.Session
+	manager  *sessions.Manager
+	users    v1.UsersServer
+	games    v1.GamesServer
+	broker   *engine.ReceiptBroker
+	stop     func()
+}
+
+// discordBot serves the overseer and starts a bot talking to it through the fake discord api, bindings are kept in the file
+func (s *DiscordTest) discordBot(api *fakeDiscordApi, file string) *discordBot {
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	eventStore := storage.NewSqlEventStore(s.db)
+	mapStore := storage.NewSqlMapStore(s.db)
+	lockStore := storage.NewSqlLockStore(s.db)
+	keyStore := storage.NewSqlApiKeyStore(s.db)
+	usersSrv := server.NewUserServer(userStore, keyStore, nil)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, mapStore)
+	broker := engine.NewReceiptBroker()
+	eventSrv := server.NewEventServer(engine.NewEventBus([]engine.EventHandler{}, gamesSrv, usersSrv, eventStore, broker), eventStore, gamesStore)
+
+	address, stop, err := serveOverseer(eventSrv, usersSrv, gamesSrv, server.NewMapServer(mapStore, eventStore, lockStore, broker, nil),
+		&testAuthenticator{users: userStore, keys: keyStore},
+		auth.NewAuthorizer(gamesStore, mapStore),
+	)
+	s.Require().NoError(err)
+	overseer, err := client.NewOverseerClient(address, "test")
+	s.Require().NoError(err)
+
+	session, err := discordgo.New("Bot test")
+	s.Require().NoError(err)
+	session.Client = &http.Client{Transport: api}
+	manager := sessions.NewManager(overseer, session, file)
+
+	return &discordBot{
+		dispatch: discordhandlers.InteractionCreate(overseer, manager),
+		session:  session,
+		manager:  manager,
+		users:    usersSrv,
+		games:    gamesSrv,
+		broker:   broker,
+		stop: func() {
+			manager.Close()
+			overseer.Close()
+			stop()
+		},
+	}
+}
+
+func (s *DiscordTest) TestSlashCommands() {
+	api := &fakeDiscordApi{}
+	bot := s.discordBot(api, "")
+	defer bot.stop()
+	systemCtx, _ := auth.SystemContext(context.Background())
+
+	for _, tc := range []struct {
+		name      string
+		event     *discordgo.InteractionCreate
+		content   string
+		ephemeral bool
+	}{
+		{
+			name:      "playing before registering",
+			event:     slashCommand("U1", "say", &discordgo.ApplicationCommandInteractionDataOption{Name: "message", Type: discordgo.ApplicationCommandOptionString, Value: "hello"}),
+			content:   "You need to `/register` before you can play",
+			ephemeral: false,
+		},
+		{
+			name:      "registering",
+			event:     slashCommand("U1", "register"),
+			content:   "Welcome U1, you are registered as",
+			ephemeral: true,
+		},
+		{
+			name:      "registering again",
+			event:     slashCommand("U1", "register"),
+			content:   "You are already registered as",
+			ephemeral: true,
+		},
+		{
+			name:      "playing without a game in the channel",
+			event:     slashCommand("U1", "say", &discordgo.ApplicationCommandInteractionDataOption{Name: "message", Type: discordgo.ApplicationCommandOptionString, Value: "hello"}),
+			content:   "There is no game in this channel",
+			ephemeral: false,
+		},
+	} {
+		s.Run(tc.name, func() {
+			bot.dispatch(bot.session, tc.event)
+			answers := api.answers()
+			s.Require().NotEmpty(answers)
+			answer := answers[len(answers)-1]
+			s.Equal(tc.event.ID, answer.interaction, "the interaction should be answered")
+			s.Contains(answer.content, tc.content)
+			s.Equal(tc.ephemeral, answer.ephemeral)
+		})
+	}
+
+	actor, err := bot.users.GetActorBySource(systemCtx, &v1.GetActorBySourceRequest{Source: v1.Actor_APP_DISCORD, SourceIdentity: "U1"})
+	s.Require().NoError(err, "registering should create an actor for the discord user")
+	answers := api.answers()
+	s.Contains(answers[1].content, actor.Uid, "the player should be told their actor")
+
+	count := len(api.answers())
+	bot.dispatch(bot.session, slashCommand("U1", "no-such-command"))
+	s.Len(api.answers(), count, "unknown commands should be ignored")
+}
+
+func (s *DiscordTest) TestResumingAHalfFinishedRegistration() {
+	api := &fakeDiscordApi{}
+	bot := s.discordBot(api, "")
+	defer bot.stop()
+	systemCtx, _ := auth.SystemContext(context.Background())
+
+	// an earlier attempt got as far as creating the user before it failed
+	user, err := bot.users.RegisterUser(systemCtx, &v1.User{Uid: common.SourceUserId(v1.Actor_APP_DISCORD, "U2")})
+	s.Require().NoError(err)
+
+	bot.dispatch(bot.session, slashCommand("U2", "register"))
+	answers := api.answers()
+	s.Require().Len(answers, 1)
+	s.Contains(answers[0].content, "Welcome U2, you are registered as", "registering again should finish the job")
+
+	actor, err := bot.users.GetActorBySource(systemCtx, &v1.GetActorBySourceRequest{Source: v1.Actor_APP_DISCORD, SourceIdentity: "U2"})
+	s.Require().NoError(err)
+	owner, err := bot.users.GetUser(systemCtx, actor)
+	s.Require().NoError(err)
+	s.Equal(user.Uid, owner.Uid, "the actor should belong to the user the earlier attempt created")
+	s.Contains(answers[0].content, actor.Uid)
+	actors, err := bot.users.GetActors(systemCtx, user)
+	s.Require().NoError(err)
+	s.Len(actors.GetActors(), 1, "the player should be left with a single actor")
+}
+
+func (s *DiscordTest) TestStoppingWhenTheCallerCannotBeIdentified() {
+	api := &fakeDiscordApi{}
+	bot := s.discordBot(api, "")
+	// the overseer going away leaves the bot unable to tell who anyone is
+	bot.stop()
+
+	bot.dispatch(bot.session, slashCommand("U1", "register"))
+	answers := api.answers()
+	s.Require().Len(answers, 1, "the command should not run once the caller cannot be identified")
+	s.True(answers[0].ephemeral)
+	s.True(strings.HasPrefix(answers[0].content, "Something went wrong:"), answers[0].content)
+}
+
+// discordTable registers two discord players and one playing over telnet and starts a game for each of the names
+func (s *DiscordTest) discordTable(bot *discordBot, names ...string) (map[string]*v1.Actor, []*v1.Game) {
+	systemCtx, _ := auth.SystemContext(context.Background())
+	user := &v1.User{Uid: "test"}
+	_, err := bot.users.RegisterUser(systemCtx, user)
+	s.Require().NoError(err)
+
+	actors := make(map[string]*v1.Actor)
+	for identity, source := range map[string]v1.Actor_Source{"U1": v1.Actor_APP_DISCORD, "U2": v1.Actor_APP_DISCORD, "bilbo": v1.Actor_APP_TELNET} {
+		actor, err := bot.users.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: user.Uid, SourceIdentity: identity, Source: source})
+		s.Require().NoError(err)
+		actors[identity] = actor
+	}
+
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user, Actor: actors["U1"]})
+	games := make([]*v1.Game, 0, len(names))
+	for _, name := range names {
+		game, err := bot.games.CreateGame(ctx, &v1.CreateGameRequest{
+			Name:         name,
+			Participants: []*v1.Actor{actors["U1"], actors["U2"], actors["bilbo"]},
+		})
+		s.Require().NoError(err)
+		games = append(games, game)
+	}
+	return actors, games
+}
+
+func (s *DiscordTest) TestBindingChannels() {
+	bindings := path.Join(os.TempDir(), fmt.Sprintf("bindings-%s.json", uuid.NewString()))
+	defer os.Remove(bindings)
+	bot := s.discordBot(&fakeDiscordApi{}, bindings)
+	_, games := s.discordTable(bot, "the shire", "mordor")
+	shire, mordor := games[0].Uid, games[1].Uid
+
+	for _, tc := range []struct {
+		name   string
+		bind   map[string]string
+		unbind string
+		want   map[string]string
+	}{
+		{
+			name: "binding a channel",
+			bind: map[string]string{"C1": shire},
+			want: map[string]string{"C1": shire},
+		},
+		{
+			name: "binding a second channel to the same game",
+			bind: map[string]string{"C2": shire},
+			want: map[string]string{"C1": shire, "C2": shire},
+		},
+		{
+			name: "binding a channel again replaces its game",
+			bind: map[string]string{"C1": mordor},
+			want: map[string]string{"C1": mordor, "C2": shire},
+		},
+		{
+			name:   "unbinding a game frees every channel bound to it",
+			bind:   map[string]string{"C3": shire},
+			unbind: shire,
+			want:   map[string]string{"C1": mordor},
+		},
+		{
+			name:   "unbinding a game no channel is bound to",
+			unbind: shire,
+			want:   map[string]string{"C1": mordor},
+		},
+	} {
+		s.Run(tc.name, func() {
+			for channel, game := range tc.bind {
+				s.Require().NoError(bot.manager.Bind(channel, game))
+			}
+			if tc.unbind != "" {
+				bot.manager.UnbindGame(tc.unbind)
+			}
+			for _, channel := range []string{"C1", "C2", "C3"} {
+				game, ok := bot.manager.Game(channel)
+				want, bound := tc.want[channel]
+				s.Equal(bound, ok, "channel %s", channel)
+				s.Equal(want, game, "channel %s", channel)
+			}
+		})
+	}
+
+	bot.stop()
+	restarted := s.discordBot(&fakeDiscordApi{}, bindings)
+	defer restarted.stop()
+	s.Require().NoError(restarted.manager.Restore())
+	game, ok := restarted.manager.Game("C1")
+	s.True(ok, "the bindings should survive the bot restarting")
+	s.Equal(mordor, game)
+	_, ok = restarted.manager.Game("C2")
+	s.False(ok, "unbound channels should stay unbound")
+}
+
+func (s *DiscordTest) TestDeliveringReceipts() {
+	api := &fakeDiscordApi{}
+	bot := s.discordBot(api, "")
+	defer bot.stop()
+	actors, games := s.discordTable(bot, "the shire")
+	game := games[0]
+	s.Require().NoError(bot.manager.Bind("C1", game.Uid))
+	s.Require().NoError(bot.manager.Bind("C2", game.Uid))
+
+	for _, tc := range []struct {
+		name      string
+		utterance *v1.UtteranceEffect
+		channels  []string
+	}{
+		{
+			name:      "spoken to the table",
+			utterance: &v1.UtteranceEffect{Actor: actors["U1"].Uid, Content: "hello everyone", Audience: v1.UtteranceEffect_TABLE, Recipients: []string{actors["U2"].Uid, actors["bilbo"].Uid}},
+			channels:  []string{"C1", "C2"},
+		},
+		{
+			name:      "whispered to a discord player",
+			utterance: &v1.UtteranceEffect{Actor: actors["U1"].Uid, Content: "psst sam", Audience: v1.UtteranceEffect_PLAYER, Whisper: true, Recipients: []string{actors["U2"].Uid}},
+			channels:  []string{"dm-U2"},
+		},
+		{
+			name:      "whispered to several players on different front ends",
+			utterance: &v1.UtteranceEffect{Actor: actors["U2"].Uid, Content: "run", Audience: v1.UtteranceEffect_PLAYERS, Whisper: true, Recipients: []string{actors["U1"].Uid, actors["bilbo"].Uid}},
+			channels:  []string{"dm-U1"},
+		},
+		{
+			name:      "answered by the dungeon master",
+			utterance: &v1.UtteranceEffect{Actor: auth.DungeonMasterActorId, Content: "The cave is dark.", Audience: v1.UtteranceEffect_DUNGEON_MASTER, Recipients: []string{actors["U1"].Uid, actors["U2"].Uid, actors["bilbo"].Uid}},
+			channels:  []string{"C1", "C2"},
+		},
+	} {
+		s.Run(tc.name, func() {
+			bot.broker.Publish(&v1.EventReceipt{
+				Uid:     common.GenerateUniqueId(),
+				GameUid: game.Uid,
+				Effect:  &v1.EventReceipt_Utterance{Utterance: tc.utterance},
+			})
+			s.Eventually(func() bool {
+				return len(api.channels(tc.utterance.Content)) >= len(tc.channels)
+			}, 5*time.Second, 10*time.Millisecond, "the receipt should be delivered")
+			s.Never(func() bool {
+				return len(api.channels(tc.utterance.Content)) > len(tc.channels)
+			}, 100*time.Millisecond, 10*time.Millisecond, "the receipt should be delivered once to each destination")
+			s.ElementsMatch(tc.channels, api.channels(tc.utterance.Content))
+		})
+	}
+}
+
+func (s *DiscordTest) TestRenderingReceipts() {
+	bot := s.discordBot(&fakeDiscordApi{}, "")
+	defer bot.stop()
+	actors, _ := s.discordTable(bot)
+	damage := &v1.DiceRoll{Total: 4}
+
+	for _, tc := range []struct {
+		name    string
+		receipt *v1.EventReceipt
+		want    *discordgo.MessageEmbed
+	}{
+		{
+			name:    "an acknowledgement",
+			receipt: &v1.EventReceipt{Effect: &v1.EventReceipt_Ack{Ack: &v1.Acknowledgement{Message: proto.String("game created successfully")}}},
+			want:    &discordgo.MessageEmbed{Description: "game created successfully"},
+		},
+		{
+			name:    "an acknowledgement without a message",
+			receipt: &v1.EventReceipt{Effect: &v1.EventReceipt_Ack{Ack: &v1.Acknowledgement{}}},
+		},
+		{
+			name:    "an error",
+			receipt: &v1.EventReceipt{Effect: &v1.EventReceipt_Error{Error: &v1.ErrorEffect{Type: v1.ErrorEffect_INVALID, Message: "the way is blocked"}}},
+			want:    &discordgo.MessageEmbed{Title: "Something went wrong", Description: "the way is blocked"},
+		},
+		{
+			name:    "a discord player speaking",
+			receipt: &v1.EventReceipt{Effect: &v1.EventReceipt_Utterance{Utterance: &v1.UtteranceEffect{Actor: actors["U1"].Uid, Content: "hello", Audience: v1.UtteranceEffect_TABLE}}},
+			want:    &discordgo.MessageEmbed{Description: "<@U1>: hello"},
+		},
+		{
+			name:    "a player on another front end speaking",
+			receipt: &v1.EventReceipt{Effect: &v1.EventReceipt_Utterance{Utterance: &v1.UtteranceEffect{Actor: actors["bilbo"].Uid, Content: "good morning", Audience: v1.UtteranceEffect_PLAYER, Recipients: []string{actors["U1"].Uid}}}},
+			want:    &discordgo.MessageEmbed{Description: "**bilbo**: good morning"},
+		},
+		{
+			name:    "a player whispering",
+			receipt: &v1.EventReceipt{Effect: &v1.EventReceipt_Utterance{Utterance: &v1.UtteranceEffect{Actor: actors["U1"].Uid, Content: "psst", Audience: v1.UtteranceEffect_PLAYER, Whisper: true, Recipients: []string{actors["U2"].Uid}}}},
+			want:    &discordgo.MessageEmbed{Title: "Whisper", Description: "<@U1>: psst"},
+		},
+		{
+			name:    "the dungeon master speaking",
+			receipt: &v1.EventReceipt{Effect: &v1.EventReceipt_Utterance{Utterance: &v1.UtteranceEffect{Actor: auth.DungeonMasterActorId, Content: "The cave is dark.", Audience: v1.UtteranceEffect_DUNGEON_MASTER}}},
+			want:    &discordgo.MessageEmbed{Description: "**Dungeon Master**: The cave is dark."},
+		},
+		{
+			name:    "the dungeon master whispering",
+			receipt: &v1.EventReceipt{Effect: &v1.EventReceipt_Utterance{Utterance: &v1.UtteranceEffect{Actor: auth.DungeonMasterActorId, Content: "You sense a trap.", Audience: v1.UtteranceEffect_DUNGEON_MASTER, Whisper: true, Recipients: []string{actors["U1"].Uid}}}},
+			want:    &discordgo.MessageEmbed{Title: "Whisper", Description: "**Dungeon Master**: You sense a trap."},
+		},
+		{
+			name:    "someone the overseer does not know speaking",
+			receipt: &v1.EventReceipt{Effect: &v1.EventReceipt_Utterance{Utterance: &v1.UtteranceEffect{Actor: "nobody", Content: "hello?", Audience: v1.UtteranceEffect_TABLE}}},
+			want:    &discordgo.MessageEmbed{Description: "**nobody**: hello?"},
+		},
+		{
+			name: "a movement over difficult terrain",
+			receipt: &v1.EventReceipt{Effect: &v1.EventReceipt_GameState{GameState: &v1.GameStateEffect{Change: &v1.GameStateEffect_Movement{Movement: &v1.MovementEffect{
+				Actor: actors["U2"], Direction: "north", Destination: &v1.MapPosition{X: 0, Y: 1}, DifficultTerrain: true,
+			}}}}},
+			want: &discordgo.MessageEmbed{Description: "<@U2> moves north to (0, 1)", Footer: &discordgo.MessageEmbedFooter{Text: "difficult terrain"}},
+		},
+		{
+			name: "a successful attack",
+			receipt: &v1.EventReceipt{Effect: &v1.EventReceipt_GameState{GameState: &v1.GameStateEffect{Change: &v1.GameStateEffect_Action{Action: &v1.ActionEffect{
+				Actor: actors["U1"], Action: "I swing at the goblin", Check: &v1.DiceRoll{Total: 15}, Difficulty: 12, Outcome: v1.ActionEffect_SUCCESS, Damage: damage,
+			}}}}},
+			want: &discordgo.MessageEmbed{Title: "I swing at the goblin", Description: "<@U1> rolled 15 against 12", Fields: []*discordgo.MessageEmbedField{
+				{Name: "Outcome", Value: "success", Inline: true},
+				{Name: "Damage", Value: "4", Inline: true},
+			}},
+		},
+		{
+			name: "a failed action",
+			receipt: &v1.EventReceipt{Effect: &v1.EventReceipt_GameState{GameState: &v1.GameStateEffect{Change: &v1.GameStateEffect_Action{Action: &v1.ActionEffect{
+				Actor: actors["U2"], Action: "I climb the wall", Check: &v1.DiceRoll{Total: 3}, Difficulty: 12, Outcome: v1.ActionEffect_FAILURE,
+			}}}}},
+			want: &discordgo.MessageEmbed{Title: "I climb the wall", Description: "<@U2> rolled 3 against 12", Fields: []*discordgo.MessageEmbedField{
+				{Name: "Outcome", Value: "failure", Inline: true},
+			}},
+		},
+		{
+			name:    "a change of game state with nothing to show",
+			receipt: &v1.EventReceipt{Effect: &v1.EventReceipt_GameState{GameState: &v1.GameStateEffect{}}},
+		},
+	} {
+		s.Run(tc.name, func() {
+			embed := bot.manager.Embed(context.Background(), tc.receipt)
+			if tc.want == nil {
+				s.Nil(embed, "there should be nothing to show")
+				return
+			}
+			s.Require().NotNil(embed)
+			s.Equal(tc.want.Title, embed.Title)
+			s.Equal(tc.want.Description, embed.Description)
+			s.Equal(tc.want.Footer, embed.Footer)
+			s.Equal(tc.want.Fields, embed.Fields)
+		})
+	}
+
+	whisper := bot.manager.Embed(context.Background(), &v1.EventReceipt{Effect: &v1.EventReceipt_Utterance{Utterance: &v1.UtteranceEffect{Actor: actors["U1"].Uid, Content: "psst", Whisper: true}}})
+	spoken := bot.manager.Embed(context.Background(), &v1.EventReceipt{Effect: &v1.EventReceipt_Utterance{Utterance: &v1.UtteranceEffect{Actor: actors["U1"].Uid, Content: "psst"}}})
+	s.NotEqual(spoken.Color, whisper.Color, "whispers should stand out from what the table hears")
+}
@@ -0,0 +1,367 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/engine/handlers"
+	"overseer/generative"
+	"overseer/generative/ollama"
+	"overseer/server"
+	"overseer/storage"
+	"path"
+	"testing"
+	"text/template"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type DungeonMasterTest struct {
+	db     *gorm.DB
+	dbFile string
+	suite.Suite
+}
+
+func TestDungeonMaster(t *testing.T) {
+	suite.Run(t, new(DungeonMasterTest))
+}
+
+func (s *DungeonMasterTest) SetupTest() {
+	s.dbFile = path.Join(os.TempDir(), fmt.Sprintf("test-%s.db", uuid.NewString()))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *DungeonMasterTest) TearDownTest() {
+	s.Require().NoError(os.Remove(s.dbFile), "failed to remove test database")
+	s.db = nil
+}
+
+func converseStream(chunks ...string) chan ollama.ConverseResponse {
+	stream := make(chan ollama.ConverseResponse, len(chunks))
+	for _, chunk := range chunks {
+		stream <- ollama.ConverseResponse{Message: ollama.ConversationMessage{Role: ollama.Assistant, Content: chunk}}
+	}
+	close(stream)
+	return stream
+}
+
+func (s *DungeonMasterTest) TestAskingTheDungeonMaster() {
+	mockOllama := new(MockOllamaClient)
+	mockTemplatingClient := new(MockTemplatingClient)
+	mockTemplatingClient.On("DungeonMasterTemplate").Return(template.Must(template.New("mock").Parse("you are the dungeon master")))
+	dm, err := generative.NewOllamaDungeonMasterService(mockTemplatingClient, mockOllama)
+	s.Require().NoError(err)
+
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	eventStore := storage.NewSqlEventStore(s.db)
+	lockStore := storage.NewSqlLockStore(s.db)
+	mapStore := storage.NewSqlMapStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, mapStore)
+	eventBus := engine.NewEventBus(
+		[]engine.EventHandler{
+			handlers.NewDungeonMasterHandler(dm, gamesStore, mapStore, eventStore),
+		},
+		gamesSrv,
+		usersSrv,
+		eventStore,
+		engine.NewReceiptBroker(),
+	)
+	eventSrv := server.NewEventServer(eventBus, eventStore, gamesStore)
+	user := &v1.User{
+		Uid: "test",
+	}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{
+		User: user,
+	})
+
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err = usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err, "error should be nil on creating user")
+
+	actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{
+		UserId:         user.Uid,
+		SourceIdentity: "gandalf",
+		Source:         v1.Actor_APP_DISCORD,
+	})
+	s.Require().NoError(err, "error should be nil on creating actor")
+
+	ctx, _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{
+		User:  user,
+		Actor: actor,
+	})
+
+	game, err := gamesSrv.CreateGame(ctx, &v1.CreateGameRequest{
+		Name:         "test game",
+		Participants: []*v1.Actor{actor},
+	})
+	s.Require().NoError(err, "error should be nil")
+
+	var requests []ollama.ConverseRequest
+	capture := func(args mock.Arguments) {
+		requests = append(requests, args.Get(1).(ollama.ConverseRequest))
+	}
+	mockOllama.On("Converse", mock.Anything, mock.Anything).Return(converseStream("The cave is dark.", "\n\nA goblin ", "stirs."), nil).Run(capture).Once()
+	mockOllama.On("Converse", mock.Anything, mock.Anything).Return(converseStream("It flees."), nil).Run(capture).Once()
+
+	ask := func(content string, whisper bool) []*v1.EventReceipt {
+		receipts, err := eventSrv.Submit(ctx, &v1.Event{
+			GameUid: game.Uid,
+			Actor:   actor,
+			Origin:  &v1.Event_Discord{Discord: &v1.EventOriginDiscord{Guild: "test", Channel: "test"}},
+			Payload: &v1.Event_Interaction{Interaction: &v1.InteractionEvent{
+				Interaction: &v1.InteractionEvent_Utterance{Utterance: &v1.UtteranceInteraction{
+					Content:   content,
+					Utterance: &v1.UtteranceInteraction_DungeonMaster{DungeonMaster: &v1.DungeonMasterUtterance{Whisper: whisper}},
+				}},
+			}},
+		})
+		s.Require().NoError(err, "error should be nil on asking the dungeon master")
+		return receipts.Receipts
+	}
+
+	receipts := ask("what do I see?", false)
+	s.Require().Len(receipts, 2, "each paragraph of the reply should be its own receipt")
+	s.Equal("The cave is dark.", receipts[0].GetUtterance().GetContent())
+	s.Equal("A goblin stirs.", receipts[1].GetUtterance().GetContent())
+	s.Equal(auth.DungeonMasterActorId, receipts[0].GetUtterance().GetActor())
+	s.Equal([]string{actor.Uid}, receipts[0].GetUtterance().GetRecipients())
+
+	receipts = ask("I shout at the goblin", true)
+	s.Require().Len(receipts, 1)
+	s.True(receipts[0].GetUtterance().GetWhisper(), "a whispered question should be answered in a whisper")
+
+	s.Require().Len(requests, 2)
+	last := requests[1].Messages
+	s.Equal(ollama.System, last[0].Role, "conversation should start with the system prompt")
+	s.Equal(ollama.Assistant, last[1].Role, "previous replies should be remembered")
+	s.Equal("The cave is dark.", last[1].Content)
+	s.Equal(ollama.Assistant, last[2].Role)
+	s.Equal(ollama.User, last[len(last)-1].Role)
+	s.Equal("gandalf: I shout at the goblin", last[len(last)-1].Content)
+}
+
+func (s *DungeonMasterTest) TestTheTableHearsTheQuestionFirst() {
+	mockOllama := new(MockOllamaClient)
+	mockTemplatingClient := new(MockTemplatingClient)
+	mockTemplatingClient.On("DungeonMasterTemplate").Return(template.Must(template.New("mock").Parse("you are the dungeon master")))
+	dm, err := generative.NewOllamaDungeonMasterService(mockTemplatingClient, mockOllama)
+	s.Require().NoError(err)
+
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	eventStore := storage.NewSqlEventStore(s.db)
+	mapStore := storage.NewSqlMapStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	gamesSrv := server.NewGameServer(usersSrv, storage.NewSqlLockStore(s.db), gamesStore, mapStore)
+	// registered the same as the server does
+	eventSrv := server.NewEventServer(engine.NewEventBus(
+		[]engine.EventHandler{
+			engine.Register(handlers.NewUtteranceValidator(eventStore), engine.StageValidate, 0),
+			engine.Register(handlers.NewUtteranceHandler(gamesStore, eventStore), engine.StageMutate, 0),
+			engine.Register(handlers.NewDungeonMasterHandler(dm, gamesStore, mapStore, eventStore), engine.StageNarrate, 0),
+		},
+		gamesSrv,
+		usersSrv,
+		eventStore,
+		engine.NewReceiptBroker(),
+	), eventStore, gamesStore)
+
+	user := &v1.User{Uid: "test"}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err = usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err)
+	actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: user.Uid, SourceIdentity: "gandalf", Source: v1.Actor_APP_DISCORD})
+	s.Require().NoError(err)
+	ctx, _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actor})
+	game, err := gamesSrv.CreateGame(ctx, &v1.CreateGameRequest{Name: "test game", Participants: []*v1.Actor{actor}})
+	s.Require().NoError(err)
+
+	mockOllama.On("Converse", mock.Anything, mock.Anything).Return(converseStream("The cave is dark."), nil).Once()
+
+	ask := func(content string) []*v1.EventReceipt {
+		receipts, err := eventSrv.Submit(ctx, &v1.Event{
+			GameUid: game.Uid,
+			Actor:   actor,
+			Origin:  &v1.Event_Discord{Discord: &v1.EventOriginDiscord{Guild: "test", Channel: "test"}},
+			Payload: &v1.Event_Interaction{Interaction: &v1.InteractionEvent{
+				Interaction: &v1.InteractionEvent_Utterance{Utterance: &v1.UtteranceInteraction{
+					Content:   content,
+					Utterance: &v1.UtteranceInteraction_DungeonMaster{DungeonMaster: &v1.DungeonMasterUtterance{}},
+				}},
+			}},
+		})
+		s.Require().NoError(err)
+		return receipts.Receipts
+	}
+
+	receipts := ask("what do I see?")
+	s.Require().Len(receipts, 2)
+	s.Equal(actor.Uid, receipts[0].GetUtterance().GetActor(), "the question should be heard before it is answered")
+	s.Equal("what do I see?", receipts[0].GetUtterance().GetContent())
+	s.Equal(auth.DungeonMasterActorId, receipts[1].GetUtterance().GetActor())
+
+	receipts = ask("   ")
+	s.Require().Len(receipts, 1, "an empty utterance should go no further than validation")
+	s.Equal("you open your mouth but say nothing", receipts[0].GetError().GetMessage())
+	mockOllama.AssertNumberOfCalls(s.T(), "Converse", 1)
+}
+
+func (s *DungeonMasterTest) TestWhispersStayOutOfPublicReplies() {
+	mockOllama := new(MockOllamaClient)
+	mockTemplatingClient := new(MockTemplatingClient)
+	mockTemplatingClient.On("DungeonMasterTemplate").Return(template.Must(template.New("mock").Parse("you are the dungeon master")))
+	dm, err := generative.NewOllamaDungeonMasterService(mockTemplatingClient, mockOllama)
+	s.Require().NoError(err)
+
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	eventStore := storage.NewSqlEventStore(s.db)
+	mapStore := storage.NewSqlMapStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	gamesSrv := server.NewGameServer(usersSrv, storage.NewSqlLockStore(s.db), gamesStore, mapStore)
+	eventSrv := server.NewEventServer(engine.NewEventBus(
+		[]engine.EventHandler{
+			engine.Register(handlers.NewUtteranceValidator(eventStore), engine.StageValidate, 0),
+			engine.Register(handlers.NewUtteranceHandler(gamesStore, eventStore), engine.StageMutate, 0),
+			engine.Register(handlers.NewDungeonMasterHandler(dm, gamesStore, mapStore, eventStore), engine.StageNarrate, 0),
+		},
+		gamesSrv,
+		usersSrv,
+		eventStore,
+		engine.NewReceiptBroker(),
+	), eventStore, gamesStore)
+
+	user := &v1.User{Uid: "test"}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err = usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err)
+	actors := make(map[string]*v1.Actor)
+	contexts := make(map[string]context.Context)
+	for _, name := range []string{"frodo", "sam", "gollum"} {
+		actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: user.Uid, SourceIdentity: name, Source: v1.Actor_APP_DISCORD})
+		s.Require().NoError(err)
+		actors[name] = actor
+		contexts[name], _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actor})
+	}
+	game, err := gamesSrv.CreateGame(contexts["frodo"], &v1.CreateGameRequest{
+		Name:         "test game",
+		Participants: []*v1.Actor{actors["frodo"], actors["sam"], actors["gollum"]},
+	})
+	s.Require().NoError(err)
+
+	var prompts []string
+	capture := func(args mock.Arguments) {
+		prompt := ""
+		for _, message := range args.Get(1).(ollama.ConverseRequest).Messages {
+			prompt += message.Content + "\n"
+		}
+		prompts = append(prompts, prompt)
+	}
+	for range 3 {
+		mockOllama.On("Converse", mock.Anything, mock.Anything).Return(converseStream("The road goes ever on."), nil).Run(capture).Once()
+	}
+
+	say := func(name string, utterance *v1.UtteranceInteraction) {
+		_, err := eventSrv.Submit(contexts[name], &v1.Event{
+			GameUid: game.Uid,
+			Actor:   actors[name],
+			Origin:  &v1.Event_Discord{Discord: &v1.EventOriginDiscord{Guild: "test", Channel: "test"}},
+			Payload: &v1.Event_Interaction{Interaction: &v1.InteractionEvent{
+				Interaction: &v1.InteractionEvent_Utterance{Utterance: utterance},
+			}},
+		})
+		s.Require().NoError(err)
+	}
+	ask := func(name string, content string, whisper bool) string {
+		say(name, &v1.UtteranceInteraction{
+			Content:   content,
+			Utterance: &v1.UtteranceInteraction_DungeonMaster{DungeonMaster: &v1.DungeonMasterUtterance{Whisper: whisper}},
+		})
+		s.Require().NotEmpty(prompts)
+		return prompts[len(prompts)-1]
+	}
+
+	say("frodo", &v1.UtteranceInteraction{
+		Content:   "the ring is in my pocket",
+		Utterance: &v1.UtteranceInteraction_Player{Player: &v1.PlayerUtterance{Target: actors["sam"], Whisper: true}},
+	})
+	say("frodo", &v1.UtteranceInteraction{Content: "lovely weather"})
+
+	prompt := ask("sam", "what does frodo carry?", false)
+	s.Contains(prompt, "lovely weather", "what the table heard should be remembered")
+	s.NotContains(prompt, "the ring", "a whisper should never reach a reply the whole table hears")
+
+	prompt = ask("gollum", "where is my precious?", true)
+	s.NotContains(prompt, "the ring", "a whisper should not reach someone it was not addressed to")
+
+	prompt = ask("sam", "what does frodo carry?", true)
+	s.Contains(prompt, "the ring", "a private reply should remember what was whispered to the speaker")
+	s.NotContains(prompt, "where is my precious?", "someone else's private question should not be remembered")
+}
+
+func (s *DungeonMasterTest) TestABrokenReplyIsReported() {
+	fake := &fakeChatCompletions{chunks: []string{"The cave ", "is dark."}, broken: true}
+	openaiServer := httptest.NewServer(fake)
+	defer openaiServer.Close()
+	mockTemplatingClient := new(MockTemplatingClient)
+	mockTemplatingClient.On("DungeonMasterTemplate").Return(template.Must(template.New("mock").Parse("you are the dungeon master")))
+	dm, err := generative.NewOpenAIDungeonMasterService(mockTemplatingClient, fake.client(openaiServer, "sk-test"))
+	s.Require().NoError(err)
+
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	eventStore := storage.NewSqlEventStore(s.db)
+	mapStore := storage.NewSqlMapStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	gamesSrv := server.NewGameServer(usersSrv, storage.NewSqlLockStore(s.db), gamesStore, mapStore)
+	eventSrv := server.NewEventServer(engine.NewEventBus(
+		[]engine.EventHandler{
+			handlers.NewDungeonMasterHandler(dm, gamesStore, mapStore, eventStore),
+		},
+		gamesSrv,
+		usersSrv,
+		eventStore,
+		engine.NewReceiptBroker(),
+	), eventStore, gamesStore)
+
+	user := &v1.User{Uid: "test"}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err = usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err)
+	actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: user.Uid, SourceIdentity: "gandalf", Source: v1.Actor_APP_DISCORD})
+	s.Require().NoError(err)
+	ctx, _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actor})
+	game, err := gamesSrv.CreateGame(ctx, &v1.CreateGameRequest{Name: "test game", Participants: []*v1.Actor{actor}})
+	s.Require().NoError(err)
+
+	receipts, err := eventSrv.Submit(ctx, &v1.Event{
+		GameUid: game.Uid,
+		Actor:   actor,
+		Origin:  &v1.Event_Discord{Discord: &v1.EventOriginDiscord{Guild: "test", Channel: "test"}},
+		Payload: &v1.Event_Interaction{Interaction: &v1.InteractionEvent{
+			Interaction: &v1.InteractionEvent_Utterance{Utterance: &v1.UtteranceInteraction{
+				Content:   "what do I see?",
+				Utterance: &v1.UtteranceInteraction_DungeonMaster{DungeonMaster: &v1.DungeonMasterUtterance{}},
+			}},
+		}},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(receipts.Receipts, 2)
+	s.Equal("The cave is dark.", receipts.Receipts[0].GetUtterance().GetContent(), "what was said before the reply broke should be delivered")
+	s.Equal(v1.ErrorEffect_INTERNAL, receipts.Receipts[1].GetError().GetType(), "the player should learn the reply was cut short")
+}
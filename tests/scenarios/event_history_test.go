@@ -0,0 +1,249 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/engine/handlers"
+	"overseer/server"
+	"overseer/storage"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+type EventHistoryTest struct {
+	db     *gorm.DB
+	dbFile string
+	suite.Suite
+}
+
+func TestEventHistory(t *testing.T) {
+	suite.Run(t, new(EventHistoryTest))
+}
+
+func (s *EventHistoryTest) SetupTest() {
+	s.dbFile = path.Join(os.TempDir(), fmt.Sprintf("test-%s.db", uuid.NewString()))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *EventHistoryTest) TearDownTest() {
+	s.Require().NoError(os.Remove(s.dbFile), "failed to remove test database")
+	s.db = nil
+}
+
+func (s *EventHistoryTest) TestPagingThroughHistory() {
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	eventStore := storage.NewSqlEventStore(s.db)
+	lockStore := storage.NewSqlLockStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, storage.NewSqlMapStore(s.db))
+	eventBus := engine.NewEventBus(
+		[]engine.EventHandler{
+			handlers.NewUtteranceHandler(gamesStore, eventStore),
+		},
+		gamesSrv,
+		usersSrv,
+		eventStore,
+		engine.NewReceiptBroker(),
+	)
+	eventSrv := server.NewEventServer(eventBus, eventStore, gamesStore)
+	user := &v1.User{
+		Uid: "test",
+	}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{
+		User: user,
+	})
+
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err := usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err, "error should be nil on creating user")
+
+	actors := make([]*v1.Actor, 0)
+	for _, name := range []string{"frodo", "sam"} {
+		actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{
+			UserId:         user.Uid,
+			SourceIdentity: name,
+			Source:         v1.Actor_APP_DISCORD,
+		})
+		s.Require().NoError(err, "error should be nil on creating actor")
+		actors = append(actors, actor)
+	}
+
+	ctx, _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{
+		User:  user,
+		Actor: actors[0],
+	})
+
+	game, err := gamesSrv.CreateGame(ctx, &v1.CreateGameRequest{
+		Name:         "test game",
+		Participants: actors,
+	})
+	s.Require().NoError(err, "error should be nil")
+
+	// every actor speaks for themselves, the bus refuses events submitted for someone else
+	speakers := make([]context.Context, len(actors))
+	for idx, actor := range actors {
+		speakers[idx], _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actor})
+	}
+	_, err = eventSrv.Submit(speakers[0], &v1.Event{
+		GameUid: game.Uid,
+		Actor:   actors[1],
+		Origin:  &v1.Event_Discord{Discord: &v1.EventOriginDiscord{Guild: "test", Channel: "test"}},
+		Payload: &v1.Event_Interaction{Interaction: &v1.InteractionEvent{
+			Interaction: &v1.InteractionEvent_Utterance{Utterance: &v1.UtteranceInteraction{Content: "i am sam"}},
+		}},
+	})
+	s.Equal(codes.PermissionDenied, status.Code(err), "players should not submit events for another actor")
+	_, err = eventSrv.Submit(speakers[0], &v1.Event{GameUid: game.Uid})
+	s.Equal(codes.InvalidArgument, status.Code(err), "events without an actor should be refused")
+
+	start := time.Now().Unix()
+	for i := 0; i < 5; i++ {
+		_, err := eventSrv.Submit(speakers[i%2], &v1.Event{
+			GameUid: game.Uid,
+			Actor:   actors[i%2],
+			Origin:  &v1.Event_Discord{Discord: &v1.EventOriginDiscord{Guild: "test", Channel: "test"}},
+			Payload: &v1.Event_Interaction{Interaction: &v1.InteractionEvent{
+				Interaction: &v1.InteractionEvent_Utterance{Utterance: &v1.UtteranceInteraction{Content: fmt.Sprintf("line %d", i)}},
+			}},
+		})
+		s.Require().NoError(err, "error should be nil on submitting an utterance")
+	}
+
+	// page through everything two at a time
+	transcript := make([]string, 0)
+	token := ""
+	for pages := 0; ; pages++ {
+		s.Require().Less(pages, 5, "paging should terminate")
+		page, err := eventSrv.ListEvents(ctx, &v1.ListEventsRequest{GameUid: game.Uid, PageSize: 2, PageToken: token})
+		s.Require().NoError(err, "error should be nil on listing events")
+		s.LessOrEqual(len(page.Events), 2)
+		for _, record := range page.Events {
+			transcript = append(transcript, record.GetPayload().GetInteraction().GetUtterance().GetContent())
+			s.Len(record.Receipts, 1, "receipts should be included with each event")
+			s.GreaterOrEqual(record.CreatedAt, start)
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		token = page.NextPageToken
+	}
+	s.Equal([]string{"line 0", "line 1", "line 2", "line 3", "line 4"}, transcript, "events should be returned oldest first")
+
+	sam := actors[1].Uid
+	page, err := eventSrv.ListEvents(ctx, &v1.ListEventsRequest{GameUid: game.Uid, ActorUid: &sam})
+	s.Require().NoError(err)
+	s.Len(page.Events, 2, "events should be filtered by actor")
+	s.Empty(page.NextPageToken)
+
+	page, err = eventSrv.ListEvents(ctx, &v1.ListEventsRequest{GameUid: game.Uid, PayloadType: v1.ListEventsRequest_NEW_GAME})
+	s.Require().NoError(err)
+	s.Empty(page.Events, "events should be filtered by payload type")
+
+	future := time.Now().Add(time.Hour).Unix()
+	page, err = eventSrv.ListEvents(ctx, &v1.ListEventsRequest{GameUid: game.Uid, CreatedAfter: &future})
+	s.Require().NoError(err)
+	s.Empty(page.Events, "events should be filtered by time")
+
+	_, err = eventSrv.ListEvents(ctx, &v1.ListEventsRequest{GameUid: game.Uid, PageToken: "bogus"})
+	s.Equal(codes.InvalidArgument, status.Code(err), "unknown page tokens should be rejected")
+
+	page, err = eventSrv.ListEvents(ctx, &v1.ListEventsRequest{GameUid: game.Uid, PageSize: 1})
+	s.Require().NoError(err)
+	record, err := eventSrv.GetEvent(ctx, &v1.GetEventRequest{Uid: page.Events[0].Uid})
+	s.Require().NoError(err, "error should be nil on getting an event")
+	s.Equal("line 0", record.GetPayload().GetInteraction().GetUtterance().GetContent())
+	s.Len(record.Receipts, 1)
+
+	_, err = eventSrv.GetEvent(ctx, &v1.GetEventRequest{Uid: "missing"})
+	s.Equal(codes.NotFound, status.Code(err))
+}
+
+func (s *EventHistoryTest) TestWhispersStayPrivate() {
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	eventStore := storage.NewSqlEventStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	gamesSrv := server.NewGameServer(usersSrv, storage.NewSqlLockStore(s.db), gamesStore, storage.NewSqlMapStore(s.db))
+	eventBus := engine.NewEventBus(
+		[]engine.EventHandler{
+			handlers.NewUtteranceHandler(gamesStore, eventStore),
+		},
+		gamesSrv,
+		usersSrv,
+		eventStore,
+		engine.NewReceiptBroker(),
+	)
+	eventSrv := server.NewEventServer(eventBus, eventStore, gamesStore)
+	user := &v1.User{Uid: "test"}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err := usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err)
+
+	actors := make([]*v1.Actor, 0)
+	for _, name := range []string{"frodo", "sam", "pippin", "gollum"} {
+		actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: user.Uid, SourceIdentity: name, Source: v1.Actor_APP_DISCORD})
+		s.Require().NoError(err)
+		actors = append(actors, actor)
+	}
+	frodo, sam, pippin, gollum := actors[0], actors[1], actors[2], actors[3]
+	frodoCtx, _ := common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: frodo})
+	samCtx, _ := common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: sam})
+	pippinCtx, _ := common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: pippin})
+	gollumCtx, _ := common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: gollum})
+
+	game, err := gamesSrv.CreateGame(frodoCtx, &v1.CreateGameRequest{Name: "test game", Participants: []*v1.Actor{frodo, sam, pippin}})
+	s.Require().NoError(err)
+
+	_, err = eventSrv.Submit(frodoCtx, &v1.Event{
+		GameUid: game.Uid,
+		Actor:   frodo,
+		Origin:  &v1.Event_Discord{Discord: &v1.EventOriginDiscord{Guild: "test", Channel: "test"}},
+		Payload: &v1.Event_Interaction{Interaction: &v1.InteractionEvent{
+			Interaction: &v1.InteractionEvent_Utterance{Utterance: &v1.UtteranceInteraction{
+				Content:   "psst sam",
+				Utterance: &v1.UtteranceInteraction_Player{Player: &v1.PlayerUtterance{Target: sam, Whisper: true}},
+			}},
+		}},
+	})
+	s.Require().NoError(err)
+	page, err := eventSrv.ListEvents(frodoCtx, &v1.ListEventsRequest{GameUid: game.Uid})
+	s.Require().NoError(err)
+	s.Require().Len(page.Events, 1)
+	whisper := page.Events[0].Uid
+
+	record, err := eventSrv.GetEvent(samCtx, &v1.GetEventRequest{Uid: whisper})
+	s.Require().NoError(err)
+	s.Len(record.Receipts, 1, "the recipient should see the whisper")
+
+	record, err = eventSrv.GetEvent(pippinCtx, &v1.GetEventRequest{Uid: whisper})
+	s.Require().NoError(err)
+	s.Empty(record.Receipts, "a player the whisper is not for should not see it")
+
+	_, err = eventSrv.GetEvent(gollumCtx, &v1.GetEventRequest{Uid: whisper})
+	s.Equal(codes.PermissionDenied, status.Code(err), "events should not be read from outside their game")
+
+	page, err = eventSrv.ListEvents(pippinCtx, &v1.ListEventsRequest{GameUid: game.Uid})
+	s.Require().NoError(err)
+	s.Require().Len(page.Events, 1, "the event itself is part of the history")
+	s.Empty(page.Events[0].Receipts, "listing the game should not reveal whispers to others")
+
+	page, err = eventSrv.ListEvents(samCtx, &v1.ListEventsRequest{GameUid: game.Uid})
+	s.Require().NoError(err)
+	s.Len(page.Events[0].Receipts, 1, "the recipient should find the whisper in the history")
+}
@@ -0,0 +1,170 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/engine/handlers"
+	"overseer/server"
+	"overseer/storage"
+	"path"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+type FogOfWarTest struct {
+	db     *gorm.DB
+	dbFile string
+	suite.Suite
+}
+
+func TestFogOfWar(t *testing.T) {
+	suite.Run(t, new(FogOfWarTest))
+}
+
+func (s *FogOfWarTest) SetupTest() {
+	s.dbFile = path.Join(os.TempDir(), fmt.Sprintf("test-%s.db", uuid.NewString()))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *FogOfWarTest) TearDownTest() {
+	s.Require().NoError(os.Remove(s.dbFile), "failed to remove test database")
+	s.db = nil
+}
+
+func (s *FogOfWarTest) TestDiscoveringTheMap() {
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	eventStore := storage.NewSqlEventStore(s.db)
+	mapStore := storage.NewSqlMapStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	lockStore := storage.NewSqlLockStore(s.db)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, mapStore)
+	broker := engine.NewReceiptBroker()
+	mapsSrv := server.NewMapServer(mapStore, eventStore, lockStore, broker, nil)
+	eventSrv := server.NewEventServer(engine.NewEventBus(
+		[]engine.EventHandler{handlers.NewMovementHandler(mapStore, eventStore)},
+		gamesSrv,
+		usersSrv,
+		eventStore,
+		broker,
+	), eventStore, gamesStore)
+
+	user := &v1.User{Uid: "test"}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err := usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err)
+	actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: user.Uid, Source: v1.Actor_APP_DISCORD})
+	s.Require().NoError(err)
+	ctx, _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actor})
+
+	game, err := gamesSrv.CreateGame(ctx, &v1.CreateGameRequest{Name: "test game", Participants: []*v1.Actor{actor}})
+	s.Require().NoError(err)
+
+	// a 9x9 field with the actor in the center, a wall of forest to the north and a hermit far to the west
+	gameMap, err := mapStore.CreateMap(ctx, &v1.CreateMapRequest{GameUid: game.Uid, Name: "test map", MaxX: 4, MaxY: 4})
+	s.Require().NoError(err)
+	for x := int64(-4); x <= 4; x++ {
+		for y := int64(-4); y <= 4; y++ {
+			coord := &v1.MapCoordinateDetail{
+				Uid:      common.GenerateUniqueId(),
+				GameUid:  game.Uid,
+				MapUid:   gameMap.Uid,
+				Position: &v1.MapPosition{X: x, Y: y},
+				Type:     v1.MapCoordinateDetail_OPEN_FIELD,
+			}
+			switch {
+			case x == 0 && y == 0:
+				coord.Actors = []*v1.Actor{actor}
+				coord.Sprites = []*v1.Sprite{{Uid: "player", Actor: actor, IsObstacle: true, IsMoveable: true}}
+			case x == -3 && y == 0:
+				coord.Sprites = []*v1.Sprite{{Uid: "hermit", LorePublic: "an old hermit", LoreInternal: "the hermit is the lost king"}}
+			case y == 1:
+				coord.Type = v1.MapCoordinateDetail_FOREST
+			}
+			s.Require().NoError(mapStore.CreateCoordinate(ctx, coord))
+		}
+	}
+
+	// the actor arrives seeing what is around them the same as when map generation places them
+	start, err := mapStore.GetCoordinate(ctx, game.Uid, gameMap.Uid, 0, 0)
+	s.Require().NoError(err)
+	s.Require().NoError(storage.Discover(ctx, mapStore, gameMap.Uid, actor.Uid, start))
+
+	knownMap := func() map[[2]int64]*v1.KnownCoordinate {
+		known, err := mapsSrv.GetKnownMap(ctx, &v1.GetKnownMapRequest{GameUid: game.Uid})
+		s.Require().NoError(err)
+		byPosition := make(map[[2]int64]*v1.KnownCoordinate)
+		for _, coordinate := range known.Coordinates {
+			byPosition[[2]int64{coordinate.Coordinate.Position.X, coordinate.Coordinate.Position.Y}] = coordinate
+		}
+		return byPosition
+	}
+
+	known := knownMap()
+	s.Require().Contains(known, [2]int64{-3, 0}, "the hermit is within sight across the open field")
+	s.Equal(v1.KnownCoordinate_VISIBLE, known[[2]int64{-3, 0}].Visibility)
+	s.Require().Len(known[[2]int64{-3, 0}].Coordinate.Sprites, 1, "who is in sight should be seen")
+	s.Contains(known, [2]int64{0, 1}, "the forest itself should be seen")
+	s.NotContains(known, [2]int64{0, 2}, "nothing should be seen behind the forest")
+	s.NotContains(known, [2]int64{4, 0}, "nothing should be seen beyond the sight radius")
+
+	peek := func(x int64, y int64) (*v1.MapCoordinateDetail, error) {
+		return mapsSrv.PeekCoordinate(ctx, &v1.PeekCoordinateRequest{GameUid: game.Uid, Coordinate: &v1.MapPosition{X: x, Y: y}})
+	}
+	_, err = peek(0, 2)
+	s.Equal(codes.PermissionDenied, status.Code(err), "peeking behind the forest should fail")
+	_, err = peek(4, 0)
+	s.Equal(codes.PermissionDenied, status.Code(err), "peeking beyond the sight radius should fail")
+
+	receipts, err := eventSrv.Submit(ctx, &v1.Event{
+		GameUid: game.Uid,
+		Actor:   actor,
+		Origin:  &v1.Event_Discord{Discord: &v1.EventOriginDiscord{Guild: "test", Channel: "test"}},
+		Payload: &v1.Event_Interaction{Interaction: &v1.InteractionEvent{
+			Interaction: &v1.InteractionEvent_Movement{Movement: &v1.MovementInteraction{Direction: "east"}},
+		}},
+	})
+	s.Require().NoError(err)
+	s.Require().NotNil(receipts.Receipts[0].GetGameState().GetMovement(), "the actor should have moved east")
+
+	known = knownMap()
+	s.Require().Contains(known, [2]int64{-3, 0}, "what was seen should be remembered")
+	s.Equal(v1.KnownCoordinate_REMEMBERED, known[[2]int64{-3, 0}].Visibility, "the hermit is now out of sight")
+	s.Empty(known[[2]int64{-3, 0}].Coordinate.Sprites, "who was there is not known once out of sight")
+	s.Equal(v1.KnownCoordinate_VISIBLE, known[[2]int64{4, 0}].GetVisibility(), "the east comes into sight")
+	s.True(known[[2]int64{0, 0}].GetVisited(), "where the actor stood should be visited")
+	s.True(known[[2]int64{1, 0}].GetVisited(), "where the actor stands should be visited")
+	s.False(known[[2]int64{-1, 0}].GetVisited(), "what was only seen should not be visited")
+	s.NotContains(known, [2]int64{1, 3}, "nothing should be seen behind the forest")
+
+	peeked, err := peek(-3, 0)
+	s.Require().NoError(err, "what was seen should be peeked at")
+	s.Empty(peeked.Sprites, "a remembered peek should not show who is there now")
+	peeked, err = peek(4, 0)
+	s.Require().NoError(err, "what comes into sight should be peeked at")
+	s.Equal(v1.MapCoordinateDetail_OPEN_FIELD, peeked.Type)
+
+	result, err := mapsSrv.PlayerMovement(ctx, &v1.PlayerMovementRequest{GameUid: game.Uid, ActorUid: actor.Uid, X: 1, Y: 1})
+	s.Require().NoError(err)
+	s.Require().True(result.Success, result.GetMessage())
+
+	known = knownMap()
+	s.True(known[[2]int64{1, 1}].GetVisited(), "moving through the maps service should discover too")
+	s.Equal(v1.KnownCoordinate_REMEMBERED, known[[2]int64{4, 0}].GetVisibility(), "inside the forest the actor sees less")
+
+	_, err = mapsSrv.GetKnownMap(ctx, &v1.GetKnownMapRequest{GameUid: game.Uid, ActorUid: "someone else"})
+	s.Equal(codes.PermissionDenied, status.Code(err), "only the dungeon master may see what another player knows")
+}
@@ -0,0 +1,179 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/server"
+	"overseer/storage"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+type GameLockTest struct {
+	db       *gorm.DB
+	dbFile   string
+	games    v1.GamesServer
+	events   storage.EventStore
+	game     *v1.Game
+	ctx      context.Context
+	adminCtx context.Context
+	suite.Suite
+}
+
+func TestGameLock(t *testing.T) {
+	suite.Run(t, new(GameLockTest))
+}
+
+func (s *GameLockTest) SetupTest() {
+	s.dbFile = path.Join(os.TempDir(), fmt.Sprintf("test-%s.db", uuid.NewString()))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	s.db = db
+
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	s.games = server.NewGameServer(usersSrv, storage.NewSqlLockStore(s.db), gamesStore, storage.NewSqlMapStore(s.db))
+	s.events = storage.NewSqlEventStore(s.db)
+
+	user := &v1.User{Uid: "test"}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err = usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err)
+	actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{
+		UserId:         user.Uid,
+		SourceIdentity: "frodo",
+		Source:         v1.Actor_APP_DISCORD,
+	})
+	s.Require().NoError(err)
+
+	s.ctx, _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actor})
+	s.adminCtx, _ = common.SetContextInformation(context.Background(), &common.OverseerContextInformation{
+		User:  &v1.User{Uid: auth.SystemUserId},
+		Actor: &v1.Actor{Uid: auth.SystemActorId},
+	})
+	s.game, err = s.games.CreateGame(s.ctx, &v1.CreateGameRequest{Name: "test game", Participants: []*v1.Actor{actor}})
+	s.Require().NoError(err)
+}
+
+func (s *GameLockTest) TearDownTest() {
+	s.Require().NoError(os.Remove(s.dbFile), "failed to remove test database")
+	s.db = nil
+}
+
+func (s *GameLockTest) lock(claim string, wait bool, ttl int64) *v1.LockGameResponse {
+	res, err := s.games.LockGame(s.ctx, &v1.LockGameRequest{GameUid: s.game.Uid, ClaimUid: claim, Wait: wait, TtlSeconds: ttl})
+	s.Require().NoError(err)
+	return res
+}
+
+func (s *GameLockTest) unlock(lock *v1.GameLock) bool {
+	res, err := s.games.UnlockGame(s.ctx, &v1.UnlockGameRequest{GameUid: s.game.Uid, ClaimUid: lock.ClaimUid, FencingToken: lock.FencingToken})
+	s.Require().NoError(err)
+	return res.Success
+}
+
+func (s *GameLockTest) TestWaitersAreServedInOrder() {
+	first := s.lock("first", false, 0)
+	s.Require().True(first.Success)
+	s.False(s.lock("impatient", false, 0).Success, "a held lock should not be granted without waiting")
+
+	var mu sync.Mutex
+	order := make([]string, 0)
+	var wg sync.WaitGroup
+	for idx, claim := range []string{"second", "third", "fourth"} {
+		wg.Add(1)
+		go func(claim string) {
+			defer wg.Done()
+			res := s.lock(claim, true, 0)
+			mu.Lock()
+			order = append(order, claim)
+			mu.Unlock()
+			s.True(s.unlock(res.Lock))
+		}(claim)
+		// let each waiter join the queue before the next
+		s.Eventually(func() bool {
+			locks, err := s.games.ListLocks(s.adminCtx, &v1.ListLocksRequest{})
+			return err == nil && len(locks.Locks) == 1 && int(locks.Locks[0].Waiting) == idx+1
+		}, time.Second, 5*time.Millisecond)
+	}
+
+	s.True(s.unlock(first.Lock))
+	wg.Wait()
+	s.Equal([]string{"second", "third", "fourth"}, order)
+}
+
+func (s *GameLockTest) TestExpiredLeaseIsFenced() {
+	stale := s.lock("stale", false, 1)
+	s.Require().True(stale.Success)
+
+	// the holder stalls past its lease and another claim takes over
+	fresh := s.lock("fresh", true, 0)
+	s.Require().True(fresh.Success)
+	s.Greater(fresh.Lock.FencingToken, stale.Lock.FencingToken, "fencing tokens should increase with every lease")
+
+	s.False(s.unlock(stale.Lock), "a stale holder should not be able to release the new lease")
+	_, err := s.games.RenewLock(s.ctx, &v1.RenewLockRequest{GameUid: s.game.Uid, ClaimUid: "stale", FencingToken: stale.Lock.FencingToken})
+	s.Equal(codes.FailedPrecondition, status.Code(err), "a stale holder should not be able to renew")
+
+	renewed, err := s.games.RenewLock(s.ctx, &v1.RenewLockRequest{GameUid: s.game.Uid, ClaimUid: "fresh", FencingToken: fresh.Lock.FencingToken, TtlSeconds: 60})
+	s.Require().NoError(err)
+	s.GreaterOrEqual(renewed.Lock.ExpiresAtMs, fresh.Lock.ExpiresAtMs)
+	s.True(s.unlock(fresh.Lock))
+}
+
+func (s *GameLockTest) TestStaleHoldersCannotWrite() {
+	stale := s.lock("stale", false, 1)
+	s.Require().True(stale.Success)
+	fresh := s.lock("fresh", true, 0)
+	s.Require().True(fresh.Success)
+
+	write := func(lock *v1.GameLock) error {
+		ctx := common.WithGameLease(s.ctx, s.game.Uid, lock.FencingToken)
+		return s.events.RecordReceipt(ctx, &v1.EventReceipt{
+			Uid:      uuid.NewString(),
+			GameUid:  s.game.Uid,
+			EventUid: "event",
+			Effect:   &v1.EventReceipt_Ack{Ack: &v1.Acknowledgement{}},
+		})
+	}
+	s.Equal(codes.FailedPrecondition, status.Code(write(stale.Lock)), "a stale holder should not be able to write receipts")
+	s.NoError(write(fresh.Lock), "the current holder should be able to write receipts")
+	s.True(s.unlock(fresh.Lock))
+}
+
+func (s *GameLockTest) TestAdministratorsCanBreakLocks() {
+	held := s.lock("stuck", false, 0)
+	s.Require().True(held.Success)
+
+	_, err := s.games.ListLocks(s.ctx, &v1.ListLocksRequest{})
+	s.Equal(codes.PermissionDenied, status.Code(err), "players should not see the lock table")
+	_, err = s.games.BreakLock(s.ctx, &v1.BreakLockRequest{GameUid: s.game.Uid})
+	s.Equal(codes.PermissionDenied, status.Code(err), "players should not break locks")
+
+	locks, err := s.games.ListLocks(s.adminCtx, &v1.ListLocksRequest{GameUid: &s.game.Uid})
+	s.Require().NoError(err)
+	s.Require().Len(locks.Locks, 1)
+	s.Equal("stuck", locks.Locks[0].ClaimUid)
+
+	broken, err := s.games.BreakLock(s.adminCtx, &v1.BreakLockRequest{GameUid: s.game.Uid})
+	s.Require().NoError(err)
+	s.True(broken.Success)
+	s.Equal(held.Lock.FencingToken, broken.Broken.FencingToken)
+
+	s.False(s.unlock(held.Lock), "a broken lease cannot be released again")
+	s.True(s.lock("next", false, 0).Success, "the game should be free once the lock is broken")
+}
@@ -0,0 +1,103 @@
+package scenarios
+
+import (
+	"context"
+	"net"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/storage"
+
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// serveOverseer runs the servers on a local port so front ends can be tested through their overseer client
+func serveOverseer(events v1.EventsServer, users v1.UsersServer, games v1.GamesServer, maps v1.MapsServer, authenticator *testAuthenticator, authorizer *auth.Authorizer) (string, func(), error) {
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authenticator.unary, authorizer.UnaryServerAuthorizeFunc),
+		grpc.ChainStreamInterceptor(authenticator.stream, authorizer.StreamServerAuthorizeFunc),
+	)
+	v1.RegisterEventsServer(grpcServer, events)
+	v1.RegisterUsersServer(grpcServer, users)
+	v1.RegisterGamesServer(grpcServer, games)
+	v1.RegisterMapsServer(grpcServer, maps)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+	go grpcServer.Serve(listener)
+	return listener.Addr().String(), grpcServer.Stop, nil
+}
+
+// testAuthenticator stands in for the system token, which is disabled in tests,
+// api keys are checked as usual, other calls naming an actor act as it and every other call acts as the system
+type testAuthenticator struct {
+	users storage.UserStore
+	keys  storage.ApiKeyStore
+}
+
+func (a *testAuthenticator) authenticate(ctx context.Context) (context.Context, error) {
+	system := &common.OverseerContextInformation{
+		User:  &v1.User{Uid: auth.SystemUserId},
+		Actor: &v1.Actor{Uid: auth.SystemActorId, Source: v1.Actor_SYSTEM},
+	}
+	systemCtx, err := common.SetContextInformation(ctx, system)
+	if err != nil {
+		return nil, err
+	}
+
+	var userId string
+	var actorId string
+	if actorIds := metadata.ValueFromIncomingContext(ctx, "x-actor"); len(actorIds) > 0 {
+		actorId = actorIds[0]
+	}
+	if secrets := metadata.ValueFromIncomingContext(ctx, "x-api-key"); len(secrets) > 0 {
+		key, err := a.keys.GetApiKeyBySecretHash(systemCtx, auth.HashApiKeySecret(secrets[0]))
+		if err != nil || key.RevokedAt != nil {
+			return nil, status.Error(codes.Unauthenticated, "unknown api key")
+		}
+		userId = key.GetUserId()
+		if key.ActorId != nil {
+			actorId = key.GetActorId()
+		}
+	}
+	if userId == "" && actorId == "" {
+		return systemCtx, nil
+	}
+	if actorId == "" {
+		return common.SetContextInformation(ctx, &common.OverseerContextInformation{User: &v1.User{Uid: userId}})
+	}
+
+	owner, err := a.users.GetUserForActor(systemCtx, actorId)
+	if err != nil || (userId != "" && owner.GetUid() != userId) {
+		return nil, status.Error(codes.Unauthenticated, "actor does not belong to user")
+	}
+	actor, err := a.users.GetActor(systemCtx, actorId)
+	if err != nil {
+		return nil, err
+	}
+	return common.SetContextInformation(ctx, &common.OverseerContextInformation{User: owner, Actor: actor})
+}
+
+func (a *testAuthenticator) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *testAuthenticator) stream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	wrapped := middleware.WrapServerStream(stream)
+	wrapped.WrappedContext = ctx
+	return handler(srv, wrapped)
+}
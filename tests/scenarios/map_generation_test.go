@@ -0,0 +1,286 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/server"
+	"overseer/storage"
+	"path"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type MapGenerationTest struct {
+	db     *gorm.DB
+	dbFile string
+	suite.Suite
+}
+
+func TestMapGeneration(t *testing.T) {
+	suite.Run(t, new(MapGenerationTest))
+}
+
+func (s *MapGenerationTest) SetupTest() {
+	s.dbFile = path.Join(os.TempDir(), fmt.Sprintf("test-%s.db", uuid.NewString()))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *MapGenerationTest) TearDownTest() {
+	s.Require().NoError(os.Remove(s.dbFile), "failed to remove test database")
+	s.db = nil
+}
+
+// recordingGenerator checks every coordinate is generated after its neighbors with its terrain decided and remembers how many ran at once
+type recordingGenerator struct {
+	mu        sync.Mutex
+	generated map[string]bool
+	running   int
+	peak      int
+	failures  []string
+}
+
+func newRecordingGenerator() *recordingGenerator {
+	return &recordingGenerator{generated: make(map[string]bool)}
+}
+
+func (g *recordingGenerator) GenerateCoordinate(ctx context.Context, _ v1.GameTheme, _ float32, coordinate *v1.MapCoordinateDetail, neighbors []*v1.MapCoordinateDetail) (*v1.MapCoordinateDetail, error) {
+	g.mu.Lock()
+	g.running++
+	g.peak = max(g.peak, g.running)
+	for _, neighbor := range neighbors {
+		if neighbor == nil || !g.generated[fmt.Sprintf("%d:%d", neighbor.Position.X, neighbor.Position.Y)] {
+			g.failures = append(g.failures, fmt.Sprintf("(%d, %d) was generated before its neighbors", coordinate.Position.X, coordinate.Position.Y))
+		}
+	}
+	if coordinate.Type == v1.MapCoordinateDetail_TYPE_UNSPECIFIED {
+		g.failures = append(g.failures, fmt.Sprintf("(%d, %d) has no terrain", coordinate.Position.X, coordinate.Position.Y))
+	}
+	g.mu.Unlock()
+
+	// the lore stands in for what the model would have been asked to write about the draws made for the coordinate
+	coordinate.Lore = fmt.Sprintf("%d", common.Random(ctx).Int63())
+	time.Sleep(time.Millisecond)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.running--
+	g.generated[fmt.Sprintf("%d:%d", coordinate.Position.X, coordinate.Position.Y)] = true
+	return coordinate, nil
+}
+
+func (s *MapGenerationTest) awaitGeneration(ctx context.Context, maps server.MapServer, mapUid string) *v1.MapGenerationStatus {
+	var generation *v1.MapGenerationStatus
+	s.Eventually(func() bool {
+		var err error
+		generation, err = maps.GetMapGenerationStatus(ctx, &v1.GetMapRequest{Uid: mapUid})
+		return err == nil && generation.State != v1.MapGenerationStatus_GENERATING
+	}, 10*time.Second, 10*time.Millisecond, "map generation should finish")
+	return generation
+}
+
+func (s *MapGenerationTest) TestGeneratingInTheBackground() {
+	generator := newRecordingGenerator()
+	mapStore := storage.NewSqlMapStore(s.db)
+	maps := server.NewMapServer(mapStore, storage.NewSqlEventStore(s.db), storage.NewSqlLockStore(s.db), engine.NewReceiptBroker(), generator)
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: &v1.User{Uid: "test"}})
+
+	// the caller may be gone long before the job finishes, so the job never reports to it
+	var reports atomic.Int64
+	reporting := common.WithProgressReporter(ctx, func(completed int64, total int64) {
+		reports.Add(1)
+	})
+	gameMap, err := maps.CreateMap(reporting, &v1.CreateMapRequest{
+		GameUid:                "game",
+		Name:                   "the shire",
+		MaxX:                   4,
+		MaxY:                   3,
+		DifficultTerrainChance: 0.3,
+		SpriteDensity:          0.2,
+		Actors:                 []*v1.Actor{{Uid: "frodo"}},
+		TerrainGenerator:       v1.TerrainGenerator_CELLULAR_AUTOMATA,
+	})
+	s.Require().NoError(err)
+
+	generation := s.awaitGeneration(ctx, maps, gameMap.Uid)
+	s.Equal(v1.MapGenerationStatus_COMPLETE, generation.State)
+	s.Zero(reports.Load(), "the background job should not report to the caller")
+	s.Equal(int64(9*7), generation.Total)
+	s.Equal(generation.Total, generation.Completed)
+
+	generator.mu.Lock()
+	defer generator.mu.Unlock()
+	s.Empty(generator.failures)
+	s.LessOrEqual(generator.peak, common.GetConfiguration().MapGeneration.Concurrency, "generation should respect the concurrency limit")
+	coordinates, err := mapStore.GetCoordinates(ctx, gameMap.Uid)
+	s.NoError(err)
+	s.Len(coordinates, 9*7)
+	for _, coordinate := range coordinates {
+		s.Contains([]v1.MapCoordinateDetail_CoordinateType{v1.MapCoordinateDetail_MOUNTAIN, v1.MapCoordinateDetail_CAVE, v1.MapCoordinateDetail_SEA}, coordinate.Type,
+			"caves should only be carved out of mountains")
+	}
+
+	discovered, err := mapStore.GetDiscoveredCoordinates(ctx, gameMap.Uid, "frodo")
+	s.NoError(err)
+	s.NotEmpty(discovered, "the players should arrive seeing what is around them")
+	visited := 0
+	for _, known := range discovered {
+		if known.Visited {
+			visited++
+		}
+	}
+	s.Equal(1, visited, "the players should only have stood where they start")
+}
+
+func (s *MapGenerationTest) TestArrivingLate() {
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	mapStore := storage.NewSqlMapStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	lockStore := storage.NewSqlLockStore(s.db)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, mapStore)
+	maps := server.NewMapServer(mapStore, storage.NewSqlEventStore(s.db), lockStore, engine.NewReceiptBroker(), newRecordingGenerator())
+
+	user := &v1.User{Uid: "test"}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err := usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err)
+	contexts := make(map[string]context.Context)
+	actors := make(map[string]*v1.Actor)
+	for _, name := range []string{"frodo", "sam"} {
+		actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: user.Uid, SourceIdentity: name, Source: v1.Actor_APP_DISCORD})
+		s.Require().NoError(err)
+		actors[name] = actor
+		contexts[name], _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actor})
+	}
+
+	game, err := gamesSrv.CreateGame(contexts["frodo"], &v1.CreateGameRequest{Name: "test game", Participants: []*v1.Actor{actors["frodo"]}})
+	s.Require().NoError(err)
+	gameMap, err := maps.CreateMap(contexts["frodo"], &v1.CreateMapRequest{GameUid: game.Uid, Name: "the shire", MaxX: 2, MaxY: 2, DifficultTerrainChance: 0.3, SpriteDensity: 0.2, Actors: []*v1.Actor{actors["frodo"]}})
+	s.Require().NoError(err)
+	s.Require().Equal(v1.MapGenerationStatus_COMPLETE, s.awaitGeneration(ctx, maps, gameMap.Uid).State)
+
+	_, err = gamesSrv.JoinGame(contexts["sam"], &v1.JoinGameRequest{GameUid: game.Uid})
+	s.Require().NoError(err)
+
+	start, err := mapStore.GetActorPosition(ctx, actors["frodo"].Uid)
+	s.Require().NoError(err)
+	arrived, err := mapStore.GetActorPosition(ctx, actors["sam"].Uid)
+	s.Require().NoError(err, "a late arrival should be placed on the map")
+	s.Equal(start.X, arrived.X, "a late arrival should start where everyone else did")
+	s.Equal(start.Y, arrived.Y, "a late arrival should start where everyone else did")
+
+	discovered, err := mapStore.GetDiscoveredCoordinates(ctx, gameMap.Uid, actors["sam"].Uid)
+	s.Require().NoError(err)
+	s.NotEmpty(discovered, "a late arrival should see what is around them")
+
+	_, err = gamesSrv.JoinGame(contexts["sam"], &v1.JoinGameRequest{GameUid: game.Uid})
+	s.Require().NoError(err, "joining again should leave the actor where they are")
+}
+
+func (s *MapGenerationTest) TestResumingAfterARestart() {
+	mapStore := storage.NewSqlMapStore(s.db)
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: &v1.User{Uid: "test"}})
+	req := &v1.CreateMapRequest{GameUid: "game", Name: "the shire", MaxX: 2, MaxY: 2}
+	gameMap, err := mapStore.CreateMap(ctx, req)
+	s.Require().NoError(err)
+
+	// a previous server charted the bottom row before it stopped
+	s.Require().NoError(mapStore.SaveMapGeneration(ctx, &v1.MapGenerationJob{
+		MapUid:  gameMap.Uid,
+		GameUid: req.GameUid,
+		Request: req,
+		Start:   &v1.MapPosition{X: 0, Y: 0},
+		Total:   25,
+		State:   v1.MapGenerationStatus_GENERATING,
+	}))
+	charted := make(map[string]string)
+	for x := int64(-2); x <= 2; x++ {
+		uid := common.GenerateUniqueId()
+		charted[uid] = fmt.Sprintf("%d:%d", x, -2)
+		s.Require().NoError(mapStore.CreateCoordinate(ctx, &v1.MapCoordinateDetail{
+			Uid:      uid,
+			GameUid:  req.GameUid,
+			MapUid:   gameMap.Uid,
+			Position: &v1.MapPosition{X: x, Y: -2},
+			Type:     v1.MapCoordinateDetail_FOREST,
+		}))
+	}
+
+	generator := newRecordingGenerator()
+	for _, position := range charted {
+		generator.generated[position] = true
+	}
+	maps := server.NewMapServer(mapStore, storage.NewSqlEventStore(s.db), storage.NewSqlLockStore(s.db), engine.NewReceiptBroker(), generator)
+	s.Require().NoError(maps.ResumeMapGeneration(ctx))
+
+	generation := s.awaitGeneration(ctx, maps, gameMap.Uid)
+	s.Equal(v1.MapGenerationStatus_COMPLETE, generation.State)
+	s.Equal(int64(25), generation.Completed)
+
+	coordinates, err := mapStore.GetCoordinates(ctx, gameMap.Uid)
+	s.NoError(err)
+	s.Len(coordinates, 25)
+	kept := 0
+	for _, coordinate := range coordinates {
+		if _, ok := charted[coordinate.Uid]; ok {
+			kept++
+		}
+	}
+	s.Equal(len(charted), kept, "coordinates charted before the restart should be kept")
+
+	generator.mu.Lock()
+	defer generator.mu.Unlock()
+	s.Empty(generator.failures)
+	s.Len(generator.generated, 25)
+}
+
+func (s *MapGenerationTest) TestTheSameSeedMakesTheSameMap() {
+	mapStore := storage.NewSqlMapStore(s.db)
+	maps := server.NewMapServer(mapStore, storage.NewSqlEventStore(s.db), storage.NewSqlLockStore(s.db), engine.NewReceiptBroker(), newRecordingGenerator())
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: &v1.User{Uid: "test"}})
+
+	layouts := make([]map[string]string, 0, 3)
+	for _, seed := range []int64{7, 7, 8} {
+		gameMap, err := maps.CreateMap(ctx, &v1.CreateMapRequest{
+			GameUid:                "game",
+			Name:                   "the shire",
+			MaxX:                   5,
+			MaxY:                   5,
+			DifficultTerrainChance: 0.5,
+			SpriteDensity:          0.2,
+			Actors:                 []*v1.Actor{{Uid: "frodo"}},
+			TerrainGenerator:       v1.TerrainGenerator_WAVE_FUNCTION_COLLAPSE,
+			Seed:                   &seed,
+		})
+		s.Require().NoError(err)
+		s.Equal(seed, gameMap.Seed, "the map should record its seed")
+		s.Require().Equal(v1.MapGenerationStatus_COMPLETE, s.awaitGeneration(ctx, maps, gameMap.Uid).State)
+
+		coordinates, err := mapStore.GetCoordinates(ctx, gameMap.Uid)
+		s.Require().NoError(err)
+		layout := make(map[string]string, len(coordinates))
+		for _, coordinate := range coordinates {
+			layout[fmt.Sprintf("%d:%d", coordinate.Position.X, coordinate.Position.Y)] = fmt.Sprintf(
+				"%s %t %s %d", coordinate.Type, coordinate.DifficultTerrain, coordinate.Lore, len(coordinate.Actors),
+			)
+		}
+		layouts = append(layouts, layout)
+	}
+
+	s.Equal(layouts[0], layouts[1], "the same seed should make the same map")
+	s.NotEqual(layouts[0], layouts[2], "a different seed should make a different map")
+}
@@ -10,17 +10,32 @@ type MockTemplatingClient struct {
 	mock.Mock
 }
 
-func (m *MockTemplatingClient) InternalLoreTemplate() *template.Template {
+func (m *MockTemplatingClient) SpriteTemplate() *template.Template {
 	args := m.Called()
 	return args.Get(0).(*template.Template)
 }
 
-func (m *MockTemplatingClient) PublicLoreTemplate() *template.Template {
+func (m *MockTemplatingClient) CoordinateLoreTemplate() *template.Template {
 	args := m.Called()
 	return args.Get(0).(*template.Template)
 }
 
-func (m *MockTemplatingClient) CoordinateLoreTemplate() *template.Template {
+func (m *MockTemplatingClient) DungeonMasterTemplate() *template.Template {
+	args := m.Called()
+	return args.Get(0).(*template.Template)
+}
+
+func (m *MockTemplatingClient) AdjudicationTemplate() *template.Template {
+	args := m.Called()
+	return args.Get(0).(*template.Template)
+}
+
+func (m *MockTemplatingClient) NarrationTemplate() *template.Template {
+	args := m.Called()
+	return args.Get(0).(*template.Template)
+}
+
+func (m *MockTemplatingClient) IntroductionTemplate() *template.Template {
 	args := m.Called()
 	return args.Get(0).(*template.Template)
 }
@@ -0,0 +1,161 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/engine/handlers"
+	"overseer/server"
+	"overseer/storage"
+	"path"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type MovementTest struct {
+	db     *gorm.DB
+	dbFile string
+	suite.Suite
+}
+
+func TestMovement(t *testing.T) {
+	suite.Run(t, new(MovementTest))
+}
+
+func (s *MovementTest) SetupTest() {
+	s.dbFile = path.Join(os.TempDir(), fmt.Sprintf("test-%s.db", uuid.NewString()))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *MovementTest) TearDownTest() {
+	s.Require().NoError(os.Remove(s.dbFile), "failed to remove test database")
+	s.db = nil
+}
+
+func (s *MovementTest) TestMovingAroundTheMap() {
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	eventStore := storage.NewSqlEventStore(s.db)
+	lockStore := storage.NewSqlLockStore(s.db)
+	mapStore := storage.NewSqlMapStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, mapStore)
+	eventBus := engine.NewEventBus(
+		[]engine.EventHandler{
+			handlers.NewMovementHandler(mapStore, eventStore),
+		},
+		gamesSrv,
+		usersSrv,
+		eventStore,
+		engine.NewReceiptBroker(),
+	)
+	eventSrv := server.NewEventServer(eventBus, eventStore, gamesStore)
+	user := &v1.User{
+		Uid: "test",
+	}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{
+		User: user,
+	})
+
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err := usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err, "error should be nil on creating user")
+
+	actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{
+		UserId: user.Uid,
+		Source: v1.Actor_APP_DISCORD,
+	})
+	s.Require().NoError(err, "error should be nil on creating actor")
+
+	ctx, _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{
+		User:  user,
+		Actor: actor,
+	})
+
+	game, err := gamesSrv.CreateGame(ctx, &v1.CreateGameRequest{
+		Name:         "test game",
+		Participants: []*v1.Actor{actor},
+	})
+	s.Require().NoError(err, "error should be nil")
+
+	// a 3x3 map with the actor in the center and an immovable boulder to the east
+	gameMap, err := mapStore.CreateMap(ctx, &v1.CreateMapRequest{
+		GameUid: game.Uid,
+		Name:    "test map",
+		MaxX:    1,
+		MaxY:    1,
+	})
+	s.Require().NoError(err, "error should be nil on creating map")
+	for x := int64(-1); x <= 1; x++ {
+		for y := int64(-1); y <= 1; y++ {
+			coord := &v1.MapCoordinateDetail{
+				Uid:      common.GenerateUniqueId(),
+				GameUid:  game.Uid,
+				MapUid:   gameMap.Uid,
+				Position: &v1.MapPosition{X: x, Y: y},
+				Type:     v1.MapCoordinateDetail_OPEN_FIELD,
+			}
+			if x == 0 && y == 0 {
+				coord.Actors = []*v1.Actor{actor}
+				coord.Sprites = []*v1.Sprite{{Uid: "player", Actor: actor, IsObstacle: true, IsMoveable: true}}
+			}
+			if x == 1 && y == 0 {
+				coord.Sprites = []*v1.Sprite{{Uid: "boulder", IsObstacle: true, IsMoveable: false}}
+			}
+			if x == 0 && y == 1 {
+				coord.DifficultTerrain = true
+			}
+			s.Require().NoError(mapStore.CreateCoordinate(ctx, coord))
+		}
+	}
+
+	move := func(direction string) *v1.EventReceipt {
+		receipts, err := eventSrv.Submit(ctx, &v1.Event{
+			GameUid: game.Uid,
+			Actor:   actor,
+			Origin:  &v1.Event_Discord{Discord: &v1.EventOriginDiscord{Guild: "test", Channel: "test"}},
+			Payload: &v1.Event_Interaction{Interaction: &v1.InteractionEvent{
+				Interaction: &v1.InteractionEvent_Movement{Movement: &v1.MovementInteraction{Direction: direction}},
+			}},
+		})
+		s.Require().NoError(err, "error should be nil on submitting movement")
+		s.Require().Len(receipts.Receipts, 1, "movement should produce a single receipt")
+		return receipts.Receipts[0]
+	}
+
+	receipt := move("east")
+	s.Equal(v1.ErrorEffect_INVALID, receipt.GetError().GetType(), "boulder should block movement")
+
+	receipt = move("sideways")
+	s.Equal(v1.ErrorEffect_INVALID, receipt.GetError().GetType(), "unknown directions should be rejected")
+
+	receipt = move("north")
+	movement := receipt.GetGameState().GetMovement()
+	s.Require().NotNil(movement, "receipt should describe the movement: ", receipt)
+	s.Equal(int64(0), movement.Destination.X)
+	s.Equal(int64(1), movement.Destination.Y)
+	s.True(movement.DifficultTerrain, "destination is difficult terrain")
+	s.Equal(int64(2), movement.Cost, "difficult terrain should cost double")
+
+	origin, err := mapStore.GetCoordinate(ctx, game.Uid, gameMap.Uid, 0, 0)
+	s.Require().NoError(err)
+	s.Empty(origin.Actors, "actor should have left the origin")
+	s.Empty(origin.Sprites, "actor sprite should have left the origin")
+
+	destination, err := mapStore.GetCoordinate(ctx, game.Uid, gameMap.Uid, 0, 1)
+	s.Require().NoError(err)
+	s.Len(destination.Actors, 1, "actor should be at the destination")
+	s.Equal("player", destination.Sprites[0].Uid, "actor sprite should travel with them")
+
+	receipt = move("north")
+	s.Equal(v1.ErrorEffect_INVALID, receipt.GetError().GetType(), "moving off the map should be rejected")
+}
@@ -4,16 +4,21 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"overseer/auth"
 	v1 "overseer/build/go"
 	"overseer/common"
 	"overseer/engine"
 	"overseer/engine/handlers"
+	"overseer/generative"
+	"overseer/generative/ollama"
 	"overseer/server"
 	"overseer/storage"
 	"path"
 	"testing"
+	"text/template"
 
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/gorm"
 )
@@ -41,21 +46,40 @@ func (s *NewGameTest) TearDownTest() {
 }
 
 func (s *NewGameTest) TestEventingANewGame() {
-	userStore := storage.NewSqlUserStore(s.db)
-	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	mockOllama := new(MockOllamaClient)
+	mockTemplatingClient := new(MockTemplatingClient)
+	tmpl := template.Must(template.New("mock").Parse("mock"))
+	mockTemplatingClient.On("SpriteTemplate").Return(tmpl)
+	mockTemplatingClient.On("CoordinateLoreTemplate").Return(tmpl)
+	mockTemplatingClient.On("DungeonMasterTemplate").Return(tmpl)
+	mockTemplatingClient.On("IntroductionTemplate").Return(tmpl)
+	fakeChan := make(chan ollama.GenerateResponse)
+	close(fakeChan)
+	mockOllama.On("Generate", mock.Anything, mock.Anything).Return(fakeChan, nil)
+	mockOllama.On("Converse", mock.Anything, mock.Anything).Return(converseStream("Welcome, adventurers."), nil).Once()
+	mapSvc, err := generative.NewOllamaMapGenerationService(mockTemplatingClient, mockOllama)
+	s.Require().NoError(err)
+	dm, err := generative.NewOllamaDungeonMasterService(mockTemplatingClient, mockOllama)
+	s.Require().NoError(err)
+
+	mapStore := storage.NewSqlMapStore(s.db)
 	eventStore := storage.NewSqlEventStore(s.db)
 	lockStore := storage.NewSqlLockStore(s.db)
-	usersSrv := server.NewUserServer(userStore)
-	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore)
+	mapServer := server.NewMapServer(mapStore, eventStore, lockStore, engine.NewReceiptBroker(), mapSvc)
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, mapStore)
 	eventBus := engine.NewEventBus(
 		[]engine.EventHandler{
-			handlers.NewGameHandler(gamesStore, eventStore),
+			handlers.NewGameHandler(mapServer, dm, gamesStore, eventStore),
 		},
 		gamesSrv,
 		usersSrv,
 		eventStore,
+		engine.NewReceiptBroker(),
 	)
-	eventSrv := server.NewEventServer(eventBus)
+	eventSrv := server.NewEventServer(eventBus, eventStore, gamesStore)
 	user := &v1.User{
 		Uid: "test",
 	}
@@ -64,10 +88,11 @@ func (s *NewGameTest) TestEventingANewGame() {
 		Actor: nil,
 	})
 
-	_, err := usersSrv.RegisterUser(ctx, user)
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err = usersSrv.RegisterUser(ctx, user)
 	s.NoError(err, "error should be nil on creating user")
 
-	actor, err := usersSrv.RegisterActor(ctx, &v1.RegisterActorRequest{
+	actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{
 		UserId: user.Uid,
 		Source: v1.Actor_APP_DISCORD,
 	})
@@ -119,8 +144,24 @@ func (s *NewGameTest) TestEventingANewGame() {
 	)
 
 	s.NoError(err, "error should be nil")
-	s.Len(receipts.Receipts, 1, "new game receipt should be received")
-	s.NotNil(receipts.Receipts[0].GetAck(), "receipt should contain new game event: ", receipts.Receipts[0])
+	s.Require().Greater(len(receipts.Receipts), 3, "progress should be reported while the world is created")
+	s.Equal("the world is being created", receipts.Receipts[0].GetAck().GetMessage())
+	last := receipts.Receipts[len(receipts.Receipts)-1]
+	s.Equal("game created successfully", last.GetAck().GetMessage(), "receipt should contain new game event: ", last)
+
+	opening := receipts.Receipts[len(receipts.Receipts)-2].GetUtterance()
+	s.Require().NotNil(opening, "the dungeon master should open the game")
+	s.Equal(auth.DungeonMasterActorId, opening.GetActor())
+	s.Equal("Welcome, adventurers.", opening.GetContent())
+
+	gameMap, err := mapStore.GetMapForGame(ctx, game.Uid)
+	s.Require().NoError(err, "a map should be created for the game")
+	s.Equal(common.GetConfiguration().MapGeneration.DefaultMaxX, gameMap.MaxX)
+	s.Require().NotNil(game.Seed, "a game should be given a seed")
+	s.Equal(game.GetSeed(), gameMap.Seed, "the map should be made from the seed of the game")
+	start, err := mapStore.GetActorCoordinate(ctx, gameMap.Uid, actor.Uid)
+	s.Require().NoError(err, "participants should be placed on the map")
+	s.NotNil(start)
 
 	game, err = gamesSrv.GetGame(ctx, &v1.GetGameRequest{
 		GameUid: game.Uid,
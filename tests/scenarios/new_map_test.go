@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"overseer/auth"
 	v1 "overseer/build/go"
 	"overseer/common"
 	"overseer/engine"
@@ -15,6 +16,7 @@ import (
 	"path"
 	"testing"
 	"text/template"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
@@ -49,22 +51,25 @@ func (s *mapServerTestSuite) TestMapServer_CreateMap() {
 	mapSvc, err := generative.NewOllamaMapGenerationService(mockTemplatingClient, mockOllama)
 	s.Require().NoError(err)
 	mapStore := storage.NewSqlMapStore(s.db)
-	mapServer := server.NewMapServer(mapStore, mapSvc)
-	userStore := storage.NewSqlUserStore(s.db)
-	gamesStore := storage.NewSqlGameStore(s.db, userStore)
 	eventStore := storage.NewSqlEventStore(s.db)
 	lockStore := storage.NewSqlLockStore(s.db)
-	usersSrv := server.NewUserServer(userStore)
-	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore)
+	mapServer := server.NewMapServer(mapStore, eventStore, lockStore, engine.NewReceiptBroker(), mapSvc)
+	dm, err := generative.NewOllamaDungeonMasterService(mockTemplatingClient, mockOllama)
+	s.Require().NoError(err)
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, mapStore)
 	eventBus := engine.NewEventBus(
 		[]engine.EventHandler{
-			handlers.NewGameHandler(gamesStore, eventStore),
+			handlers.NewGameHandler(mapServer, dm, gamesStore, eventStore),
 		},
 		gamesSrv,
 		usersSrv,
 		eventStore,
+		engine.NewReceiptBroker(),
 	)
-	eventSrv := server.NewEventServer(eventBus)
+	eventSrv := server.NewEventServer(eventBus, eventStore, gamesStore)
 	user := &v1.User{
 		Uid: "test",
 	}
@@ -75,17 +80,20 @@ func (s *mapServerTestSuite) TestMapServer_CreateMap() {
 
 	// mocks
 	tmpl := template.Must(template.New("mock").Parse("mock"))
-	mockTemplatingClient.On("InternalLoreTemplate").Return(tmpl)
-	mockTemplatingClient.On("PublicLoreTemplate").Return(tmpl)
+	mockTemplatingClient.On("SpriteTemplate").Return(tmpl)
 	mockTemplatingClient.On("CoordinateLoreTemplate").Return(tmpl)
+	mockTemplatingClient.On("DungeonMasterTemplate").Return(tmpl)
+	mockTemplatingClient.On("IntroductionTemplate").Return(tmpl)
 	fakeChan := make(chan ollama.GenerateResponse)
 	close(fakeChan)
 	mockOllama.On("Generate", mock.Anything, mock.Anything).Return(fakeChan, nil)
+	mockOllama.On("Converse", mock.Anything, mock.Anything).Return(converseStream("Welcome, adventurers."), nil)
 
+	systemCtx, _ := auth.SystemContext(context.Background())
 	_, err = usersSrv.RegisterUser(ctx, user)
 	s.NoError(err, "error should be nil on creating user")
 
-	actor, err := usersSrv.RegisterActor(ctx, &v1.RegisterActorRequest{
+	actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{
 		UserId: user.Uid,
 		Source: v1.Actor_APP_DISCORD,
 	})
@@ -137,8 +145,8 @@ func (s *mapServerTestSuite) TestMapServer_CreateMap() {
 	)
 
 	s.NoError(err, "error should be nil")
-	s.Len(receipts.Receipts, 1, "new game receipt should be received")
-	s.NotNil(receipts.Receipts[0].GetAck(), "receipt should contain new game event: ", receipts.Receipts[0])
+	last := receipts.Receipts[len(receipts.Receipts)-1]
+	s.Equal("game created successfully", last.GetAck().GetMessage(), "receipt should contain new game event: ", last)
 
 	game, err = gamesSrv.GetGame(ctx, &v1.GetGameRequest{
 		GameUid: game.Uid,
@@ -148,7 +156,7 @@ func (s *mapServerTestSuite) TestMapServer_CreateMap() {
 	s.True(game.Initialized, "game should be initialized")
 	s.False(game.Completed, "game should not be completed")
 
-	_, err = mapServer.CreateMap(ctx, &v1.CreateMapRequest{
+	gameMap, err := mapServer.CreateMap(ctx, &v1.CreateMapRequest{
 		GameUid:                game.Uid,
 		MaxX:                   10,
 		MaxY:                   10,
@@ -157,5 +165,14 @@ func (s *mapServerTestSuite) TestMapServer_CreateMap() {
 		SpriteDensity:          0.2,
 		Actors:                 []*v1.Actor{actor},
 	})
+	s.Require().NoError(err, "error should be nil")
+
+	// the map is generated in the background
+	s.Eventually(func() bool {
+		generation, err := mapServer.GetMapGenerationStatus(ctx, &v1.GetMapRequest{Uid: gameMap.Uid})
+		return err == nil && generation.State == v1.MapGenerationStatus_COMPLETE
+	}, 10*time.Second, 20*time.Millisecond, "map generation should complete")
+	coordinates, err := mapStore.GetCoordinates(ctx, gameMap.Uid)
 	s.NoError(err, "error should be nil")
+	s.Len(coordinates, 21*21, "every coordinate should be generated")
 }
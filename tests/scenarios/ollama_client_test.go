@@ -40,3 +40,8 @@ func (m *MockOllamaClient) Converse(ctx context.Context, req ollama.ConverseRequ
 	args := m.Called(ctx, req)
 	return args.Get(0).(chan ollama.ConverseResponse), args.Error(1)
 }
+
+func (m *MockOllamaClient) Embed(ctx context.Context, req ollama.EmbedRequest) (*ollama.EmbedResponse, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(*ollama.EmbedResponse), args.Error(1)
+}
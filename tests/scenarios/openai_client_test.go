@@ -0,0 +1,221 @@
+package scenarios
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/generative"
+	"overseer/generative/openai"
+	"sync"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type OpenAIClientTest struct {
+	suite.Suite
+}
+
+func TestOpenAIClient(t *testing.T) {
+	suite.Run(t, new(OpenAIClientTest))
+}
+
+// fakeChatCompletions is a chat completions endpoint, streamed requests are answered with the chunks as server sent events
+type fakeChatCompletions struct {
+	mu       sync.Mutex
+	requests []openai.ChatCompletionRequest
+	headers  []http.Header
+	reply    string
+	chunks   []string
+	// broken drops the connection after the chunks instead of finishing the stream
+	broken bool
+}
+
+func (f *fakeChatCompletions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer sk-test" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"incorrect api key provided","type":"invalid_request_error"}}`)
+		return
+	}
+	switch r.URL.Path {
+	case "/v1/chat/completions":
+		f.chatCompletions(w, r)
+	case "/v1/embeddings":
+		f.embeddings(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// embeddings answers each input with its length, in reverse order as the api does not promise any order
+func (f *fakeChatCompletions) embeddings(w http.ResponseWriter, r *http.Request) {
+	var req openai.EmbeddingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	response := openai.EmbeddingsResponse{Object: "list", Model: req.Model}
+	for idx := len(req.Input) - 1; idx >= 0; idx-- {
+		response.Data = append(response.Data, openai.Embedding{Object: "embedding", Index: int64(idx), Embedding: []float32{float32(len(req.Input[idx]))}})
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeChatCompletions) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req openai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	f.headers = append(f.headers, r.Header.Clone())
+	f.mu.Unlock()
+
+	if !req.Stream {
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Id:      "chatcmpl-test",
+			Object:  "chat.completion",
+			Model:   req.Model,
+			Choices: []openai.ChatCompletionChoice{{Message: openai.Message{Role: openai.Assistant, Content: f.reply}, FinishReason: "stop"}},
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	fmt.Fprint(w, ": keep-alive\n\n")
+	for _, chunk := range f.chunks {
+		payload, _ := json.Marshal(openai.ChatCompletionChunk{
+			Id:      "chatcmpl-test",
+			Object:  "chat.completion.chunk",
+			Model:   req.Model,
+			Choices: []openai.ChatCompletionChunkChoice{{Delta: openai.Message{Content: chunk}}},
+		})
+		fmt.Fprintf(w, "data: %s\n\n", payload)
+		w.(http.Flusher).Flush()
+	}
+	if f.broken {
+		if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			conn.Close()
+		}
+		return
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+func (f *fakeChatCompletions) client(server *httptest.Server, apiKey string) openai.Client {
+	base, _ := url.Parse(server.URL)
+	return openai.NewOpenAIClient(base, apiKey)
+}
+
+func (s *OpenAIClientTest) TestChatCompletion() {
+	fake := &fakeChatCompletions{reply: `{"kind":"attack"}`}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	response, err := fake.client(server, "sk-test").ChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Model:          "local-model",
+		Messages:       []openai.Message{{Role: openai.User, Content: "classify this"}},
+		ResponseFormat: &openai.ResponseFormat{Type: "json_object"},
+	})
+	s.Require().NoError(err)
+	s.Equal(`{"kind":"attack"}`, response.Choices[0].Message.Content)
+	s.Require().Len(fake.requests, 1)
+	s.Equal("local-model", fake.requests[0].Model)
+	s.False(fake.requests[0].Stream)
+	s.Equal("json_object", fake.requests[0].ResponseFormat.Type)
+	s.Equal("application/json", fake.headers[0].Get("Content-Type"))
+
+	_, err = fake.client(server, "sk-wrong").ChatCompletion(context.Background(), openai.ChatCompletionRequest{Model: "local-model"})
+	s.Equal(codes.Unauthenticated, status.Code(err), "a rejected key should be unauthenticated")
+	s.Contains(status.Convert(err).Message(), "incorrect api key provided", "the message of the server should be kept")
+}
+
+func (s *OpenAIClientTest) TestTheOpenAIAdapter() {
+	fake := &fakeChatCompletions{reply: `{"kind":"attack"}`}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	llm := generative.NewOpenAILLM(fake.client(server, "sk-test"))
+
+	reply, err := llm.CompleteJSON(context.Background(), "classify this")
+	s.Require().NoError(err)
+	s.Equal(`{"kind":"attack"}`, reply)
+	s.Require().Len(fake.requests, 1)
+	s.Equal("json_object", fake.requests[0].ResponseFormat.Type, "json mode should ask for a json object")
+	s.Equal([]openai.Message{{Role: openai.User, Content: "classify this"}}, fake.requests[0].Messages)
+
+	embeddings, err := llm.Embed(context.Background(), "a", "goblin")
+	s.Require().NoError(err)
+	s.Equal([][]float32{{1}, {6}}, embeddings, "embeddings should be in the order of the inputs")
+}
+
+func (s *OpenAIClientTest) TestStreamingTheDungeonMaster() {
+	fake := &fakeChatCompletions{chunks: []string{"The cave ", "is dark.", "\n\nA goblin ", "stirs."}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	mockTemplatingClient := new(MockTemplatingClient)
+	mockTemplatingClient.On("DungeonMasterTemplate").Return(template.Must(template.New("mock").Parse("you are the dungeon master")))
+	dm, err := generative.NewOpenAIDungeonMasterService(mockTemplatingClient, fake.client(server, "sk-test"))
+	s.Require().NoError(err)
+
+	user := &v1.User{Uid: "test"}
+	actor := &v1.Actor{Uid: "gandalf", SourceIdentity: "gandalf", Source: v1.Actor_APP_DISCORD}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user, Actor: actor})
+
+	replies, err := dm.Respond(ctx, generative.DungeonMasterScene{
+		Speaker:      actor,
+		Participants: []*v1.Actor{actor},
+		History:      []generative.DungeonMasterLine{{FromDungeonMaster: true, Content: "Welcome"}},
+		Utterance:    "what do I see?",
+	})
+	s.Require().NoError(err)
+
+	paragraphs := make([]string, 0)
+	for reply := range replies {
+		s.Require().NoError(reply.Err)
+		paragraphs = append(paragraphs, reply.Content)
+	}
+	s.Equal([]string{"The cave is dark.", "A goblin stirs."}, paragraphs, "the stream should be split into paragraphs")
+
+	s.Require().Len(fake.requests, 1)
+	request := fake.requests[0]
+	s.True(request.Stream)
+	s.Equal(common.GetConfiguration().OpenAI.Model, request.Model, "the configured model should be used")
+	s.Equal("text/event-stream", fake.headers[0].Get("Accept"))
+	s.Equal([]openai.Message{
+		{Role: openai.System, Content: "you are the dungeon master"},
+		{Role: openai.Assistant, Content: "Welcome"},
+		{Role: openai.User, Content: "gandalf: what do I see?"},
+	}, request.Messages)
+}
+
+func (s *OpenAIClientTest) TestABrokenStreamIsReported() {
+	fake := &fakeChatCompletions{chunks: []string{"The cave ", "is dark."}, broken: true}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	llm := generative.NewOpenAILLM(fake.client(server, "sk-test"))
+
+	chunks, err := llm.ChatStream(context.Background(), []generative.Message{{Role: generative.RoleUser, Content: "what do I see?"}})
+	s.Require().NoError(err)
+
+	var reply string
+	var streamErr error
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			streamErr = chunk.Err
+			continue
+		}
+		reply += chunk.Content
+	}
+	s.Equal("The cave is dark.", reply, "what arrived before the stream broke should be kept")
+	s.Equal(codes.Unavailable, status.Code(streamErr), "a stream that broke should end with its error")
+}
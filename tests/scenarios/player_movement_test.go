@@ -0,0 +1,230 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/server"
+	"overseer/storage"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+type PlayerMovementTest struct {
+	db     *gorm.DB
+	dbFile string
+	suite.Suite
+}
+
+func TestPlayerMovement(t *testing.T) {
+	suite.Run(t, new(PlayerMovementTest))
+}
+
+func (s *PlayerMovementTest) SetupTest() {
+	s.dbFile = path.Join(os.TempDir(), fmt.Sprintf("test-%s.db", uuid.NewString()))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *PlayerMovementTest) TearDownTest() {
+	s.Require().NoError(os.Remove(s.dbFile), "failed to remove test database")
+	s.db = nil
+}
+
+func (s *PlayerMovementTest) TestMovingThroughTheMapsService() {
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	eventStore := storage.NewSqlEventStore(s.db)
+	mapStore := storage.NewSqlMapStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	lockStore := storage.NewSqlLockStore(s.db)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, mapStore)
+	broker := engine.NewReceiptBroker()
+	mapsSrv := server.NewMapServer(mapStore, eventStore, lockStore, broker, nil)
+	eventSrv := server.NewEventServer(engine.NewEventBus([]engine.EventHandler{}, gamesSrv, usersSrv, eventStore, broker), eventStore, gamesStore)
+
+	user := &v1.User{Uid: "test"}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err := usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err)
+	actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: user.Uid, Source: v1.Actor_APP_DISCORD})
+	s.Require().NoError(err)
+	ctx, _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actor})
+
+	game, err := gamesSrv.CreateGame(ctx, &v1.CreateGameRequest{Name: "test game", Participants: []*v1.Actor{actor}})
+	s.Require().NoError(err)
+
+	// a 3x3 map with the actor in the center, an immovable boulder to the east, a hermit to the west and difficult terrain to the north
+	gameMap, err := mapStore.CreateMap(ctx, &v1.CreateMapRequest{GameUid: game.Uid, Name: "test map", MaxX: 1, MaxY: 1})
+	s.Require().NoError(err)
+	for x := int64(-1); x <= 1; x++ {
+		for y := int64(-1); y <= 1; y++ {
+			coord := &v1.MapCoordinateDetail{
+				Uid:      common.GenerateUniqueId(),
+				GameUid:  game.Uid,
+				MapUid:   gameMap.Uid,
+				Position: &v1.MapPosition{X: x, Y: y},
+				Type:     v1.MapCoordinateDetail_OPEN_FIELD,
+			}
+			switch {
+			case x == 0 && y == 0:
+				coord.Actors = []*v1.Actor{actor}
+				coord.Sprites = []*v1.Sprite{{Uid: "player", Actor: actor, IsObstacle: true, IsMoveable: true}}
+			case x == 1 && y == 0:
+				coord.Sprites = []*v1.Sprite{{Uid: "boulder", IsObstacle: true, IsMoveable: false}}
+			case x == -1 && y == 0:
+				coord.Sprites = []*v1.Sprite{{Uid: "hermit", LorePublic: "an old hermit", LoreInternal: "the hermit is the lost king"}}
+			case x == 0 && y == 1:
+				coord.DifficultTerrain = true
+			}
+			s.Require().NoError(mapStore.CreateCoordinate(ctx, coord))
+		}
+	}
+
+	position, err := mapsSrv.GetPosition(ctx, actor)
+	s.Require().NoError(err)
+	s.Equal(int64(0), position.X)
+	s.Equal(int64(0), position.Y)
+
+	_, err = mapsSrv.GetPosition(ctx, &v1.Actor{Uid: "someone else"})
+	s.Equal(codes.PermissionDenied, status.Code(err), "an actor may only ask for their own position")
+
+	peeked, err := mapsSrv.PeekCoordinate(ctx, &v1.PeekCoordinateRequest{GameUid: game.Uid, Coordinate: &v1.MapPosition{X: -1, Y: 0}})
+	s.Require().NoError(err)
+	s.Require().Len(peeked.Sprites, 1)
+	s.Equal("an old hermit", peeked.Sprites[0].LorePublic)
+
+	_, err = mapsSrv.PeekCoordinate(ctx, &v1.PeekCoordinateRequest{GameUid: game.Uid, Coordinate: &v1.MapPosition{X: 5, Y: 0}})
+	s.Equal(codes.InvalidArgument, status.Code(err), "peeking beyond the edge of the map should fail")
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream := &fakeWatchStream{ctx: watchCtx}
+	done := make(chan error, 1)
+	go func() {
+		done <- eventSrv.WatchGame(&v1.WatchGameRequest{GameUid: game.Uid}, stream)
+	}()
+	s.Eventually(stream.isSubscribed, time.Second, 10*time.Millisecond, "the watcher should be subscribed before moving")
+
+	move := func(x int64, y int64) *v1.MovementResult {
+		result, err := mapsSrv.PlayerMovement(ctx, &v1.PlayerMovementRequest{GameUid: game.Uid, ActorUid: actor.Uid, X: x, Y: y})
+		s.Require().NoError(err)
+		return result
+	}
+
+	result := move(1, 0)
+	s.False(result.Success, "the boulder should block movement")
+	s.Equal("the way is blocked", result.GetMessage())
+
+	_, err = mapsSrv.PlayerMovement(ctx, &v1.PlayerMovementRequest{GameUid: game.Uid, ActorUid: actor.Uid, SpriteUid: "boulder", X: 0, Y: 1})
+	s.Equal(codes.InvalidArgument, status.Code(err), "an actor may only move their own sprite")
+
+	stale, err := mapStore.GetCoordinate(ctx, game.Uid, gameMap.Uid, 0, 0)
+	s.Require().NoError(err)
+
+	result = move(0, 1)
+	s.Require().True(result.Success, result.GetMessage())
+
+	// saving a copy read before the move should not bring the actor back
+	stale.Lore = "trampled grass"
+	s.Require().NoError(mapStore.UpdateCoordinate(ctx, stale))
+	s.Equal(int64(2), result.Cost, "difficult terrain should cost double")
+	s.Equal(int64(1), result.Destination.Y)
+
+	position, err = mapsSrv.GetPosition(ctx, actor)
+	s.Require().NoError(err)
+	s.Equal(int64(1), position.Y, "the position should follow the actor")
+
+	origin, err := mapStore.GetCoordinate(ctx, game.Uid, gameMap.Uid, 0, 0)
+	s.Require().NoError(err)
+	s.Empty(origin.Actors, "actor should have left the origin")
+	s.Empty(origin.Sprites, "actor sprite should have left the origin")
+	s.Equal("trampled grass", origin.Lore, "the rest of the update should be saved")
+
+	destination, err := mapStore.GetCoordinate(ctx, game.Uid, gameMap.Uid, 0, 1)
+	s.Require().NoError(err)
+	s.Len(destination.Actors, 1, "actor should be at the destination")
+	s.Equal("player", destination.Sprites[0].Uid, "actor sprite should travel with them")
+
+	result = move(0, -1)
+	s.False(result.Success)
+	s.Equal("you can only travel to a neighboring location", result.GetMessage())
+
+	result = move(0, 2)
+	s.False(result.Success, "moving off the map should be refused")
+
+	events, err := eventStore.GetRecentEvents(ctx, game.Uid, 10)
+	s.Require().NoError(err)
+	s.Require().Len(events, 1, "only the successful step should be recorded")
+	s.Equal("north", events[0].GetPayload().GetInteraction().GetMovement().GetDirection())
+
+	s.Eventually(func() bool {
+		stream.mu.Lock()
+		defer stream.mu.Unlock()
+		return len(stream.receipts) == 1
+	}, time.Second, 10*time.Millisecond, "watchers of the game should see the step")
+	s.Equal(int64(1), stream.receipts[0].GetGameState().GetMovement().GetDestination().GetY())
+
+	// a handler holding the game keeps the step waiting until it lets go
+	lock, err := lockStore.LockGame(ctx, &v1.LockGameRequest{GameUid: game.Uid, ClaimUid: "handler", Wait: true})
+	s.Require().NoError(err)
+	moved := make(chan *v1.MovementResult, 1)
+	go func() {
+		result, _ := mapsSrv.PlayerMovement(ctx, &v1.PlayerMovementRequest{GameUid: game.Uid, ActorUid: actor.Uid, X: 0, Y: 0})
+		moved <- result
+	}()
+	s.Never(func() bool { return len(moved) > 0 }, 200*time.Millisecond, 10*time.Millisecond, "the step should wait for the game lease")
+	_, err = lockStore.UnlockGame(ctx, &v1.UnlockGameRequest{GameUid: game.Uid, ClaimUid: "handler", FencingToken: lock.GetFencingToken()})
+	s.Require().NoError(err)
+	select {
+	case result := <-moved:
+		s.Require().NotNil(result)
+		s.True(result.Success, result.GetMessage())
+	case <-time.After(time.Second):
+		s.Fail("the step should be made once the game is released")
+	}
+	locks, err := lockStore.ListLocks(ctx, game.Uid)
+	s.Require().NoError(err)
+	s.Empty(locks, "the step should release the game lease")
+
+	cancel()
+	s.NoError(<-done)
+}
+
+func (s *PlayerMovementTest) TestIndexingPositionsWrittenBeforeTheIndex() {
+	user := &v1.User{Uid: "test"}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	mapStore := storage.NewSqlMapStore(s.db)
+	gameMap, err := mapStore.CreateMap(ctx, &v1.CreateMapRequest{GameUid: "game", Name: "test map", MaxX: 1, MaxY: 1})
+	s.Require().NoError(err)
+	s.Require().NoError(mapStore.CreateCoordinate(ctx, &v1.MapCoordinateDetail{
+		Uid:      common.GenerateUniqueId(),
+		GameUid:  "game",
+		MapUid:   gameMap.Uid,
+		Position: &v1.MapPosition{X: 1, Y: -1},
+		Actors:   []*v1.Actor{{Uid: "frodo"}},
+	}))
+
+	// a database from before the index only has the coordinates
+	s.Require().NoError(s.db.Migrator().DropTable("actor_positions"))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+
+	position, err := storage.NewSqlMapStore(db).GetActorPosition(ctx, "frodo")
+	s.Require().NoError(err, "migrating should index where existing actors stand")
+	s.Equal(int64(1), position.X)
+	s.Equal(int64(-1), position.Y)
+}
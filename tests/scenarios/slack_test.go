@@ -0,0 +1,252 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"overseer/auth"
+	"overseer/client"
+	"overseer/engine"
+	"overseer/engine/handlers"
+	"overseer/generative"
+	"overseer/generative/ollama"
+	"overseer/server"
+	"overseer/slack"
+	"overseer/storage"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	slackapi "github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type SlackTest struct {
+	db     *gorm.DB
+	dbFile string
+	suite.Suite
+}
+
+func TestSlack(t *testing.T) {
+	suite.Run(t, new(SlackTest))
+}
+
+func (s *SlackTest) SetupTest() {
+	s.dbFile = path.Join(os.TempDir(), fmt.Sprintf("test-%s.db", uuid.NewString()))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *SlackTest) TearDownTest() {
+	s.Require().NoError(os.Remove(s.dbFile), "failed to remove test database")
+	s.db = nil
+}
+
+// slackPost is a message the bot posted to the fake slack api
+type slackPost struct {
+	method   string
+	channel  string
+	user     string
+	text     string
+	threadTs string
+	ts       string
+}
+
+// fakeSlackApi records chat.postMessage and chat.postEphemeral calls
+type fakeSlackApi struct {
+	mu    sync.Mutex
+	posts []slackPost
+}
+
+func (f *fakeSlackApi) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	method := strings.TrimPrefix(r.URL.Path, "/api/")
+
+	f.mu.Lock()
+	ts := fmt.Sprintf("1700000000.%06d", len(f.posts)+1)
+	f.posts = append(f.posts, slackPost{
+		method:   method,
+		channel:  r.Form.Get("channel"),
+		user:     r.Form.Get("user"),
+		text:     r.Form.Get("text"),
+		threadTs: r.Form.Get("thread_ts"),
+		ts:       ts,
+	})
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch method {
+	case "chat.postMessage":
+		fmt.Fprintf(w, `{"ok":true,"channel":%q,"ts":%q}`, r.Form.Get("channel"), ts)
+	case "chat.postEphemeral":
+		fmt.Fprintf(w, `{"ok":true,"message_ts":%q}`, ts)
+	default:
+		fmt.Fprint(w, `{"ok":false,"error":"unknown_method"}`)
+	}
+}
+
+// find returns the posts made with the method whose text contains the content
+func (f *fakeSlackApi) find(method string, content string) []slackPost {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	found := make([]slackPost, 0)
+	for _, post := range f.posts {
+		if post.method == method && strings.Contains(post.text, content) {
+			found = append(found, post)
+		}
+	}
+	return found
+}
+
+func (s *SlackTest) TestPlayingInASlackThread() {
+	mockOllama := new(MockOllamaClient)
+	mockTemplatingClient := new(MockTemplatingClient)
+	tmpl := template.Must(template.New("mock").Parse("mock"))
+	mockTemplatingClient.On("SpriteTemplate").Return(tmpl)
+	mockTemplatingClient.On("CoordinateLoreTemplate").Return(tmpl)
+	mockTemplatingClient.On("DungeonMasterTemplate").Return(tmpl)
+	mockTemplatingClient.On("IntroductionTemplate").Return(tmpl)
+	fakeChan := make(chan ollama.GenerateResponse)
+	close(fakeChan)
+	mockOllama.On("Generate", mock.Anything, mock.Anything).Return(fakeChan, nil)
+	mockOllama.On("Converse", mock.Anything, mock.Anything).Return(converseStream("Welcome, adventurers."), nil).Once()
+	mapSvc, err := generative.NewOllamaMapGenerationService(mockTemplatingClient, mockOllama)
+	s.Require().NoError(err)
+	dm, err := generative.NewOllamaDungeonMasterService(mockTemplatingClient, mockOllama)
+	s.Require().NoError(err)
+
+	mapStore := storage.NewSqlMapStore(s.db)
+	eventStore := storage.NewSqlEventStore(s.db)
+	lockStore := storage.NewSqlLockStore(s.db)
+	mapServer := server.NewMapServer(mapStore, eventStore, lockStore, engine.NewReceiptBroker(), mapSvc)
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	keyStore := storage.NewSqlApiKeyStore(s.db)
+	usersSrv := server.NewUserServer(userStore, keyStore, nil)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, mapStore)
+	eventBus := engine.NewEventBus(
+		[]engine.EventHandler{
+			handlers.NewGameHandler(mapServer, dm, gamesStore, eventStore),
+			handlers.NewUtteranceHandler(gamesStore, eventStore),
+		},
+		gamesSrv,
+		usersSrv,
+		eventStore,
+		engine.NewReceiptBroker(),
+	)
+	eventSrv := server.NewEventServer(eventBus, eventStore, gamesStore)
+
+	address, stop, err := serveOverseer(eventSrv, usersSrv, gamesSrv, mapServer,
+		&testAuthenticator{users: userStore, keys: keyStore},
+		auth.NewAuthorizer(gamesStore, mapStore),
+	)
+	s.Require().NoError(err)
+	defer stop()
+
+	overseer, err := client.NewOverseerClient(address, "test")
+	s.Require().NoError(err)
+	defer overseer.Close()
+
+	api := &fakeSlackApi{}
+	slackServer := httptest.NewServer(api)
+	defer slackServer.Close()
+	bot := slack.NewBot(slackapi.New("xoxb-test", slackapi.OptionAPIURL(slackServer.URL+"/api/")), overseer, "")
+	defer bot.Close()
+
+	ctx := context.Background()
+	command := func(userId string, name string, text string) {
+		bot.HandleCommand(ctx, slackapi.SlashCommand{
+			Command:   name,
+			Text:      text,
+			UserID:    userId,
+			UserName:  userId,
+			TeamID:    "T1",
+			ChannelID: "C1",
+		})
+	}
+
+	command("U1", "/register", "")
+	command("U2", "/register", "")
+	s.Len(api.find("chat.postEphemeral", "you are registered as"), 2, "both players should be told they are registered")
+
+	command("U1", "/game", "new the shire")
+	threads := api.find("chat.postMessage", "is played in this thread")
+	s.Require().Len(threads, 1, "a thread should be started for the game")
+	threadTs := threads[0].ts
+	s.Empty(threads[0].threadTs, "the root of the thread should be posted to the channel")
+	s.Eventually(func() bool {
+		posts := api.find("chat.postMessage", "Welcome, adventurers.")
+		return len(posts) == 1 && posts[0].threadTs == threadTs
+	}, 5*time.Second, 10*time.Millisecond, "the introduction should be posted to the thread")
+
+	command("U2", "/game", "join")
+	s.Len(api.find("chat.postMessage", "<@U2> joined *the shire*"), 1, "joining should be announced in the thread")
+	s.Len(api.find("chat.postMessage", "is played in this thread"), 1, "joining a bound game should not start another thread")
+
+	bot.HandleMessage(ctx, "T1", &slackevents.MessageEvent{
+		User:            "U1",
+		Channel:         "C1",
+		Text:            "hello everyone",
+		ThreadTimeStamp: threadTs,
+	})
+	s.Eventually(func() bool {
+		posts := api.find("chat.postMessage", "hello everyone")
+		return len(posts) == 1 && posts[0].threadTs == threadTs && strings.Contains(posts[0].text, "<@U1>")
+	}, 5*time.Second, 10*time.Millisecond, "replies in the thread should be said to the table")
+
+	command("U1", "/whisper", "<@U2|sam> psst sam")
+	s.Eventually(func() bool {
+		return len(api.find("chat.postEphemeral", "psst sam")) == 2
+	}, 5*time.Second, 10*time.Millisecond, "whispers should be shown to the speaker and the target")
+	for _, post := range api.find("chat.postEphemeral", "psst sam") {
+		s.Contains([]string{"U1", "U2"}, post.user, "whispers should only be shown to the speaker and the target")
+		s.Equal(threadTs, post.threadTs, "whispers should be shown in the thread")
+	}
+	s.Empty(api.find("chat.postMessage", "psst sam"), "whispers should never be posted for everyone")
+
+	// the game can be followed from a second channel, the table is posted to both threads but whispers only once
+	_, rest, _ := strings.Cut(threads[0].text, "(`")
+	gameUid, _, _ := strings.Cut(rest, "`)")
+	command("U3", "/register", "")
+	bot.HandleCommand(ctx, slackapi.SlashCommand{
+		Command:   "/game",
+		Text:      "join spectate " + gameUid,
+		UserID:    "U3",
+		UserName:  "U3",
+		TeamID:    "T1",
+		ChannelID: "C2",
+	})
+	s.Require().Len(api.find("chat.postMessage", "is played in this thread"), 2, "the second channel should get a thread of its own")
+
+	bot.HandleMessage(ctx, "T1", &slackevents.MessageEvent{
+		User:            "U1",
+		Channel:         "C1",
+		Text:            "hello both tables",
+		ThreadTimeStamp: threadTs,
+	})
+	s.Eventually(func() bool {
+		posts := api.find("chat.postMessage", "hello both tables")
+		return len(posts) == 2 && posts[0].channel != posts[1].channel
+	}, 5*time.Second, 10*time.Millisecond, "the table should be posted to every bound channel")
+
+	command("U1", "/whisper", "<@U2|sam> psst again")
+	s.Eventually(func() bool {
+		return len(api.find("chat.postEphemeral", "psst again")) == 2
+	}, 5*time.Second, 10*time.Millisecond, "whispers should be shown to the speaker and the target")
+	s.Never(func() bool {
+		return len(api.find("chat.postEphemeral", "psst again")) > 2
+	}, 200*time.Millisecond, 10*time.Millisecond, "whispers should only be sent once however many channels are bound")
+}
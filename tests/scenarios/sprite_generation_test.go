@@ -0,0 +1,117 @@
+package scenarios
+
+import (
+	"context"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/generative"
+	"sync"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SpriteGenerationTest struct {
+	suite.Suite
+}
+
+func TestSpriteGeneration(t *testing.T) {
+	suite.Run(t, new(SpriteGenerationTest))
+}
+
+// fakeLLM answers json prompts from a script, everything else is answered with lore
+type fakeLLM struct {
+	mu          sync.Mutex
+	jsonReplies []string
+	jsonPrompts []string
+}
+
+func (f *fakeLLM) Complete(ctx context.Context, prompt string) (string, error) {
+	return "a quiet glade", nil
+}
+
+func (f *fakeLLM) CompleteJSON(ctx context.Context, prompt string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.jsonPrompts = append(f.jsonPrompts, prompt)
+	if len(f.jsonReplies) == 0 {
+		return "", nil
+	}
+	reply := f.jsonReplies[0]
+	f.jsonReplies = f.jsonReplies[1:]
+	return reply, nil
+}
+
+func (f *fakeLLM) Chat(ctx context.Context, messages []generative.Message) (string, error) {
+	return "", nil
+}
+
+func (f *fakeLLM) ChatStream(ctx context.Context, messages []generative.Message) (<-chan generative.Chunk, error) {
+	stream := make(chan generative.Chunk)
+	close(stream)
+	return stream, nil
+}
+
+func (f *fakeLLM) Embed(ctx context.Context, inputs ...string) ([][]float32, error) {
+	return make([][]float32, len(inputs)), nil
+}
+
+func (s *SpriteGenerationTest) generate(llm generative.LLM) *v1.MapCoordinateDetail {
+	mockTemplatingClient := new(MockTemplatingClient)
+	mockTemplatingClient.On("SpriteTemplate").Return(template.Must(template.New("mock").Parse("{{.Schema}}")))
+	mockTemplatingClient.On("CoordinateLoreTemplate").Return(template.Must(template.New("mock").Parse("lore")))
+	mapSvc, err := generative.NewLLMMapGenerationService(mockTemplatingClient, llm)
+	s.Require().NoError(err)
+
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: &v1.User{Uid: "test"}})
+	// the minimum density always asks for exactly one sprite
+	coordinate, err := mapSvc.GenerateCoordinate(ctx, v1.GameTheme_DEFAULT, 0, &v1.MapCoordinateDetail{
+		Uid:      "coordinate",
+		Position: &v1.MapPosition{X: 0, Y: 0},
+		Type:     v1.MapCoordinateDetail_FOREST,
+	}, nil)
+	s.Require().NoError(err)
+	return coordinate
+}
+
+func (s *SpriteGenerationTest) TestMalformedSpritesAreRetriedAndStatsClamped() {
+	llm := &fakeLLM{jsonReplies: []string{
+		`the goblin is called grug`,
+		`{"name": "Grug", "stats": {"health": 10}, "is_obstacle": true, "is_moveable": false, "lore_internal": "hungry", "lore_public": "a goblin"}`,
+		`{"name": "Grug", "stats": {"Health": 500, "attack": -3, "defense": 12.5, "speed": 40}, "is_obstacle": false, "is_moveable": true, "lore_internal": "he is hungry", "lore_public": "a small goblin"}`,
+	}}
+
+	coordinate := s.generate(llm)
+	s.Require().Len(coordinate.Sprites, 1)
+	sprite := coordinate.Sprites[0]
+	s.Equal("Grug", sprite.Name)
+	s.False(sprite.IsObstacle, "the model should decide whether the sprite is an obstacle")
+	s.True(sprite.IsMoveable, "the model should decide whether the sprite can be moved")
+	s.Equal("he is hungry", sprite.LoreInternal)
+	s.Equal("a small goblin", sprite.LorePublic)
+
+	stats := make(map[v1.Characteristic_Type]float32)
+	for _, characteristic := range sprite.Characteristics {
+		stats[characteristic.Type] = characteristic.Value
+	}
+	s.Equal(map[v1.Characteristic_Type]float32{
+		v1.Characteristic_HEALTH:  100,
+		v1.Characteristic_ATTACK:  0,
+		v1.Characteristic_DEFENSE: 12.5,
+		v1.Characteristic_SPEED:   40,
+	}, stats, "stats should be clamped into their configured ranges")
+
+	s.Require().Len(llm.jsonPrompts, 3, "malformed sprites should be asked for again")
+	s.Contains(llm.jsonPrompts[0], `"lore_public"`, "the schema should be part of the prompt")
+	s.Contains(llm.jsonPrompts[2], "stats.attack is missing", "the model should be told why it was rejected")
+}
+
+func (s *SpriteGenerationTest) TestASpriteTheModelCannotDescribeIsSkipped() {
+	llm := &fakeLLM{}
+
+	coordinate := s.generate(llm)
+	s.Empty(coordinate.Sprites, "the coordinate should be kept without the sprite")
+	s.Equal("a quiet glade", coordinate.Lore)
+	s.Len(llm.jsonPrompts, common.GetConfiguration().MapGeneration.SpriteGenerationAttempts)
+}
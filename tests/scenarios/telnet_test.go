@@ -0,0 +1,193 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/client"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/engine/handlers"
+	"overseer/server"
+	"overseer/storage"
+	"overseer/telnet"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type TelnetTest struct {
+	db     *gorm.DB
+	dbFile string
+	suite.Suite
+}
+
+func TestTelnet(t *testing.T) {
+	suite.Run(t, new(TelnetTest))
+}
+
+func (s *TelnetTest) SetupTest() {
+	s.dbFile = path.Join(os.TempDir(), fmt.Sprintf("test-%s.db", uuid.NewString()))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *TelnetTest) TearDownTest() {
+	s.Require().NoError(os.Remove(s.dbFile), "failed to remove test database")
+	s.db = nil
+}
+
+// terminal is the player's end of a telnet session
+type terminal struct {
+	conn   net.Conn
+	mu     sync.Mutex
+	output strings.Builder
+}
+
+func openTerminal(ctx context.Context, server telnet.TelnetServer) *terminal {
+	serverConn, clientConn := net.Pipe()
+	t := &terminal{conn: clientConn}
+	go server.Handle(ctx, serverConn)
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := clientConn.Read(buf)
+			if err != nil {
+				return
+			}
+			t.mu.Lock()
+			t.output.Write(buf[:n])
+			t.mu.Unlock()
+		}
+	}()
+	return t
+}
+
+func (t *terminal) send(line string) {
+	t.conn.Write([]byte(line + "\r\n"))
+}
+
+func (t *terminal) contains(text string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.Contains(t.output.String(), text)
+}
+
+func (t *terminal) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.output.String()
+}
+
+func (s *TelnetTest) TestPlayingFromATerminal() {
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	eventStore := storage.NewSqlEventStore(s.db)
+	mapStore := storage.NewSqlMapStore(s.db)
+	keyStore := storage.NewSqlApiKeyStore(s.db)
+	usersSrv := server.NewUserServer(userStore, keyStore, nil)
+	lockStore := storage.NewSqlLockStore(s.db)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, mapStore)
+	eventBus := engine.NewEventBus(
+		[]engine.EventHandler{
+			handlers.NewUtteranceHandler(gamesStore, eventStore),
+		},
+		gamesSrv,
+		usersSrv,
+		eventStore,
+		engine.NewReceiptBroker(),
+	)
+	eventSrv := server.NewEventServer(eventBus, eventStore, gamesStore)
+
+	address, stop, err := serveOverseer(eventSrv, usersSrv, gamesSrv, server.NewMapServer(mapStore, eventStore, lockStore, engine.NewReceiptBroker(), nil),
+		&testAuthenticator{users: userStore, keys: keyStore},
+		auth.NewAuthorizer(gamesStore, mapStore),
+	)
+	s.Require().NoError(err)
+	defer stop()
+	overseer, err := client.NewOverseerClient(address, "test")
+	s.Require().NoError(err)
+	defer overseer.Close()
+
+	// bilbo plays from discord
+	user := &v1.User{Uid: "bilbo"}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err = usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err)
+	bilbo, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: user.Uid, SourceIdentity: "bilbo", Source: v1.Actor_APP_DISCORD})
+	s.Require().NoError(err)
+	bilboCtx, _ := common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: bilbo})
+	game, err := gamesSrv.CreateGame(bilboCtx, &v1.CreateGameRequest{Name: "the shire", Participants: []*v1.Actor{bilbo}})
+	s.Require().NoError(err)
+
+	telnetServer := telnet.NewTelnetServer("", overseer, func(apiKey string) (*client.OverseerClient, error) {
+		return client.NewOverseerClientWithApiKey(address, apiKey)
+	})
+	sessionCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	waitFor := func(t *terminal, text string) {
+		s.Eventually(func() bool { return t.contains(text) }, 5*time.Second, 10*time.Millisecond, "expected %q in %q", text, t)
+	}
+
+	frodo := openTerminal(sessionCtx, telnetServer)
+	waitFor(frodo, "name: ")
+	frodo.send("frodo")
+	waitFor(frodo, "create them? [y/n]")
+	frodo.send("y")
+	waitFor(frodo, "Keep it safe")
+	key := regexp.MustCompile(`Your key is (\S+)`).FindStringSubmatch(frodo.String())
+	s.Require().Len(key, 2, "a new character should be handed a key")
+
+	frodo.send("look")
+	waitFor(frodo, "You are not in a game")
+	frodo.send("join " + game.Uid)
+	waitFor(frodo, "You joined the shire")
+	frodo.send("say hello from the terminal")
+	waitFor(frodo, "You say: hello from the terminal")
+
+	frodoActor, err := usersSrv.GetActorBySource(ctx, &v1.GetActorBySourceRequest{Source: v1.Actor_APP_TELNET, SourceIdentity: "frodo"})
+	s.Require().NoError(err, "the character should be registered as a telnet actor")
+	_, err = eventSrv.Submit(bilboCtx, &v1.Event{
+		GameUid: game.Uid,
+		Actor:   bilbo,
+		Origin:  &v1.Event_Discord{Discord: &v1.EventOriginDiscord{Guild: "test", Channel: "test"}},
+		Payload: &v1.Event_Interaction{Interaction: &v1.InteractionEvent{
+			Interaction: &v1.InteractionEvent_Utterance{Utterance: &v1.UtteranceInteraction{
+				Content:   "psst frodo",
+				Utterance: &v1.UtteranceInteraction_Player{Player: &v1.PlayerUtterance{Target: frodoActor, Whisper: true}},
+			}},
+		}},
+	})
+	s.Require().NoError(err)
+	waitFor(frodo, "discord:bilbo whispers to you: psst frodo")
+
+	frodo.send("whisper discord:bilbo the ring is safe")
+	waitFor(frodo, "You whisper to discord:bilbo: the ring is safe")
+	frodo.send("dance")
+	waitFor(frodo, "I don't know how to dance")
+	frodo.send("quit")
+	waitFor(frodo, "Farewell")
+
+	// coming back requires the key
+	again := openTerminal(sessionCtx, telnetServer)
+	waitFor(again, "name: ")
+	again.send("frodo")
+	waitFor(again, "key: ")
+	again.send("not-the-key")
+	waitFor(again, "That key does not fit")
+	again.send(key[1])
+	waitFor(again, "Hello frodo")
+	s.False(again.contains("psst frodo"), "nothing should be printed before a game is joined")
+}
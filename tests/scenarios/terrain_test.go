@@ -0,0 +1,89 @@
+package scenarios
+
+import (
+	v1 "overseer/build/go"
+	"overseer/terrain"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type TerrainTest struct {
+	suite.Suite
+}
+
+func TestTerrain(t *testing.T) {
+	suite.Run(t, new(TerrainTest))
+}
+
+var terrainGenerators = []v1.TerrainGenerator{
+	v1.TerrainGenerator_NOISE,
+	v1.TerrainGenerator_CELLULAR_AUTOMATA,
+	v1.TerrainGenerator_WAVE_FUNCTION_COLLAPSE,
+}
+
+func (s *TerrainTest) generate(kind v1.TerrainGenerator, seed int64, maxX int64, maxY int64) *terrain.Terrain {
+	generator, err := terrain.NewTerrainGenerator(kind, seed)
+	s.Require().NoError(err)
+	land, err := generator.Generate(maxX, maxY)
+	s.Require().NoError(err, "%s should generate terrain", kind)
+	return land
+}
+
+func (s *TerrainTest) TestTheSameSeedMakesTheSameWorld() {
+	for _, kind := range terrainGenerators {
+		first := s.generate(kind, 42, 12, 8)
+		second := s.generate(kind, 42, 12, 8)
+		other := s.generate(kind, 43, 12, 8)
+
+		differs := false
+		for y := int64(-8); y <= 8; y++ {
+			for x := int64(-12); x <= 12; x++ {
+				position := &v1.MapPosition{X: x, Y: y}
+				s.NotEqual(v1.MapCoordinateDetail_TYPE_UNSPECIFIED, first.At(position), "%s left (%d, %d) undecided", kind, x, y)
+				s.Equal(first.At(position), second.At(position), "%s should be reproducible at (%d, %d)", kind, x, y)
+				differs = differs || first.At(position) != other.At(position)
+			}
+		}
+		s.True(differs, "%s should make a different world from a different seed", kind)
+	}
+}
+
+func (s *TerrainTest) TestWaveFunctionCollapseObeysTheAdjacencyRules() {
+	for seed := int64(0); seed < 20; seed++ {
+		land := s.generate(v1.TerrainGenerator_WAVE_FUNCTION_COLLAPSE, seed, 10, 10)
+		for y := int64(-10); y <= 10; y++ {
+			for x := int64(-10); x <= 10; x++ {
+				here := land.At(&v1.MapPosition{X: x, Y: y})
+				for _, neighbor := range surrounding(x, y) {
+					there := land.At(neighbor)
+					if there == v1.MapCoordinateDetail_TYPE_UNSPECIFIED {
+						continue
+					}
+					s.True(terrain.CanBorder(here, there), "seed %d put %s next to %s at (%d, %d)", seed, here, there, x, y)
+				}
+			}
+		}
+	}
+	s.False(terrain.CanBorder(v1.MapCoordinateDetail_SEA, v1.MapCoordinateDetail_DESERT), "the sea never touches the desert")
+	s.False(terrain.CanBorder(v1.MapCoordinateDetail_DESERT, v1.MapCoordinateDetail_SEA), "the rules should be symmetric")
+}
+
+func (s *TerrainTest) TestUnknownGenerator() {
+	_, err := terrain.NewTerrainGenerator(v1.TerrainGenerator(99), 1)
+	s.Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func surrounding(x int64, y int64) []*v1.MapPosition {
+	neighbors := make([]*v1.MapPosition, 0, 8)
+	for dy := int64(-1); dy <= 1; dy++ {
+		for dx := int64(-1); dx <= 1; dx++ {
+			if dx != 0 || dy != 0 {
+				neighbors = append(neighbors, &v1.MapPosition{X: x + dx, Y: y + dy})
+			}
+		}
+	}
+	return neighbors
+}
@@ -0,0 +1,184 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/engine/handlers"
+	"overseer/server"
+	"overseer/storage"
+	"path"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type UtteranceTest struct {
+	db     *gorm.DB
+	dbFile string
+	suite.Suite
+}
+
+func TestUtterance(t *testing.T) {
+	suite.Run(t, new(UtteranceTest))
+}
+
+func (s *UtteranceTest) SetupTest() {
+	s.dbFile = path.Join(os.TempDir(), fmt.Sprintf("test-%s.db", uuid.NewString()))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *UtteranceTest) TearDownTest() {
+	s.Require().NoError(os.Remove(s.dbFile), "failed to remove test database")
+	s.db = nil
+}
+
+func (s *UtteranceTest) TestRecipients() {
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	eventStore := storage.NewSqlEventStore(s.db)
+	mapStore := storage.NewSqlMapStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	gamesSrv := server.NewGameServer(usersSrv, storage.NewSqlLockStore(s.db), gamesStore, mapStore)
+	eventSrv := server.NewEventServer(engine.NewEventBus(
+		[]engine.EventHandler{
+			engine.Register(handlers.NewUtteranceValidator(eventStore), engine.StageValidate, 0),
+			engine.Register(handlers.NewUtteranceHandler(gamesStore, eventStore), engine.StageMutate, 0),
+		},
+		gamesSrv,
+		usersSrv,
+		eventStore,
+		engine.NewReceiptBroker(),
+	), eventStore, gamesStore)
+
+	user := &v1.User{Uid: "test"}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{User: user})
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err := usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err)
+	actors := make(map[string]*v1.Actor)
+	for _, name := range []string{"frodo", "sam", "gollum", "stranger"} {
+		actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{UserId: user.Uid, SourceIdentity: name, Source: v1.Actor_APP_DISCORD})
+		s.Require().NoError(err)
+		actors[name] = actor
+	}
+	ctx, _ = common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: actors["frodo"]})
+	game, err := gamesSrv.CreateGame(ctx, &v1.CreateGameRequest{
+		Name:         "test game",
+		Participants: []*v1.Actor{actors["frodo"], actors["sam"], actors["gollum"]},
+	})
+	s.Require().NoError(err)
+
+	uids := func(names ...string) []string {
+		result := make([]string, 0, len(names))
+		for _, name := range names {
+			result = append(result, actors[name].Uid)
+		}
+		return result
+	}
+
+	for _, tc := range []struct {
+		name       string
+		utterance  *v1.UtteranceInteraction
+		audience   v1.UtteranceEffect_Audience
+		whisper    bool
+		recipients []string
+		rejection  string
+	}{
+		{
+			name:       "spoken to the table",
+			utterance:  &v1.UtteranceInteraction{Content: "good morning"},
+			audience:   v1.UtteranceEffect_TABLE,
+			recipients: uids("sam", "gollum"),
+		},
+		{
+			name:       "whispered to the table",
+			utterance:  &v1.UtteranceInteraction{Content: "keep your voices down", Utterance: &v1.UtteranceInteraction_Table{Table: &v1.TableUtterance{Whisper: true}}},
+			audience:   v1.UtteranceEffect_TABLE,
+			whisper:    true,
+			recipients: uids("sam", "gollum"),
+		},
+		{
+			name:       "spoken to a player",
+			utterance:  &v1.UtteranceInteraction{Content: "come along sam", Utterance: &v1.UtteranceInteraction_Player{Player: &v1.PlayerUtterance{Target: actors["sam"]}}},
+			audience:   v1.UtteranceEffect_PLAYER,
+			recipients: uids("sam"),
+		},
+		{
+			name:       "whispered to a player",
+			utterance:  &v1.UtteranceInteraction{Content: "the ring is in my pocket", Utterance: &v1.UtteranceInteraction_Player{Player: &v1.PlayerUtterance{Target: actors["sam"], Whisper: true}}},
+			audience:   v1.UtteranceEffect_PLAYER,
+			whisper:    true,
+			recipients: uids("sam"),
+		},
+		{
+			name:      "whispered to someone who is not playing",
+			utterance: &v1.UtteranceInteraction{Content: "psst", Utterance: &v1.UtteranceInteraction_Player{Player: &v1.PlayerUtterance{Target: actors["stranger"], Whisper: true}}},
+			rejection: "there is nobody by that name at the table",
+		},
+		{
+			name:      "whispered to yourself",
+			utterance: &v1.UtteranceInteraction{Content: "my precious", Utterance: &v1.UtteranceInteraction_Player{Player: &v1.PlayerUtterance{Target: actors["frodo"], Whisper: true}}},
+			rejection: "you mutter quietly to yourself",
+		},
+		{
+			name:       "whispered to several players",
+			utterance:  &v1.UtteranceInteraction{Content: "run", Utterance: &v1.UtteranceInteraction_Players{Players: &v1.PlayersUtterance{Targets: []*v1.Actor{actors["sam"], actors["frodo"], actors["gollum"]}, Whisper: true}}},
+			audience:   v1.UtteranceEffect_PLAYERS,
+			whisper:    true,
+			recipients: uids("sam", "gollum"),
+		},
+		{
+			name:      "spoken to several players including someone who is not playing",
+			utterance: &v1.UtteranceInteraction{Content: "run", Utterance: &v1.UtteranceInteraction_Players{Players: &v1.PlayersUtterance{Targets: []*v1.Actor{actors["sam"], actors["stranger"]}}}},
+			rejection: "there is nobody by that name at the table",
+		},
+		{
+			name:      "spoken to an empty list of players",
+			utterance: &v1.UtteranceInteraction{Content: "anyone?", Utterance: &v1.UtteranceInteraction_Players{Players: &v1.PlayersUtterance{}}},
+			rejection: "you must address at least one player",
+		},
+		{
+			name:       "whispered to the dungeon master",
+			utterance:  &v1.UtteranceInteraction{Content: "I pocket the ring", Utterance: &v1.UtteranceInteraction_DungeonMaster{DungeonMaster: &v1.DungeonMasterUtterance{Whisper: true}}},
+			audience:   v1.UtteranceEffect_DUNGEON_MASTER,
+			whisper:    true,
+			recipients: []string{},
+		},
+	} {
+		s.Run(tc.name, func() {
+			receipts, err := eventSrv.Submit(ctx, &v1.Event{
+				GameUid: game.Uid,
+				Actor:   actors["frodo"],
+				Origin:  &v1.Event_Discord{Discord: &v1.EventOriginDiscord{Guild: "test", Channel: "test"}},
+				Payload: &v1.Event_Interaction{Interaction: &v1.InteractionEvent{
+					Interaction: &v1.InteractionEvent_Utterance{Utterance: tc.utterance},
+				}},
+			})
+			s.Require().NoError(err)
+			s.Require().Len(receipts.Receipts, 1)
+			receipt := receipts.Receipts[0]
+
+			if tc.rejection != "" {
+				s.Equal(v1.ErrorEffect_INVALID, receipt.GetError().GetType())
+				s.Equal(tc.rejection, receipt.GetError().GetMessage())
+				return
+			}
+			utterance := receipt.GetUtterance()
+			s.Require().NotNil(utterance, "the utterance should be heard")
+			s.Equal(actors["frodo"].Uid, utterance.GetActor())
+			s.Equal(tc.utterance.GetContent(), utterance.GetContent())
+			s.Equal(tc.audience, utterance.GetAudience())
+			s.Equal(tc.whisper, utterance.GetWhisper())
+			s.Equal(tc.recipients, utterance.GetRecipients(), "the speaker should never be among the recipients")
+		})
+	}
+}
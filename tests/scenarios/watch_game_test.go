@@ -0,0 +1,198 @@
+package scenarios
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"overseer/auth"
+	v1 "overseer/build/go"
+	"overseer/common"
+	"overseer/engine"
+	"overseer/engine/handlers"
+	"overseer/server"
+	"overseer/storage"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"gorm.io/gorm"
+)
+
+type WatchGameTest struct {
+	db     *gorm.DB
+	dbFile string
+	suite.Suite
+}
+
+func TestWatchGame(t *testing.T) {
+	suite.Run(t, new(WatchGameTest))
+}
+
+func (s *WatchGameTest) SetupTest() {
+	s.dbFile = path.Join(os.TempDir(), fmt.Sprintf("test-%s.db", uuid.NewString()))
+	db, err := storage.NewSqliteDB(s.dbFile, true)
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *WatchGameTest) TearDownTest() {
+	s.Require().NoError(os.Remove(s.dbFile), "failed to remove test database")
+	s.db = nil
+}
+
+// fakeWatchStream collects everything sent to a watcher
+type fakeWatchStream struct {
+	grpc.ServerStream
+	ctx        context.Context
+	mu         sync.Mutex
+	receipts   []*v1.EventReceipt
+	subscribed bool
+}
+
+func (f *fakeWatchStream) SendHeader(metadata.MD) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subscribed = true
+	return nil
+}
+
+func (f *fakeWatchStream) isSubscribed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.subscribed
+}
+
+func (f *fakeWatchStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeWatchStream) Send(receipt *v1.EventReceipt) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.receipts = append(f.receipts, receipt)
+	return nil
+}
+
+func (f *fakeWatchStream) contents() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return common.Filter(f.receipts, func(r *v1.EventReceipt) string {
+		return r.GetUtterance().GetContent()
+	})
+}
+
+func (s *WatchGameTest) TestWatchingAGame() {
+	userStore := storage.NewSqlUserStore(s.db)
+	gamesStore := storage.NewSqlGameStore(s.db, userStore)
+	eventStore := storage.NewSqlEventStore(s.db)
+	lockStore := storage.NewSqlLockStore(s.db)
+	usersSrv := server.NewUserServer(userStore, storage.NewSqlApiKeyStore(s.db), nil)
+	gamesSrv := server.NewGameServer(usersSrv, lockStore, gamesStore, storage.NewSqlMapStore(s.db))
+	eventBus := engine.NewEventBus(
+		[]engine.EventHandler{
+			handlers.NewUtteranceHandler(gamesStore, eventStore),
+		},
+		gamesSrv,
+		usersSrv,
+		eventStore,
+		engine.NewReceiptBroker(),
+	)
+	eventSrv := server.NewEventServer(eventBus, eventStore, gamesStore)
+	user := &v1.User{
+		Uid: "test",
+	}
+	ctx, _ := common.SetContextInformation(context.Background(), &common.OverseerContextInformation{
+		User: user,
+	})
+
+	systemCtx, _ := auth.SystemContext(context.Background())
+	_, err := usersSrv.RegisterUser(ctx, user)
+	s.Require().NoError(err, "error should be nil on creating user")
+
+	actors := make([]*v1.Actor, 0)
+	for _, name := range []string{"frodo", "sam", "pippin"} {
+		actor, err := usersSrv.RegisterActor(systemCtx, &v1.RegisterActorRequest{
+			UserId:         user.Uid,
+			SourceIdentity: name,
+			Source:         v1.Actor_APP_DISCORD,
+		})
+		s.Require().NoError(err, "error should be nil on creating actor")
+		actors = append(actors, actor)
+	}
+	frodo, sam, pippin := actors[0], actors[1], actors[2]
+
+	frodoCtx, _ := common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: frodo})
+	samCtx, _ := common.SetContextInformation(ctx, &common.OverseerContextInformation{User: user, Actor: sam})
+
+	game, err := gamesSrv.CreateGame(frodoCtx, &v1.CreateGameRequest{
+		Name:         "test game",
+		Participants: actors,
+	})
+	s.Require().NoError(err, "error should be nil")
+
+	say := func(content string, utterance *v1.UtteranceInteraction) {
+		utterance.Content = content
+		_, err := eventSrv.Submit(frodoCtx, &v1.Event{
+			GameUid: game.Uid,
+			Actor:   frodo,
+			Origin:  &v1.Event_Discord{Discord: &v1.EventOriginDiscord{Guild: "test", Channel: "test"}},
+			Payload: &v1.Event_Interaction{Interaction: &v1.InteractionEvent{
+				Interaction: &v1.InteractionEvent_Utterance{Utterance: utterance},
+			}},
+		})
+		s.Require().NoError(err, "error should be nil on submitting an utterance")
+	}
+	watch := func(ctx context.Context, req *v1.WatchGameRequest) (*fakeWatchStream, context.CancelFunc, chan error) {
+		watchCtx, cancel := context.WithCancel(ctx)
+		stream := &fakeWatchStream{ctx: watchCtx}
+		done := make(chan error, 1)
+		go func() {
+			done <- eventSrv.WatchGame(req, stream)
+		}()
+		return stream, cancel, done
+	}
+
+	stream, cancel, done := watch(samCtx, &v1.WatchGameRequest{GameUid: game.Uid})
+	systemStream, systemCancel, systemDone := watch(systemCtx, &v1.WatchGameRequest{GameUid: game.Uid})
+	s.Eventually(func() bool {
+		return stream.isSubscribed() && systemStream.isSubscribed()
+	}, time.Second, 10*time.Millisecond, "headers should be sent once the watchers are subscribed")
+
+	say("hello everyone", &v1.UtteranceInteraction{})
+	say("psst sam", &v1.UtteranceInteraction{Utterance: &v1.UtteranceInteraction_Player{Player: &v1.PlayerUtterance{Target: sam, Whisper: true}}})
+	say("psst pippin", &v1.UtteranceInteraction{Utterance: &v1.UtteranceInteraction_Player{Player: &v1.PlayerUtterance{Target: pippin, Whisper: true}}})
+	say("goodbye", &v1.UtteranceInteraction{})
+
+	s.Eventually(func() bool {
+		return len(stream.contents()) == 3
+	}, time.Second, 10*time.Millisecond, "sam should see everything said to the table and their own whispers")
+	s.Equal([]string{"hello everyone", "psst sam", "goodbye"}, stream.contents())
+	cancel()
+	s.NoError(<-done, "cancelling the watch should end the stream cleanly")
+
+	s.Eventually(func() bool {
+		return len(systemStream.contents()) == 4
+	}, time.Second, 10*time.Millisecond, "the system should see every whisper so front ends can deliver them")
+	systemCancel()
+	s.NoError(<-systemDone)
+
+	// resume after the first receipt as if sam had reconnected
+	first := stream.receipts[0].Uid
+	resumed, cancel, done := watch(samCtx, &v1.WatchGameRequest{GameUid: game.Uid, AfterReceiptUid: &first})
+	s.Eventually(func() bool {
+		return len(resumed.contents()) == 2
+	}, time.Second, 10*time.Millisecond, "receipts after the resume point should be replayed")
+	s.Equal([]string{"psst sam", "goodbye"}, resumed.contents())
+	cancel()
+	s.NoError(<-done)
+
+	missing := "missing"
+	_, cancel, done = watch(samCtx, &v1.WatchGameRequest{GameUid: game.Uid, AfterReceiptUid: &missing})
+	s.Error(<-done, "resuming from an unknown receipt should fail")
+	cancel()
+}